@@ -0,0 +1,279 @@
+package signer
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	tmBytes "github.com/tendermint/tendermint/libs/bytes"
+)
+
+const (
+	// AuditSignerCosigner marks the share signatures of a LocalCosigner
+	AuditSignerCosigner = "cosigner"
+	// AuditSignerValidator marks the block signatures combined by a ThresholdValidator
+	AuditSignerValidator = "validator"
+)
+
+// AuditRecord is a line of the audit log
+//
+// Hash covers the JSON encoding of the record without its hash, PrevHash included,
+// so changing or removing a record breaks the chain of the records after it.
+type AuditRecord struct {
+	Seq           uint64           `json:"seq"`
+	Time          time.Time        `json:"time"`
+	Signer        string           `json:"signer"`
+	CosignerID    int              `json:"cosigner_id"`
+	ChainID       string           `json:"chain_id,omitempty"`
+	Height        int64            `json:"height"`
+	Round         int64            `json:"round"`
+	Step          int8             `json:"step"`
+	SignBytesHash tmBytes.HexBytes `json:"sign_bytes_hash"`
+	Signature     tmBytes.HexBytes `json:"signature"`
+	// IDs of the cosigners whose ephemeral secret parts or share signatures were combined
+	Cosigners []int `json:"cosigners"`
+	// Node or cosigner that requested the signature
+	Source   string           `json:"source,omitempty"`
+	PrevHash tmBytes.HexBytes `json:"prev_hash"`
+	Hash     tmBytes.HexBytes `json:"hash,omitempty"`
+}
+
+// computeHash returns the hash of the record without its own hash
+func (record AuditRecord) computeHash() ([]byte, error) {
+	record.Hash = nil
+	recordBytes, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(recordBytes)
+	return hash[:], nil
+}
+
+// AuditLog is an append-only, hash-chained log of the signatures produced by a process
+// Every record is synced to disk before the signature it describes is handed out.
+type AuditLog struct {
+	mtx      sync.Mutex
+	file     string
+	out      *os.File
+	seq      uint64
+	lastHash []byte
+}
+
+// OpenAuditLog verifies the records of the audit log at file and opens it for appending
+// A last record cut short by a crash is removed, the signature it described was never handed out.
+func OpenAuditLog(file string) (*AuditLog, error) {
+	records, validSize, err := readAuditLog(file)
+	if err != nil {
+		return nil, err
+	}
+	if err := VerifyAuditLog(records); err != nil {
+		return nil, fmt.Errorf("Audit log %v is corrupted: %v", file, err)
+	}
+
+	out, err := os.OpenFile(file, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	info, err := out.Stat()
+	if err != nil {
+		out.Close()
+		return nil, err
+	}
+	if info.Size() > validSize {
+		logger.Info("Removing the truncated last record of the audit log", "file", file)
+		if err := out.Truncate(validSize); err != nil {
+			out.Close()
+			return nil, err
+		}
+	}
+	if _, err := out.Seek(validSize, 0); err != nil {
+		out.Close()
+		return nil, err
+	}
+
+	auditLog := &AuditLog{file: file, out: out}
+	if len(records) > 0 {
+		last := records[len(records)-1]
+		auditLog.seq = last.Seq
+		auditLog.lastHash = last.Hash
+	}
+	return auditLog, nil
+}
+
+// Append chains the record to the previous one and waits for it to reach the disk
+func (auditLog *AuditLog) Append(record AuditRecord) error {
+	auditLog.mtx.Lock()
+	defer auditLog.mtx.Unlock()
+
+	record.Seq = auditLog.seq + 1
+	if record.Time.IsZero() {
+		record.Time = time.Now()
+	}
+	record.Time = record.Time.UTC()
+	if record.Cosigners == nil {
+		record.Cosigners = []int{}
+	}
+	record.PrevHash = auditLog.lastHash
+	hash, err := record.computeHash()
+	if err != nil {
+		return err
+	}
+	record.Hash = hash
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if _, err := auditLog.out.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	if err := auditLog.out.Sync(); err != nil {
+		return err
+	}
+
+	auditLog.seq = record.Seq
+	auditLog.lastHash = hash
+	return nil
+}
+
+// Close closes the audit log file
+func (auditLog *AuditLog) Close() error {
+	auditLog.mtx.Lock()
+	defer auditLog.mtx.Unlock()
+	return auditLog.out.Close()
+}
+
+// ReadAuditLog returns the records of the audit log at file, without verifying them
+// A last record cut short by a crash is left out.
+func ReadAuditLog(file string) ([]AuditRecord, error) {
+	records, _, err := readAuditLog(file)
+	return records, err
+}
+
+// readAuditLog returns the records of the audit log and the size of the file they take
+func readAuditLog(file string) ([]AuditRecord, int64, error) {
+	records := make([]AuditRecord, 0)
+
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return records, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var offset int64
+	for lineNumber := 1; len(data) > 0; lineNumber++ {
+		end := bytes.IndexByte(data, '\n')
+		// only a complete line was synced, what follows the last newline was never handed out
+		if end < 0 {
+			break
+		}
+
+		var record AuditRecord
+		if err := json.Unmarshal(data[:end], &record); err != nil {
+			return nil, 0, fmt.Errorf("Invalid record on line %d of %v: %v", lineNumber, file, err)
+		}
+		records = append(records, record)
+
+		offset += int64(end + 1)
+		data = data[end+1:]
+	}
+	return records, offset, nil
+}
+
+// VerifyAuditLog checks the sequence numbers and the hash chain of the records
+func VerifyAuditLog(records []AuditRecord) error {
+	var prevHash []byte
+	for idx, record := range records {
+		if record.Seq != uint64(idx+1) {
+			return fmt.Errorf("record %d has sequence number %d", idx+1, record.Seq)
+		}
+		if !bytes.Equal(record.PrevHash, prevHash) {
+			return fmt.Errorf("record %d is not chained to the previous record", record.Seq)
+		}
+		hash, err := record.computeHash()
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(record.Hash, hash) {
+			return fmt.Errorf("record %d doesn't match its hash", record.Seq)
+		}
+		prevHash = record.Hash
+	}
+	return nil
+}
+
+// AuditFilter selects audit records, the zero value matches all of them
+type AuditFilter struct {
+	MinHeight     int64
+	MaxHeight     int64
+	Round         *int64
+	Step          int8
+	Signer        string
+	CosignerID    int
+	ChainID       string
+	Source        string
+	SignBytesHash []byte
+}
+
+// Match returns true if the record passes all the criteria of the filter
+func (filter AuditFilter) Match(record AuditRecord) bool {
+	switch {
+	case filter.MinHeight != 0 && record.Height < filter.MinHeight:
+		return false
+	case filter.MaxHeight != 0 && record.Height > filter.MaxHeight:
+		return false
+	case filter.Round != nil && record.Round != *filter.Round:
+		return false
+	case filter.Step != stepNone && record.Step != filter.Step:
+		return false
+	case filter.Signer != "" && record.Signer != filter.Signer:
+		return false
+	case filter.CosignerID != 0 && record.CosignerID != filter.CosignerID:
+		return false
+	case filter.ChainID != "" && record.ChainID != filter.ChainID:
+		return false
+	case filter.Source != "" && record.Source != filter.Source:
+		return false
+	case len(filter.SignBytesHash) > 0 && !bytes.Equal(record.SignBytesHash, filter.SignBytesHash):
+		return false
+	}
+	return true
+}
+
+// newAuditRecord returns the record of a signature of signBytes
+func newAuditRecord(ctx context.Context, signer string, cosignerID int, height int64, round int64, step int8, signBytes []byte, signature []byte, cosigners []int) AuditRecord {
+	signBytesHash := sha256.Sum256(signBytes)
+	return AuditRecord{
+		Signer:        signer,
+		CosignerID:    cosignerID,
+		Height:        height,
+		Round:         round,
+		Step:          step,
+		SignBytesHash: signBytesHash[:],
+		Signature:     signature,
+		Cosigners:     cosigners,
+		Source:        SignSourceFromContext(ctx),
+	}
+}
+
+type signSourceContextKey struct{}
+
+// ContextWithSignSource returns a context carrying the node or cosigner a sign request came from
+// It is recorded in the audit log of the signatures made for the request.
+func ContextWithSignSource(ctx context.Context, source string) context.Context {
+	return context.WithValue(ctx, signSourceContextKey{}, source)
+}
+
+// SignSourceFromContext returns the node or cosigner a sign request came from, if known
+func SignSourceFromContext(ctx context.Context) string {
+	source, _ := ctx.Value(signSourceContextKey{}).(string)
+	return source
+}
@@ -0,0 +1,167 @@
+package signer
+
+import (
+	"crypto/sha256"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	tmProto "github.com/tendermint/tendermint/proto/tendermint/types"
+	tm "github.com/tendermint/tendermint/types"
+)
+
+func TestAuditLog(test *testing.T) {
+	dir, err := ioutil.TempDir("", "audit-log")
+	require.NoError(test, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "audit.jsonl")
+	auditLog, err := OpenAuditLog(file)
+	require.NoError(test, err)
+	for height := int64(1); height <= 3; height++ {
+		require.NoError(test, auditLog.Append(AuditRecord{Signer: AuditSignerCosigner, Height: height, Signature: []byte{1}}))
+	}
+	require.NoError(test, auditLog.Close())
+
+	// a reopened log continues the chain
+	auditLog, err = OpenAuditLog(file)
+	require.NoError(test, err)
+	require.NoError(test, auditLog.Append(AuditRecord{Signer: AuditSignerValidator, Height: 4, Cosigners: []int{1, 3}}))
+	require.NoError(test, auditLog.Close())
+
+	records, err := ReadAuditLog(file)
+	require.NoError(test, err)
+	require.Len(test, records, 4)
+	require.NoError(test, VerifyAuditLog(records))
+	require.Equal(test, uint64(4), records[3].Seq)
+	require.Equal(test, records[2].Hash, records[3].PrevHash)
+
+	// a record cut short by a crash is removed when the log is opened
+	data, err := ioutil.ReadFile(file)
+	require.NoError(test, err)
+	require.NoError(test, ioutil.WriteFile(file, append(data, []byte(`{"seq":5,"ti`)...), 0600))
+	records, err = ReadAuditLog(file)
+	require.NoError(test, err)
+	require.Len(test, records, 4)
+	auditLog, err = OpenAuditLog(file)
+	require.NoError(test, err)
+	require.NoError(test, auditLog.Append(AuditRecord{Height: 5}))
+	require.NoError(test, auditLog.Close())
+	records, err = ReadAuditLog(file)
+	require.NoError(test, err)
+	require.NoError(test, VerifyAuditLog(records))
+	require.Len(test, records, 5)
+
+	// changing a record breaks the chain
+	data, err = ioutil.ReadFile(file)
+	require.NoError(test, err)
+	tampered := strings.Replace(string(data), `"height":2,`, `"height":20,`, 1)
+	require.NotEqual(test, string(data), tampered)
+	require.NoError(test, ioutil.WriteFile(file, []byte(tampered), 0600))
+	records, err = ReadAuditLog(file)
+	require.NoError(test, err)
+	require.Error(test, VerifyAuditLog(records))
+	_, err = OpenAuditLog(file)
+	require.Error(test, err)
+
+	// and so does removing one
+	require.Error(test, VerifyAuditLog(append(records[:1:1], records[2:]...)))
+}
+
+func TestAuditFilter(test *testing.T) {
+	round := int64(1)
+	record := AuditRecord{
+		Signer:        AuditSignerValidator,
+		CosignerID:    2,
+		ChainID:       "chain-id",
+		Height:        10,
+		Round:         1,
+		Step:          stepPrecommit,
+		SignBytesHash: []byte{1, 2},
+		Source:        "tcp://node:1234",
+	}
+
+	require.True(test, AuditFilter{}.Match(record))
+	require.True(test, AuditFilter{MinHeight: 10, MaxHeight: 10, Round: &round, Step: stepPrecommit, Signer: AuditSignerValidator,
+		CosignerID: 2, ChainID: "chain-id", Source: "tcp://node:1234", SignBytesHash: []byte{1, 2}}.Match(record))
+
+	require.False(test, AuditFilter{MinHeight: 11}.Match(record))
+	require.False(test, AuditFilter{MaxHeight: 9}.Match(record))
+	round = 0
+	require.False(test, AuditFilter{Round: &round}.Match(record))
+	require.False(test, AuditFilter{Step: stepPrevote}.Match(record))
+	require.False(test, AuditFilter{Signer: AuditSignerCosigner}.Match(record))
+	require.False(test, AuditFilter{Source: "tcp://other:1234"}.Match(record))
+	require.False(test, AuditFilter{SignBytesHash: []byte{2}}.Match(record))
+
+	step, err := ParseStep("precommit")
+	require.NoError(test, err)
+	require.Equal(test, stepPrecommit, step)
+	require.Equal(test, "precommit", StepName(step))
+	_, err = ParseStep("none")
+	require.Error(test, err)
+}
+
+func TestThresholdValidatorAuditLog(test *testing.T) {
+	privateKey, cosigners, cleanup := newTestCosigners(test, 2, 3)
+	defer cleanup()
+
+	dir, err := ioutil.TempDir("", "audit-log")
+	require.NoError(test, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "audit.jsonl")
+	auditLog, err := OpenAuditLog(file)
+	require.NoError(test, err)
+	defer auditLog.Close()
+	cosigners[0].auditLog = auditLog
+
+	stateFile, err := ioutil.TempFile("", "priv_validator_state.json")
+	require.NoError(test, err)
+	defer os.Remove(stateFile.Name())
+	signState, err := LoadOrCreateSignState(stateFile.Name())
+	require.NoError(test, err)
+
+	validator := NewThresholdValidator(&ThresholdValidatorOpt{
+		Pubkey:    privateKey.PubKey(),
+		Threshold: 2,
+		SignState: signState,
+		Cosigner:  cosigners[0],
+		Peers:     []Cosigner{cosigners[1], cosigners[2]},
+		AuditLog:  auditLog,
+	})
+
+	exchangeAllEphemeralSecretParts(test, cosigners, 1, 0, stepPropose)
+	proposal := &tmProto.Proposal{
+		Height: 1,
+		Type:   tmProto.ProposalType,
+	}
+	pv := &PvGuard{PrivValidator: validator}
+	require.NoError(test, pv.SignProposalFrom("tcp://node:1234", "chain-id", proposal))
+
+	records, err := ReadAuditLog(file)
+	require.NoError(test, err)
+	require.NoError(test, VerifyAuditLog(records))
+	require.Len(test, records, 2)
+
+	// our share signature comes first, then the block signature it went into
+	signBytesHash := sha256.Sum256(tm.ProposalSignBytes("chain-id", proposal))
+	share, block := records[0], records[1]
+	require.Equal(test, AuditSignerCosigner, share.Signer)
+	require.Equal(test, "tcp://node:1234", share.Source)
+	require.Equal(test, []int{1, 2, 3}, share.Cosigners)
+	require.Equal(test, signBytesHash[:], []byte(share.SignBytesHash))
+
+	require.Equal(test, AuditSignerValidator, block.Signer)
+	require.Equal(test, 1, block.CosignerID)
+	require.Equal(test, "chain-id", block.ChainID)
+	require.Equal(test, "tcp://node:1234", block.Source)
+	require.Equal(test, int64(1), block.Height)
+	require.Equal(test, stepPropose, block.Step)
+	require.Equal(test, signBytesHash[:], []byte(block.SignBytesHash))
+	require.Equal(test, proposal.Signature, []byte(block.Signature))
+	require.GreaterOrEqual(test, len(block.Cosigners), 2)
+}
@@ -1,30 +1,245 @@
 package signer
 
 import (
+	"errors"
+	"fmt"
+	"net"
 	"os"
+	"path"
+	"time"
 
 	"github.com/BurntSushi/toml"
+	tmCrypto "github.com/tendermint/tendermint/crypto"
+	tmnet "github.com/tendermint/tendermint/libs/net"
+)
+
+const (
+	// NodeModeDial dials the privval address of the node, the default
+	NodeModeDial = "dial"
+	// NodeModeListen listens on the address for the node to dial in
+	NodeModeListen = "listen"
 )
 
 type NodeConfig struct {
 	Address string `toml:"address"`
+	// dial (default) or listen
+	Mode string `toml:"mode"`
+	// Base64 ed25519 public keys of the SecretConnection of the nodes allowed to connect in listen mode
+	AllowedKeys []string `toml:"allowed_keys"`
+	// Base64 ed25519 public key of the SecretConnection of the node dialed in dial mode
+	PubKey string `toml:"pub_key"`
+	// Privval protocol of the node: v0.34 (default), v0.37 or v0.38
+	Protocol string `toml:"protocol"`
+}
+
+// allowedKeys returns the public keys the SecretConnection of the node may present, none if any is accepted
+func (cfg NodeConfig) allowedKeys() ([]tmCrypto.PubKey, error) {
+	keys := cfg.AllowedKeys
+	if cfg.PubKey != "" {
+		keys = []string{cfg.PubKey}
+	}
+	pubKeys := make([]tmCrypto.PubKey, 0, len(keys))
+	for _, key := range keys {
+		pubKey, err := ParseNodePubKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("node %v: %v", cfg.Address, err)
+		}
+		pubKeys = append(pubKeys, pubKey)
+	}
+	return pubKeys, nil
+}
+
+// Validate returns an error if the privval connection of the node can't be set up with the config
+func (cfg NodeConfig) Validate() error {
+	if cfg.Address == "" {
+		return errors.New("node address is required")
+	}
+	switch cfg.Mode {
+	case "", NodeModeDial:
+		if len(cfg.AllowedKeys) > 0 {
+			return fmt.Errorf("node %v: allowed_keys only applies to listen mode, use pub_key", cfg.Address)
+		}
+	case NodeModeListen:
+		if cfg.PubKey != "" {
+			return fmt.Errorf("node %v: pub_key only applies to dial mode, use allowed_keys", cfg.Address)
+		}
+		// anyone reaching the address could otherwise send sign requests
+		if len(cfg.AllowedKeys) == 0 {
+			return fmt.Errorf("node %v: allowed_keys is required in listen mode", cfg.Address)
+		}
+	default:
+		return fmt.Errorf("node %v: unknown mode %q", cfg.Address, cfg.Mode)
+	}
+	switch cfg.Protocol {
+	case "", PrivvalProtocolV034, PrivvalProtocolV037, PrivvalProtocolV038:
+	default:
+		return fmt.Errorf("node %v: unknown privval protocol %q", cfg.Address, cfg.Protocol)
+	}
+	_, err := cfg.allowedKeys()
+	return err
 }
 
 type CosignerConfig struct {
 	ID      int    `toml:"id"`
 	Address string `toml:"remote_address"`
+	TLSName string `toml:"tls_name"`
+	// Address of the raft transport of the cosigner, when the cosigners elect a leader
+	RaftAddress string `toml:"raft_address"`
+}
+
+// TLSServerName returns the name expected in the certificate of the cosigner
+// It defaults to the host of the remote address
+func (cfg CosignerConfig) TLSServerName() string {
+	if cfg.TLSName != "" {
+		return cfg.TLSName
+	}
+	_, address := tmnet.ProtocolAndAddress(cfg.Address)
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return address
+	}
+	return host
 }
 
 type Config struct {
 	Mode              string           `toml:"mode"`
 	Moniker           string           `toml:"moniker"`
 	PrivValKeyFile    string           `toml:"key_file"`
+	KeyPassphraseFile string           `toml:"key_passphrase_file"`
+	KeyBackend        string           `toml:"key_backend"`
+	PKCS11            PKCS11Config     `toml:"pkcs11"`
 	PrivValStateDir   string           `toml:"state_dir"`
+	SignerKeyFile     string           `toml:"signer_key_file"`
+	SignState         SignStateConfig  `toml:"sign_state"`
 	ChainID           string           `toml:"chain_id"`
 	CosignerThreshold int              `toml:"cosigner_threshold"`
 	ListenAddress     string           `toml:"cosigner_listen_address"`
 	Nodes             []NodeConfig     `toml:"node"`
 	Cosigners         []CosignerConfig `toml:"cosigner"`
+	TLS               TLSConfig        `toml:"tls"`
+	Signing           SigningConfig    `toml:"signing"`
+	Chains            []ChainConfig    `toml:"chain"`
+	Raft              RaftConfig       `toml:"raft"`
+}
+
+// SignerKeyPath returns the file of the key the signer presents on the SecretConnection to the nodes
+// It defaults to signer_key.json in the state directory.
+func (config Config) SignerKeyPath() string {
+	if config.SignerKeyFile != "" {
+		return config.SignerKeyFile
+	}
+	return path.Join(config.PrivValStateDir, "signer_key.json")
+}
+
+// ChainConfig is a validator hosted by the cosigner, next to the validators of other chains
+// Unset options default to the ones at the top of the config, except for the chain ID and the nodes.
+type ChainConfig struct {
+	ChainID           string       `toml:"chain_id"`
+	PrivValKeyFile    string       `toml:"key_file"`
+	KeyPassphraseFile string       `toml:"key_passphrase_file"`
+	KeyBackend        string       `toml:"key_backend"`
+	PKCS11            PKCS11Config `toml:"pkcs11"`
+	PrivValStateDir   string       `toml:"state_dir"`
+	CosignerThreshold int          `toml:"cosigner_threshold"`
+	Nodes             []NodeConfig `toml:"node"`
+}
+
+// ChainConfigs returns the config of every validator hosted by the cosigner, without [[chain]] sections
+// A config without [[chain]] sections hosts the validator of its own chain_id.
+func (config Config) ChainConfigs() ([]Config, error) {
+	if len(config.Chains) == 0 {
+		if config.ChainID == "" {
+			return nil, errors.New("chain_id option is required")
+		}
+		return []Config{config}, nil
+	}
+	if config.ChainID != "" {
+		return nil, errors.New("chain_id must be set in the [[chain]] sections only")
+	}
+	if len(config.Nodes) > 0 {
+		return nil, errors.New("nodes must be set in the [[chain]] sections only")
+	}
+
+	chainConfigs := make([]Config, 0, len(config.Chains))
+	seen := make(map[string]bool)
+	for _, chain := range config.Chains {
+		if chain.ChainID == "" {
+			return nil, errors.New("chain_id option is required in every [[chain]] section")
+		}
+		if seen[chain.ChainID] {
+			return nil, fmt.Errorf("chain %v is configured twice", chain.ChainID)
+		}
+		seen[chain.ChainID] = true
+
+		chainConfig := config
+		chainConfig.Chains = nil
+		chainConfig.ChainID = chain.ChainID
+		chainConfig.Nodes = chain.Nodes
+		if chain.PrivValKeyFile != "" {
+			chainConfig.PrivValKeyFile = chain.PrivValKeyFile
+		}
+		if chain.KeyPassphraseFile != "" {
+			chainConfig.KeyPassphraseFile = chain.KeyPassphraseFile
+		}
+		if chain.KeyBackend != "" {
+			chainConfig.KeyBackend = chain.KeyBackend
+		}
+		if chain.PKCS11.Library != "" {
+			chainConfig.PKCS11.Library = chain.PKCS11.Library
+		}
+		if chain.PKCS11.TokenLabel != "" {
+			chainConfig.PKCS11.TokenLabel = chain.PKCS11.TokenLabel
+		}
+		if chain.PKCS11.KeyLabel != "" {
+			chainConfig.PKCS11.KeyLabel = chain.PKCS11.KeyLabel
+		}
+		if chain.PKCS11.PinFile != "" {
+			chainConfig.PKCS11.PinFile = chain.PKCS11.PinFile
+		}
+		if chain.PrivValStateDir != "" {
+			chainConfig.PrivValStateDir = chain.PrivValStateDir
+		}
+		if chain.CosignerThreshold != 0 {
+			chainConfig.CosignerThreshold = chain.CosignerThreshold
+		}
+		chainConfigs = append(chainConfigs, chainConfig)
+	}
+	return chainConfigs, nil
+}
+
+// ForChain returns the config of the validator of chainID, or of the only validator if chainID is empty
+func (config Config) ForChain(chainID string) (Config, error) {
+	chainConfigs, err := config.ChainConfigs()
+	if err != nil {
+		return config, err
+	}
+	if chainID == "" {
+		if len(chainConfigs) > 1 {
+			return config, errors.New("the cosigner hosts several chains, pick one")
+		}
+		return chainConfigs[0], nil
+	}
+	for _, chainConfig := range chainConfigs {
+		if chainConfig.ChainID == chainID {
+			return chainConfig, nil
+		}
+	}
+	return config, fmt.Errorf("chain %v is not configured", chainID)
+}
+
+// Duration is a time.Duration read from a toml string such as "1s" or "500ms"
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	var err error
+	d.Duration, err = time.ParseDuration(string(text))
+	return err
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.Duration.String()), nil
 }
 
 func LoadConfigFromFile(file string) (Config, error) {
@@ -32,6 +247,7 @@ func LoadConfigFromFile(file string) (Config, error) {
 
 	// default mode is mpc
 	config.Mode = "mpc"
+	config.Signing = DefaultSigningConfig()
 
 	reader, err := os.Open(file)
 	if err != nil {
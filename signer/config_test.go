@@ -0,0 +1,97 @@
+package signer
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChainConfigs(test *testing.T) {
+	file, err := ioutil.TempFile("", "config.toml")
+	require.NoError(test, err)
+	defer os.Remove(file.Name())
+
+	_, err = file.WriteString(`
+key_file = "share.json"
+state_dir = "state"
+cosigner_threshold = 2
+cosigner_listen_address = "tcp://0.0.0.0:1234"
+
+[pkcs11]
+library = "/usr/lib/softhsm/libsofthsm2.so"
+token_label = "cosigner"
+
+[[chain]]
+chain_id = "chain-a"
+[[chain.node]]
+address = "tcp://node-a:1234"
+
+[[chain]]
+chain_id = "chain-b"
+key_file = "share-b.json"
+cosigner_threshold = 3
+[chain.pkcs11]
+key_label = "share-b"
+[[chain.node]]
+address = "tcp://node-b:1234"
+`)
+	require.NoError(test, err)
+	require.NoError(test, file.Close())
+
+	config, err := LoadConfigFromFile(file.Name())
+	require.NoError(test, err)
+
+	chainConfigs, err := config.ChainConfigs()
+	require.NoError(test, err)
+	require.Len(test, chainConfigs, 2)
+
+	// unset options are inherited from the top level
+	chainA := chainConfigs[0]
+	require.Equal(test, "chain-a", chainA.ChainID)
+	require.Equal(test, "share.json", chainA.PrivValKeyFile)
+	require.Equal(test, "state", chainA.PrivValStateDir)
+	require.Equal(test, 2, chainA.CosignerThreshold)
+	require.Equal(test, "tcp://node-a:1234", chainA.Nodes[0].Address)
+	require.Empty(test, chainA.Chains)
+
+	chainB, err := config.ForChain("chain-b")
+	require.NoError(test, err)
+	require.Equal(test, "share-b.json", chainB.PrivValKeyFile)
+	require.Equal(test, 3, chainB.CosignerThreshold)
+	require.Equal(test, "/usr/lib/softhsm/libsofthsm2.so", chainB.PKCS11.Library)
+	require.Equal(test, "cosigner", chainB.PKCS11.TokenLabel)
+	require.Equal(test, "share-b", chainB.PKCS11.KeyLabel)
+	require.Equal(test, "tcp://node-b:1234", chainB.Nodes[0].Address)
+
+	_, err = config.ForChain("chain-c")
+	require.Error(test, err)
+	_, err = config.ForChain("")
+	require.Error(test, err)
+
+	// chain_id and nodes belong to the [[chain]] sections once there are some
+	invalid := config
+	invalid.ChainID = "chain-a"
+	_, err = invalid.ChainConfigs()
+	require.Error(test, err)
+	invalid = config
+	invalid.Nodes = chainA.Nodes
+	_, err = invalid.ChainConfigs()
+	require.Error(test, err)
+	invalid = config
+	invalid.Chains = []ChainConfig{{ChainID: "chain-a"}, {ChainID: "chain-a"}}
+	_, err = invalid.ChainConfigs()
+	require.Error(test, err)
+	invalid.Chains = []ChainConfig{{}}
+	_, err = invalid.ChainConfigs()
+	require.Error(test, err)
+
+	// a config without [[chain]] sections hosts the validator of its chain_id
+	single := Config{ChainID: "chain-a"}
+	singleConfig, err := single.ForChain("")
+	require.NoError(test, err)
+	require.Equal(test, "chain-a", singleConfig.ChainID)
+	_, err = Config{}.ChainConfigs()
+	require.Error(test, err)
+}
@@ -1,31 +1,14 @@
 package signer
 
-type CosignerServer struct{}
-
-type CosignerHasEphemeralSecretPartRequest struct {
-	ID     int
-	Height int64
-	Round  int64
-	Step   int8
-}
+import "context"
 
-type CosignerHasEphemeralSecretPartResponse struct {
-	Exists                   bool
-	EphemeralSecretPublicKey []byte
-}
-
-type CosignerSetEphemeralSecretPartRequest struct {
-	SourceID                       int
-	SourceEphemeralSecretPublicKey []byte
-	Height                         int64
-	Round                          int64
-	Step                           int8
-	EncryptedSharePart             []byte
-	SourceSig                      []byte
-}
+type CosignerServer struct{}
 
 // Cosigner interface is a set of methods for an m-of-n threshold signature.
 // This interface abstracts the underlying key storage and management
+//
+// Every request takes a context. Implementations must abort the request
+// once the context is cancelled or its deadline is exceeded.
 type Cosigner interface {
 	// Get the ID of the cosigner
 	// The ID is the shamir index: 1, 2, etc...
@@ -33,14 +16,57 @@ type Cosigner interface {
 
 	// Get the ephemeral secret part for an ephemeral share
 	// The ephemeral secret part is encrypted for the receiver
-	GetEphemeralSecretPart(req *CosignerGetEphemeralSecretPartRequest) (*CosignerGetEphemeralSecretPartResponse, error)
+	GetEphemeralSecretPart(ctx context.Context, req *CosignerGetEphemeralSecretPartRequest) (*CosignerGetEphemeralSecretPartResponse, error)
 
 	// Store an ephemeral secret share part provided by another cosigner
-	SetEphemeralSecretPart(req CosignerSetEphemeralSecretPartRequest) error
+	SetEphemeralSecretPart(ctx context.Context, req *CosignerSetEphemeralSecretPartRequest) error
 
 	// Query whether the cosigner has an ehpemeral secret part set
-	HasEphemeralSecretPart(req CosignerHasEphemeralSecretPartRequest) (CosignerHasEphemeralSecretPartResponse, error)
+	HasEphemeralSecretPart(ctx context.Context, req *CosignerHasEphemeralSecretPartRequest) (*CosignerHasEphemeralSecretPartResponse, error)
 
 	// Sign the requested bytes
-	Sign(req *CosignerSignRequest) (*CosignerSignResponse, error)
+	Sign(ctx context.Context, req *CosignerSignRequest) (*CosignerSignResponse, error)
+}
+
+// exchangeEphemeralSecretPart makes sure the local cosigner holds the ephemeral secret part of the peer for the HRS
+// The part is only requested from the peer if the local cosigner doesn't have it yet
+func exchangeEphemeralSecretPart(ctx context.Context, local Cosigner, peer Cosigner, height int64, round int64, step int8) error {
+	hasResp, err := local.HasEphemeralSecretPart(ctx, &CosignerHasEphemeralSecretPartRequest{
+		ID:     int32(peer.GetID()),
+		Height: height,
+		Round:  round,
+		Step:   int32(step),
+	})
+	if err != nil {
+		return err
+	}
+
+	if hasResp.Exists {
+		return nil
+	}
+
+	// if we don't already have an ephemeral secret part for the HRS, we need to get one
+	// the peer answers with the highest protocol version we both support
+	ephSecretResp, err := peer.GetEphemeralSecretPart(ctx, &CosignerGetEphemeralSecretPartRequest{
+		ID:              int32(local.GetID()),
+		Height:          height,
+		Round:           round,
+		Step:            int32(step),
+		ProtocolVersion: CosignerProtocolVersion,
+	})
+	if err != nil {
+		return err
+	}
+
+	// set the response for ourselves
+	return local.SetEphemeralSecretPart(ctx, &CosignerSetEphemeralSecretPartRequest{
+		SourceSig:                      ephSecretResp.SourceSig,
+		SourceID:                       ephSecretResp.SourceID,
+		SourceEphemeralSecretPublicKey: ephSecretResp.SourceEphemeralSecretPublicKey,
+		EncryptedSharePart:             ephSecretResp.EncryptedSharePart,
+		Height:                         height,
+		Round:                          round,
+		Step:                           int32(step),
+		ProtocolVersion:                ephSecretResp.ProtocolVersion,
+	})
 }
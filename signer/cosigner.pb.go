@@ -30,6 +30,7 @@ type CosignerSignRequest struct {
 	unknownFields protoimpl.UnknownFields
 
 	SignBytes []byte `protobuf:"bytes,1,opt,name=sign_bytes,json=signBytes,proto3" json:"sign_bytes,omitempty"`
+	ChainID   string `protobuf:"bytes,2,opt,name=chain_iD,json=chainID,proto3" json:"chain_iD,omitempty"` // chain of the validator, empty for the only chain of the cosigner
 }
 
 func (x *CosignerSignRequest) Reset() {
@@ -71,14 +72,22 @@ func (x *CosignerSignRequest) GetSignBytes() []byte {
 	return nil
 }
 
+func (x *CosignerSignRequest) GetChainID() string {
+	if x != nil {
+		return x.ChainID
+	}
+	return ""
+}
+
 type CosignerSignResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	EphemeralPublic []byte `protobuf:"bytes,1,opt,name=ephemeral_public,json=ephemeralPublic,proto3" json:"ephemeral_public,omitempty"`
-	Timestamp       int64  `protobuf:"varint,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
-	Signature       []byte `protobuf:"bytes,3,opt,name=signature,proto3" json:"signature,omitempty"`
+	EphemeralPublic      []byte `protobuf:"bytes,1,opt,name=ephemeral_public,json=ephemeralPublic,proto3" json:"ephemeral_public,omitempty"`
+	Timestamp            int64  `protobuf:"varint,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Signature            []byte `protobuf:"bytes,3,opt,name=signature,proto3" json:"signature,omitempty"`
+	EphemeralSharePublic []byte `protobuf:"bytes,4,opt,name=ephemeral_share_public,json=ephemeralSharePublic,proto3" json:"ephemeral_share_public,omitempty"` // ephemeral share of the cosigner times the base point
 }
 
 func (x *CosignerSignResponse) Reset() {
@@ -134,15 +143,24 @@ func (x *CosignerSignResponse) GetSignature() []byte {
 	return nil
 }
 
+func (x *CosignerSignResponse) GetEphemeralSharePublic() []byte {
+	if x != nil {
+		return x.EphemeralSharePublic
+	}
+	return nil
+}
+
 type CosignerGetEphemeralSecretPartRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	ID     int32 `protobuf:"varint,1,opt,name=iD,proto3" json:"iD,omitempty"` // --> int?
-	Height int64 `protobuf:"varint,2,opt,name=height,proto3" json:"height,omitempty"`
-	Round  int64 `protobuf:"varint,3,opt,name=round,proto3" json:"round,omitempty"`
-	Step   int32 `protobuf:"varint,4,opt,name=step,proto3" json:"step,omitempty"` // --> int8
+	ID              int32  `protobuf:"varint,1,opt,name=iD,proto3" json:"iD,omitempty"` // --> int?
+	Height          int64  `protobuf:"varint,2,opt,name=height,proto3" json:"height,omitempty"`
+	Round           int64  `protobuf:"varint,3,opt,name=round,proto3" json:"round,omitempty"`
+	Step            int32  `protobuf:"varint,4,opt,name=step,proto3" json:"step,omitempty"`                                              // --> int8
+	ProtocolVersion int32  `protobuf:"varint,5,opt,name=protocol_version,json=protocolVersion,proto3" json:"protocol_version,omitempty"` // highest version supported by the requester, 0 for legacy
+	ChainID         string `protobuf:"bytes,6,opt,name=chain_iD,json=chainID,proto3" json:"chain_iD,omitempty"`                          // chain of the validator, empty for the only chain of the cosigner
 }
 
 func (x *CosignerGetEphemeralSecretPartRequest) Reset() {
@@ -205,6 +223,20 @@ func (x *CosignerGetEphemeralSecretPartRequest) GetStep() int32 {
 	return 0
 }
 
+func (x *CosignerGetEphemeralSecretPartRequest) GetProtocolVersion() int32 {
+	if x != nil {
+		return x.ProtocolVersion
+	}
+	return 0
+}
+
+func (x *CosignerGetEphemeralSecretPartRequest) GetChainID() string {
+	if x != nil {
+		return x.ChainID
+	}
+	return ""
+}
+
 type CosignerGetEphemeralSecretPartResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -214,6 +246,7 @@ type CosignerGetEphemeralSecretPartResponse struct {
 	SourceEphemeralSecretPublicKey []byte `protobuf:"bytes,2,opt,name=source_ephemeral_secret_publicKey,json=sourceEphemeralSecretPublicKey,proto3" json:"source_ephemeral_secret_publicKey,omitempty"`
 	EncryptedSharePart             []byte `protobuf:"bytes,3,opt,name=encrypted_share_part,json=encryptedSharePart,proto3" json:"encrypted_share_part,omitempty"`
 	SourceSig                      []byte `protobuf:"bytes,4,opt,name=source_sig,json=sourceSig,proto3" json:"source_sig,omitempty"`
+	ProtocolVersion                int32  `protobuf:"varint,5,opt,name=protocol_version,json=protocolVersion,proto3" json:"protocol_version,omitempty"` // version of the source_sig payload, 0 for legacy
 }
 
 func (x *CosignerGetEphemeralSecretPartResponse) Reset() {
@@ -276,266 +309,1695 @@ func (x *CosignerGetEphemeralSecretPartResponse) GetSourceSig() []byte {
 	return nil
 }
 
-var File_proto_cosigner_proto protoreflect.FileDescriptor
-
-var file_proto_cosigner_proto_rawDesc = []byte{
-	0x0a, 0x14, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x73, 0x69, 0x67, 0x6e, 0x65, 0x72,
-	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x34, 0x0a, 0x13, 0x43, 0x6f, 0x73, 0x69, 0x67, 0x6e,
-	0x65, 0x72, 0x53, 0x69, 0x67, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1d, 0x0a,
-	0x0a, 0x73, 0x69, 0x67, 0x6e, 0x5f, 0x62, 0x79, 0x74, 0x65, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28,
-	0x0c, 0x52, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x42, 0x79, 0x74, 0x65, 0x73, 0x22, 0x7d, 0x0a, 0x14,
-	0x43, 0x6f, 0x73, 0x69, 0x67, 0x6e, 0x65, 0x72, 0x53, 0x69, 0x67, 0x6e, 0x52, 0x65, 0x73, 0x70,
-	0x6f, 0x6e, 0x73, 0x65, 0x12, 0x29, 0x0a, 0x10, 0x65, 0x70, 0x68, 0x65, 0x6d, 0x65, 0x72, 0x61,
-	0x6c, 0x5f, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0f,
-	0x65, 0x70, 0x68, 0x65, 0x6d, 0x65, 0x72, 0x61, 0x6c, 0x50, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x12,
-	0x1c, 0x0a, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x18, 0x02, 0x20, 0x01,
-	0x28, 0x03, 0x52, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x12, 0x1c, 0x0a,
-	0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c,
-	0x52, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x22, 0x79, 0x0a, 0x25, 0x43,
-	0x6f, 0x73, 0x69, 0x67, 0x6e, 0x65, 0x72, 0x47, 0x65, 0x74, 0x45, 0x70, 0x68, 0x65, 0x6d, 0x65,
-	0x72, 0x61, 0x6c, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74, 0x50, 0x61, 0x72, 0x74, 0x52, 0x65, 0x71,
-	0x75, 0x65, 0x73, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05,
-	0x52, 0x02, 0x69, 0x44, 0x12, 0x16, 0x0a, 0x06, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x02,
-	0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x12, 0x14, 0x0a, 0x05,
-	0x72, 0x6f, 0x75, 0x6e, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x72, 0x6f, 0x75,
-	0x6e, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x74, 0x65, 0x70, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05,
-	0x52, 0x04, 0x73, 0x74, 0x65, 0x70, 0x22, 0xe1, 0x01, 0x0a, 0x26, 0x43, 0x6f, 0x73, 0x69, 0x67,
-	0x6e, 0x65, 0x72, 0x47, 0x65, 0x74, 0x45, 0x70, 0x68, 0x65, 0x6d, 0x65, 0x72, 0x61, 0x6c, 0x53,
-	0x65, 0x63, 0x72, 0x65, 0x74, 0x50, 0x61, 0x72, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
-	0x65, 0x12, 0x1b, 0x0a, 0x09, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x5f, 0x69, 0x44, 0x18, 0x01,
-	0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x49, 0x44, 0x12, 0x49,
-	0x0a, 0x21, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x5f, 0x65, 0x70, 0x68, 0x65, 0x6d, 0x65, 0x72,
-	0x61, 0x6c, 0x5f, 0x73, 0x65, 0x63, 0x72, 0x65, 0x74, 0x5f, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x63,
-	0x4b, 0x65, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x1e, 0x73, 0x6f, 0x75, 0x72, 0x63,
-	0x65, 0x45, 0x70, 0x68, 0x65, 0x6d, 0x65, 0x72, 0x61, 0x6c, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74,
-	0x50, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x4b, 0x65, 0x79, 0x12, 0x30, 0x0a, 0x14, 0x65, 0x6e, 0x63,
-	0x72, 0x79, 0x70, 0x74, 0x65, 0x64, 0x5f, 0x73, 0x68, 0x61, 0x72, 0x65, 0x5f, 0x70, 0x61, 0x72,
-	0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x12, 0x65, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74,
-	0x65, 0x64, 0x53, 0x68, 0x61, 0x72, 0x65, 0x50, 0x61, 0x72, 0x74, 0x12, 0x1d, 0x0a, 0x0a, 0x73,
-	0x6f, 0x75, 0x72, 0x63, 0x65, 0x5f, 0x73, 0x69, 0x67, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0c, 0x52,
-	0x09, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x53, 0x69, 0x67, 0x32, 0xb1, 0x01, 0x0a, 0x0f, 0x43,
-	0x6f, 0x73, 0x69, 0x67, 0x6e, 0x65, 0x72, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x33,
-	0x0a, 0x04, 0x53, 0x69, 0x67, 0x6e, 0x12, 0x14, 0x2e, 0x43, 0x6f, 0x73, 0x69, 0x67, 0x6e, 0x65,
-	0x72, 0x53, 0x69, 0x67, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x15, 0x2e, 0x43,
-	0x6f, 0x73, 0x69, 0x67, 0x6e, 0x65, 0x72, 0x53, 0x69, 0x67, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f,
-	0x6e, 0x73, 0x65, 0x12, 0x69, 0x0a, 0x16, 0x47, 0x65, 0x74, 0x45, 0x70, 0x68, 0x65, 0x6d, 0x65,
-	0x72, 0x61, 0x6c, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74, 0x50, 0x61, 0x72, 0x74, 0x12, 0x26, 0x2e,
-	0x43, 0x6f, 0x73, 0x69, 0x67, 0x6e, 0x65, 0x72, 0x47, 0x65, 0x74, 0x45, 0x70, 0x68, 0x65, 0x6d,
-	0x65, 0x72, 0x61, 0x6c, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74, 0x50, 0x61, 0x72, 0x74, 0x52, 0x65,
-	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x27, 0x2e, 0x43, 0x6f, 0x73, 0x69, 0x67, 0x6e, 0x65, 0x72,
-	0x47, 0x65, 0x74, 0x45, 0x70, 0x68, 0x65, 0x6d, 0x65, 0x72, 0x61, 0x6c, 0x53, 0x65, 0x63, 0x72,
-	0x65, 0x74, 0x50, 0x61, 0x72, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x42, 0x09,
-	0x5a, 0x07, 0x2f, 0x73, 0x69, 0x67, 0x6e, 0x65, 0x72, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f,
-	0x33,
+func (x *CosignerGetEphemeralSecretPartResponse) GetProtocolVersion() int32 {
+	if x != nil {
+		return x.ProtocolVersion
+	}
+	return 0
 }
 
-var (
-	file_proto_cosigner_proto_rawDescOnce sync.Once
-	file_proto_cosigner_proto_rawDescData = file_proto_cosigner_proto_rawDesc
-)
+type CosignerHasEphemeralSecretPartRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
 
-func file_proto_cosigner_proto_rawDescGZIP() []byte {
-	file_proto_cosigner_proto_rawDescOnce.Do(func() {
-		file_proto_cosigner_proto_rawDescData = protoimpl.X.CompressGZIP(file_proto_cosigner_proto_rawDescData)
-	})
-	return file_proto_cosigner_proto_rawDescData
+	ID      int32  `protobuf:"varint,1,opt,name=iD,proto3" json:"iD,omitempty"`
+	Height  int64  `protobuf:"varint,2,opt,name=height,proto3" json:"height,omitempty"`
+	Round   int64  `protobuf:"varint,3,opt,name=round,proto3" json:"round,omitempty"`
+	Step    int32  `protobuf:"varint,4,opt,name=step,proto3" json:"step,omitempty"`                     // --> int8
+	ChainID string `protobuf:"bytes,5,opt,name=chain_iD,json=chainID,proto3" json:"chain_iD,omitempty"` // chain of the validator, empty for the only chain of the cosigner
 }
 
-var file_proto_cosigner_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
-var file_proto_cosigner_proto_goTypes = []interface{}{
-	(*CosignerSignRequest)(nil),                    // 0: CosignerSignRequest
-	(*CosignerSignResponse)(nil),                   // 1: CosignerSignResponse
-	(*CosignerGetEphemeralSecretPartRequest)(nil),  // 2: CosignerGetEphemeralSecretPartRequest
-	(*CosignerGetEphemeralSecretPartResponse)(nil), // 3: CosignerGetEphemeralSecretPartResponse
+func (x *CosignerHasEphemeralSecretPartRequest) Reset() {
+	*x = CosignerHasEphemeralSecretPartRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_cosigner_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
 }
-var file_proto_cosigner_proto_depIdxs = []int32{
-	0, // 0: CosignerService.Sign:input_type -> CosignerSignRequest
-	2, // 1: CosignerService.GetEphemeralSecretPart:input_type -> CosignerGetEphemeralSecretPartRequest
-	1, // 2: CosignerService.Sign:output_type -> CosignerSignResponse
-	3, // 3: CosignerService.GetEphemeralSecretPart:output_type -> CosignerGetEphemeralSecretPartResponse
-	2, // [2:4] is the sub-list for method output_type
-	0, // [0:2] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+
+func (x *CosignerHasEphemeralSecretPartRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
-func init() { file_proto_cosigner_proto_init() }
-func file_proto_cosigner_proto_init() {
-	if File_proto_cosigner_proto != nil {
-		return
-	}
-	if !protoimpl.UnsafeEnabled {
-		file_proto_cosigner_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*CosignerSignRequest); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_proto_cosigner_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*CosignerSignResponse); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_proto_cosigner_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*CosignerGetEphemeralSecretPartRequest); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_proto_cosigner_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*CosignerGetEphemeralSecretPartResponse); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
+func (*CosignerHasEphemeralSecretPartRequest) ProtoMessage() {}
+
+func (x *CosignerHasEphemeralSecretPartRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_cosigner_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
 		}
+		return ms
 	}
-	type x struct{}
-	out := protoimpl.TypeBuilder{
-		File: protoimpl.DescBuilder{
-			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
-			RawDescriptor: file_proto_cosigner_proto_rawDesc,
-			NumEnums:      0,
-			NumMessages:   4,
-			NumExtensions: 0,
-			NumServices:   1,
-		},
-		GoTypes:           file_proto_cosigner_proto_goTypes,
-		DependencyIndexes: file_proto_cosigner_proto_depIdxs,
-		MessageInfos:      file_proto_cosigner_proto_msgTypes,
-	}.Build()
-	File_proto_cosigner_proto = out.File
-	file_proto_cosigner_proto_rawDesc = nil
-	file_proto_cosigner_proto_goTypes = nil
-	file_proto_cosigner_proto_depIdxs = nil
+	return mi.MessageOf(x)
 }
 
-// Reference imports to suppress errors if they are not otherwise used.
-var _ context.Context
-var _ grpc.ClientConnInterface
-
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the grpc package it is being compiled against.
-const _ = grpc.SupportPackageIsVersion6
+// Deprecated: Use CosignerHasEphemeralSecretPartRequest.ProtoReflect.Descriptor instead.
+func (*CosignerHasEphemeralSecretPartRequest) Descriptor() ([]byte, []int) {
+	return file_proto_cosigner_proto_rawDescGZIP(), []int{4}
+}
 
-// CosignerServiceClient is the client API for CosignerService service.
-//
-// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
-type CosignerServiceClient interface {
-	Sign(ctx context.Context, in *CosignerSignRequest, opts ...grpc.CallOption) (*CosignerSignResponse, error)
-	GetEphemeralSecretPart(ctx context.Context, in *CosignerGetEphemeralSecretPartRequest, opts ...grpc.CallOption) (*CosignerGetEphemeralSecretPartResponse, error)
+func (x *CosignerHasEphemeralSecretPartRequest) GetID() int32 {
+	if x != nil {
+		return x.ID
+	}
+	return 0
 }
 
-type cosignerServiceClient struct {
-	cc grpc.ClientConnInterface
+func (x *CosignerHasEphemeralSecretPartRequest) GetHeight() int64 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
 }
 
-func NewCosignerServiceClient(cc grpc.ClientConnInterface) CosignerServiceClient {
-	return &cosignerServiceClient{cc}
+func (x *CosignerHasEphemeralSecretPartRequest) GetRound() int64 {
+	if x != nil {
+		return x.Round
+	}
+	return 0
 }
 
-func (c *cosignerServiceClient) Sign(ctx context.Context, in *CosignerSignRequest, opts ...grpc.CallOption) (*CosignerSignResponse, error) {
-	out := new(CosignerSignResponse)
-	err := c.cc.Invoke(ctx, "/CosignerService/Sign", in, out, opts...)
-	if err != nil {
-		return nil, err
+func (x *CosignerHasEphemeralSecretPartRequest) GetStep() int32 {
+	if x != nil {
+		return x.Step
 	}
-	return out, nil
+	return 0
 }
 
-func (c *cosignerServiceClient) GetEphemeralSecretPart(ctx context.Context, in *CosignerGetEphemeralSecretPartRequest, opts ...grpc.CallOption) (*CosignerGetEphemeralSecretPartResponse, error) {
-	out := new(CosignerGetEphemeralSecretPartResponse)
-	err := c.cc.Invoke(ctx, "/CosignerService/GetEphemeralSecretPart", in, out, opts...)
-	if err != nil {
-		return nil, err
+func (x *CosignerHasEphemeralSecretPartRequest) GetChainID() string {
+	if x != nil {
+		return x.ChainID
 	}
-	return out, nil
+	return ""
 }
 
-// CosignerServiceServer is the server API for CosignerService service.
-type CosignerServiceServer interface {
-	Sign(context.Context, *CosignerSignRequest) (*CosignerSignResponse, error)
-	GetEphemeralSecretPart(context.Context, *CosignerGetEphemeralSecretPartRequest) (*CosignerGetEphemeralSecretPartResponse, error)
+type CosignerHasEphemeralSecretPartResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Exists                   bool   `protobuf:"varint,1,opt,name=exists,proto3" json:"exists,omitempty"`
+	EphemeralSecretPublicKey []byte `protobuf:"bytes,2,opt,name=ephemeral_secret_publicKey,json=ephemeralSecretPublicKey,proto3" json:"ephemeral_secret_publicKey,omitempty"`
 }
 
-// UnimplementedCosignerServiceServer can be embedded to have forward compatible implementations.
-type UnimplementedCosignerServiceServer struct {
+func (x *CosignerHasEphemeralSecretPartResponse) Reset() {
+	*x = CosignerHasEphemeralSecretPartResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_cosigner_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
 }
 
-func (*UnimplementedCosignerServiceServer) Sign(context.Context, *CosignerSignRequest) (*CosignerSignResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method Sign not implemented")
+func (x *CosignerHasEphemeralSecretPartResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
-func (*UnimplementedCosignerServiceServer) GetEphemeralSecretPart(context.Context, *CosignerGetEphemeralSecretPartRequest) (*CosignerGetEphemeralSecretPartResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetEphemeralSecretPart not implemented")
+
+func (*CosignerHasEphemeralSecretPartResponse) ProtoMessage() {}
+
+func (x *CosignerHasEphemeralSecretPartResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_cosigner_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
 }
 
-func RegisterCosignerServiceServer(s *grpc.Server, srv CosignerServiceServer) {
-	s.RegisterService(&_CosignerService_serviceDesc, srv)
+// Deprecated: Use CosignerHasEphemeralSecretPartResponse.ProtoReflect.Descriptor instead.
+func (*CosignerHasEphemeralSecretPartResponse) Descriptor() ([]byte, []int) {
+	return file_proto_cosigner_proto_rawDescGZIP(), []int{5}
 }
 
-func _CosignerService_Sign_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(CosignerSignRequest)
-	if err := dec(in); err != nil {
-		return nil, err
-	}
-	if interceptor == nil {
-		return srv.(CosignerServiceServer).Sign(ctx, in)
-	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: "/CosignerService/Sign",
-	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(CosignerServiceServer).Sign(ctx, req.(*CosignerSignRequest))
+func (x *CosignerHasEphemeralSecretPartResponse) GetExists() bool {
+	if x != nil {
+		return x.Exists
 	}
-	return interceptor(ctx, in, info, handler)
+	return false
 }
 
-func _CosignerService_GetEphemeralSecretPart_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(CosignerGetEphemeralSecretPartRequest)
-	if err := dec(in); err != nil {
-		return nil, err
-	}
-	if interceptor == nil {
-		return srv.(CosignerServiceServer).GetEphemeralSecretPart(ctx, in)
-	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: "/CosignerService/GetEphemeralSecretPart",
+func (x *CosignerHasEphemeralSecretPartResponse) GetEphemeralSecretPublicKey() []byte {
+	if x != nil {
+		return x.EphemeralSecretPublicKey
 	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(CosignerServiceServer).GetEphemeralSecretPart(ctx, req.(*CosignerGetEphemeralSecretPartRequest))
+	return nil
+}
+
+type CosignerSetEphemeralSecretPartRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	SourceID                       int32  `protobuf:"varint,1,opt,name=source_iD,json=sourceID,proto3" json:"source_iD,omitempty"`
+	SourceEphemeralSecretPublicKey []byte `protobuf:"bytes,2,opt,name=source_ephemeral_secret_publicKey,json=sourceEphemeralSecretPublicKey,proto3" json:"source_ephemeral_secret_publicKey,omitempty"`
+	Height                         int64  `protobuf:"varint,3,opt,name=height,proto3" json:"height,omitempty"`
+	Round                          int64  `protobuf:"varint,4,opt,name=round,proto3" json:"round,omitempty"`
+	Step                           int32  `protobuf:"varint,5,opt,name=step,proto3" json:"step,omitempty"` // --> int8
+	EncryptedSharePart             []byte `protobuf:"bytes,6,opt,name=encrypted_share_part,json=encryptedSharePart,proto3" json:"encrypted_share_part,omitempty"`
+	SourceSig                      []byte `protobuf:"bytes,7,opt,name=source_sig,json=sourceSig,proto3" json:"source_sig,omitempty"`
+	ProtocolVersion                int32  `protobuf:"varint,8,opt,name=protocol_version,json=protocolVersion,proto3" json:"protocol_version,omitempty"` // version of the source_sig payload, 0 for legacy
+	ChainID                        string `protobuf:"bytes,9,opt,name=chain_iD,json=chainID,proto3" json:"chain_iD,omitempty"`                          // chain of the validator, empty for the only chain of the cosigner
+}
+
+func (x *CosignerSetEphemeralSecretPartRequest) Reset() {
+	*x = CosignerSetEphemeralSecretPartRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_cosigner_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
 	}
-	return interceptor(ctx, in, info, handler)
 }
 
-var _CosignerService_serviceDesc = grpc.ServiceDesc{
-	ServiceName: "CosignerService",
+func (x *CosignerSetEphemeralSecretPartRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CosignerSetEphemeralSecretPartRequest) ProtoMessage() {}
+
+func (x *CosignerSetEphemeralSecretPartRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_cosigner_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CosignerSetEphemeralSecretPartRequest.ProtoReflect.Descriptor instead.
+func (*CosignerSetEphemeralSecretPartRequest) Descriptor() ([]byte, []int) {
+	return file_proto_cosigner_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *CosignerSetEphemeralSecretPartRequest) GetSourceID() int32 {
+	if x != nil {
+		return x.SourceID
+	}
+	return 0
+}
+
+func (x *CosignerSetEphemeralSecretPartRequest) GetSourceEphemeralSecretPublicKey() []byte {
+	if x != nil {
+		return x.SourceEphemeralSecretPublicKey
+	}
+	return nil
+}
+
+func (x *CosignerSetEphemeralSecretPartRequest) GetHeight() int64 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *CosignerSetEphemeralSecretPartRequest) GetRound() int64 {
+	if x != nil {
+		return x.Round
+	}
+	return 0
+}
+
+func (x *CosignerSetEphemeralSecretPartRequest) GetStep() int32 {
+	if x != nil {
+		return x.Step
+	}
+	return 0
+}
+
+func (x *CosignerSetEphemeralSecretPartRequest) GetEncryptedSharePart() []byte {
+	if x != nil {
+		return x.EncryptedSharePart
+	}
+	return nil
+}
+
+func (x *CosignerSetEphemeralSecretPartRequest) GetSourceSig() []byte {
+	if x != nil {
+		return x.SourceSig
+	}
+	return nil
+}
+
+func (x *CosignerSetEphemeralSecretPartRequest) GetProtocolVersion() int32 {
+	if x != nil {
+		return x.ProtocolVersion
+	}
+	return 0
+}
+
+func (x *CosignerSetEphemeralSecretPartRequest) GetChainID() string {
+	if x != nil {
+		return x.ChainID
+	}
+	return ""
+}
+
+type CosignerSetEphemeralSecretPartResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *CosignerSetEphemeralSecretPartResponse) Reset() {
+	*x = CosignerSetEphemeralSecretPartResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_cosigner_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CosignerSetEphemeralSecretPartResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CosignerSetEphemeralSecretPartResponse) ProtoMessage() {}
+
+func (x *CosignerSetEphemeralSecretPartResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_cosigner_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CosignerSetEphemeralSecretPartResponse.ProtoReflect.Descriptor instead.
+func (*CosignerSetEphemeralSecretPartResponse) Descriptor() ([]byte, []int) {
+	return file_proto_cosigner_proto_rawDescGZIP(), []int{7}
+}
+
+type CosignerRefreshDealRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	RefreshID string `protobuf:"bytes,1,opt,name=refresh_iD,json=refreshID,proto3" json:"refresh_iD,omitempty"`
+	ChainID   string `protobuf:"bytes,2,opt,name=chain_iD,json=chainID,proto3" json:"chain_iD,omitempty"` // chain of the validator, empty for the only chain of the cosigner
+}
+
+func (x *CosignerRefreshDealRequest) Reset() {
+	*x = CosignerRefreshDealRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_cosigner_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CosignerRefreshDealRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CosignerRefreshDealRequest) ProtoMessage() {}
+
+func (x *CosignerRefreshDealRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_cosigner_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CosignerRefreshDealRequest.ProtoReflect.Descriptor instead.
+func (*CosignerRefreshDealRequest) Descriptor() ([]byte, []int) {
+	return file_proto_cosigner_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *CosignerRefreshDealRequest) GetRefreshID() string {
+	if x != nil {
+		return x.RefreshID
+	}
+	return ""
+}
+
+func (x *CosignerRefreshDealRequest) GetChainID() string {
+	if x != nil {
+		return x.ChainID
+	}
+	return ""
+}
+
+type CosignerRefreshDeal struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	RefreshID       string   `protobuf:"bytes,1,opt,name=refresh_iD,json=refreshID,proto3" json:"refresh_iD,omitempty"`
+	SourceID        int32    `protobuf:"varint,2,opt,name=source_iD,json=sourceID,proto3" json:"source_iD,omitempty"`
+	Commitments     [][]byte `protobuf:"bytes,3,rep,name=commitments,proto3" json:"commitments,omitempty"`                                // feldman commitments of the coefficients 0 .. threshold - 1, the first one is the identity
+	EncryptedShares [][]byte `protobuf:"bytes,4,rep,name=encrypted_shares,json=encryptedShares,proto3" json:"encrypted_shares,omitempty"` // share of cosigner i at index i - 1, encrypted for it
+	SourceSig       []byte   `protobuf:"bytes,5,opt,name=source_sig,json=sourceSig,proto3" json:"source_sig,omitempty"`
+}
+
+func (x *CosignerRefreshDeal) Reset() {
+	*x = CosignerRefreshDeal{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_cosigner_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CosignerRefreshDeal) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CosignerRefreshDeal) ProtoMessage() {}
+
+func (x *CosignerRefreshDeal) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_cosigner_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CosignerRefreshDeal.ProtoReflect.Descriptor instead.
+func (*CosignerRefreshDeal) Descriptor() ([]byte, []int) {
+	return file_proto_cosigner_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *CosignerRefreshDeal) GetRefreshID() string {
+	if x != nil {
+		return x.RefreshID
+	}
+	return ""
+}
+
+func (x *CosignerRefreshDeal) GetSourceID() int32 {
+	if x != nil {
+		return x.SourceID
+	}
+	return 0
+}
+
+func (x *CosignerRefreshDeal) GetCommitments() [][]byte {
+	if x != nil {
+		return x.Commitments
+	}
+	return nil
+}
+
+func (x *CosignerRefreshDeal) GetEncryptedShares() [][]byte {
+	if x != nil {
+		return x.EncryptedShares
+	}
+	return nil
+}
+
+func (x *CosignerRefreshDeal) GetSourceSig() []byte {
+	if x != nil {
+		return x.SourceSig
+	}
+	return nil
+}
+
+type CosignerRefreshPrepareRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	RefreshID        string                 `protobuf:"bytes,1,opt,name=refresh_iD,json=refreshID,proto3" json:"refresh_iD,omitempty"`
+	ActivationHeight int64                  `protobuf:"varint,2,opt,name=activation_height,json=activationHeight,proto3" json:"activation_height,omitempty"`
+	Deals            []*CosignerRefreshDeal `protobuf:"bytes,3,rep,name=deals,proto3" json:"deals,omitempty"`
+	ChainID          string                 `protobuf:"bytes,4,opt,name=chain_iD,json=chainID,proto3" json:"chain_iD,omitempty"` // chain of the validator, empty for the only chain of the cosigner
+}
+
+func (x *CosignerRefreshPrepareRequest) Reset() {
+	*x = CosignerRefreshPrepareRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_cosigner_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CosignerRefreshPrepareRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CosignerRefreshPrepareRequest) ProtoMessage() {}
+
+func (x *CosignerRefreshPrepareRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_cosigner_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CosignerRefreshPrepareRequest.ProtoReflect.Descriptor instead.
+func (*CosignerRefreshPrepareRequest) Descriptor() ([]byte, []int) {
+	return file_proto_cosigner_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *CosignerRefreshPrepareRequest) GetRefreshID() string {
+	if x != nil {
+		return x.RefreshID
+	}
+	return ""
+}
+
+func (x *CosignerRefreshPrepareRequest) GetActivationHeight() int64 {
+	if x != nil {
+		return x.ActivationHeight
+	}
+	return 0
+}
+
+func (x *CosignerRefreshPrepareRequest) GetDeals() []*CosignerRefreshDeal {
+	if x != nil {
+		return x.Deals
+	}
+	return nil
+}
+
+func (x *CosignerRefreshPrepareRequest) GetChainID() string {
+	if x != nil {
+		return x.ChainID
+	}
+	return ""
+}
+
+type CosignerRefreshPrepareResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	SharePubKeys [][]byte `protobuf:"bytes,1,rep,name=share_pub_keys,json=sharePubKeys,proto3" json:"share_pub_keys,omitempty"` // public keys of the refreshed shares, empty if unknown
+}
+
+func (x *CosignerRefreshPrepareResponse) Reset() {
+	*x = CosignerRefreshPrepareResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_cosigner_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CosignerRefreshPrepareResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CosignerRefreshPrepareResponse) ProtoMessage() {}
+
+func (x *CosignerRefreshPrepareResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_cosigner_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CosignerRefreshPrepareResponse.ProtoReflect.Descriptor instead.
+func (*CosignerRefreshPrepareResponse) Descriptor() ([]byte, []int) {
+	return file_proto_cosigner_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *CosignerRefreshPrepareResponse) GetSharePubKeys() [][]byte {
+	if x != nil {
+		return x.SharePubKeys
+	}
+	return nil
+}
+
+type CosignerRefreshCommitRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	RefreshID string `protobuf:"bytes,1,opt,name=refresh_iD,json=refreshID,proto3" json:"refresh_iD,omitempty"`
+	ChainID   string `protobuf:"bytes,2,opt,name=chain_iD,json=chainID,proto3" json:"chain_iD,omitempty"` // chain of the validator, empty for the only chain of the cosigner
+}
+
+func (x *CosignerRefreshCommitRequest) Reset() {
+	*x = CosignerRefreshCommitRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_cosigner_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CosignerRefreshCommitRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CosignerRefreshCommitRequest) ProtoMessage() {}
+
+func (x *CosignerRefreshCommitRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_cosigner_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CosignerRefreshCommitRequest.ProtoReflect.Descriptor instead.
+func (*CosignerRefreshCommitRequest) Descriptor() ([]byte, []int) {
+	return file_proto_cosigner_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *CosignerRefreshCommitRequest) GetRefreshID() string {
+	if x != nil {
+		return x.RefreshID
+	}
+	return ""
+}
+
+func (x *CosignerRefreshCommitRequest) GetChainID() string {
+	if x != nil {
+		return x.ChainID
+	}
+	return ""
+}
+
+type CosignerRefreshCommitResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *CosignerRefreshCommitResponse) Reset() {
+	*x = CosignerRefreshCommitResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_cosigner_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CosignerRefreshCommitResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CosignerRefreshCommitResponse) ProtoMessage() {}
+
+func (x *CosignerRefreshCommitResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_cosigner_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CosignerRefreshCommitResponse.ProtoReflect.Descriptor instead.
+func (*CosignerRefreshCommitResponse) Descriptor() ([]byte, []int) {
+	return file_proto_cosigner_proto_rawDescGZIP(), []int{13}
+}
+
+type CosignerRefreshAbortRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	RefreshID string `protobuf:"bytes,1,opt,name=refresh_iD,json=refreshID,proto3" json:"refresh_iD,omitempty"`
+	ChainID   string `protobuf:"bytes,2,opt,name=chain_iD,json=chainID,proto3" json:"chain_iD,omitempty"` // chain of the validator, empty for the only chain of the cosigner
+}
+
+func (x *CosignerRefreshAbortRequest) Reset() {
+	*x = CosignerRefreshAbortRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_cosigner_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CosignerRefreshAbortRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CosignerRefreshAbortRequest) ProtoMessage() {}
+
+func (x *CosignerRefreshAbortRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_cosigner_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CosignerRefreshAbortRequest.ProtoReflect.Descriptor instead.
+func (*CosignerRefreshAbortRequest) Descriptor() ([]byte, []int) {
+	return file_proto_cosigner_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *CosignerRefreshAbortRequest) GetRefreshID() string {
+	if x != nil {
+		return x.RefreshID
+	}
+	return ""
+}
+
+func (x *CosignerRefreshAbortRequest) GetChainID() string {
+	if x != nil {
+		return x.ChainID
+	}
+	return ""
+}
+
+type CosignerRefreshAbortResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *CosignerRefreshAbortResponse) Reset() {
+	*x = CosignerRefreshAbortResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_cosigner_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CosignerRefreshAbortResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CosignerRefreshAbortResponse) ProtoMessage() {}
+
+func (x *CosignerRefreshAbortResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_cosigner_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CosignerRefreshAbortResponse.ProtoReflect.Descriptor instead.
+func (*CosignerRefreshAbortResponse) Descriptor() ([]byte, []int) {
+	return file_proto_cosigner_proto_rawDescGZIP(), []int{15}
+}
+
+type CosignerSignBlockRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ChainID   string `protobuf:"bytes,1,opt,name=chain_iD,json=chainID,proto3" json:"chain_iD,omitempty"` // chain of the validator, empty for the only chain of the cosigner
+	SignBytes []byte `protobuf:"bytes,2,opt,name=sign_bytes,json=signBytes,proto3" json:"sign_bytes,omitempty"`
+	Timestamp int64  `protobuf:"varint,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"` // unix nanoseconds of the timestamp of the vote or proposal
+	Source    string `protobuf:"bytes,4,opt,name=source,proto3" json:"source,omitempty"`        // node the request came from, for the audit log
+}
+
+func (x *CosignerSignBlockRequest) Reset() {
+	*x = CosignerSignBlockRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_cosigner_proto_msgTypes[16]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CosignerSignBlockRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CosignerSignBlockRequest) ProtoMessage() {}
+
+func (x *CosignerSignBlockRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_cosigner_proto_msgTypes[16]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CosignerSignBlockRequest.ProtoReflect.Descriptor instead.
+func (*CosignerSignBlockRequest) Descriptor() ([]byte, []int) {
+	return file_proto_cosigner_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *CosignerSignBlockRequest) GetChainID() string {
+	if x != nil {
+		return x.ChainID
+	}
+	return ""
+}
+
+func (x *CosignerSignBlockRequest) GetSignBytes() []byte {
+	if x != nil {
+		return x.SignBytes
+	}
+	return nil
+}
+
+func (x *CosignerSignBlockRequest) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *CosignerSignBlockRequest) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+type CosignerSignBlockResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Signature []byte `protobuf:"bytes,1,opt,name=signature,proto3" json:"signature,omitempty"`
+	Timestamp int64  `protobuf:"varint,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"` // unix nanoseconds of the timestamp signed, which differs if a cached signature is returned
+}
+
+func (x *CosignerSignBlockResponse) Reset() {
+	*x = CosignerSignBlockResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_cosigner_proto_msgTypes[17]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CosignerSignBlockResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CosignerSignBlockResponse) ProtoMessage() {}
+
+func (x *CosignerSignBlockResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_cosigner_proto_msgTypes[17]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CosignerSignBlockResponse.ProtoReflect.Descriptor instead.
+func (*CosignerSignBlockResponse) Descriptor() ([]byte, []int) {
+	return file_proto_cosigner_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *CosignerSignBlockResponse) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
+func (x *CosignerSignBlockResponse) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+var File_proto_cosigner_proto protoreflect.FileDescriptor
+
+var file_proto_cosigner_proto_rawDesc = []byte{
+	0x0a, 0x14, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x73, 0x69, 0x67, 0x6e, 0x65, 0x72,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x00, 0x22, 0x4f, 0x0a, 0x13, 0x43, 0x6f, 0x73, 0x69,
+	0x67, 0x6e, 0x65, 0x72, 0x53, 0x69, 0x67, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12,
+	0x1d, 0x0a, 0x0a, 0x73, 0x69, 0x67, 0x6e, 0x5f, 0x62, 0x79, 0x74, 0x65, 0x73, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x0c, 0x52, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x42, 0x79, 0x74, 0x65, 0x73, 0x12, 0x19,
+	0x0a, 0x08, 0x63, 0x68, 0x61, 0x69, 0x6e, 0x5f, 0x69, 0x44, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x07, 0x63, 0x68, 0x61, 0x69, 0x6e, 0x49, 0x44, 0x22, 0xb3, 0x01, 0x0a, 0x14, 0x43, 0x6f,
+	0x73, 0x69, 0x67, 0x6e, 0x65, 0x72, 0x53, 0x69, 0x67, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x12, 0x29, 0x0a, 0x10, 0x65, 0x70, 0x68, 0x65, 0x6d, 0x65, 0x72, 0x61, 0x6c, 0x5f,
+	0x70, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0f, 0x65, 0x70,
+	0x68, 0x65, 0x6d, 0x65, 0x72, 0x61, 0x6c, 0x50, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x12, 0x1c, 0x0a,
+	0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x12, 0x1c, 0x0a, 0x09, 0x73,
+	0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09,
+	0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x12, 0x34, 0x0a, 0x16, 0x65, 0x70, 0x68,
+	0x65, 0x6d, 0x65, 0x72, 0x61, 0x6c, 0x5f, 0x73, 0x68, 0x61, 0x72, 0x65, 0x5f, 0x70, 0x75, 0x62,
+	0x6c, 0x69, 0x63, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x14, 0x65, 0x70, 0x68, 0x65, 0x6d,
+	0x65, 0x72, 0x61, 0x6c, 0x53, 0x68, 0x61, 0x72, 0x65, 0x50, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x22,
+	0xbf, 0x01, 0x0a, 0x25, 0x43, 0x6f, 0x73, 0x69, 0x67, 0x6e, 0x65, 0x72, 0x47, 0x65, 0x74, 0x45,
+	0x70, 0x68, 0x65, 0x6d, 0x65, 0x72, 0x61, 0x6c, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74, 0x50, 0x61,
+	0x72, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x44, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x02, 0x69, 0x44, 0x12, 0x16, 0x0a, 0x06, 0x68, 0x65, 0x69,
+	0x67, 0x68, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x68, 0x65, 0x69, 0x67, 0x68,
+	0x74, 0x12, 0x14, 0x0a, 0x05, 0x72, 0x6f, 0x75, 0x6e, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x05, 0x72, 0x6f, 0x75, 0x6e, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x74, 0x65, 0x70, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x73, 0x74, 0x65, 0x70, 0x12, 0x29, 0x0a, 0x10, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18,
+	0x05, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x56,
+	0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x19, 0x0a, 0x08, 0x63, 0x68, 0x61, 0x69, 0x6e, 0x5f,
+	0x69, 0x44, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x63, 0x68, 0x61, 0x69, 0x6e, 0x49,
+	0x44, 0x22, 0x8c, 0x02, 0x0a, 0x26, 0x43, 0x6f, 0x73, 0x69, 0x67, 0x6e, 0x65, 0x72, 0x47, 0x65,
+	0x74, 0x45, 0x70, 0x68, 0x65, 0x6d, 0x65, 0x72, 0x61, 0x6c, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74,
+	0x50, 0x61, 0x72, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x1b, 0x0a, 0x09,
+	0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x5f, 0x69, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52,
+	0x08, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x49, 0x44, 0x12, 0x49, 0x0a, 0x21, 0x73, 0x6f, 0x75,
+	0x72, 0x63, 0x65, 0x5f, 0x65, 0x70, 0x68, 0x65, 0x6d, 0x65, 0x72, 0x61, 0x6c, 0x5f, 0x73, 0x65,
+	0x63, 0x72, 0x65, 0x74, 0x5f, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x4b, 0x65, 0x79, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0c, 0x52, 0x1e, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x45, 0x70, 0x68, 0x65,
+	0x6d, 0x65, 0x72, 0x61, 0x6c, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74, 0x50, 0x75, 0x62, 0x6c, 0x69,
+	0x63, 0x4b, 0x65, 0x79, 0x12, 0x30, 0x0a, 0x14, 0x65, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x65,
+	0x64, 0x5f, 0x73, 0x68, 0x61, 0x72, 0x65, 0x5f, 0x70, 0x61, 0x72, 0x74, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x0c, 0x52, 0x12, 0x65, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x65, 0x64, 0x53, 0x68, 0x61,
+	0x72, 0x65, 0x50, 0x61, 0x72, 0x74, 0x12, 0x1d, 0x0a, 0x0a, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65,
+	0x5f, 0x73, 0x69, 0x67, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x73, 0x6f, 0x75, 0x72,
+	0x63, 0x65, 0x53, 0x69, 0x67, 0x12, 0x29, 0x0a, 0x10, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f,
+	0x6c, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x05, 0x20, 0x01, 0x28, 0x05, 0x52,
+	0x0f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e,
+	0x22, 0x94, 0x01, 0x0a, 0x25, 0x43, 0x6f, 0x73, 0x69, 0x67, 0x6e, 0x65, 0x72, 0x48, 0x61, 0x73,
+	0x45, 0x70, 0x68, 0x65, 0x6d, 0x65, 0x72, 0x61, 0x6c, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74, 0x50,
+	0x61, 0x72, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x44,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x02, 0x69, 0x44, 0x12, 0x16, 0x0a, 0x06, 0x68, 0x65,
+	0x69, 0x67, 0x68, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x68, 0x65, 0x69, 0x67,
+	0x68, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x72, 0x6f, 0x75, 0x6e, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x03, 0x52, 0x05, 0x72, 0x6f, 0x75, 0x6e, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x74, 0x65, 0x70,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x73, 0x74, 0x65, 0x70, 0x12, 0x19, 0x0a, 0x08,
+	0x63, 0x68, 0x61, 0x69, 0x6e, 0x5f, 0x69, 0x44, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07,
+	0x63, 0x68, 0x61, 0x69, 0x6e, 0x49, 0x44, 0x22, 0x7e, 0x0a, 0x26, 0x43, 0x6f, 0x73, 0x69, 0x67,
+	0x6e, 0x65, 0x72, 0x48, 0x61, 0x73, 0x45, 0x70, 0x68, 0x65, 0x6d, 0x65, 0x72, 0x61, 0x6c, 0x53,
+	0x65, 0x63, 0x72, 0x65, 0x74, 0x50, 0x61, 0x72, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x12, 0x16, 0x0a, 0x06, 0x65, 0x78, 0x69, 0x73, 0x74, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x08, 0x52, 0x06, 0x65, 0x78, 0x69, 0x73, 0x74, 0x73, 0x12, 0x3c, 0x0a, 0x1a, 0x65, 0x70, 0x68,
+	0x65, 0x6d, 0x65, 0x72, 0x61, 0x6c, 0x5f, 0x73, 0x65, 0x63, 0x72, 0x65, 0x74, 0x5f, 0x70, 0x75,
+	0x62, 0x6c, 0x69, 0x63, 0x4b, 0x65, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x18, 0x65,
+	0x70, 0x68, 0x65, 0x6d, 0x65, 0x72, 0x61, 0x6c, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74, 0x50, 0x75,
+	0x62, 0x6c, 0x69, 0x63, 0x4b, 0x65, 0x79, 0x22, 0xe8, 0x02, 0x0a, 0x25, 0x43, 0x6f, 0x73, 0x69,
+	0x67, 0x6e, 0x65, 0x72, 0x53, 0x65, 0x74, 0x45, 0x70, 0x68, 0x65, 0x6d, 0x65, 0x72, 0x61, 0x6c,
+	0x53, 0x65, 0x63, 0x72, 0x65, 0x74, 0x50, 0x61, 0x72, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x12, 0x1b, 0x0a, 0x09, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x5f, 0x69, 0x44, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x49, 0x44, 0x12, 0x49,
+	0x0a, 0x21, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x5f, 0x65, 0x70, 0x68, 0x65, 0x6d, 0x65, 0x72,
+	0x61, 0x6c, 0x5f, 0x73, 0x65, 0x63, 0x72, 0x65, 0x74, 0x5f, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x63,
+	0x4b, 0x65, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x1e, 0x73, 0x6f, 0x75, 0x72, 0x63,
+	0x65, 0x45, 0x70, 0x68, 0x65, 0x6d, 0x65, 0x72, 0x61, 0x6c, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74,
+	0x50, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x4b, 0x65, 0x79, 0x12, 0x16, 0x0a, 0x06, 0x68, 0x65, 0x69,
+	0x67, 0x68, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x68, 0x65, 0x69, 0x67, 0x68,
+	0x74, 0x12, 0x14, 0x0a, 0x05, 0x72, 0x6f, 0x75, 0x6e, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x05, 0x72, 0x6f, 0x75, 0x6e, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x74, 0x65, 0x70, 0x18,
+	0x05, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x73, 0x74, 0x65, 0x70, 0x12, 0x30, 0x0a, 0x14, 0x65,
+	0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x65, 0x64, 0x5f, 0x73, 0x68, 0x61, 0x72, 0x65, 0x5f, 0x70,
+	0x61, 0x72, 0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x12, 0x65, 0x6e, 0x63, 0x72, 0x79,
+	0x70, 0x74, 0x65, 0x64, 0x53, 0x68, 0x61, 0x72, 0x65, 0x50, 0x61, 0x72, 0x74, 0x12, 0x1d, 0x0a,
+	0x0a, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x5f, 0x73, 0x69, 0x67, 0x18, 0x07, 0x20, 0x01, 0x28,
+	0x0c, 0x52, 0x09, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x53, 0x69, 0x67, 0x12, 0x29, 0x0a, 0x10,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e,
+	0x18, 0x08, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c,
+	0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x19, 0x0a, 0x08, 0x63, 0x68, 0x61, 0x69, 0x6e,
+	0x5f, 0x69, 0x44, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x63, 0x68, 0x61, 0x69, 0x6e,
+	0x49, 0x44, 0x22, 0x28, 0x0a, 0x26, 0x43, 0x6f, 0x73, 0x69, 0x67, 0x6e, 0x65, 0x72, 0x53, 0x65,
+	0x74, 0x45, 0x70, 0x68, 0x65, 0x6d, 0x65, 0x72, 0x61, 0x6c, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74,
+	0x50, 0x61, 0x72, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x56, 0x0a, 0x1a,
+	0x43, 0x6f, 0x73, 0x69, 0x67, 0x6e, 0x65, 0x72, 0x52, 0x65, 0x66, 0x72, 0x65, 0x73, 0x68, 0x44,
+	0x65, 0x61, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1d, 0x0a, 0x0a, 0x72, 0x65,
+	0x66, 0x72, 0x65, 0x73, 0x68, 0x5f, 0x69, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09,
+	0x72, 0x65, 0x66, 0x72, 0x65, 0x73, 0x68, 0x49, 0x44, 0x12, 0x19, 0x0a, 0x08, 0x63, 0x68, 0x61,
+	0x69, 0x6e, 0x5f, 0x69, 0x44, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x63, 0x68, 0x61,
+	0x69, 0x6e, 0x49, 0x44, 0x22, 0xbd, 0x01, 0x0a, 0x13, 0x43, 0x6f, 0x73, 0x69, 0x67, 0x6e, 0x65,
+	0x72, 0x52, 0x65, 0x66, 0x72, 0x65, 0x73, 0x68, 0x44, 0x65, 0x61, 0x6c, 0x12, 0x1d, 0x0a, 0x0a,
+	0x72, 0x65, 0x66, 0x72, 0x65, 0x73, 0x68, 0x5f, 0x69, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x09, 0x72, 0x65, 0x66, 0x72, 0x65, 0x73, 0x68, 0x49, 0x44, 0x12, 0x1b, 0x0a, 0x09, 0x73,
+	0x6f, 0x75, 0x72, 0x63, 0x65, 0x5f, 0x69, 0x44, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08,
+	0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x49, 0x44, 0x12, 0x20, 0x0a, 0x0b, 0x63, 0x6f, 0x6d, 0x6d,
+	0x69, 0x74, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x0b, 0x63,
+	0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x12, 0x29, 0x0a, 0x10, 0x65, 0x6e,
+	0x63, 0x72, 0x79, 0x70, 0x74, 0x65, 0x64, 0x5f, 0x73, 0x68, 0x61, 0x72, 0x65, 0x73, 0x18, 0x04,
+	0x20, 0x03, 0x28, 0x0c, 0x52, 0x0f, 0x65, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x65, 0x64, 0x53,
+	0x68, 0x61, 0x72, 0x65, 0x73, 0x12, 0x1d, 0x0a, 0x0a, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x5f,
+	0x73, 0x69, 0x67, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x73, 0x6f, 0x75, 0x72, 0x63,
+	0x65, 0x53, 0x69, 0x67, 0x22, 0xb2, 0x01, 0x0a, 0x1d, 0x43, 0x6f, 0x73, 0x69, 0x67, 0x6e, 0x65,
+	0x72, 0x52, 0x65, 0x66, 0x72, 0x65, 0x73, 0x68, 0x50, 0x72, 0x65, 0x70, 0x61, 0x72, 0x65, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1d, 0x0a, 0x0a, 0x72, 0x65, 0x66, 0x72, 0x65, 0x73,
+	0x68, 0x5f, 0x69, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x72, 0x65, 0x66, 0x72,
+	0x65, 0x73, 0x68, 0x49, 0x44, 0x12, 0x2b, 0x0a, 0x11, 0x61, 0x63, 0x74, 0x69, 0x76, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x5f, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x10, 0x61, 0x63, 0x74, 0x69, 0x76, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x48, 0x65, 0x69, 0x67,
+	0x68, 0x74, 0x12, 0x2a, 0x0a, 0x05, 0x64, 0x65, 0x61, 0x6c, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x14, 0x2e, 0x43, 0x6f, 0x73, 0x69, 0x67, 0x6e, 0x65, 0x72, 0x52, 0x65, 0x66, 0x72,
+	0x65, 0x73, 0x68, 0x44, 0x65, 0x61, 0x6c, 0x52, 0x05, 0x64, 0x65, 0x61, 0x6c, 0x73, 0x12, 0x19,
+	0x0a, 0x08, 0x63, 0x68, 0x61, 0x69, 0x6e, 0x5f, 0x69, 0x44, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x07, 0x63, 0x68, 0x61, 0x69, 0x6e, 0x49, 0x44, 0x22, 0x46, 0x0a, 0x1e, 0x43, 0x6f, 0x73,
+	0x69, 0x67, 0x6e, 0x65, 0x72, 0x52, 0x65, 0x66, 0x72, 0x65, 0x73, 0x68, 0x50, 0x72, 0x65, 0x70,
+	0x61, 0x72, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x24, 0x0a, 0x0e, 0x73,
+	0x68, 0x61, 0x72, 0x65, 0x5f, 0x70, 0x75, 0x62, 0x5f, 0x6b, 0x65, 0x79, 0x73, 0x18, 0x01, 0x20,
+	0x03, 0x28, 0x0c, 0x52, 0x0c, 0x73, 0x68, 0x61, 0x72, 0x65, 0x50, 0x75, 0x62, 0x4b, 0x65, 0x79,
+	0x73, 0x22, 0x58, 0x0a, 0x1c, 0x43, 0x6f, 0x73, 0x69, 0x67, 0x6e, 0x65, 0x72, 0x52, 0x65, 0x66,
+	0x72, 0x65, 0x73, 0x68, 0x43, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x12, 0x1d, 0x0a, 0x0a, 0x72, 0x65, 0x66, 0x72, 0x65, 0x73, 0x68, 0x5f, 0x69, 0x44, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x72, 0x65, 0x66, 0x72, 0x65, 0x73, 0x68, 0x49, 0x44,
+	0x12, 0x19, 0x0a, 0x08, 0x63, 0x68, 0x61, 0x69, 0x6e, 0x5f, 0x69, 0x44, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x07, 0x63, 0x68, 0x61, 0x69, 0x6e, 0x49, 0x44, 0x22, 0x1f, 0x0a, 0x1d, 0x43,
+	0x6f, 0x73, 0x69, 0x67, 0x6e, 0x65, 0x72, 0x52, 0x65, 0x66, 0x72, 0x65, 0x73, 0x68, 0x43, 0x6f,
+	0x6d, 0x6d, 0x69, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x57, 0x0a, 0x1b,
+	0x43, 0x6f, 0x73, 0x69, 0x67, 0x6e, 0x65, 0x72, 0x52, 0x65, 0x66, 0x72, 0x65, 0x73, 0x68, 0x41,
+	0x62, 0x6f, 0x72, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1d, 0x0a, 0x0a, 0x72,
+	0x65, 0x66, 0x72, 0x65, 0x73, 0x68, 0x5f, 0x69, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x09, 0x72, 0x65, 0x66, 0x72, 0x65, 0x73, 0x68, 0x49, 0x44, 0x12, 0x19, 0x0a, 0x08, 0x63, 0x68,
+	0x61, 0x69, 0x6e, 0x5f, 0x69, 0x44, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x63, 0x68,
+	0x61, 0x69, 0x6e, 0x49, 0x44, 0x22, 0x1e, 0x0a, 0x1c, 0x43, 0x6f, 0x73, 0x69, 0x67, 0x6e, 0x65,
+	0x72, 0x52, 0x65, 0x66, 0x72, 0x65, 0x73, 0x68, 0x41, 0x62, 0x6f, 0x72, 0x74, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x8a, 0x01, 0x0a, 0x18, 0x43, 0x6f, 0x73, 0x69, 0x67, 0x6e,
+	0x65, 0x72, 0x53, 0x69, 0x67, 0x6e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x12, 0x19, 0x0a, 0x08, 0x63, 0x68, 0x61, 0x69, 0x6e, 0x5f, 0x69, 0x44, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x63, 0x68, 0x61, 0x69, 0x6e, 0x49, 0x44, 0x12, 0x1d, 0x0a,
+	0x0a, 0x73, 0x69, 0x67, 0x6e, 0x5f, 0x62, 0x79, 0x74, 0x65, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x0c, 0x52, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x42, 0x79, 0x74, 0x65, 0x73, 0x12, 0x1c, 0x0a, 0x09,
+	0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x6f,
+	0x75, 0x72, 0x63, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x6f, 0x75, 0x72,
+	0x63, 0x65, 0x22, 0x57, 0x0a, 0x19, 0x43, 0x6f, 0x73, 0x69, 0x67, 0x6e, 0x65, 0x72, 0x53, 0x69,
+	0x67, 0x6e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12,
+	0x1c, 0x0a, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x0c, 0x52, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x12, 0x1c, 0x0a,
+	0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x32, 0xfd, 0x05, 0x0a, 0x0f,
+	0x43, 0x6f, 0x73, 0x69, 0x67, 0x6e, 0x65, 0x72, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12,
+	0x33, 0x0a, 0x04, 0x53, 0x69, 0x67, 0x6e, 0x12, 0x14, 0x2e, 0x43, 0x6f, 0x73, 0x69, 0x67, 0x6e,
+	0x65, 0x72, 0x53, 0x69, 0x67, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x15, 0x2e,
+	0x43, 0x6f, 0x73, 0x69, 0x67, 0x6e, 0x65, 0x72, 0x53, 0x69, 0x67, 0x6e, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x12, 0x69, 0x0a, 0x16, 0x47, 0x65, 0x74, 0x45, 0x70, 0x68, 0x65, 0x6d,
+	0x65, 0x72, 0x61, 0x6c, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74, 0x50, 0x61, 0x72, 0x74, 0x12, 0x26,
+	0x2e, 0x43, 0x6f, 0x73, 0x69, 0x67, 0x6e, 0x65, 0x72, 0x47, 0x65, 0x74, 0x45, 0x70, 0x68, 0x65,
+	0x6d, 0x65, 0x72, 0x61, 0x6c, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74, 0x50, 0x61, 0x72, 0x74, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x27, 0x2e, 0x43, 0x6f, 0x73, 0x69, 0x67, 0x6e, 0x65,
+	0x72, 0x47, 0x65, 0x74, 0x45, 0x70, 0x68, 0x65, 0x6d, 0x65, 0x72, 0x61, 0x6c, 0x53, 0x65, 0x63,
+	0x72, 0x65, 0x74, 0x50, 0x61, 0x72, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12,
+	0x69, 0x0a, 0x16, 0x48, 0x61, 0x73, 0x45, 0x70, 0x68, 0x65, 0x6d, 0x65, 0x72, 0x61, 0x6c, 0x53,
+	0x65, 0x63, 0x72, 0x65, 0x74, 0x50, 0x61, 0x72, 0x74, 0x12, 0x26, 0x2e, 0x43, 0x6f, 0x73, 0x69,
+	0x67, 0x6e, 0x65, 0x72, 0x48, 0x61, 0x73, 0x45, 0x70, 0x68, 0x65, 0x6d, 0x65, 0x72, 0x61, 0x6c,
+	0x53, 0x65, 0x63, 0x72, 0x65, 0x74, 0x50, 0x61, 0x72, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x27, 0x2e, 0x43, 0x6f, 0x73, 0x69, 0x67, 0x6e, 0x65, 0x72, 0x48, 0x61, 0x73, 0x45,
+	0x70, 0x68, 0x65, 0x6d, 0x65, 0x72, 0x61, 0x6c, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74, 0x50, 0x61,
+	0x72, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x69, 0x0a, 0x16, 0x53, 0x65,
+	0x74, 0x45, 0x70, 0x68, 0x65, 0x6d, 0x65, 0x72, 0x61, 0x6c, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74,
+	0x50, 0x61, 0x72, 0x74, 0x12, 0x26, 0x2e, 0x43, 0x6f, 0x73, 0x69, 0x67, 0x6e, 0x65, 0x72, 0x53,
+	0x65, 0x74, 0x45, 0x70, 0x68, 0x65, 0x6d, 0x65, 0x72, 0x61, 0x6c, 0x53, 0x65, 0x63, 0x72, 0x65,
+	0x74, 0x50, 0x61, 0x72, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x27, 0x2e, 0x43,
+	0x6f, 0x73, 0x69, 0x67, 0x6e, 0x65, 0x72, 0x53, 0x65, 0x74, 0x45, 0x70, 0x68, 0x65, 0x6d, 0x65,
+	0x72, 0x61, 0x6c, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74, 0x50, 0x61, 0x72, 0x74, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x40, 0x0a, 0x0b, 0x52, 0x65, 0x66, 0x72, 0x65, 0x73, 0x68,
+	0x44, 0x65, 0x61, 0x6c, 0x12, 0x1b, 0x2e, 0x43, 0x6f, 0x73, 0x69, 0x67, 0x6e, 0x65, 0x72, 0x52,
+	0x65, 0x66, 0x72, 0x65, 0x73, 0x68, 0x44, 0x65, 0x61, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x14, 0x2e, 0x43, 0x6f, 0x73, 0x69, 0x67, 0x6e, 0x65, 0x72, 0x52, 0x65, 0x66, 0x72,
+	0x65, 0x73, 0x68, 0x44, 0x65, 0x61, 0x6c, 0x12, 0x51, 0x0a, 0x0e, 0x52, 0x65, 0x66, 0x72, 0x65,
+	0x73, 0x68, 0x50, 0x72, 0x65, 0x70, 0x61, 0x72, 0x65, 0x12, 0x1e, 0x2e, 0x43, 0x6f, 0x73, 0x69,
+	0x67, 0x6e, 0x65, 0x72, 0x52, 0x65, 0x66, 0x72, 0x65, 0x73, 0x68, 0x50, 0x72, 0x65, 0x70, 0x61,
+	0x72, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1f, 0x2e, 0x43, 0x6f, 0x73, 0x69,
+	0x67, 0x6e, 0x65, 0x72, 0x52, 0x65, 0x66, 0x72, 0x65, 0x73, 0x68, 0x50, 0x72, 0x65, 0x70, 0x61,
+	0x72, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x4e, 0x0a, 0x0d, 0x52, 0x65,
+	0x66, 0x72, 0x65, 0x73, 0x68, 0x43, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x12, 0x1d, 0x2e, 0x43, 0x6f,
+	0x73, 0x69, 0x67, 0x6e, 0x65, 0x72, 0x52, 0x65, 0x66, 0x72, 0x65, 0x73, 0x68, 0x43, 0x6f, 0x6d,
+	0x6d, 0x69, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1e, 0x2e, 0x43, 0x6f, 0x73,
+	0x69, 0x67, 0x6e, 0x65, 0x72, 0x52, 0x65, 0x66, 0x72, 0x65, 0x73, 0x68, 0x43, 0x6f, 0x6d, 0x6d,
+	0x69, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x4b, 0x0a, 0x0c, 0x52, 0x65,
+	0x66, 0x72, 0x65, 0x73, 0x68, 0x41, 0x62, 0x6f, 0x72, 0x74, 0x12, 0x1c, 0x2e, 0x43, 0x6f, 0x73,
+	0x69, 0x67, 0x6e, 0x65, 0x72, 0x52, 0x65, 0x66, 0x72, 0x65, 0x73, 0x68, 0x41, 0x62, 0x6f, 0x72,
+	0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1d, 0x2e, 0x43, 0x6f, 0x73, 0x69, 0x67,
+	0x6e, 0x65, 0x72, 0x52, 0x65, 0x66, 0x72, 0x65, 0x73, 0x68, 0x41, 0x62, 0x6f, 0x72, 0x74, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x42, 0x0a, 0x09, 0x53, 0x69, 0x67, 0x6e, 0x42,
+	0x6c, 0x6f, 0x63, 0x6b, 0x12, 0x19, 0x2e, 0x43, 0x6f, 0x73, 0x69, 0x67, 0x6e, 0x65, 0x72, 0x53,
+	0x69, 0x67, 0x6e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
+	0x1a, 0x2e, 0x43, 0x6f, 0x73, 0x69, 0x67, 0x6e, 0x65, 0x72, 0x53, 0x69, 0x67, 0x6e, 0x42, 0x6c,
+	0x6f, 0x63, 0x6b, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x42, 0x09, 0x5a, 0x07, 0x2f,
+	0x73, 0x69, 0x67, 0x6e, 0x65, 0x72, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_proto_cosigner_proto_rawDescOnce sync.Once
+	file_proto_cosigner_proto_rawDescData = file_proto_cosigner_proto_rawDesc
+)
+
+func file_proto_cosigner_proto_rawDescGZIP() []byte {
+	file_proto_cosigner_proto_rawDescOnce.Do(func() {
+		file_proto_cosigner_proto_rawDescData = protoimpl.X.CompressGZIP(file_proto_cosigner_proto_rawDescData)
+	})
+	return file_proto_cosigner_proto_rawDescData
+}
+
+var file_proto_cosigner_proto_msgTypes = make([]protoimpl.MessageInfo, 18)
+var file_proto_cosigner_proto_goTypes = []interface{}{
+	(*CosignerSignRequest)(nil),                    // 0: CosignerSignRequest
+	(*CosignerSignResponse)(nil),                   // 1: CosignerSignResponse
+	(*CosignerGetEphemeralSecretPartRequest)(nil),  // 2: CosignerGetEphemeralSecretPartRequest
+	(*CosignerGetEphemeralSecretPartResponse)(nil), // 3: CosignerGetEphemeralSecretPartResponse
+	(*CosignerHasEphemeralSecretPartRequest)(nil),  // 4: CosignerHasEphemeralSecretPartRequest
+	(*CosignerHasEphemeralSecretPartResponse)(nil), // 5: CosignerHasEphemeralSecretPartResponse
+	(*CosignerSetEphemeralSecretPartRequest)(nil),  // 6: CosignerSetEphemeralSecretPartRequest
+	(*CosignerSetEphemeralSecretPartResponse)(nil), // 7: CosignerSetEphemeralSecretPartResponse
+	(*CosignerRefreshDealRequest)(nil),             // 8: CosignerRefreshDealRequest
+	(*CosignerRefreshDeal)(nil),                    // 9: CosignerRefreshDeal
+	(*CosignerRefreshPrepareRequest)(nil),          // 10: CosignerRefreshPrepareRequest
+	(*CosignerRefreshPrepareResponse)(nil),         // 11: CosignerRefreshPrepareResponse
+	(*CosignerRefreshCommitRequest)(nil),           // 12: CosignerRefreshCommitRequest
+	(*CosignerRefreshCommitResponse)(nil),          // 13: CosignerRefreshCommitResponse
+	(*CosignerRefreshAbortRequest)(nil),            // 14: CosignerRefreshAbortRequest
+	(*CosignerRefreshAbortResponse)(nil),           // 15: CosignerRefreshAbortResponse
+	(*CosignerSignBlockRequest)(nil),               // 16: CosignerSignBlockRequest
+	(*CosignerSignBlockResponse)(nil),              // 17: CosignerSignBlockResponse
+}
+var file_proto_cosigner_proto_depIdxs = []int32{
+	9,  // 0: CosignerRefreshPrepareRequest.deals:type_name -> CosignerRefreshDeal
+	0,  // 1: CosignerService.Sign:input_type -> CosignerSignRequest
+	2,  // 2: CosignerService.GetEphemeralSecretPart:input_type -> CosignerGetEphemeralSecretPartRequest
+	4,  // 3: CosignerService.HasEphemeralSecretPart:input_type -> CosignerHasEphemeralSecretPartRequest
+	6,  // 4: CosignerService.SetEphemeralSecretPart:input_type -> CosignerSetEphemeralSecretPartRequest
+	8,  // 5: CosignerService.RefreshDeal:input_type -> CosignerRefreshDealRequest
+	10, // 6: CosignerService.RefreshPrepare:input_type -> CosignerRefreshPrepareRequest
+	12, // 7: CosignerService.RefreshCommit:input_type -> CosignerRefreshCommitRequest
+	14, // 8: CosignerService.RefreshAbort:input_type -> CosignerRefreshAbortRequest
+	16, // 9: CosignerService.SignBlock:input_type -> CosignerSignBlockRequest
+	1,  // 10: CosignerService.Sign:output_type -> CosignerSignResponse
+	3,  // 11: CosignerService.GetEphemeralSecretPart:output_type -> CosignerGetEphemeralSecretPartResponse
+	5,  // 12: CosignerService.HasEphemeralSecretPart:output_type -> CosignerHasEphemeralSecretPartResponse
+	7,  // 13: CosignerService.SetEphemeralSecretPart:output_type -> CosignerSetEphemeralSecretPartResponse
+	9,  // 14: CosignerService.RefreshDeal:output_type -> CosignerRefreshDeal
+	11, // 15: CosignerService.RefreshPrepare:output_type -> CosignerRefreshPrepareResponse
+	13, // 16: CosignerService.RefreshCommit:output_type -> CosignerRefreshCommitResponse
+	15, // 17: CosignerService.RefreshAbort:output_type -> CosignerRefreshAbortResponse
+	17, // 18: CosignerService.SignBlock:output_type -> CosignerSignBlockResponse
+	10, // [10:19] is the sub-list for method output_type
+	1,  // [1:10] is the sub-list for method input_type
+	1,  // [1:1] is the sub-list for extension type_name
+	1,  // [1:1] is the sub-list for extension extendee
+	0,  // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_proto_cosigner_proto_init() }
+func file_proto_cosigner_proto_init() {
+	if File_proto_cosigner_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_proto_cosigner_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CosignerSignRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_cosigner_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CosignerSignResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_cosigner_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CosignerGetEphemeralSecretPartRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_cosigner_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CosignerGetEphemeralSecretPartResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_cosigner_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CosignerHasEphemeralSecretPartRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_cosigner_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CosignerHasEphemeralSecretPartResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_cosigner_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CosignerSetEphemeralSecretPartRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_cosigner_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CosignerSetEphemeralSecretPartResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_cosigner_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CosignerRefreshDealRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_cosigner_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CosignerRefreshDeal); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_cosigner_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CosignerRefreshPrepareRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_cosigner_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CosignerRefreshPrepareResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_cosigner_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CosignerRefreshCommitRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_cosigner_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CosignerRefreshCommitResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_cosigner_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CosignerRefreshAbortRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_cosigner_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CosignerRefreshAbortResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_cosigner_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CosignerSignBlockRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_cosigner_proto_msgTypes[17].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CosignerSignBlockResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_proto_cosigner_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   18,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_proto_cosigner_proto_goTypes,
+		DependencyIndexes: file_proto_cosigner_proto_depIdxs,
+		MessageInfos:      file_proto_cosigner_proto_msgTypes,
+	}.Build()
+	File_proto_cosigner_proto = out.File
+	file_proto_cosigner_proto_rawDesc = nil
+	file_proto_cosigner_proto_goTypes = nil
+	file_proto_cosigner_proto_depIdxs = nil
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConnInterface
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion6
+
+// CosignerServiceClient is the client API for CosignerService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type CosignerServiceClient interface {
+	Sign(ctx context.Context, in *CosignerSignRequest, opts ...grpc.CallOption) (*CosignerSignResponse, error)
+	GetEphemeralSecretPart(ctx context.Context, in *CosignerGetEphemeralSecretPartRequest, opts ...grpc.CallOption) (*CosignerGetEphemeralSecretPartResponse, error)
+	HasEphemeralSecretPart(ctx context.Context, in *CosignerHasEphemeralSecretPartRequest, opts ...grpc.CallOption) (*CosignerHasEphemeralSecretPartResponse, error)
+	SetEphemeralSecretPart(ctx context.Context, in *CosignerSetEphemeralSecretPartRequest, opts ...grpc.CallOption) (*CosignerSetEphemeralSecretPartResponse, error)
+	RefreshDeal(ctx context.Context, in *CosignerRefreshDealRequest, opts ...grpc.CallOption) (*CosignerRefreshDeal, error)
+	RefreshPrepare(ctx context.Context, in *CosignerRefreshPrepareRequest, opts ...grpc.CallOption) (*CosignerRefreshPrepareResponse, error)
+	RefreshCommit(ctx context.Context, in *CosignerRefreshCommitRequest, opts ...grpc.CallOption) (*CosignerRefreshCommitResponse, error)
+	RefreshAbort(ctx context.Context, in *CosignerRefreshAbortRequest, opts ...grpc.CallOption) (*CosignerRefreshAbortResponse, error)
+	SignBlock(ctx context.Context, in *CosignerSignBlockRequest, opts ...grpc.CallOption) (*CosignerSignBlockResponse, error)
+}
+
+type cosignerServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCosignerServiceClient(cc grpc.ClientConnInterface) CosignerServiceClient {
+	return &cosignerServiceClient{cc}
+}
+
+func (c *cosignerServiceClient) Sign(ctx context.Context, in *CosignerSignRequest, opts ...grpc.CallOption) (*CosignerSignResponse, error) {
+	out := new(CosignerSignResponse)
+	err := c.cc.Invoke(ctx, "/CosignerService/Sign", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cosignerServiceClient) GetEphemeralSecretPart(ctx context.Context, in *CosignerGetEphemeralSecretPartRequest, opts ...grpc.CallOption) (*CosignerGetEphemeralSecretPartResponse, error) {
+	out := new(CosignerGetEphemeralSecretPartResponse)
+	err := c.cc.Invoke(ctx, "/CosignerService/GetEphemeralSecretPart", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cosignerServiceClient) HasEphemeralSecretPart(ctx context.Context, in *CosignerHasEphemeralSecretPartRequest, opts ...grpc.CallOption) (*CosignerHasEphemeralSecretPartResponse, error) {
+	out := new(CosignerHasEphemeralSecretPartResponse)
+	err := c.cc.Invoke(ctx, "/CosignerService/HasEphemeralSecretPart", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cosignerServiceClient) SetEphemeralSecretPart(ctx context.Context, in *CosignerSetEphemeralSecretPartRequest, opts ...grpc.CallOption) (*CosignerSetEphemeralSecretPartResponse, error) {
+	out := new(CosignerSetEphemeralSecretPartResponse)
+	err := c.cc.Invoke(ctx, "/CosignerService/SetEphemeralSecretPart", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cosignerServiceClient) RefreshDeal(ctx context.Context, in *CosignerRefreshDealRequest, opts ...grpc.CallOption) (*CosignerRefreshDeal, error) {
+	out := new(CosignerRefreshDeal)
+	err := c.cc.Invoke(ctx, "/CosignerService/RefreshDeal", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cosignerServiceClient) RefreshPrepare(ctx context.Context, in *CosignerRefreshPrepareRequest, opts ...grpc.CallOption) (*CosignerRefreshPrepareResponse, error) {
+	out := new(CosignerRefreshPrepareResponse)
+	err := c.cc.Invoke(ctx, "/CosignerService/RefreshPrepare", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cosignerServiceClient) RefreshCommit(ctx context.Context, in *CosignerRefreshCommitRequest, opts ...grpc.CallOption) (*CosignerRefreshCommitResponse, error) {
+	out := new(CosignerRefreshCommitResponse)
+	err := c.cc.Invoke(ctx, "/CosignerService/RefreshCommit", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cosignerServiceClient) RefreshAbort(ctx context.Context, in *CosignerRefreshAbortRequest, opts ...grpc.CallOption) (*CosignerRefreshAbortResponse, error) {
+	out := new(CosignerRefreshAbortResponse)
+	err := c.cc.Invoke(ctx, "/CosignerService/RefreshAbort", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cosignerServiceClient) SignBlock(ctx context.Context, in *CosignerSignBlockRequest, opts ...grpc.CallOption) (*CosignerSignBlockResponse, error) {
+	out := new(CosignerSignBlockResponse)
+	err := c.cc.Invoke(ctx, "/CosignerService/SignBlock", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CosignerServiceServer is the server API for CosignerService service.
+type CosignerServiceServer interface {
+	Sign(context.Context, *CosignerSignRequest) (*CosignerSignResponse, error)
+	GetEphemeralSecretPart(context.Context, *CosignerGetEphemeralSecretPartRequest) (*CosignerGetEphemeralSecretPartResponse, error)
+	HasEphemeralSecretPart(context.Context, *CosignerHasEphemeralSecretPartRequest) (*CosignerHasEphemeralSecretPartResponse, error)
+	SetEphemeralSecretPart(context.Context, *CosignerSetEphemeralSecretPartRequest) (*CosignerSetEphemeralSecretPartResponse, error)
+	RefreshDeal(context.Context, *CosignerRefreshDealRequest) (*CosignerRefreshDeal, error)
+	RefreshPrepare(context.Context, *CosignerRefreshPrepareRequest) (*CosignerRefreshPrepareResponse, error)
+	RefreshCommit(context.Context, *CosignerRefreshCommitRequest) (*CosignerRefreshCommitResponse, error)
+	RefreshAbort(context.Context, *CosignerRefreshAbortRequest) (*CosignerRefreshAbortResponse, error)
+	SignBlock(context.Context, *CosignerSignBlockRequest) (*CosignerSignBlockResponse, error)
+}
+
+// UnimplementedCosignerServiceServer can be embedded to have forward compatible implementations.
+type UnimplementedCosignerServiceServer struct {
+}
+
+func (*UnimplementedCosignerServiceServer) Sign(context.Context, *CosignerSignRequest) (*CosignerSignResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Sign not implemented")
+}
+func (*UnimplementedCosignerServiceServer) GetEphemeralSecretPart(context.Context, *CosignerGetEphemeralSecretPartRequest) (*CosignerGetEphemeralSecretPartResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetEphemeralSecretPart not implemented")
+}
+func (*UnimplementedCosignerServiceServer) HasEphemeralSecretPart(context.Context, *CosignerHasEphemeralSecretPartRequest) (*CosignerHasEphemeralSecretPartResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method HasEphemeralSecretPart not implemented")
+}
+func (*UnimplementedCosignerServiceServer) SetEphemeralSecretPart(context.Context, *CosignerSetEphemeralSecretPartRequest) (*CosignerSetEphemeralSecretPartResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetEphemeralSecretPart not implemented")
+}
+func (*UnimplementedCosignerServiceServer) RefreshDeal(context.Context, *CosignerRefreshDealRequest) (*CosignerRefreshDeal, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RefreshDeal not implemented")
+}
+func (*UnimplementedCosignerServiceServer) RefreshPrepare(context.Context, *CosignerRefreshPrepareRequest) (*CosignerRefreshPrepareResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RefreshPrepare not implemented")
+}
+func (*UnimplementedCosignerServiceServer) RefreshCommit(context.Context, *CosignerRefreshCommitRequest) (*CosignerRefreshCommitResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RefreshCommit not implemented")
+}
+func (*UnimplementedCosignerServiceServer) RefreshAbort(context.Context, *CosignerRefreshAbortRequest) (*CosignerRefreshAbortResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RefreshAbort not implemented")
+}
+func (*UnimplementedCosignerServiceServer) SignBlock(context.Context, *CosignerSignBlockRequest) (*CosignerSignBlockResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SignBlock not implemented")
+}
+
+func RegisterCosignerServiceServer(s *grpc.Server, srv CosignerServiceServer) {
+	s.RegisterService(&_CosignerService_serviceDesc, srv)
+}
+
+func _CosignerService_Sign_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CosignerSignRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CosignerServiceServer).Sign(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/CosignerService/Sign",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CosignerServiceServer).Sign(ctx, req.(*CosignerSignRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CosignerService_GetEphemeralSecretPart_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CosignerGetEphemeralSecretPartRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CosignerServiceServer).GetEphemeralSecretPart(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/CosignerService/GetEphemeralSecretPart",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CosignerServiceServer).GetEphemeralSecretPart(ctx, req.(*CosignerGetEphemeralSecretPartRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CosignerService_HasEphemeralSecretPart_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CosignerHasEphemeralSecretPartRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CosignerServiceServer).HasEphemeralSecretPart(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/CosignerService/HasEphemeralSecretPart",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CosignerServiceServer).HasEphemeralSecretPart(ctx, req.(*CosignerHasEphemeralSecretPartRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CosignerService_SetEphemeralSecretPart_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CosignerSetEphemeralSecretPartRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CosignerServiceServer).SetEphemeralSecretPart(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/CosignerService/SetEphemeralSecretPart",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CosignerServiceServer).SetEphemeralSecretPart(ctx, req.(*CosignerSetEphemeralSecretPartRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CosignerService_RefreshDeal_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CosignerRefreshDealRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CosignerServiceServer).RefreshDeal(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/CosignerService/RefreshDeal",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CosignerServiceServer).RefreshDeal(ctx, req.(*CosignerRefreshDealRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CosignerService_RefreshPrepare_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CosignerRefreshPrepareRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CosignerServiceServer).RefreshPrepare(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/CosignerService/RefreshPrepare",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CosignerServiceServer).RefreshPrepare(ctx, req.(*CosignerRefreshPrepareRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CosignerService_RefreshCommit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CosignerRefreshCommitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CosignerServiceServer).RefreshCommit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/CosignerService/RefreshCommit",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CosignerServiceServer).RefreshCommit(ctx, req.(*CosignerRefreshCommitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CosignerService_RefreshAbort_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CosignerRefreshAbortRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CosignerServiceServer).RefreshAbort(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/CosignerService/RefreshAbort",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CosignerServiceServer).RefreshAbort(ctx, req.(*CosignerRefreshAbortRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CosignerService_SignBlock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CosignerSignBlockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CosignerServiceServer).SignBlock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/CosignerService/SignBlock",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CosignerServiceServer).SignBlock(ctx, req.(*CosignerSignBlockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _CosignerService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "CosignerService",
 	HandlerType: (*CosignerServiceServer)(nil),
 	Methods: []grpc.MethodDesc{
 		{
@@ -546,6 +2008,34 @@ var _CosignerService_serviceDesc = grpc.ServiceDesc{
 			MethodName: "GetEphemeralSecretPart",
 			Handler:    _CosignerService_GetEphemeralSecretPart_Handler,
 		},
+		{
+			MethodName: "HasEphemeralSecretPart",
+			Handler:    _CosignerService_HasEphemeralSecretPart_Handler,
+		},
+		{
+			MethodName: "SetEphemeralSecretPart",
+			Handler:    _CosignerService_SetEphemeralSecretPart_Handler,
+		},
+		{
+			MethodName: "RefreshDeal",
+			Handler:    _CosignerService_RefreshDeal_Handler,
+		},
+		{
+			MethodName: "RefreshPrepare",
+			Handler:    _CosignerService_RefreshPrepare_Handler,
+		},
+		{
+			MethodName: "RefreshCommit",
+			Handler:    _CosignerService_RefreshCommit_Handler,
+		},
+		{
+			MethodName: "RefreshAbort",
+			Handler:    _CosignerService_RefreshAbort_Handler,
+		},
+		{
+			MethodName: "SignBlock",
+			Handler:    _CosignerService_SignBlock_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/cosigner.proto",
@@ -1,36 +1,149 @@
 package signer
 
 import (
+	"bytes"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io/ioutil"
 
 	amino "github.com/tendermint/go-amino"
 	tmCrypto "github.com/tendermint/tendermint/crypto"
 	tmEd25519 "github.com/tendermint/tendermint/crypto/ed25519"
 	tmCryptoEncoding "github.com/tendermint/tendermint/crypto/encoding"
+	"github.com/tendermint/tendermint/libs/tempfile"
 	tmProtoCrypto "github.com/tendermint/tendermint/proto/tendermint/crypto"
 )
 
 // CosignerKey is a single key for an m-of-n threshold signer.
 type CosignerKey struct {
-	PubKey       tmCrypto.PubKey  `json:"pub_key"`
-	ShareKey     []byte           `json:"secret_share"`
+	PubKey   tmCrypto.PubKey `json:"pub_key"`
+	ShareKey []byte          `json:"secret_share"`
+	ID       int             `json:"id"`
+
+	// Public key of every share, used to verify the share signatures one by one
+	SharePubKeys [][]byte `json:"share_pubs,omitempty"`
+
+	// Feldman commitments of the polynomial the shares are dealt from, the first one is PubKey
+	Commitments [][]byte `json:"commitments,omitempty"`
+
+	// Refreshed share, replaces ShareKey and SharePubKeys from its activation height on
+	NextShare *CosignerNextShare `json:"next_share,omitempty"`
+
+	// Transport keys used to exchange the ephemeral share parts with the other cosigners
+	TransportKey     *CosignerTransportKey      `json:"transport_key,omitempty"`
+	TransportPubKeys []*CosignerTransportPubKey `json:"transport_pubs,omitempty"`
+
+	// Legacy RSA transport keys, only set for key files that haven't been migrated
 	RSAKey       rsa.PrivateKey   `json:"rsa_key"`
-	ID           int              `json:"id"`
 	CosignerKeys []*rsa.PublicKey `json:"rsa_pubs"`
+
+	// passphrase of the key file if it is encrypted, the file is encrypted again when it is saved
+	passphrase []byte
+}
+
+// CosignerNextShare is a refreshed share waiting for its activation height
+type CosignerNextShare struct {
+	ShareKey         []byte   `json:"secret_share"`
+	SharePubKeys     [][]byte `json:"share_pubs,omitempty"`
+	Commitments      [][]byte `json:"commitments,omitempty"`
+	ActivationHeight int64    `json:"activation_height"`
+}
+
+// IsLegacy returns true if the key still uses RSA transport keys
+// The private transport key may be kept by the key backend, the public ones are always in the key.
+func (cosignerKey *CosignerKey) IsLegacy() bool {
+	return cosignerKey.TransportKey == nil && len(cosignerKey.TransportPubKeys) == 0
+}
+
+// CosignerPeer returns the transport public key of the cosigner with the ID
+func (cosignerKey *CosignerKey) CosignerPeer(id int) (CosignerPeer, error) {
+	if cosignerKey.IsLegacy() {
+		if id < 1 || id > len(cosignerKey.CosignerKeys) {
+			return CosignerPeer{}, fmt.Errorf("Unexpected cosigner ID %d", id)
+		}
+		return CosignerPeer{
+			ID:        id,
+			PublicKey: *cosignerKey.CosignerKeys[id-1],
+		}, nil
+	}
+
+	if id < 1 || id > len(cosignerKey.TransportPubKeys) {
+		return CosignerPeer{}, fmt.Errorf("Unexpected cosigner ID %d", id)
+	}
+	return CosignerPeer{
+		ID:              id,
+		TransportPubKey: cosignerKey.TransportPubKeys[id-1],
+	}, nil
+}
+
+// Validate checks the share against the share public keys and the commitments stored with it
+// Key files created before them only hold the share and can't be checked.
+func (cosignerKey *CosignerKey) Validate() error {
+	pubKey := cosignerKey.PubKey.Bytes()
+	err := validateShare(pubKey, cosignerKey.ID, cosignerKey.ShareKey, cosignerKey.SharePubKeys, cosignerKey.Commitments)
+	if err != nil {
+		return err
+	}
+
+	if next := cosignerKey.NextShare; next != nil {
+		err := validateShare(pubKey, cosignerKey.ID, next.ShareKey, next.SharePubKeys, next.Commitments)
+		if err != nil {
+			return fmt.Errorf("Refreshed share: %v", err)
+		}
+	}
+	return nil
+}
+
+// validateShare checks that the share of cosigner id matches the share public keys and the commitments
+func validateShare(pubKey []byte, id int, share []byte, sharePubKeys [][]byte, commitments [][]byte) error {
+	if len(sharePubKeys) > 0 {
+		if id < 1 || id > len(sharePubKeys) {
+			return fmt.Errorf("No share public key for cosigner %d", id)
+		}
+		if !bytes.Equal(SharePubKey(share), sharePubKeys[id-1]) {
+			return errors.New("The secret share doesn't match its public key")
+		}
+	}
+
+	if len(commitments) > 0 {
+		if !bytes.Equal(commitments[0], pubKey) {
+			return errors.New("The commitments don't match the validator public key")
+		}
+		if !verifyFeldmanShare(commitments, id, share) {
+			return errors.New("The secret share doesn't match the commitments")
+		}
+
+		// the other shares must be dealt from the same polynomial
+		for idx, sharePubKey := range sharePubKeys {
+			expected, err := feldmanEvaluate(commitments, idx+1)
+			if err != nil {
+				return err
+			}
+			if !bytes.Equal(sharePubKey, expected) {
+				return fmt.Errorf("The public key of share %d doesn't match the commitments", idx+1)
+			}
+		}
+	}
+	return nil
 }
 
 func (cosignerKey *CosignerKey) MarshalJSON() ([]byte, error) {
 	type Alias CosignerKey
 
 	// marshal our private key and all public keys
-	privateBytes := x509.MarshalPKCS1PrivateKey(&cosignerKey.RSAKey)
-	rsaPubKeysBytes := make([][]byte, 0)
-	for _, pubKey := range cosignerKey.CosignerKeys {
-		publicBytes := x509.MarshalPKCS1PublicKey(pubKey)
-		rsaPubKeysBytes = append(rsaPubKeysBytes, publicBytes)
+	// migrated keys don't have RSA keys anymore
+	var privateBytes []byte
+	var rsaPubKeysBytes [][]byte
+	if cosignerKey.IsLegacy() {
+		privateBytes = x509.MarshalPKCS1PrivateKey(&cosignerKey.RSAKey)
+		rsaPubKeysBytes = make([][]byte, 0)
+		for _, pubKey := range cosignerKey.CosignerKeys {
+			publicBytes := x509.MarshalPKCS1PublicKey(pubKey)
+			rsaPubKeysBytes = append(rsaPubKeysBytes, publicBytes)
+		}
 	}
 
 	protoPubkey, err := tmCryptoEncoding.PubKeyToProto(cosignerKey.PubKey)
@@ -44,9 +157,9 @@ func (cosignerKey *CosignerKey) MarshalJSON() ([]byte, error) {
 	}
 
 	return json.Marshal(&struct {
-		RSAKey       []byte   `json:"rsa_key"`
+		RSAKey       []byte   `json:"rsa_key,omitempty"`
 		Pubkey       []byte   `json:"pub_key"`
-		CosignerKeys [][]byte `json:"rsa_pubs"`
+		CosignerKeys [][]byte `json:"rsa_pubs,omitempty"`
 		*Alias
 	}{
 		Pubkey:       protoBytes,
@@ -70,14 +183,10 @@ func (cosignerKey *CosignerKey) UnmarshalJSON(data []byte) error {
 	if err := json.Unmarshal(data, &aux); err != nil {
 		return err
 	}
-	privateKey, err := x509.ParsePKCS1PrivateKey(aux.RSAKey)
-	if err != nil {
-		return err
-	}
 
 	var pubkey tmCrypto.PubKey
 	var protoPubkey tmProtoCrypto.PublicKey
-	err = protoPubkey.Unmarshal(aux.PubkeyBytes)
+	err := protoPubkey.Unmarshal(aux.PubkeyBytes)
 
 	// Prior to the tendermint protobuf migration, the public key bytes in key files
 	// were encoded using the go-amino libraries via
@@ -102,6 +211,32 @@ func (cosignerKey *CosignerKey) UnmarshalJSON(data []byte) error {
 		}
 	}
 
+	cosignerKey.PubKey = pubkey
+
+	if !cosignerKey.IsLegacy() {
+		if cosignerKey.TransportKey != nil {
+			if err := cosignerKey.TransportKey.Validate(); err != nil {
+				return err
+			}
+		}
+		for _, pub := range cosignerKey.TransportPubKeys {
+			if err := pub.Validate(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// key files created before the transport keys use RSA
+	if len(aux.RSAKey) == 0 {
+		return errors.New("Key file has neither a transport_key nor an rsa_key")
+	}
+
+	privateKey, err := x509.ParsePKCS1PrivateKey(aux.RSAKey)
+	if err != nil {
+		return err
+	}
+
 	// unmarshal the public key bytes for each cosigner
 	cosignerKey.CosignerKeys = make([]*rsa.PublicKey, 0)
 	for _, bytes := range aux.CosignerKeys {
@@ -113,22 +248,119 @@ func (cosignerKey *CosignerKey) UnmarshalJSON(data []byte) error {
 	}
 
 	cosignerKey.RSAKey = *privateKey
-	cosignerKey.PubKey = pubkey
 	return nil
 }
 
 // LoadCosignerKey loads a CosignerKey from file.
+// The passphrase of an encrypted file is read from the VALINK_KEY_PASSPHRASE environment variable or asked for.
 func LoadCosignerKey(file string) (CosignerKey, error) {
+	return LoadCosignerKeyWithPassphrase(file, KeyPassphrase(""))
+}
+
+// LoadCosignerKeyWithPassphrase loads a CosignerKey from file, decrypting it with the passphrase if it is encrypted.
+func LoadCosignerKeyWithPassphrase(file string, passphrase PassphraseFunc) (CosignerKey, error) {
 	pvKey := CosignerKey{}
 	keyJSONBytes, err := ioutil.ReadFile(file)
 	if err != nil {
 		return pvKey, err
 	}
 
+	var keyPassphrase []byte
+	if IsEncryptedKey(keyJSONBytes) {
+		keyPassphrase, err = passphrase(file)
+		if err != nil {
+			return pvKey, err
+		}
+		keyJSONBytes, err = DecryptKey(keyJSONBytes, keyPassphrase)
+		if err != nil {
+			return pvKey, fmt.Errorf("Error decrypting %v: %v", file, err)
+		}
+	}
+
 	err = json.Unmarshal(keyJSONBytes, &pvKey)
 	if err != nil {
 		return pvKey, err
 	}
+	pvKey.passphrase = keyPassphrase
+
+	// catch a corrupted or mixed up share file before it is used to sign
+	if err := pvKey.Validate(); err != nil {
+		return pvKey, fmt.Errorf("Invalid share in %v: %v", file, err)
+	}
 
 	return pvKey, nil
 }
+
+// SaveCosignerKey atomically writes a CosignerKey to file.
+// A key loaded from an encrypted file is encrypted again with the same passphrase.
+func SaveCosignerKey(file string, key *CosignerKey) error {
+	jsonBytes, err := json.MarshalIndent(key, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if len(key.passphrase) > 0 {
+		jsonBytes, err = EncryptKey(jsonBytes, key.passphrase)
+		if err != nil {
+			return err
+		}
+	}
+	return tempfile.WriteFileAtomic(file, jsonBytes, 0600)
+}
+
+// MigrateCosignerKey returns the key with its transport keys replaced, for instance to move off RSA transport keys
+//
+// Every cosigner migrates its own key with the transport key it generated and the public transport keys of all
+// the cosigners, by ID. The secret share is left untouched. The share public keys are kept, or computed from the
+// commitments if the key has none.
+func MigrateCosignerKey(key *CosignerKey, transportKey *CosignerTransportKey, transportPubKeys []*CosignerTransportPubKey) (*CosignerKey, error) {
+	total := len(key.TransportPubKeys)
+	if key.IsLegacy() {
+		total = len(key.CosignerKeys)
+	}
+	if len(transportPubKeys) != total {
+		return nil, fmt.Errorf("Expected the transport public keys of the %d cosigners, got %d", total, len(transportPubKeys))
+	}
+	if err := validateTransportPubKeys(transportPubKeys); err != nil {
+		return nil, err
+	}
+	if err := transportKey.Validate(); err != nil {
+		return nil, err
+	}
+
+	// the set handed to the other cosigners must hold our own public key
+	ourPub, err := transportKey.PubKey()
+	if err != nil {
+		return nil, err
+	}
+	if key.ID < 1 || key.ID > total || !equalTransportPubKeys([]*CosignerTransportPubKey{ourPub}, transportPubKeys[key.ID-1:key.ID]) {
+		return nil, fmt.Errorf("The transport public key of cosigner %d doesn't match the transport key", key.ID)
+	}
+
+	sharePubKeys := key.SharePubKeys
+	if len(sharePubKeys) == 0 && len(key.Commitments) > 0 {
+		sharePubKeys = make([][]byte, total)
+		for idx := range sharePubKeys {
+			sharePubKeys[idx], err = feldmanEvaluate(key.Commitments, idx+1)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	migrated := &CosignerKey{
+		PubKey:           key.PubKey,
+		ShareKey:         key.ShareKey,
+		ID:               key.ID,
+		SharePubKeys:     sharePubKeys,
+		Commitments:      key.Commitments,
+		NextShare:        key.NextShare,
+		TransportKey:     transportKey,
+		TransportPubKeys: transportPubKeys,
+		passphrase:       key.passphrase,
+	}
+	if err := migrated.Validate(); err != nil {
+		return nil, err
+	}
+	return migrated, nil
+}
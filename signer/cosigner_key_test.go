@@ -1,9 +1,13 @@
 package signer
 
 import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	tmCryptoEd25519 "github.com/tendermint/tendermint/crypto/ed25519"
 )
 
 func TestLoadCosignerKey(test *testing.T) {
@@ -14,3 +18,157 @@ func TestLoadCosignerKey(test *testing.T) {
 	// public key from cosigner pubs array should match public key from our private key
 	require.Equal(test, &key.RSAKey.PublicKey, key.CosignerKeys[key.ID-1])
 }
+
+func TestCosignerKeyTransportKeys(test *testing.T) {
+	transportKey, err := GenerateCosignerTransportKey()
+	require.NoError(test, err)
+	transportPubKey, err := transportKey.PubKey()
+	require.NoError(test, err)
+
+	key := CosignerKey{
+		PubKey:           tmCryptoEd25519.GenPrivKey().PubKey(),
+		ShareKey:         []byte{1, 2, 3},
+		ID:               1,
+		TransportKey:     transportKey,
+		TransportPubKeys: []*CosignerTransportPubKey{transportPubKey},
+	}
+
+	jsonBytes, err := json.Marshal(&key)
+	require.NoError(test, err)
+	require.NotContains(test, string(jsonBytes), "rsa_key")
+
+	file, err := ioutil.TempFile("", "private_share.json")
+	require.NoError(test, err)
+	defer os.Remove(file.Name())
+	_, err = file.Write(jsonBytes)
+	require.NoError(test, err)
+	require.NoError(test, file.Close())
+
+	loaded, err := LoadCosignerKey(file.Name())
+	require.NoError(test, err)
+	require.False(test, loaded.IsLegacy())
+	require.Equal(test, key.PubKey, loaded.PubKey)
+	require.Equal(test, key.ShareKey, loaded.ShareKey)
+	require.Equal(test, transportKey, loaded.TransportKey)
+
+	peer, err := loaded.CosignerPeer(1)
+	require.NoError(test, err)
+	require.Equal(test, transportPubKey, peer.TransportPubKey)
+
+	_, err = loaded.CosignerPeer(2)
+	require.Error(test, err)
+}
+
+func TestLoadLegacyCosignerKeyPeer(test *testing.T) {
+	key, err := LoadCosignerKey("../test/cosigner-key.json")
+	require.NoError(test, err)
+	require.True(test, key.IsLegacy())
+
+	peer, err := key.CosignerPeer(key.ID)
+	require.NoError(test, err)
+	require.Equal(test, key.RSAKey.PublicKey, peer.PublicKey)
+	require.Nil(test, peer.TransportPubKey)
+}
+
+func TestCosignerKeyValidateCommitments(test *testing.T) {
+	privateKey, cosigners, cleanup := newTestCosigners(test, 2, 3)
+	defer cleanup()
+
+	dir, err := ioutil.TempDir("", "cosigner-key")
+	require.NoError(test, err)
+	defer os.RemoveAll(dir)
+
+	key := cosigners[0].key
+	require.Equal(test, privateKey.PubKey().Bytes(), key.Commitments[0])
+	require.NoError(test, key.Validate())
+
+	file := dir + "/private_share_1.json"
+	require.NoError(test, SaveCosignerKey(file, &key))
+	loaded, err := LoadCosignerKey(file)
+	require.NoError(test, err)
+	require.Equal(test, key.Commitments, loaded.Commitments)
+
+	// the share of another cosigner in our key file
+	mixedUp := key
+	mixedUp.ShareKey = cosigners[1].key.ShareKey
+	require.Error(test, mixedUp.Validate())
+	require.NoError(test, SaveCosignerKey(file, &mixedUp))
+	_, err = LoadCosignerKey(file)
+	require.Error(test, err)
+
+	// a corrupted share that still matches its public key
+	corrupted := key
+	corrupted.ShareKey = cosigners[1].key.ShareKey
+	corrupted.SharePubKeys = [][]byte{cosigners[1].key.SharePubKeys[1], key.SharePubKeys[1], key.SharePubKeys[2]}
+	require.Error(test, corrupted.Validate())
+
+	// the commitments of another validator
+	_, otherCosigners, otherCleanup := newTestCosigners(test, 2, 3)
+	defer otherCleanup()
+	mismatched := key
+	mismatched.Commitments = otherCosigners[0].key.Commitments
+	require.Error(test, mismatched.Validate())
+
+	// key files without share public keys and commitments can't be checked
+	legacy := key
+	legacy.SharePubKeys = nil
+	legacy.Commitments = nil
+	legacy.ShareKey = cosigners[1].key.ShareKey
+	require.NoError(test, legacy.Validate())
+}
+
+func TestMigrateCosignerKey(test *testing.T) {
+	key, err := LoadCosignerKey("../test/cosigner-key.json")
+	require.NoError(test, err)
+	require.True(test, key.IsLegacy())
+	total := len(key.CosignerKeys)
+
+	// every cosigner generates its own transport key, only the public keys are exchanged
+	transportKeys := make([]*CosignerTransportKey, total)
+	transportPubKeys := make([]*CosignerTransportPubKey, total)
+	for idx := range transportKeys {
+		transportKeys[idx], err = GenerateCosignerTransportKey()
+		require.NoError(test, err)
+		transportPubKeys[idx], err = transportKeys[idx].PubKey()
+		require.NoError(test, err)
+	}
+
+	migrated, err := MigrateCosignerKey(&key, transportKeys[key.ID-1], transportPubKeys)
+	require.NoError(test, err)
+	require.False(test, migrated.IsLegacy())
+	require.Equal(test, key.ShareKey, migrated.ShareKey)
+	require.Equal(test, key.PubKey, migrated.PubKey)
+	require.Equal(test, transportPubKeys, migrated.TransportPubKeys)
+
+	// the transport key of another cosigner
+	_, err = MigrateCosignerKey(&key, transportKeys[0], transportPubKeys)
+	require.Error(test, err)
+
+	// a public key is missing
+	_, err = MigrateCosignerKey(&key, transportKeys[key.ID-1], transportPubKeys[:total-1])
+	require.Error(test, err)
+}
+
+func TestMigrateCosignerKeySharePubKeys(test *testing.T) {
+	_, cosigners, cleanup := newTestCosigners(test, 2, 3)
+	defer cleanup()
+
+	transportKeys := make([]*CosignerTransportKey, 3)
+	transportPubKeys := make([]*CosignerTransportPubKey, 3)
+	for idx := range transportKeys {
+		var err error
+		transportKeys[idx], err = GenerateCosignerTransportKey()
+		require.NoError(test, err)
+		transportPubKeys[idx], err = transportKeys[idx].PubKey()
+		require.NoError(test, err)
+	}
+
+	// the share public keys of the other cosigners are computed from the commitments
+	key := cosigners[0].key
+	key.TransportPubKeys = make([]*CosignerTransportPubKey, 3)
+	sharePubKeys := key.SharePubKeys
+	key.SharePubKeys = nil
+	migrated, err := MigrateCosignerKey(&key, transportKeys[0], transportPubKeys)
+	require.NoError(test, err)
+	require.Equal(test, sharePubKeys, migrated.SharePubKeys)
+}
@@ -0,0 +1,216 @@
+package signer
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	tmCryptoEd25519 "github.com/tendermint/tendermint/crypto/ed25519"
+	tmJson "github.com/tendermint/tendermint/libs/json"
+)
+
+// DKGDeal is the contribution of one cosigner to a distributed key generation
+//
+// Every cosigner deals a random secret to all the cosigners, including itself. The validator secret is the
+// sum of the dealt secrets and the share of a cosigner is the sum of the values it got, so the validator
+// key never exists in one place. The validator public key is the sum of the first commitment of every deal.
+type DKGDeal struct {
+	Threshold uint8 `json:"threshold"`
+
+	// Transport public keys of all the cosigners, the cosigner ID is the index + 1
+	TransportPubKeys []*CosignerTransportPubKey `json:"transport_pubs"`
+
+	SourceID int `json:"source_id"`
+
+	// Feldman commitments of the coefficients 0 .. threshold - 1 and the value for every cosigner
+	Commitments     [][]byte `json:"commitments"`
+	EncryptedShares [][]byte `json:"encrypted_shares"`
+
+	// Signature of the deal with the transport key of the dealer
+	Signature []byte `json:"signature"`
+}
+
+// digest returns the digest of the deal signed by the dealer
+func (deal *DKGDeal) digest() ([]byte, error) {
+	unsigned := *deal
+	unsigned.Signature = nil
+
+	jsonBytes, err := tmJson.Marshal(&unsigned)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := sha256.Sum256(append([]byte("valink dkg deal"), jsonBytes...))
+	return digest[:], nil
+}
+
+func (deal *DKGDeal) vssDeal() vssDeal {
+	return vssDeal{
+		sourceID:        deal.SourceID,
+		commitments:     deal.Commitments,
+		encryptedShares: deal.EncryptedShares,
+	}
+}
+
+// DealDKG deals a random secret from cosigner id to all the cosigners
+func DealDKG(id int, threshold uint8, transportKey *CosignerTransportKey, transportPubKeys []*CosignerTransportPubKey) (*DKGDeal, error) {
+	total := len(transportPubKeys)
+	if threshold < 2 || total > 255 || int(threshold) > total {
+		return nil, fmt.Errorf("Invalid threshold %d for %d cosigners", threshold, total)
+	}
+	if err := validateTransportPubKeys(transportPubKeys); err != nil {
+		return nil, err
+	}
+	if id < 1 || id > total {
+		return nil, fmt.Errorf("Unexpected cosigner ID %d", id)
+	}
+
+	transportPubKey, err := transportKey.PubKey()
+	if err != nil {
+		return nil, err
+	}
+	if !equalTransportPubKeys([]*CosignerTransportPubKey{transportPubKey}, transportPubKeys[id-1:id]) {
+		return nil, fmt.Errorf("The transport key doesn't belong to cosigner %d", id)
+	}
+
+	secret, err := rand.Int(rand.Reader, curveOrder)
+	if err != nil {
+		return nil, err
+	}
+	coeffs, shares, err := dealPolynomial(secret, threshold, uint8(total))
+	if err != nil {
+		return nil, err
+	}
+
+	deal := &DKGDeal{
+		Threshold:        threshold,
+		TransportPubKeys: transportPubKeys,
+		SourceID:         id,
+		Commitments:      feldmanCommitments(coeffs),
+		EncryptedShares:  make([][]byte, total),
+	}
+
+	for idx, share := range shares {
+		deal.EncryptedShares[idx], err = transportPubKeys[idx].Seal(share)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	digest, err := deal.digest()
+	if err != nil {
+		return nil, err
+	}
+	deal.Signature = ed25519.Sign(transportKey.SigningKey, digest)
+	return deal, nil
+}
+
+// VerifyDKG checks that every cosigner dealt once and that the deals are consistent
+// It only needs public data and returns the validator public key and the public keys of the shares.
+func VerifyDKG(deals []*DKGDeal) ([]byte, [][]byte, error) {
+	commitments, sharePubKeys, err := verifyDKG(deals)
+	if err != nil {
+		return nil, nil, err
+	}
+	return commitments[0], sharePubKeys, nil
+}
+
+// verifyDKG returns the commitments of the validator polynomial and the public keys of the shares
+func verifyDKG(deals []*DKGDeal) ([][]byte, [][]byte, error) {
+	if len(deals) == 0 {
+		return nil, nil, errors.New("No deals")
+	}
+
+	first := deals[0]
+	if err := validateTransportPubKeys(first.TransportPubKeys); err != nil {
+		return nil, nil, err
+	}
+
+	total := len(first.TransportPubKeys)
+	threshold := int(first.Threshold)
+	if threshold < 2 || threshold > total {
+		return nil, nil, fmt.Errorf("Invalid threshold %d for %d cosigners", threshold, total)
+	}
+	if len(deals) != total {
+		return nil, nil, fmt.Errorf("Expected the deals of the %d cosigners, got %d deals", total, len(deals))
+	}
+
+	seen := make(map[int]bool)
+	vssDeals := make([]vssDeal, 0, len(deals))
+	for _, deal := range deals {
+		// every dealer must agree on the threshold and the cosigners
+		if deal.Threshold != first.Threshold || !equalTransportPubKeys(deal.TransportPubKeys, first.TransportPubKeys) {
+			return nil, nil, fmt.Errorf("Deal of cosigner %d is for another key generation", deal.SourceID)
+		}
+
+		if deal.SourceID < 1 || deal.SourceID > total || seen[deal.SourceID] {
+			return nil, nil, fmt.Errorf("Unexpected deal of cosigner %d", deal.SourceID)
+		}
+		seen[deal.SourceID] = true
+
+		if len(deal.Commitments) == 0 {
+			return nil, nil, fmt.Errorf("Deal of cosigner %d has no commitments", deal.SourceID)
+		}
+
+		digest, err := deal.digest()
+		if err != nil {
+			return nil, nil, err
+		}
+		if !ed25519.Verify(deal.TransportPubKeys[deal.SourceID-1].SigningKey, digest, deal.Signature) {
+			return nil, nil, fmt.Errorf("Deal of cosigner %d has an invalid signature", deal.SourceID)
+		}
+
+		vssDeals = append(vssDeals, deal.vssDeal())
+	}
+
+	commitments, sharePubKeys, err := combineVSSDeals(vssDeals, threshold, total)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pubKey := commitments[0]
+	if bytes.Equal(pubKey, identityElement) {
+		return nil, nil, errors.New("The deals add up to the identity")
+	}
+
+	if err := verifySharePubKeys(pubKey, threshold, sharePubKeys); err != nil {
+		return nil, nil, err
+	}
+	return commitments, sharePubKeys, nil
+}
+
+// CombineDKG verifies the deals and adds up the share of cosigner id
+func CombineDKG(deals []*DKGDeal, id int, transportKey *CosignerTransportKey) (*CosignerKey, error) {
+	commitments, sharePubKeys, err := verifyDKG(deals)
+	if err != nil {
+		return nil, err
+	}
+
+	vssDeals := make([]vssDeal, len(deals))
+	for idx, deal := range deals {
+		vssDeals[idx] = deal.vssDeal()
+	}
+
+	first := deals[0]
+	share, err := openVSSDeals(vssDeals, id, transportKey, first.TransportPubKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(SharePubKey(share), sharePubKeys[id-1]) {
+		return nil, errors.New("The share doesn't match its public key")
+	}
+
+	return &CosignerKey{
+		PubKey:           tmCryptoEd25519.PubKey(commitments[0]),
+		ShareKey:         share,
+		ID:               id,
+		SharePubKeys:     sharePubKeys,
+		Commitments:      commitments,
+		TransportKey:     transportKey,
+		TransportPubKeys: first.TransportPubKeys,
+	}, nil
+}
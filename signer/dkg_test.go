@@ -0,0 +1,129 @@
+package signer
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	tmCryptoEd25519 "github.com/tendermint/tendermint/crypto/ed25519"
+	tm "github.com/tendermint/tendermint/types"
+)
+
+func newDKGTestTransportKeys(test *testing.T, total int) ([]*CosignerTransportKey, []*CosignerTransportPubKey) {
+	transportKeys := make([]*CosignerTransportKey, total)
+	transportPubKeys := make([]*CosignerTransportPubKey, total)
+	for idx := range transportKeys {
+		key, err := GenerateCosignerTransportKey()
+		require.NoError(test, err)
+		pub, err := key.PubKey()
+		require.NoError(test, err)
+		transportKeys[idx] = key
+		transportPubKeys[idx] = pub
+	}
+	return transportKeys, transportPubKeys
+}
+
+func TestDKG(test *testing.T) {
+	transportKeys, transportPubKeys := newDKGTestTransportKeys(test, 3)
+
+	deals := make([]*DKGDeal, len(transportKeys))
+	for idx, transportKey := range transportKeys {
+		var err error
+		deals[idx], err = DealDKG(idx+1, 2, transportKey, transportPubKeys)
+		require.NoError(test, err)
+	}
+
+	pubKey, sharePubKeys, err := VerifyDKG(deals)
+	require.NoError(test, err)
+
+	keys := make([]CosignerKey, len(transportKeys))
+	for idx, transportKey := range transportKeys {
+		key, err := CombineDKG(deals, idx+1, transportKey)
+		require.NoError(test, err)
+		require.Equal(test, pubKey, key.PubKey.Bytes())
+		require.Equal(test, sharePubKeys, key.SharePubKeys)
+		require.NoError(test, key.Validate())
+		keys[idx] = *key
+	}
+
+	// the generated shares sign for the validator key
+	peers := make([]CosignerPeer, len(keys))
+	for idx := range keys {
+		peers[idx] = CosignerPeer{ID: idx + 1, TransportPubKey: transportPubKeys[idx]}
+	}
+
+	cosigners := make([]*LocalCosigner, len(keys))
+	for idx, key := range keys {
+		stateFile, err := ioutil.TempFile("", "share_state.json")
+		require.NoError(test, err)
+		defer os.Remove(stateFile.Name())
+
+		signState, err := LoadOrCreateSignState(stateFile.Name())
+		require.NoError(test, err)
+
+		cosigners[idx] = NewLocalCosigner(LocalCosignerConfig{
+			CosignerKey: key,
+			SignState:   &signState,
+			Peers:       peers,
+			Total:       3,
+			Threshold:   2,
+		})
+	}
+
+	stateFile, err := ioutil.TempFile("", "priv_validator_state.json")
+	require.NoError(test, err)
+	defer os.Remove(stateFile.Name())
+	signState, err := LoadOrCreateSignState(stateFile.Name())
+	require.NoError(test, err)
+
+	validatorPubKey := tmCryptoEd25519.PubKey(pubKey)
+	validator := NewThresholdValidator(&ThresholdValidatorOpt{
+		Pubkey:    validatorPubKey,
+		Threshold: 2,
+		SignState: signState,
+		Cosigner:  cosigners[0],
+		Peers:     []Cosigner{cosigners[1], cosigners[2]},
+	})
+
+	proposal := signTestProposal(test, validator, cosigners, 1)
+	require.True(test, validatorPubKey.VerifySignature(tm.ProposalSignBytes("chain-id", proposal), proposal.Signature))
+
+	// another cosigner's transport key can't open our share
+	_, err = CombineDKG(deals, 1, transportKeys[1])
+	require.Error(test, err)
+}
+
+func TestDKGRejectsBadDeals(test *testing.T) {
+	transportKeys, transportPubKeys := newDKGTestTransportKeys(test, 3)
+
+	deals := make([]*DKGDeal, len(transportKeys))
+	for idx, transportKey := range transportKeys {
+		var err error
+		deals[idx], err = DealDKG(idx+1, 2, transportKey, transportPubKeys)
+		require.NoError(test, err)
+	}
+
+	// every cosigner must deal
+	_, _, err := VerifyDKG(deals[:2])
+	require.Error(test, err)
+
+	_, _, err = VerifyDKG([]*DKGDeal{deals[0], deals[1], deals[1]})
+	require.Error(test, err)
+
+	// deals with another threshold
+	other, err := DealDKG(3, 3, transportKeys[2], transportPubKeys)
+	require.NoError(test, err)
+	_, _, err = VerifyDKG([]*DKGDeal{deals[0], deals[1], other})
+	require.Error(test, err)
+
+	// a cosigner can't deal in the name of another one
+	_, err = DealDKG(2, 2, transportKeys[0], transportPubKeys)
+	require.Error(test, err)
+
+	forged := *deals[2]
+	forged.SourceID = 3
+	forged.Commitments = deals[0].Commitments
+	_, _, err = VerifyDKG([]*DKGDeal{deals[0], deals[1], &forged})
+	require.Error(test, err)
+}
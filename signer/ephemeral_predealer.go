@@ -0,0 +1,167 @@
+package signer
+
+import (
+	"context"
+	"sync"
+
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/libs/service"
+)
+
+type EphemeralPreDealerConfig struct {
+	Logger        log.Logger
+	LocalCosigner Cosigner
+	Peers         []Cosigner
+
+	// Number of heights above the last signed height to pre-deal
+	Window int64
+
+	// Timeout and retry policy for the exchanges with the peers
+	Signing SigningConfig
+}
+
+// EphemeralPreDealer deals and exchanges the ephemeral secret parts of upcoming heights in the background
+//
+// Ephemeral parts are otherwise dealt when a HRS is signed for the first time, which puts the
+// exchange with every peer on the critical path of the signature. With the parts already in place,
+// signing only needs the share signing round trip.
+//
+// Only round 0 is pre-dealt, later rounds fall back to the exchange at signing time.
+type EphemeralPreDealer struct {
+	service.BaseService
+
+	logger        log.Logger
+	localCosigner Cosigner
+	peers         []Cosigner
+	window        int64
+	signing       SigningConfig
+
+	// latest height reported by Advance
+	heightMutex sync.Mutex
+	height      int64
+
+	wake   chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewEphemeralPreDealer returns a pre-dealer for the local cosigner and its peers
+func NewEphemeralPreDealer(config *EphemeralPreDealerConfig) *EphemeralPreDealer {
+	preDealer := &EphemeralPreDealer{
+		logger:        config.Logger,
+		localCosigner: config.LocalCosigner,
+		peers:         config.Peers,
+		window:        config.Window,
+		signing:       config.Signing.withDefaults(),
+		wake:          make(chan struct{}, 1),
+	}
+
+	preDealer.BaseService = *service.NewBaseService(config.Logger, "EphemeralPreDealer", preDealer)
+	return preDealer
+}
+
+// OnStart starts pre-dealing in the background
+func (preDealer *EphemeralPreDealer) OnStart() error {
+	preDealer.ctx, preDealer.cancel = context.WithCancel(context.Background())
+	go preDealer.loop()
+	return nil
+}
+
+// OnStop aborts any exchange in progress
+func (preDealer *EphemeralPreDealer) OnStop() {
+	preDealer.cancel()
+}
+
+// Advance reports the height that is being signed
+// The parts for the following heights are then pre-dealt in the background.
+// Advance never blocks and is a no-op on a nil pre-dealer.
+func (preDealer *EphemeralPreDealer) Advance(height int64) {
+	if preDealer == nil {
+		return
+	}
+
+	preDealer.heightMutex.Lock()
+	if height <= preDealer.height {
+		preDealer.heightMutex.Unlock()
+		return
+	}
+	preDealer.height = height
+	preDealer.heightMutex.Unlock()
+
+	select {
+	case preDealer.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (preDealer *EphemeralPreDealer) loop() {
+	for {
+		select {
+		case <-preDealer.ctx.Done():
+			return
+		case <-preDealer.wake:
+		}
+
+		preDealer.heightMutex.Lock()
+		height := preDealer.height
+		preDealer.heightMutex.Unlock()
+
+		preDealer.preDeal(preDealer.ctx, height)
+	}
+}
+
+// preDeal makes sure the ephemeral parts of every peer are in place for the heights above height
+func (preDealer *EphemeralPreDealer) preDeal(ctx context.Context, height int64) {
+	for h := height + 1; h <= height+preDealer.window; h++ {
+		for _, step := range []int8{stepPropose, stepPrevote, stepPrecommit} {
+			if ctx.Err() != nil {
+				return
+			}
+			preDealer.preDealHRS(ctx, h, 0, step)
+		}
+	}
+}
+
+func (preDealer *EphemeralPreDealer) preDealHRS(ctx context.Context, height int64, round int64, step int8) {
+	ourID := int32(preDealer.localCosigner.GetID())
+
+	// deal our own secret for the HRS
+	hasResp, err := preDealer.localCosigner.HasEphemeralSecretPart(ctx, &CosignerHasEphemeralSecretPartRequest{
+		ID:     ourID,
+		Height: height,
+		Round:  round,
+		Step:   int32(step),
+	})
+	if err == nil && !hasResp.Exists {
+		_, err = preDealer.localCosigner.GetEphemeralSecretPart(ctx, &CosignerGetEphemeralSecretPartRequest{
+			ID:     ourID,
+			Height: height,
+			Round:  round,
+			Step:   int32(step),
+		})
+	}
+	if err != nil {
+		preDealer.logger.Error("Failed to deal ephemeral secret", "height", height, "step", step, "error", err)
+		return
+	}
+
+	wg := sync.WaitGroup{}
+	wg.Add(len(preDealer.peers))
+
+	for _, peer := range preDealer.peers {
+		request := func(peer Cosigner) {
+			defer wg.Done()
+
+			err := preDealer.signing.retry(ctx, preDealer.signing.EphemeralTimeout.Duration, func(ctx context.Context) error {
+				return exchangeEphemeralSecretPart(ctx, preDealer.localCosigner, peer, height, round, step)
+			})
+			if err != nil {
+				preDealer.logger.Debug("Failed to pre-deal ephemeral secret part", "peer", peer.GetID(), "height", height, "step", step, "error", err)
+			}
+		}
+
+		go request(peer)
+	}
+
+	wg.Wait()
+}
@@ -0,0 +1,122 @@
+package signer
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tendermint/tendermint/libs/log"
+	tmProto "github.com/tendermint/tendermint/proto/tendermint/types"
+	tm "github.com/tendermint/tendermint/types"
+)
+
+// signOnlyCosigner refuses to hand out ephemeral secret parts
+type signOnlyCosigner struct {
+	Cosigner
+}
+
+func (cosigner *signOnlyCosigner) GetEphemeralSecretPart(ctx context.Context, req *CosignerGetEphemeralSecretPartRequest) (*CosignerGetEphemeralSecretPartResponse, error) {
+	return nil, errors.New("ephemeral secret part requested at signing time")
+}
+
+func TestEphemeralPreDealerSignWithoutExchange(test *testing.T) {
+	privateKey, cosigners, cleanup := newTestCosigners(test, 2, 2)
+	defer cleanup()
+
+	preDealer1 := NewEphemeralPreDealer(&EphemeralPreDealerConfig{
+		Logger:        log.NewNopLogger(),
+		LocalCosigner: cosigners[0],
+		Peers:         []Cosigner{cosigners[1]},
+		Window:        2,
+	})
+	preDealer2 := NewEphemeralPreDealer(&EphemeralPreDealerConfig{
+		Logger:        log.NewNopLogger(),
+		LocalCosigner: cosigners[1],
+		Peers:         []Cosigner{cosigners[0]},
+		Window:        2,
+	})
+
+	ctx := context.Background()
+	preDealer1.preDeal(ctx, 0)
+	preDealer2.preDeal(ctx, 0)
+
+	for _, height := range []int64{1, 2} {
+		for _, step := range []int8{stepPropose, stepPrevote, stepPrecommit} {
+			hasResp, err := cosigners[0].HasEphemeralSecretPart(ctx, &CosignerHasEphemeralSecretPartRequest{
+				ID:     2,
+				Height: height,
+				Step:   int32(step),
+			})
+			require.NoError(test, err)
+			require.True(test, hasResp.Exists)
+		}
+	}
+
+	// the window ends at height 2
+	hasResp, err := cosigners[0].HasEphemeralSecretPart(ctx, &CosignerHasEphemeralSecretPartRequest{
+		ID:     2,
+		Height: 3,
+		Step:   int32(stepPropose),
+	})
+	require.NoError(test, err)
+	require.False(test, hasResp.Exists)
+
+	stateFile, err := ioutil.TempFile("", "priv_validator_state.json")
+	require.NoError(test, err)
+	defer os.Remove(stateFile.Name())
+	signState, err := LoadOrCreateSignState(stateFile.Name())
+	require.NoError(test, err)
+
+	// the peer can't exchange ephemeral parts anymore, signing only works with the pre-dealt ones
+	validator := NewThresholdValidator(&ThresholdValidatorOpt{
+		Pubkey:    privateKey.PubKey(),
+		Threshold: 2,
+		SignState: signState,
+		Cosigner:  cosigners[0],
+		Peers:     []Cosigner{&signOnlyCosigner{cosigners[1]}},
+	})
+
+	var proposal tmProto.Proposal
+	proposal.Height = 2
+	proposal.Round = 0
+	proposal.Type = tmProto.ProposalType
+
+	err = validator.SignProposal("chain-id", &proposal)
+	require.NoError(test, err)
+
+	signBytes := tm.ProposalSignBytes("chain-id", &proposal)
+	require.True(test, privateKey.PubKey().VerifySignature(signBytes, proposal.Signature))
+}
+
+func TestEphemeralPreDealerAdvance(test *testing.T) {
+	_, cosigners, cleanup := newTestCosigners(test, 2, 2)
+	defer cleanup()
+
+	preDealer := NewEphemeralPreDealer(&EphemeralPreDealerConfig{
+		Logger:        log.NewNopLogger(),
+		LocalCosigner: cosigners[0],
+		Peers:         []Cosigner{cosigners[1]},
+		Window:        1,
+	})
+	require.NoError(test, preDealer.Start())
+	defer preDealer.Stop()
+
+	preDealer.Advance(5)
+
+	require.Eventually(test, func() bool {
+		hasResp, err := cosigners[0].HasEphemeralSecretPart(context.Background(), &CosignerHasEphemeralSecretPartRequest{
+			ID:     2,
+			Height: 6,
+			Step:   int32(stepPrecommit),
+		})
+		return err == nil && hasResp.Exists
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// a nil pre-dealer is disabled
+	var disabled *EphemeralPreDealer
+	disabled.Advance(1)
+}
@@ -0,0 +1,196 @@
+package signer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"math/big"
+
+	"gitlab.com/polychainlabs/edwards25519"
+	tsed25519 "gitlab.com/polychainlabs/threshold-ed25519/pkg"
+)
+
+// order of the ed25519 base point: 2^252 + 27742317777372353535851937790883648493
+var curveOrder, _ = new(big.Int).SetString("7237005577332262213973186563042994240857116359379907606001950938285454250989", 10)
+
+// scalarToBig reads a little endian scalar
+func scalarToBig(scalar []byte) *big.Int {
+	reversed := make([]byte, len(scalar))
+	for idx, b := range scalar {
+		reversed[len(scalar)-1-idx] = b
+	}
+	return new(big.Int).SetBytes(reversed)
+}
+
+// bigToScalar writes a number modulo the curve order as a 32 bytes little endian scalar
+func bigToScalar(number *big.Int) tsed25519.Scalar {
+	reduced := new(big.Int).Mod(number, curveOrder).Bytes()
+	scalar := make(tsed25519.Scalar, 32)
+	for idx, b := range reduced {
+		scalar[len(reduced)-1-idx] = b
+	}
+	return scalar
+}
+
+// dealPolynomial splits secret with a random polynomial of degree threshold - 1
+// It returns the coefficients of the polynomial, the first one being the secret, and the
+// shares of the cosigners, the share of cosigner i at index i - 1.
+func dealPolynomial(secret *big.Int, threshold uint8, total uint8) ([]*big.Int, []tsed25519.Scalar, error) {
+	coeffs := make([]*big.Int, threshold)
+	coeffs[0] = new(big.Int).Mod(secret, curveOrder)
+	for idx := 1; idx < len(coeffs); idx++ {
+		random, err := rand.Int(rand.Reader, curveOrder)
+		if err != nil {
+			return nil, nil, err
+		}
+		coeffs[idx] = random
+	}
+
+	shares := make([]tsed25519.Scalar, total)
+	for idx := range shares {
+		shares[idx] = bigToScalar(evaluatePolynomial(coeffs, idx+1))
+	}
+	return coeffs, shares, nil
+}
+
+// DealShares splits a secret scalar into total shares, any threshold of them combine into the secret
+// It returns the feldman commitments of the polynomial along with the shares, the first commitment
+// being the public key of the secret.
+func DealShares(secret []byte, threshold uint8, total uint8) ([]tsed25519.Scalar, [][]byte, error) {
+	if threshold < 1 || threshold > total {
+		return nil, nil, errors.New("Threshold must be between 1 and the number of shares")
+	}
+
+	coeffs, shares, err := dealPolynomial(scalarToBig(secret), threshold, total)
+	if err != nil {
+		return nil, nil, err
+	}
+	return shares, feldmanCommitments(coeffs), nil
+}
+
+// evaluatePolynomial returns the value of the polynomial at x, modulo the curve order
+func evaluatePolynomial(coeffs []*big.Int, x int) *big.Int {
+	result := new(big.Int)
+	for idx := len(coeffs) - 1; idx >= 0; idx-- {
+		result.Mul(result, big.NewInt(int64(x)))
+		result.Add(result, coeffs[idx])
+		result.Mod(result, curveOrder)
+	}
+	return result
+}
+
+// feldmanCommitments returns the commitment coeff * B of every coefficient
+func feldmanCommitments(coeffs []*big.Int) [][]byte {
+	commitments := make([][]byte, len(coeffs))
+	for idx, coeff := range coeffs {
+		commitments[idx] = tsed25519.ScalarMultiplyBase(bigToScalar(coeff))
+	}
+	return commitments
+}
+
+// feldmanEvaluate returns the public key of the share of cosigner id from the commitments of the polynomial
+// It is the sum of id^k * C_k.
+func feldmanEvaluate(commitments [][]byte, id int) ([]byte, error) {
+	if len(commitments) == 0 {
+		return nil, errors.New("No commitments")
+	}
+
+	terms := make([]tsed25519.Element, len(commitments))
+	power := big.NewInt(1)
+	for k, commitment := range commitments {
+		term, err := scalarMultElement(bigToScalar(power), commitment)
+		if err != nil {
+			return nil, err
+		}
+		terms[k] = term
+
+		power = new(big.Int).Mod(new(big.Int).Mul(power, big.NewInt(int64(id))), curveOrder)
+	}
+
+	return tsed25519.AddElements(terms), nil
+}
+
+// validElement returns true if element is the encoding of a curve point
+func validElement(element []byte) bool {
+	if len(element) != 32 {
+		return false
+	}
+	var elementBytes [32]byte
+	copy(elementBytes[:], element)
+	var point edwards25519.ExtendedGroupElement
+	return point.FromBytes(&elementBytes)
+}
+
+// scalarMultElement returns scalar * element
+func scalarMultElement(scalar []byte, element []byte) (tsed25519.Element, error) {
+	if len(element) != 32 {
+		return nil, errors.New("Commitments must be 32 bytes")
+	}
+
+	var elementBytes [32]byte
+	copy(elementBytes[:], element)
+	var point edwards25519.ExtendedGroupElement
+	if !point.FromBytes(&elementBytes) {
+		return nil, errors.New("Commitment is not a valid point")
+	}
+
+	// scalar * element + 0 * B
+	var zero [32]byte
+	var scalarBytes [32]byte
+	copy(scalarBytes[:], scalar)
+	var result edwards25519.ProjectiveGroupElement
+	edwards25519.GeDoubleScalarMultVartime(&result, &scalarBytes, &point, &zero)
+
+	var resultBytes [32]byte
+	result.ToBytes(&resultBytes)
+	return resultBytes[:], nil
+}
+
+// lagrangeCoefficient returns the coefficient of the share of cosigner id when the shares of ids
+// are combined into the secret: the product of j / (j - id) over the other IDs j
+func lagrangeCoefficient(id int, ids []int) *big.Int {
+	return lagrangeCoefficientAt(id, ids, 0)
+}
+
+// lagrangeCoefficientAt returns the coefficient of the share of cosigner id when the shares of ids
+// are interpolated at x: the product of (x - j) / (id - j) over the other IDs j
+func lagrangeCoefficientAt(id int, ids []int, x int) *big.Int {
+	numerator := big.NewInt(1)
+	denominator := big.NewInt(1)
+	for _, other := range ids {
+		if other == id {
+			continue
+		}
+		numerator.Mod(numerator.Mul(numerator, big.NewInt(int64(x-other))), curveOrder)
+		denominator.Mod(denominator.Mul(denominator, big.NewInt(int64(id-other))), curveOrder)
+	}
+	return numerator.Mod(numerator.Mul(numerator, denominator.ModInverse(denominator, curveOrder)), curveOrder)
+}
+
+// interpolateElements returns the public key that the share public keys of ids combine into
+func interpolateElements(ids []int, elements [][]byte) (tsed25519.Element, error) {
+	return interpolateElementsAt(ids, elements, 0)
+}
+
+// interpolateElementsAt returns the share public key of cosigner x on the polynomial
+// that goes through the share public keys of ids
+func interpolateElementsAt(ids []int, elements [][]byte, x int) (tsed25519.Element, error) {
+	terms := make([]tsed25519.Element, len(ids))
+	for idx, id := range ids {
+		term, err := scalarMultElement(bigToScalar(lagrangeCoefficientAt(id, ids, x)), elements[idx])
+		if err != nil {
+			return nil, err
+		}
+		terms[idx] = term
+	}
+	return tsed25519.AddElements(terms), nil
+}
+
+// verifyFeldmanShare returns true if the share of cosigner id matches the commitments of the polynomial
+func verifyFeldmanShare(commitments [][]byte, id int, share []byte) bool {
+	expected, err := feldmanEvaluate(commitments, id)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(tsed25519.ScalarMultiplyBase(share), expected)
+}
@@ -0,0 +1,128 @@
+package signer
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	tmJson "github.com/tendermint/tendermint/libs/json"
+	"github.com/tendermint/tendermint/libs/tempfile"
+	"github.com/tendermint/tendermint/privval"
+)
+
+// LoadFilePVLastSignState reads the priv_validator_state.json of a tendermint FilePV
+func LoadFilePVLastSignState(file string) (privval.FilePVLastSignState, error) {
+	state := privval.FilePVLastSignState{}
+	stateJSONBytes, err := ioutil.ReadFile(file)
+	if err != nil {
+		return state, err
+	}
+	if err := tmJson.Unmarshal(stateJSONBytes, &state); err != nil {
+		return state, fmt.Errorf("Error reading %v: %v", file, err)
+	}
+	if state.Height < 0 || state.Round < 0 || state.Step < stepNone || state.Step > stepPrecommit {
+		return state, fmt.Errorf("%v holds an invalid height, round or step", file)
+	}
+	return state, nil
+}
+
+// SaveFilePVLastSignState writes a priv_validator_state.json that a tendermint FilePV can load
+// An existing file is only replaced if its watermark is not above the one of state.
+func SaveFilePVLastSignState(file string, state privval.FilePVLastSignState) error {
+	if existing, err := LoadFilePVLastSignState(file); err == nil {
+		if FilePVStateLess(state, existing) {
+			return fmt.Errorf("%v is at height %d round %d step %d, above the exported state", file, existing.Height, existing.Round, existing.Step)
+		}
+	}
+
+	jsonBytes, err := tmJson.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return tempfile.WriteFileAtomic(file, jsonBytes, 0600)
+}
+
+// filePVHRSKey returns the watermark of a FilePV state
+func filePVHRSKey(state privval.FilePVLastSignState) HRSKey {
+	return HRSKey{
+		Height: state.Height,
+		Round:  int64(state.Round),
+		Step:   state.Step,
+	}
+}
+
+// FilePVStateLess returns true if the watermark of state is below the one of other
+func FilePVStateLess(state privval.FilePVLastSignState, other privval.FilePVLastSignState) bool {
+	stateKey := filePVHRSKey(state)
+	return stateKey.Less(filePVHRSKey(other))
+}
+
+// hrsKey returns the watermark of the sign state
+func (signState *SignState) hrsKey() HRSKey {
+	return HRSKey{
+		Height: signState.Height,
+		Round:  signState.Round,
+		Step:   signState.Step,
+	}
+}
+
+// RaiseToFilePVState moves the watermark of the sign state up to the one of a FilePV state and saves it
+//
+// A sign state already at or above the FilePV watermark is left as is and false is returned.
+// The block signature of the FilePV state is only copied if withSignature is set: it is valid for a
+// validator sign state, not for a share sign state, which is left without sign bytes and so refuses
+// to sign again at the watermark.
+func (signState *SignState) RaiseToFilePVState(state privval.FilePVLastSignState, withSignature bool) (bool, error) {
+	target := filePVHRSKey(state)
+	current := signState.hrsKey()
+	if !current.Less(target) {
+		return false, nil
+	}
+
+	newState := *signState
+	newState.Height = target.Height
+	newState.Round = target.Round
+	newState.Step = target.Step
+	newState.EphemeralPublic = nil
+	newState.Signature = nil
+	newState.SignBytes = nil
+	if withSignature && len(state.SignBytes) > 0 && len(state.Signature) > 0 {
+		newState.Signature = state.Signature
+		newState.SignBytes = state.SignBytes
+	}
+
+	if err := newState.Save(); err != nil {
+		return false, err
+	}
+	*signState = newState
+	return true, nil
+}
+
+// FilePVLastSignState returns the highest watermark of the sign states as a tendermint FilePV state
+// The signature is only kept if the highest sign state is a validator one, listed first.
+func FilePVLastSignState(validatorState SignState, shareStates ...SignState) privval.FilePVLastSignState {
+	highest := validatorState
+	withSignature := true
+	for _, shareState := range shareStates {
+		shareKey := shareState.hrsKey()
+		if highestKey := highest.hrsKey(); highestKey.Less(shareKey) {
+			highest = shareState
+			withSignature = false
+		}
+	}
+
+	state := privval.FilePVLastSignState{
+		Height: highest.Height,
+		Round:  int32(highest.Round),
+		Step:   highest.Step,
+	}
+	// a FilePV has no step past the precommit, whose signature the sign state no longer holds
+	if state.Step > stepPrecommit {
+		state.Step = stepPrecommit
+		withSignature = false
+	}
+	if withSignature {
+		state.Signature = highest.Signature
+		state.SignBytes = highest.SignBytes
+	}
+	return state
+}
@@ -0,0 +1,119 @@
+package signer
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tendermint/tendermint/privval"
+	tmProto "github.com/tendermint/tendermint/proto/tendermint/types"
+	tm "github.com/tendermint/tendermint/types"
+)
+
+func TestImportFilePVState(test *testing.T) {
+	privateKey, cosigners, cleanup := newTestCosigners(test, 2, 3)
+	defer cleanup()
+
+	dir, err := ioutil.TempDir("", "filepv-state")
+	require.NoError(test, err)
+	defer os.RemoveAll(dir)
+
+	// a FilePV signed the proposal at height 5 before the move to threshold mode
+	proposal := &tmProto.Proposal{Height: 5, Type: tmProto.ProposalType}
+	signBytes := tm.ProposalSignBytes("chain-id", proposal)
+	signature, err := privateKey.Sign(signBytes)
+	require.NoError(test, err)
+
+	filePVFile := filepath.Join(dir, "priv_validator_state.json")
+	require.NoError(test, SaveFilePVLastSignState(filePVFile, privval.FilePVLastSignState{
+		Height:    5,
+		Step:      stepPropose,
+		Signature: signature,
+		SignBytes: signBytes,
+	}))
+	filePVState, err := LoadFilePVLastSignState(filePVFile)
+	require.NoError(test, err)
+
+	validatorState, err := LoadOrCreateSignState(filepath.Join(dir, "chain-id_priv_validator_state.json"))
+	require.NoError(test, err)
+	raised, err := validatorState.RaiseToFilePVState(filePVState, true)
+	require.NoError(test, err)
+	require.True(test, raised)
+
+	for _, cosigner := range cosigners {
+		raised, err := cosigner.lastSignState.RaiseToFilePVState(filePVState, false)
+		require.NoError(test, err)
+		require.True(test, raised)
+		require.Nil(test, cosigner.lastSignState.SignBytes)
+	}
+
+	// the import is saved and never lowers a watermark
+	loaded, err := LoadSignState(filepath.Join(dir, "chain-id_priv_validator_state.json"))
+	require.NoError(test, err)
+	require.Equal(test, int64(5), loaded.Height)
+	raised, err = loaded.RaiseToFilePVState(privval.FilePVLastSignState{Height: 4, Step: stepPrecommit}, true)
+	require.NoError(test, err)
+	require.False(test, raised)
+
+	validator := NewThresholdValidator(&ThresholdValidatorOpt{
+		Pubkey:    privateKey.PubKey(),
+		Threshold: 2,
+		SignState: validatorState,
+		Cosigner:  cosigners[0],
+		Peers:     []Cosigner{cosigners[1], cosigners[2]},
+	})
+
+	// the FilePV signature is returned again, another proposal at the watermark is refused
+	require.NoError(test, validator.SignProposal("chain-id", proposal))
+	require.Equal(test, signature, proposal.Signature)
+	require.Error(test, validator.SignProposal("chain-id", &tmProto.Proposal{Height: 5, Type: tmProto.ProposalType, PolRound: 1}))
+	require.Error(test, validator.SignProposal("chain-id", &tmProto.Proposal{Height: 4, Type: tmProto.ProposalType}))
+
+	// the cosigners don't sign shares at the watermark either
+	exchangeAllEphemeralSecretParts(test, cosigners, 5, 0, stepPropose)
+	_, err = cosigners[1].Sign(context.Background(), &CosignerSignRequest{SignBytes: tm.ProposalSignBytes("chain-id", &tmProto.Proposal{Height: 5, Type: tmProto.ProposalType, PolRound: 1})})
+	require.Error(test, err)
+
+	signTestProposal(test, validator, cosigners, 6)
+}
+
+func TestExportFilePVState(test *testing.T) {
+	dir, err := ioutil.TempDir("", "filepv-state")
+	require.NoError(test, err)
+	defer os.RemoveAll(dir)
+
+	validatorState := SignState{Height: 7, Step: stepPrevote, Signature: []byte("signature"), SignBytes: []byte("sign bytes")}
+	shareState := SignState{Height: 7, Step: stepPrevote}
+
+	state := FilePVLastSignState(validatorState, shareState)
+	require.Equal(test, int64(7), state.Height)
+	require.Equal(test, []byte("signature"), state.Signature)
+
+	// a share signed above the block signature moves the watermark without a signature
+	shareState.Step = stepPrecommit
+	state = FilePVLastSignState(validatorState, shareState)
+	require.Equal(test, stepPrecommit, state.Step)
+	require.Nil(test, state.Signature)
+	require.Nil(test, state.SignBytes)
+
+	// a signed vote extension exports as its precommit, which a FilePV can't sign again
+	extensionState := SignState{Height: 7, Step: stepVoteExtension, Signature: []byte("signature"), SignBytes: []byte("sign bytes")}
+	exported := FilePVLastSignState(extensionState)
+	require.Equal(test, stepPrecommit, exported.Step)
+	require.Nil(test, exported.Signature)
+
+	file := filepath.Join(dir, "priv_validator_state.json")
+	require.NoError(test, SaveFilePVLastSignState(file, state))
+	require.Error(test, SaveFilePVLastSignState(file, FilePVLastSignState(validatorState)))
+
+	// the file loads into a FilePV
+	key := filepath.Join(dir, "priv_validator_key.json")
+	privval.GenFilePV(key, filepath.Join(dir, "other_state.json")).Key.Save()
+	filePV := privval.LoadFilePV(key, file)
+	require.Equal(test, int64(7), filePV.LastSignState.Height)
+	require.Equal(test, stepPrecommit, filePV.LastSignState.Step)
+	require.Error(test, filePV.SignVote("chain-id", &tmProto.Vote{Height: 7, Type: tmProto.PrevoteType}))
+}
@@ -0,0 +1,137 @@
+package signer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+const (
+	// KeyBackendFile reads the key from a key file, encrypted or not
+	KeyBackendFile = "file"
+	// KeyBackendEncryptedFile only reads the key from an encrypted key file
+	KeyBackendEncryptedFile = "encrypted-file"
+	// KeyBackendPKCS11 reads the key from a PKCS#11 token, the transport keys never leave it
+	KeyBackendPKCS11 = "pkcs11"
+
+	// PKCS11PinEnv is the environment variable holding the user PIN of the PKCS#11 token
+	PKCS11PinEnv = "VALINK_PKCS11_PIN"
+)
+
+// KeyBackend stores the share of a cosigner and holds its transport key
+type KeyBackend interface {
+	// Load returns the key of the cosigner, checked against its share public keys and commitments
+	Load() (CosignerKey, error)
+
+	// Save stores the key once its share is refreshed
+	Save(key *CosignerKey) error
+
+	// Transport returns the transport key of a loaded key, nil for legacy RSA keys
+	Transport(key *CosignerKey) (CosignerTransport, error)
+
+	// Close releases the resources of the backend
+	Close() error
+}
+
+// PKCS11Config locates the key of a cosigner on a PKCS#11 token
+type PKCS11Config struct {
+	Library    string `toml:"library"`
+	TokenLabel string `toml:"token_label"`
+	KeyLabel   string `toml:"key_label"`
+	PinFile    string `toml:"pin_file"`
+}
+
+// Pin returns the user PIN of the token from the pin file or the VALINK_PKCS11_PIN environment variable
+func (cfg PKCS11Config) Pin() (string, error) {
+	if cfg.PinFile != "" {
+		pin, err := ioutil.ReadFile(cfg.PinFile)
+		if err != nil {
+			return "", err
+		}
+		return string(bytes.TrimRight(pin, "\r\n")), nil
+	}
+	if pin, ok := os.LookupEnv(PKCS11PinEnv); ok {
+		return pin, nil
+	}
+	return "", fmt.Errorf("The PKCS#11 PIN is required, set pin_file or %s", PKCS11PinEnv)
+}
+
+// NewKeyBackend returns the key backend selected in the configuration
+func NewKeyBackend(config Config) (KeyBackend, error) {
+	switch config.KeyBackend {
+	case "", KeyBackendFile:
+		return NewFileKeyBackend(config.PrivValKeyFile, KeyPassphrase(config.KeyPassphraseFile)), nil
+	case KeyBackendEncryptedFile:
+		return NewEncryptedFileKeyBackend(config.PrivValKeyFile, KeyPassphrase(config.KeyPassphraseFile)), nil
+	case KeyBackendPKCS11:
+		return NewPKCS11KeyBackend(config.PKCS11)
+	default:
+		return nil, fmt.Errorf("Unknown key backend: %s", config.KeyBackend)
+	}
+}
+
+// FileKeyBackend keeps the key in a key file, the key is in process memory while the cosigner runs
+type FileKeyBackend struct {
+	file       string
+	passphrase PassphraseFunc
+
+	// refuse plain key files
+	encrypted bool
+}
+
+var _ KeyBackend = &FileKeyBackend{}
+
+// NewFileKeyBackend returns a backend reading the key file, which is decrypted with the passphrase if it is encrypted
+func NewFileKeyBackend(file string, passphrase PassphraseFunc) *FileKeyBackend {
+	return &FileKeyBackend{
+		file:       file,
+		passphrase: passphrase,
+	}
+}
+
+// NewEncryptedFileKeyBackend returns a backend reading the key file, which must be encrypted
+func NewEncryptedFileKeyBackend(file string, passphrase PassphraseFunc) *FileKeyBackend {
+	return &FileKeyBackend{
+		file:       file,
+		passphrase: passphrase,
+		encrypted:  true,
+	}
+}
+
+// Load reads the key file
+func (backend *FileKeyBackend) Load() (CosignerKey, error) {
+	key, err := LoadCosignerKeyWithPassphrase(backend.file, backend.passphrase)
+	if err != nil {
+		return key, err
+	}
+	if backend.encrypted && len(key.passphrase) == 0 {
+		return CosignerKey{}, fmt.Errorf("%v is not encrypted, run `valink keys encrypt` first", backend.file)
+	}
+	return key, nil
+}
+
+// Save atomically writes the key file, encrypted again if it was loaded from an encrypted file
+func (backend *FileKeyBackend) Save(key *CosignerKey) error {
+	if backend.encrypted && len(key.passphrase) == 0 {
+		return errors.New("Refusing to save the key without encryption")
+	}
+	return SaveCosignerKey(backend.file, key)
+}
+
+// Transport returns the transport key stored in the key file
+func (backend *FileKeyBackend) Transport(key *CosignerKey) (CosignerTransport, error) {
+	if key.IsLegacy() {
+		return nil, nil
+	}
+	if key.TransportKey == nil {
+		return nil, fmt.Errorf("%v has no private transport key", backend.file)
+	}
+	return key.TransportKey, nil
+}
+
+// Close does nothing, the key file is only open while it is read or written
+func (backend *FileKeyBackend) Close() error {
+	return nil
+}
@@ -0,0 +1,131 @@
+package signer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	tm "github.com/tendermint/tendermint/types"
+)
+
+// countingTransport is a transport key kept out of the cosigner key, like the one of a token
+type countingTransport struct {
+	CosignerTransport
+	opened int
+	signed int
+}
+
+func (transport *countingTransport) Open(ciphertext []byte) ([]byte, error) {
+	transport.opened++
+	return transport.CosignerTransport.Open(ciphertext)
+}
+
+func (transport *countingTransport) Sign(digest []byte) ([]byte, error) {
+	transport.signed++
+	return transport.CosignerTransport.Sign(digest)
+}
+
+func TestFileKeyBackend(test *testing.T) {
+	_, cosigners, cleanup := newTestCosigners(test, 2, 3)
+	defer cleanup()
+
+	dir, err := ioutil.TempDir("", "key-backend")
+	require.NoError(test, err)
+	defer os.RemoveAll(dir)
+
+	key := cosigners[0].key
+	file := filepath.Join(dir, "private_share_1.json")
+	require.NoError(test, SaveCosignerKey(file, &key))
+
+	backend := NewFileKeyBackend(file, nil)
+	loaded, err := backend.Load()
+	require.NoError(test, err)
+	require.Equal(test, key.ShareKey, loaded.ShareKey)
+	transport, err := backend.Transport(&loaded)
+	require.NoError(test, err)
+	require.Equal(test, loaded.TransportKey, transport)
+	require.NoError(test, backend.Close())
+
+	// legacy keys use their RSA key instead
+	legacy, err := NewFileKeyBackend("../test/cosigner-key.json", nil).Load()
+	require.NoError(test, err)
+	transport, err = NewFileKeyBackend("../test/cosigner-key.json", nil).Transport(&legacy)
+	require.NoError(test, err)
+	require.Nil(test, transport)
+
+	passphraseFile := filepath.Join(dir, "passphrase")
+	require.NoError(test, ioutil.WriteFile(passphraseFile, []byte("passphrase"), 0600))
+	encryptedBackend := NewEncryptedFileKeyBackend(file, KeyPassphrase(passphraseFile))
+
+	// a plain key file is refused and never rewritten without encryption
+	_, err = encryptedBackend.Load()
+	require.Error(test, err)
+	require.Error(test, encryptedBackend.Save(&key))
+
+	jsonBytes, err := ioutil.ReadFile(file)
+	require.NoError(test, err)
+	encrypted, err := EncryptKey(jsonBytes, []byte("passphrase"))
+	require.NoError(test, err)
+	require.NoError(test, ioutil.WriteFile(file, encrypted, 0600))
+
+	loaded, err = encryptedBackend.Load()
+	require.NoError(test, err)
+	require.Equal(test, key.ShareKey, loaded.ShareKey)
+	require.NoError(test, encryptedBackend.Save(&loaded))
+	saved, err := ioutil.ReadFile(file)
+	require.NoError(test, err)
+	require.True(test, IsEncryptedKey(saved))
+}
+
+func TestNewKeyBackend(test *testing.T) {
+	backend, err := NewKeyBackend(Config{PrivValKeyFile: "private_share_1.json"})
+	require.NoError(test, err)
+	require.IsType(test, &FileKeyBackend{}, backend)
+
+	backend, err = NewKeyBackend(Config{KeyBackend: KeyBackendEncryptedFile, PrivValKeyFile: "private_share_1.json"})
+	require.NoError(test, err)
+	require.True(test, backend.(*FileKeyBackend).encrypted)
+
+	_, err = NewKeyBackend(Config{KeyBackend: KeyBackendPKCS11})
+	require.Error(test, err)
+
+	_, err = NewKeyBackend(Config{KeyBackend: "vault"})
+	require.Error(test, err)
+}
+
+func TestLocalCosignerExternalTransport(test *testing.T) {
+	privateKey, cosigners, cleanup := newTestCosigners(test, 2, 3)
+	defer cleanup()
+
+	// the first cosigner only reaches its transport key through the backend
+	transport := &countingTransport{CosignerTransport: cosigners[0].key.TransportKey}
+	cosigners[0].key.TransportKey = nil
+	cosigners[0].transport = transport
+	for id := 1; id <= len(cosigners); id++ {
+		cosigners[0].key.TransportPubKeys = append(cosigners[0].key.TransportPubKeys, cosigners[0].peers[id].TransportPubKey)
+	}
+	require.False(test, cosigners[0].key.IsLegacy())
+
+	stateFile, err := ioutil.TempFile("", "priv_validator_state.json")
+	require.NoError(test, err)
+	defer os.Remove(stateFile.Name())
+	signState, err := LoadOrCreateSignState(stateFile.Name())
+	require.NoError(test, err)
+
+	validator := NewThresholdValidator(&ThresholdValidatorOpt{
+		Pubkey:    privateKey.PubKey(),
+		Threshold: 2,
+		SignState: signState,
+		Cosigner:  cosigners[0],
+		Peers:     []Cosigner{cosigners[1], cosigners[2]},
+	})
+
+	proposal := signTestProposal(test, validator, cosigners, 1)
+	require.True(test, privateKey.PubKey().VerifySignature(tm.ProposalSignBytes("chain-id", proposal), proposal.Signature))
+
+	// the parts dealt to the other cosigners were signed and the ones dealt to us opened by the transport
+	require.GreaterOrEqual(test, transport.signed, 2)
+	require.GreaterOrEqual(test, transport.opened, 2)
+}
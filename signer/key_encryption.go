@@ -0,0 +1,178 @@
+package signer
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/term"
+)
+
+const (
+	encryptedKeyType = "valink/encrypted_key"
+	encryptedKeyKDF  = "argon2id"
+
+	// KeyPassphraseEnv is the environment variable holding the passphrase of encrypted key files
+	KeyPassphraseEnv = "VALINK_KEY_PASSPHRASE"
+
+	// argon2id parameters of new key files, the ones of existing files are read from them
+	argon2Time    = 3
+	argon2Memory  = 64 * 1024
+	argon2Threads = 4
+
+	// upper bound of the memory a key file may ask for, in KiB
+	argon2MaxMemory = 4 * 1024 * 1024
+)
+
+// encryptedKeyFile is a key file encrypted with a passphrase
+// The key is derived from the passphrase with argon2id and the file content is sealed with XChaCha20-Poly1305.
+// Everything but the ciphertext is authenticated as additional data.
+type encryptedKeyFile struct {
+	Type       string `json:"type"`
+	KDF        string `json:"kdf"`
+	Time       uint32 `json:"time"`
+	Memory     uint32 `json:"memory"`
+	Threads    uint8  `json:"threads"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext,omitempty"`
+}
+
+// additionalData returns the header of the file bound to the ciphertext
+func (file *encryptedKeyFile) additionalData() ([]byte, error) {
+	header := *file
+	header.Ciphertext = nil
+	return json.Marshal(&header)
+}
+
+// aead derives the key of the file from the passphrase
+func (file *encryptedKeyFile) aead(passphrase []byte) (cipher.AEAD, error) {
+	if file.KDF != encryptedKeyKDF {
+		return nil, fmt.Errorf("Unsupported key derivation: %s", file.KDF)
+	}
+	if file.Time == 0 || file.Threads == 0 || file.Memory == 0 || file.Memory > argon2MaxMemory {
+		return nil, errors.New("Invalid key derivation parameters")
+	}
+	key := argon2.IDKey(passphrase, file.Salt, file.Time, file.Memory, file.Threads, chacha20poly1305.KeySize)
+	return chacha20poly1305.NewX(key)
+}
+
+// IsEncryptedKey returns true if the content of a key file is encrypted with a passphrase
+func IsEncryptedKey(data []byte) bool {
+	var file encryptedKeyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return false
+	}
+	return file.Type == encryptedKeyType
+}
+
+// EncryptKey encrypts the content of a key file with a passphrase
+func EncryptKey(plaintext []byte, passphrase []byte) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, errors.New("The passphrase is empty")
+	}
+
+	file := &encryptedKeyFile{
+		Type:    encryptedKeyType,
+		KDF:     encryptedKeyKDF,
+		Time:    argon2Time,
+		Memory:  argon2Memory,
+		Threads: argon2Threads,
+		Salt:    make([]byte, 16),
+		Nonce:   make([]byte, chacha20poly1305.NonceSizeX),
+	}
+	if _, err := rand.Read(file.Salt); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(file.Nonce); err != nil {
+		return nil, err
+	}
+
+	aead, err := file.aead(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	additionalData, err := file.additionalData()
+	if err != nil {
+		return nil, err
+	}
+
+	file.Ciphertext = aead.Seal(nil, file.Nonce, plaintext, additionalData)
+	return json.MarshalIndent(file, "", "  ")
+}
+
+// DecryptKey decrypts the content of a key file encrypted by EncryptKey
+func DecryptKey(data []byte, passphrase []byte) ([]byte, error) {
+	var file encryptedKeyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	if file.Type != encryptedKeyType {
+		return nil, errors.New("The key file is not encrypted")
+	}
+
+	aead, err := file.aead(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if len(file.Nonce) != aead.NonceSize() {
+		return nil, errors.New("Invalid nonce")
+	}
+	additionalData, err := file.additionalData()
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := aead.Open(nil, file.Nonce, file.Ciphertext, additionalData)
+	if err != nil {
+		return nil, errors.New("Wrong passphrase or corrupted key file")
+	}
+	return plaintext, nil
+}
+
+// PassphraseFunc returns the passphrase of an encrypted key file
+type PassphraseFunc func(file string) ([]byte, error)
+
+// KeyPassphrase returns the passphrase of encrypted key files from, in order, passphraseFile if set,
+// the VALINK_KEY_PASSPHRASE environment variable or an interactive prompt.
+func KeyPassphrase(passphraseFile string) PassphraseFunc {
+	return func(file string) ([]byte, error) {
+		if passphraseFile != "" {
+			passphrase, err := ioutil.ReadFile(passphraseFile)
+			if err != nil {
+				return nil, err
+			}
+			return bytes.TrimRight(passphrase, "\r\n"), nil
+		}
+
+		if passphrase, ok := os.LookupEnv(KeyPassphraseEnv); ok {
+			return []byte(passphrase), nil
+		}
+
+		return PromptPassphrase(fmt.Sprintf("Passphrase of %s: ", file))
+	}
+}
+
+// PromptPassphrase reads a passphrase from the terminal without echoing it
+func PromptPassphrase(prompt string) ([]byte, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, fmt.Errorf("No terminal to ask for the passphrase, set %s or a passphrase file", KeyPassphraseEnv)
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(strings.TrimRight(string(passphrase), "\r\n")), nil
+}
@@ -0,0 +1,103 @@
+package signer
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptKey(test *testing.T) {
+	plaintext := []byte(`{"id": 1}`)
+	passphrase := []byte("correct horse battery staple")
+
+	encrypted, err := EncryptKey(plaintext, passphrase)
+	require.NoError(test, err)
+	require.True(test, IsEncryptedKey(encrypted))
+	require.False(test, IsEncryptedKey(plaintext))
+	require.NotContains(test, string(encrypted), `"id"`)
+
+	decrypted, err := DecryptKey(encrypted, passphrase)
+	require.NoError(test, err)
+	require.Equal(test, plaintext, decrypted)
+
+	_, err = DecryptKey(encrypted, []byte("wrong passphrase"))
+	require.Error(test, err)
+
+	_, err = EncryptKey(plaintext, nil)
+	require.Error(test, err)
+
+	// the header is authenticated along with the ciphertext
+	var file encryptedKeyFile
+	require.NoError(test, json.Unmarshal(encrypted, &file))
+	file.Time++
+	tampered, err := json.Marshal(&file)
+	require.NoError(test, err)
+	_, err = DecryptKey(tampered, passphrase)
+	require.Error(test, err)
+
+	// a file can't make us allocate any amount of memory
+	require.NoError(test, json.Unmarshal(encrypted, &file))
+	file.Memory = argon2MaxMemory + 1
+	tampered, err = json.Marshal(&file)
+	require.NoError(test, err)
+	_, err = DecryptKey(tampered, passphrase)
+	require.Error(test, err)
+}
+
+func TestLoadEncryptedCosignerKey(test *testing.T) {
+	_, cosigners, cleanup := newTestCosigners(test, 2, 3)
+	defer cleanup()
+
+	dir, err := ioutil.TempDir("", "cosigner-key")
+	require.NoError(test, err)
+	defer os.RemoveAll(dir)
+
+	key := cosigners[0].key
+	jsonBytes, err := json.Marshal(&key)
+	require.NoError(test, err)
+	encrypted, err := EncryptKey(jsonBytes, []byte("passphrase"))
+	require.NoError(test, err)
+
+	file := dir + "/private_share_1.json"
+	require.NoError(test, ioutil.WriteFile(file, encrypted, 0600))
+
+	passphraseFile := dir + "/passphrase"
+	require.NoError(test, ioutil.WriteFile(passphraseFile, []byte("passphrase\n"), 0600))
+
+	loaded, err := LoadCosignerKeyWithPassphrase(file, KeyPassphrase(passphraseFile))
+	require.NoError(test, err)
+	require.Equal(test, key.ShareKey, loaded.ShareKey)
+
+	require.NoError(test, os.Setenv(KeyPassphraseEnv, "wrong passphrase"))
+	defer os.Unsetenv(KeyPassphraseEnv)
+	_, err = LoadCosignerKey(file)
+	require.Error(test, err)
+
+	require.NoError(test, os.Setenv(KeyPassphraseEnv, "passphrase"))
+	loaded, err = LoadCosignerKey(file)
+	require.NoError(test, err)
+	require.Equal(test, key.ShareKey, loaded.ShareKey)
+
+	// a key loaded from an encrypted file stays encrypted when it is saved, by a refresh for instance
+	require.NoError(test, SaveCosignerKey(file, &loaded))
+	saved, err := ioutil.ReadFile(file)
+	require.NoError(test, err)
+	require.True(test, IsEncryptedKey(saved))
+	reloaded, err := LoadCosignerKey(file)
+	require.NoError(test, err)
+	require.Equal(test, key.ShareKey, reloaded.ShareKey)
+
+	// plain key files are still loaded without a passphrase
+	require.NoError(test, SaveCosignerKey(file, &key))
+	saved, err = ioutil.ReadFile(file)
+	require.NoError(test, err)
+	require.False(test, IsEncryptedKey(saved))
+	_, err = LoadCosignerKeyWithPassphrase(file, func(string) ([]byte, error) {
+		test.Fatal("asked for the passphrase of a plain key file")
+		return nil, nil
+	})
+	require.NoError(test, err)
+}
@@ -0,0 +1,705 @@
+package signer
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/raft"
+	tmLog "github.com/tendermint/tendermint/libs/log"
+	tmnet "github.com/tendermint/tendermint/libs/net"
+)
+
+const (
+	// DefaultRaftApplyTimeout bounds the replication of a sign state to the other cosigners
+	DefaultRaftApplyTimeout = 2 * time.Second
+
+	raftMaxPool    = 3
+	raftTCPTimeout = 10 * time.Second
+	raftSnapshots  = 2
+)
+
+// ErrNotLeader is returned for the blocks a cosigner is asked to sign while another one is the leader
+var ErrNotLeader = errors.New("this cosigner is not the leader, it does not sign blocks")
+
+// LeaderElection elects the cosigner that signs the blocks of the cluster
+type LeaderElection interface {
+	// IsLeader returns true if this cosigner may sign blocks
+	IsLeader() bool
+	// VerifyLeader confirms with the other cosigners that this one is still the leader
+	// ErrNotLeader is returned otherwise. It is called before every signature.
+	VerifyLeader() error
+	// LeaderID returns the ID of the cosigner that signs the blocks, 0 if there is none
+	LeaderID() int
+	// ReplicateSignState commits the sign state of the chain to a majority of the cosigners
+	// A block signature is only handed out once its sign state is replicated.
+	ReplicateSignState(chainID string, signState SignState) error
+}
+
+// SignStateReceiver takes the sign states replicated by the leader
+type SignStateReceiver interface {
+	// RaiseSignState raises the sign state to the given one, if it is higher
+	RaiseSignState(signState SignState) error
+}
+
+// RaftConfig enables the election of a leader among the cosigners with raft
+// The raft address of the other cosigners is set with raft_address in their [[cosigner]] section.
+type RaftConfig struct {
+	// Address the raft transport listens on, such as tcp://0.0.0.0:2223
+	ListenAddress string `toml:"listen_address"`
+	// Address the other cosigners reach this one on, defaults to ListenAddress
+	Address string `toml:"address"`
+	// Directory of the raft log and snapshots, defaults to raft in the state directory
+	DataDir          string   `toml:"data_dir"`
+	HeartbeatTimeout Duration `toml:"heartbeat_timeout"`
+	ElectionTimeout  Duration `toml:"election_timeout"`
+}
+
+// Enabled returns true if the cosigners elect a leader
+func (cfg RaftConfig) Enabled() bool {
+	return cfg.ListenAddress != ""
+}
+
+// Validate returns an error if a leader can't be elected with the config
+func (cfg RaftConfig) Validate() error {
+	if !cfg.Enabled() {
+		return nil
+	}
+	if _, err := raftServerAddress(cfg.ListenAddress); err != nil {
+		return fmt.Errorf("raft listen_address: %w", err)
+	}
+	if cfg.Address != "" {
+		if _, err := raftServerAddress(cfg.Address); err != nil {
+			return fmt.Errorf("raft address: %w", err)
+		}
+	}
+	if cfg.HeartbeatTimeout.Duration < 0 || cfg.ElectionTimeout.Duration < 0 {
+		return errors.New("raft timeouts must not be negative")
+	}
+	return nil
+}
+
+// advertiseAddress returns the address the other cosigners reach this one on
+func (cfg RaftConfig) advertiseAddress() string {
+	if cfg.Address != "" {
+		return cfg.Address
+	}
+	return cfg.ListenAddress
+}
+
+// raftServerAddress strips the protocol from a tcp address
+func raftServerAddress(address string) (raft.ServerAddress, error) {
+	protocol, hostPort := tmnet.ProtocolAndAddress(address)
+	if protocol != "tcp" {
+		return "", fmt.Errorf("%s is not a tcp address", address)
+	}
+	if _, _, err := net.SplitHostPort(hostPort); err != nil {
+		return "", err
+	}
+	return raft.ServerAddress(hostPort), nil
+}
+
+// raftSignState is the log entry replicating the sign state of a chain
+type raftSignState struct {
+	ChainID   string `json:"chain_id"`
+	Height    int64  `json:"height"`
+	Round     int64  `json:"round"`
+	Step      int8   `json:"step"`
+	Signature []byte `json:"signature,omitempty"`
+	SignBytes []byte `json:"signbytes,omitempty"`
+
+	// the vote extension signed under the precommit watermark
+	ExtensionSignature []byte `json:"extension_signature,omitempty"`
+	ExtensionSignBytes []byte `json:"extension_signbytes,omitempty"`
+}
+
+func (entry raftSignState) hrsKey() HRSKey {
+	return HRSKey{Height: entry.Height, Round: entry.Round, Step: entry.Step}
+}
+
+func (entry raftSignState) signState() SignState {
+	return SignState{
+		Height:    entry.Height,
+		Round:     entry.Round,
+		Step:      entry.Step,
+		Signature: entry.Signature,
+		SignBytes: entry.SignBytes,
+
+		ExtensionSignature: entry.ExtensionSignature,
+		ExtensionSignBytes: entry.ExtensionSignBytes,
+	}
+}
+
+// RaftElection elects the leader among the cosigners with raft
+//
+// The replicated log carries the sign state of every chain, so the cosigner elected after the
+// leader fails starts from the last watermark of the cluster rather than from its own.
+type RaftElection struct {
+	raft         *raft.Raft
+	logger       tmLog.Logger
+	applyTimeout time.Duration
+
+	// the transport and the stores, closed on shutdown
+	closers []io.Closer
+
+	// cosigner ID of the raft address of each server
+	serverIDs map[raft.ServerAddress]int
+
+	// set once the FSM applied the log committed before we won the election
+	leaderMtx   sync.Mutex
+	leaderReady bool
+
+	shutdownCh   chan struct{}
+	shutdownOnce sync.Once
+
+	mtx        sync.Mutex
+	receivers  map[string]SignStateReceiver
+	signStates map[string]raftSignState
+}
+
+// raftServers returns the raft servers of the cosigners, ours included
+func raftServers(ourID int, ourAddress raft.ServerAddress, cosigners []CosignerConfig) ([]raft.Server, error) {
+	servers := []raft.Server{{ID: raft.ServerID(strconv.Itoa(ourID)), Address: ourAddress}}
+	for _, cosigner := range cosigners {
+		if cosigner.RaftAddress == "" {
+			return nil, fmt.Errorf("cosigner %d has no raft_address", cosigner.ID)
+		}
+		address, err := raftServerAddress(cosigner.RaftAddress)
+		if err != nil {
+			return nil, fmt.Errorf("raft_address of cosigner %d: %w", cosigner.ID, err)
+		}
+		servers = append(servers, raft.Server{ID: raft.ServerID(strconv.Itoa(cosigner.ID)), Address: address})
+	}
+	return servers, nil
+}
+
+// NewRaftElection joins the election of the leader among the cosigners of the config
+//
+// The raft transport is secured with the cosigner TLS config when it is enabled.
+// stateDir holds the raft data unless the config sets data_dir.
+func NewRaftElection(config Config, ourID int, stateDir string, logger tmLog.Logger) (*RaftElection, error) {
+	cfg := config.Raft
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if !cfg.Enabled() {
+		return nil, errors.New("raft is not configured")
+	}
+
+	ourAddress, err := raftServerAddress(cfg.advertiseAddress())
+	if err != nil {
+		return nil, err
+	}
+	servers, err := raftServers(ourID, ourAddress, config.Cosigners)
+	if err != nil {
+		return nil, err
+	}
+
+	dataDir := cfg.DataDir
+	if dataDir == "" {
+		dataDir = path.Join(stateDir, "raft")
+	}
+	if err := os.MkdirAll(dataDir, 0700); err != nil {
+		return nil, err
+	}
+
+	logOutput := &raftLogWriter{logger: logger}
+
+	transport, err := newRaftTransport(config, ourAddress, logOutput)
+	if err != nil {
+		return nil, err
+	}
+	store, err := openRaftBoltStore(path.Join(dataDir, "raft.db"))
+	if err != nil {
+		transport.Close()
+		return nil, err
+	}
+	snapshots, err := raft.NewFileSnapshotStore(dataDir, raftSnapshots, logOutput)
+	if err != nil {
+		transport.Close()
+		store.Close()
+		return nil, err
+	}
+
+	conf := raftElectionConfig(cfg, ourID, logOutput)
+	election, err := newRaftElection(conf, servers, store, store, snapshots, transport, logger)
+	if err != nil {
+		transport.Close()
+		store.Close()
+		return nil, err
+	}
+	election.closers = append(election.closers, transport, store)
+	return election, nil
+}
+
+// raftElectionConfig returns the raft config of the cosigner
+func raftElectionConfig(cfg RaftConfig, ourID int, logOutput io.Writer) *raft.Config {
+	conf := raft.DefaultConfig()
+	conf.LocalID = raft.ServerID(strconv.Itoa(ourID))
+	conf.LogOutput = logOutput
+	conf.LogLevel = "INFO"
+	if cfg.HeartbeatTimeout.Duration > 0 {
+		conf.HeartbeatTimeout = cfg.HeartbeatTimeout.Duration
+		if conf.LeaderLeaseTimeout > conf.HeartbeatTimeout {
+			conf.LeaderLeaseTimeout = conf.HeartbeatTimeout
+		}
+	}
+	if cfg.ElectionTimeout.Duration > 0 {
+		conf.ElectionTimeout = cfg.ElectionTimeout.Duration
+	}
+	return conf
+}
+
+// newRaftElection starts raft over the given stores and transport
+// The cluster is bootstrapped with the servers the first time the stores are used.
+func newRaftElection(
+	conf *raft.Config,
+	servers []raft.Server,
+	logs raft.LogStore,
+	stable raft.StableStore,
+	snapshots raft.SnapshotStore,
+	transport raft.Transport,
+	logger tmLog.Logger,
+) (*RaftElection, error) {
+	election := &RaftElection{
+		logger:       logger,
+		applyTimeout: DefaultRaftApplyTimeout,
+		receivers:    make(map[string]SignStateReceiver),
+		signStates:   make(map[string]raftSignState),
+		serverIDs:    make(map[raft.ServerAddress]int),
+		shutdownCh:   make(chan struct{}),
+	}
+	for _, server := range servers {
+		id, err := strconv.Atoi(string(server.ID))
+		if err != nil {
+			return nil, fmt.Errorf("raft server %q: %w", server.ID, err)
+		}
+		election.serverIDs[server.Address] = id
+	}
+
+	hasState, err := raft.HasExistingState(logs, stable, snapshots)
+	if err != nil {
+		return nil, err
+	}
+	if !hasState {
+		err = raft.BootstrapCluster(conf, logs, stable, snapshots, transport, raft.Configuration{Servers: servers})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// unlike LeaderCh, the notify channel doesn't drop the changes we are not waiting for
+	notifyCh := make(chan bool, 1)
+	conf.NotifyCh = notifyCh
+
+	election.raft, err = raft.NewRaft(conf, (*raftSignStateFSM)(election), logs, stable, snapshots, transport)
+	if err != nil {
+		return nil, err
+	}
+	go election.watchLeadership(notifyCh)
+	return election, nil
+}
+
+// watchLeadership runs a barrier on every election we win and reports the leadership once it succeeded
+// raft reports the election as soon as it is won, before the sign states committed by the previous
+// leaders are raised. The barrier waits for them, so we never sign from a stale watermark.
+func (election *RaftElection) watchLeadership(notifyCh <-chan bool) {
+	for {
+		select {
+		case <-election.shutdownCh:
+			return
+		case leader := <-notifyCh:
+			election.setLeaderReady(false)
+			if !leader {
+				continue
+			}
+			for election.raft.State() == raft.Leader {
+				err := election.raft.Barrier(election.applyTimeout).Error()
+				if err == nil {
+					election.setLeaderReady(true)
+					break
+				}
+				election.logger.Error("Applying the raft log after the election", "error", err)
+
+				select {
+				case <-election.shutdownCh:
+					return
+				case <-time.After(election.applyTimeout / 10):
+				}
+			}
+		}
+	}
+}
+
+func (election *RaftElection) setLeaderReady(ready bool) {
+	election.leaderMtx.Lock()
+	defer election.leaderMtx.Unlock()
+	election.leaderReady = ready
+}
+
+// Register has the sign states of the chain replicated by the leader raised on the receiver
+// The sign state already replicated for the chain is raised right away.
+func (election *RaftElection) Register(chainID string, receiver SignStateReceiver) error {
+	election.mtx.Lock()
+	defer election.mtx.Unlock()
+
+	election.receivers[chainID] = receiver
+	if entry, ok := election.signStates[chainID]; ok {
+		return receiver.RaiseSignState(entry.signState())
+	}
+	return nil
+}
+
+// IsLeader returns true if this cosigner is the raft leader and applied the log of the previous leaders
+// Implements LeaderElection.
+func (election *RaftElection) IsLeader() bool {
+	election.leaderMtx.Lock()
+	ready := election.leaderReady
+	election.leaderMtx.Unlock()
+	return ready && election.raft.State() == raft.Leader
+}
+
+// VerifyLeader confirms with a majority of the cosigners that we are still the leader
+// Implements LeaderElection.
+func (election *RaftElection) VerifyLeader() error {
+	if !election.IsLeader() {
+		return ErrNotLeader
+	}
+	if err := election.raft.VerifyLeader().Error(); err != nil {
+		election.logger.Info("Lost the leadership", "error", err)
+		return ErrNotLeader
+	}
+	return nil
+}
+
+// Leader returns the raft address of the current leader, empty if there is none
+func (election *RaftElection) Leader() string {
+	return string(election.raft.Leader())
+}
+
+// LeaderID returns the cosigner ID of the current leader, 0 if there is none
+// Implements LeaderElection.
+func (election *RaftElection) LeaderID() int {
+	return election.serverIDs[election.raft.Leader()]
+}
+
+// ReplicateSignState appends the sign state of the chain to the raft log
+// Implements LeaderElection.
+func (election *RaftElection) ReplicateSignState(chainID string, signState SignState) error {
+	data, err := json.Marshal(raftSignState{
+		ChainID:   chainID,
+		Height:    signState.Height,
+		Round:     signState.Round,
+		Step:      signState.Step,
+		Signature: signState.Signature,
+		SignBytes: signState.SignBytes,
+
+		ExtensionSignature: signState.ExtensionSignature,
+		ExtensionSignBytes: signState.ExtensionSignBytes,
+	})
+	if err != nil {
+		return err
+	}
+
+	future := election.raft.Apply(data, election.applyTimeout)
+	if err := future.Error(); err != nil {
+		if err == raft.ErrNotLeader || err == raft.ErrLeadershipLost {
+			return ErrNotLeader
+		}
+		return err
+	}
+	if err, ok := future.Response().(error); ok {
+		return err
+	}
+	return nil
+}
+
+// Shutdown leaves the election and closes the raft stores
+func (election *RaftElection) Shutdown() error {
+	election.shutdownOnce.Do(func() { close(election.shutdownCh) })
+	err := election.raft.Shutdown().Error()
+	for _, closer := range election.closers {
+		if closeErr := closer.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// raftSignStateFSM applies the replicated sign states to the registered receivers
+type raftSignStateFSM RaftElection
+
+// Apply raises the sign state of the chain of the log entry
+// Implements raft.FSM.
+func (fsm *raftSignStateFSM) Apply(log *raft.Log) interface{} {
+	var entry raftSignState
+	if err := json.Unmarshal(log.Data, &entry); err != nil {
+		return err
+	}
+
+	fsm.mtx.Lock()
+	defer fsm.mtx.Unlock()
+
+	return fsm.raise(entry)
+}
+
+// raise keeps the entry if it is above the sign state of its chain,
+// or brings the vote extension of the precommit at the same HRS
+// fsm.mtx must be held.
+func (fsm *raftSignStateFSM) raise(entry raftSignState) error {
+	current, ok := fsm.signStates[entry.ChainID]
+	if ok {
+		currentState, entryState := current.signState(), entry.signState()
+		if !currentState.raisedBy(&entryState) {
+			return nil
+		}
+	}
+	fsm.signStates[entry.ChainID] = entry
+
+	receiver, ok := fsm.receivers[entry.ChainID]
+	if !ok {
+		return nil
+	}
+	if err := receiver.RaiseSignState(entry.signState()); err != nil {
+		fsm.logger.Error("Raising replicated sign state", "chain_id", entry.ChainID, "error", err)
+		return err
+	}
+	return nil
+}
+
+// Snapshot returns the sign states of all chains
+// Implements raft.FSM.
+func (fsm *raftSignStateFSM) Snapshot() (raft.FSMSnapshot, error) {
+	fsm.mtx.Lock()
+	defer fsm.mtx.Unlock()
+
+	snapshot := make(raftSignStateSnapshot, 0, len(fsm.signStates))
+	for _, entry := range fsm.signStates {
+		snapshot = append(snapshot, entry)
+	}
+	return snapshot, nil
+}
+
+// Restore raises the sign states of the snapshot
+// Implements raft.FSM.
+func (fsm *raftSignStateFSM) Restore(reader io.ReadCloser) error {
+	defer reader.Close()
+
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	var snapshot raftSignStateSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return err
+	}
+
+	fsm.mtx.Lock()
+	defer fsm.mtx.Unlock()
+
+	for _, entry := range snapshot {
+		if err := fsm.raise(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// raftSignStateSnapshot is the sign state of every chain at the time of a snapshot
+type raftSignStateSnapshot []raftSignState
+
+// Persist writes the snapshot to the sink
+// Implements raft.FSMSnapshot.
+func (snapshot raftSignStateSnapshot) Persist(sink raft.SnapshotSink) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		sink.Cancel()
+		return err
+	}
+	if _, err := sink.Write(data); err != nil {
+		sink.Cancel()
+		return err
+	}
+	return sink.Close()
+}
+
+// Release implements raft.FSMSnapshot.
+func (snapshot raftSignStateSnapshot) Release() {}
+
+// newRaftTransport listens for the raft messages of the other cosigners
+// The connections are mutually authenticated with TLS when it is configured for the cosigners.
+func newRaftTransport(config Config, advertise raft.ServerAddress, logOutput io.Writer) (*raft.NetworkTransport, error) {
+	bind, err := raftServerAddress(config.Raft.ListenAddress)
+	if err != nil {
+		return nil, err
+	}
+	advertiseAddr, err := net.ResolveTCPAddr("tcp", string(advertise))
+	if err != nil {
+		return nil, err
+	}
+
+	if !config.TLS.Enabled() {
+		return raft.NewTCPTransport(string(bind), advertiseAddr, raftMaxPool, raftTCPTimeout, logOutput)
+	}
+
+	stream, err := newRaftTLSStreamLayer(config, bind, advertiseAddr, logOutput)
+	if err != nil {
+		return nil, err
+	}
+	return raft.NewNetworkTransport(stream, raftMaxPool, raftTCPTimeout, logOutput), nil
+}
+
+// newRaftTLSStreamLayer listens on bind for the TLS connections of the cosigners of the config
+func newRaftTLSStreamLayer(config Config, bind raft.ServerAddress, advertise net.Addr, logOutput io.Writer) (*raftTLSStreamLayer, error) {
+	serverConfig, err := config.TLS.ServerTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	serverIDs := make(map[raft.ServerAddress]int)
+	peerNames := make(map[int]string)
+	for _, cosigner := range config.Cosigners {
+		address, err := raftServerAddress(cosigner.RaftAddress)
+		if err != nil {
+			return nil, err
+		}
+		serverIDs[address] = cosigner.ID
+		peerNames[cosigner.ID] = cosigner.TLSServerName()
+	}
+
+	listener, err := net.Listen("tcp", string(bind))
+	if err != nil {
+		return nil, err
+	}
+	return &raftTLSStreamLayer{
+		Listener:  tls.NewListener(listener, serverConfig),
+		advertise: advertise,
+		tlsConfig: config.TLS,
+		serverIDs: serverIDs,
+		peerNames: peerNames,
+		logOutput: logOutput,
+	}, nil
+}
+
+// raftTLSStreamLayer carries the raft messages over mutual TLS
+// Only the cosigners of the config can connect, the same way the cosigner rpc server
+// maps the certificate of its clients to a cosigner.
+// Implements raft.StreamLayer.
+type raftTLSStreamLayer struct {
+	net.Listener
+	advertise net.Addr
+	tlsConfig TLSConfig
+	serverIDs map[raft.ServerAddress]int
+	peerNames map[int]string
+	logOutput io.Writer
+}
+
+// Addr returns the address the other cosigners reach this one on
+func (layer *raftTLSStreamLayer) Addr() net.Addr {
+	return layer.advertise
+}
+
+// Accept waits for the next connection of a cosigner
+// The handshake runs on the first read of the connection, so a stalled peer doesn't hold up
+// the others. Nothing is read from a peer whose certificate does not map to a configured cosigner.
+func (layer *raftTLSStreamLayer) Accept() (net.Conn, error) {
+	conn, err := layer.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &raftPeerConn{Conn: conn.(*tls.Conn), layer: layer}, nil
+}
+
+// Dial opens a TLS connection to the cosigner at the raft address
+func (layer *raftTLSStreamLayer) Dial(address raft.ServerAddress, timeout time.Duration) (net.Conn, error) {
+	id, ok := layer.serverIDs[address]
+	if !ok {
+		return nil, fmt.Errorf("no cosigner has the raft address %s", address)
+	}
+	clientConfig, err := layer.tlsConfig.ClientTLSConfig(layer.peerNames[id])
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", string(address), clientConfig)
+	if err != nil {
+		return nil, err
+	}
+	peerID, err := layer.authenticate(conn)
+	if err == nil && peerID != id {
+		err = fmt.Errorf("certificate belongs to cosigner %d instead of %d", peerID, id)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// authenticate completes the TLS handshake and returns the ID of the cosigner on the other end
+func (layer *raftTLSStreamLayer) authenticate(conn *tls.Conn) (int, error) {
+	if err := conn.Handshake(); err != nil {
+		return 0, err
+	}
+	return connectionCosignerID(conn.ConnectionState(), layer.peerNames)
+}
+
+// raftPeerConn is a raft connection accepted from another cosigner
+// It is closed on the first read or write if the peer is not a configured cosigner.
+type raftPeerConn struct {
+	*tls.Conn
+	layer *raftTLSStreamLayer
+	once  sync.Once
+	err   error
+}
+
+func (conn *raftPeerConn) authenticated() error {
+	conn.once.Do(func() {
+		if _, err := conn.layer.authenticate(conn.Conn); err != nil {
+			conn.err = fmt.Errorf("rejected raft connection from %s: %w", conn.RemoteAddr(), err)
+			conn.Conn.Close()
+		}
+	})
+	return conn.err
+}
+
+func (conn *raftPeerConn) Read(b []byte) (int, error) {
+	if err := conn.authenticated(); err != nil {
+		return 0, err
+	}
+	return conn.Conn.Read(b)
+}
+
+func (conn *raftPeerConn) Write(b []byte) (int, error) {
+	if err := conn.authenticated(); err != nil {
+		return 0, err
+	}
+	return conn.Conn.Write(b)
+}
+
+// raftLogWriter forwards the raft logs to the cosigner logger
+type raftLogWriter struct {
+	logger tmLog.Logger
+}
+
+func (writer *raftLogWriter) Write(p []byte) (int, error) {
+	line := strings.TrimSpace(string(p))
+	switch {
+	case strings.Contains(line, "[ERROR]") || strings.Contains(line, "[ERR]"):
+		writer.logger.Error(line, "module", "raft")
+	case strings.Contains(line, "[DEBUG]"):
+		writer.logger.Debug(line, "module", "raft")
+	default:
+		writer.logger.Info(line, "module", "raft")
+	}
+	return len(p), nil
+}
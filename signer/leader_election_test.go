@@ -0,0 +1,460 @@
+package signer
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/stretchr/testify/require"
+	tmCrypto "github.com/tendermint/tendermint/crypto"
+	tmCryptoEd25519 "github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/libs/log"
+	tmProtoPrivval "github.com/tendermint/tendermint/proto/tendermint/privval"
+	tmProto "github.com/tendermint/tendermint/proto/tendermint/types"
+	tm "github.com/tendermint/tendermint/types"
+)
+
+// newTestRaftCluster starts the elections of total cosigners over an in-memory transport
+func newTestRaftCluster(test *testing.T, total int) []*RaftElection {
+	servers := make([]raft.Server, total)
+	transports := make([]*raft.InmemTransport, total)
+	for i := range servers {
+		id := strconv.Itoa(i + 1)
+		address, transport := raft.NewInmemTransport(raft.ServerAddress(id))
+		servers[i] = raft.Server{ID: raft.ServerID(id), Address: address}
+		transports[i] = transport
+	}
+	for _, transport := range transports {
+		for i, peer := range transports {
+			transport.Connect(servers[i].Address, peer)
+		}
+	}
+
+	elections := make([]*RaftElection, total)
+	for i := range elections {
+		conf := raftElectionConfig(RaftConfig{}, i+1, ioutil.Discard)
+		conf.HeartbeatTimeout = 50 * time.Millisecond
+		conf.ElectionTimeout = 50 * time.Millisecond
+		conf.LeaderLeaseTimeout = 50 * time.Millisecond
+		conf.CommitTimeout = 5 * time.Millisecond
+
+		store := raft.NewInmemStore()
+		election, err := newRaftElection(conf, servers, store, store, raft.NewInmemSnapshotStore(), transports[i], log.NewNopLogger())
+		require.NoError(test, err)
+		elections[i] = election
+	}
+	test.Cleanup(func() {
+		for _, election := range elections {
+			election.Shutdown()
+		}
+	})
+	return elections
+}
+
+// waitForLeader returns the index of the single leader among the running elections
+func waitForLeader(test *testing.T, elections []*RaftElection, running map[int]bool) int {
+	leader := -1
+	require.Eventually(test, func() bool {
+		leader = -1
+		for i, election := range elections {
+			if !running[i] || !election.IsLeader() {
+				continue
+			}
+			if leader != -1 {
+				return false
+			}
+			leader = i
+		}
+		return leader != -1
+	}, 10*time.Second, 10*time.Millisecond)
+	return leader
+}
+
+// newTestSignStateValidator returns a validator that only holds a sign state in dir
+func newTestSignStateValidator(test *testing.T, dir string, name string) (*ThresholdValidator, string) {
+	stateFile := path.Join(dir, name)
+	signState, err := LoadOrCreateSignState(stateFile)
+	require.NoError(test, err)
+	return NewThresholdValidator(&ThresholdValidatorOpt{SignState: signState}), stateFile
+}
+
+func TestRaftElectionReplicatesSignState(test *testing.T) {
+	dir, err := ioutil.TempDir("", "raft_election")
+	require.NoError(test, err)
+	defer os.RemoveAll(dir)
+
+	elections := newTestRaftCluster(test, 3)
+	validators := make([]*ThresholdValidator, len(elections))
+	stateFiles := make([]string, len(elections))
+	running := make(map[int]bool)
+	for i, election := range elections {
+		validators[i], stateFiles[i] = newTestSignStateValidator(test, dir, strconv.Itoa(i)+"_state.json")
+		require.NoError(test, election.Register("chain-id", validators[i]))
+		running[i] = true
+	}
+
+	leader := waitForLeader(test, elections, running)
+
+	// only the leader appends to the log
+	follower := (leader + 1) % len(elections)
+	err = elections[follower].ReplicateSignState("chain-id", SignState{Height: 1, Step: stepPropose})
+	require.Equal(test, ErrNotLeader, err)
+
+	signState := SignState{Height: 5, Round: 1, Step: stepPrevote, Signature: []byte("signature"), SignBytes: []byte("sign bytes")}
+	require.NoError(test, elections[leader].ReplicateSignState("chain-id", signState))
+
+	// the followers raise and persist the watermark
+	for i, validator := range validators {
+		require.Eventually(test, func() bool {
+			validator.lastSignStateMutex.Lock()
+			defer validator.lastSignStateMutex.Unlock()
+			return validator.lastSignState.Height == 5
+		}, 5*time.Second, 10*time.Millisecond)
+
+		saved, err := LoadSignState(stateFiles[i])
+		require.NoError(test, err)
+		require.Equal(test, int64(1), saved.Round)
+		require.Equal(test, stepPrevote, saved.Step)
+		require.Equal(test, []byte("signature"), saved.Signature)
+	}
+
+	// a lower sign state doesn't move the watermark back
+	require.NoError(test, elections[leader].ReplicateSignState("chain-id", SignState{Height: 4, Step: stepPrecommit}))
+	require.Equal(test, int64(5), validators[leader].lastSignState.Height)
+
+	// the next leader refuses to sign below the replicated watermark
+	require.NoError(test, elections[leader].Shutdown())
+	running[leader] = false
+	next := waitForLeader(test, elections, running)
+	require.NotEqual(test, leader, next)
+
+	_, err = validators[next].lastSignState.CheckHRS(5, 0, stepPrevote)
+	require.Error(test, err)
+}
+
+func TestRaftElectionRegisterRaisesReplicatedState(test *testing.T) {
+	dir, err := ioutil.TempDir("", "raft_election")
+	require.NoError(test, err)
+	defer os.RemoveAll(dir)
+
+	elections := newTestRaftCluster(test, 3)
+	leader := waitForLeader(test, elections, map[int]bool{0: true, 1: true, 2: true})
+
+	// the log is replicated before the chain is registered
+	require.NoError(test, elections[leader].ReplicateSignState("chain-id", SignState{Height: 7, Step: stepPrecommit}))
+
+	validator, _ := newTestSignStateValidator(test, dir, "state.json")
+	require.NoError(test, elections[leader].Register("chain-id", validator))
+	require.Equal(test, int64(7), validator.lastSignState.Height)
+	require.Equal(test, stepPrecommit, validator.lastSignState.Step)
+
+	// the vote extension is added to the precommit at the same HRS
+	require.NoError(test, elections[leader].ReplicateSignState("chain-id", SignState{
+		Height:             7,
+		Step:               stepPrecommit,
+		ExtensionSignature: []byte("extension signature"),
+		ExtensionSignBytes: []byte("extension sign bytes"),
+	}))
+	validator.lastSignStateMutex.Lock()
+	defer validator.lastSignStateMutex.Unlock()
+	require.Equal(test, stepPrecommit, validator.lastSignState.Step)
+	require.Equal(test, []byte("extension signature"), validator.lastSignState.ExtensionSignature)
+}
+
+// slowReceiver takes its time to raise the replicated sign states
+type slowReceiver struct {
+	*ThresholdValidator
+	delay time.Duration
+}
+
+func (receiver *slowReceiver) RaiseSignState(signState SignState) error {
+	time.Sleep(receiver.delay)
+	return receiver.ThresholdValidator.RaiseSignState(signState)
+}
+
+func TestRaftElectionLeaderAppliesLogBeforeSigning(test *testing.T) {
+	dir, err := ioutil.TempDir("", "raft_election")
+	require.NoError(test, err)
+	defer os.RemoveAll(dir)
+
+	elections := newTestRaftCluster(test, 3)
+	validators := make([]*ThresholdValidator, len(elections))
+	receivers := make([]*slowReceiver, len(elections))
+	running := make(map[int]bool)
+	for i, election := range elections {
+		validators[i], _ = newTestSignStateValidator(test, dir, strconv.Itoa(i)+"_state.json")
+		receivers[i] = &slowReceiver{ThresholdValidator: validators[i]}
+		require.NoError(test, election.Register("chain-id", receivers[i]))
+		running[i] = true
+	}
+
+	leader := waitForLeader(test, elections, running)
+	for i, receiver := range receivers {
+		if i != leader {
+			receiver.delay = time.Second
+		}
+	}
+	require.NoError(test, elections[leader].VerifyLeader())
+	follower := (leader + 1) % len(elections)
+	require.Equal(test, ErrNotLeader, elections[follower].VerifyLeader())
+
+	// the leader is gone before the followers applied its last sign state
+	require.NoError(test, elections[leader].ReplicateSignState("chain-id", SignState{Height: 5, Step: stepPrevote}))
+	require.NoError(test, elections[leader].Shutdown())
+	running[leader] = false
+
+	// the next leader only reports the leadership once it raised the watermark
+	next := waitForLeader(test, elections, running)
+	validators[next].lastSignStateMutex.Lock()
+	defer validators[next].lastSignStateMutex.Unlock()
+	require.Equal(test, int64(5), validators[next].lastSignState.Height)
+}
+
+// staticElection is a LeaderElection that records the replicated sign states
+type staticElection struct {
+	leader     bool
+	leaderID   int
+	replicated []SignState
+}
+
+func (election *staticElection) IsLeader() bool {
+	return election.leader
+}
+
+func (election *staticElection) LeaderID() int {
+	return election.leaderID
+}
+
+func (election *staticElection) VerifyLeader() error {
+	if !election.leader {
+		return ErrNotLeader
+	}
+	return nil
+}
+
+func (election *staticElection) ReplicateSignState(chainID string, signState SignState) error {
+	election.replicated = append(election.replicated, signState)
+	return nil
+}
+
+func TestThresholdValidatorLeaderElection(test *testing.T) {
+	privateKey, cosigners, cleanup := newTestCosigners(test, 2, 3)
+	defer cleanup()
+
+	pubKey := privateKey.PubKey().(tmCryptoEd25519.PubKey)
+	validator := newMisbehaviourTestValidator(test, cosigners, pubKey, []Cosigner{cosigners[1], cosigners[2]})
+	election := &staticElection{}
+	validator.election = election
+
+	var proposal tmProto.Proposal
+	proposal.Height = 1
+	proposal.Type = tmProto.ProposalType
+
+	// a follower without leader has nowhere to forward the block
+	err := validator.SignProposal("chain-id", &proposal)
+	require.Equal(test, ErrNotLeader, err)
+	require.Empty(test, election.replicated)
+
+	election.leader = true
+	require.NoError(test, validator.SignProposal("chain-id", &proposal))
+	require.True(test, pubKey.VerifySignature(tm.ProposalSignBytes("chain-id", &proposal), proposal.Signature))
+
+	require.Len(test, election.replicated, 1)
+	require.Equal(test, int64(1), election.replicated[0].Height)
+	require.Equal(test, stepPropose, election.replicated[0].Step)
+	require.Equal(test, proposal.Signature, election.replicated[0].Signature)
+}
+
+func TestRaftConfigValidate(test *testing.T) {
+	require.NoError(test, RaftConfig{}.Validate())
+	require.NoError(test, RaftConfig{ListenAddress: "tcp://0.0.0.0:2223", Address: "tcp://10.0.0.1:2223"}.Validate())
+	require.Error(test, RaftConfig{ListenAddress: "unix:///tmp/raft.sock"}.Validate())
+	require.Error(test, RaftConfig{ListenAddress: "tcp://0.0.0.0"}.Validate())
+	require.Error(test, RaftConfig{ListenAddress: "tcp://0.0.0.0:2223", Address: "10.0.0.1"}.Validate())
+
+	_, err := raftServers(1, "10.0.0.1:2223", []CosignerConfig{{ID: 2, Address: "tcp://10.0.0.2:2222"}})
+	require.Error(test, err)
+
+	servers, err := raftServers(1, "10.0.0.1:2223", []CosignerConfig{{ID: 2, RaftAddress: "tcp://10.0.0.2:2223"}})
+	require.NoError(test, err)
+	require.Equal(test, []raft.Server{
+		{ID: "1", Address: "10.0.0.1:2223"},
+		{ID: "2", Address: "10.0.0.2:2223"},
+	}, servers)
+}
+
+func TestRaftBoltStore(test *testing.T) {
+	dir, err := ioutil.TempDir("", "raft_store")
+	require.NoError(test, err)
+	defer os.RemoveAll(dir)
+
+	store, err := openRaftBoltStore(path.Join(dir, "raft.db"))
+	require.NoError(test, err)
+	defer store.Close()
+
+	first, err := store.FirstIndex()
+	require.NoError(test, err)
+	require.Equal(test, uint64(0), first)
+
+	var log raft.Log
+	require.Equal(test, raft.ErrLogNotFound, store.GetLog(1, &log))
+
+	require.NoError(test, store.StoreLogs([]*raft.Log{
+		{Index: 1, Term: 1, Data: []byte("one")},
+		{Index: 2, Term: 1, Data: []byte("two")},
+		{Index: 3, Term: 2, Data: []byte("three")},
+	}))
+
+	last, err := store.LastIndex()
+	require.NoError(test, err)
+	require.Equal(test, uint64(3), last)
+
+	require.NoError(test, store.GetLog(2, &log))
+	require.Equal(test, []byte("two"), log.Data)
+
+	require.NoError(test, store.DeleteRange(1, 2))
+	first, err = store.FirstIndex()
+	require.NoError(test, err)
+	require.Equal(test, uint64(3), first)
+	require.Equal(test, raft.ErrLogNotFound, store.GetLog(2, &log))
+
+	value, err := store.Get([]byte("missing"))
+	require.NoError(test, err)
+	require.Empty(test, value)
+
+	require.NoError(test, store.Set([]byte("key"), []byte("value")))
+	value, err = store.Get([]byte("key"))
+	require.NoError(test, err)
+	require.Equal(test, []byte("value"), value)
+
+	require.NoError(test, store.SetUint64([]byte("term"), 42))
+	term, err := store.GetUint64([]byte("term"))
+	require.NoError(test, err)
+	require.Equal(test, uint64(42), term)
+}
+
+func TestThresholdValidatorFollowerForwardsToLeader(test *testing.T) {
+	privateKey, cosigners, cleanup := newTestCosigners(test, 2, 3)
+	defer cleanup()
+
+	pubKey := privateKey.PubKey().(tmCryptoEd25519.PubKey)
+	leader := newMisbehaviourTestValidator(test, cosigners, pubKey, []Cosigner{cosigners[1], cosigners[2]})
+	leader.election = &staticElection{leader: true, leaderID: 1}
+
+	rpcServer := NewCosignerRpcServer(&CosignerRpcServerConfig{
+		Logger:        log.NewNopLogger(),
+		ListenAddress: "127.0.0.1:0",
+		Chains:        []CosignerRpcChain{{ChainID: "chain-id", LocalCosigner: cosigners[0], Validator: leader}},
+	})
+	require.NoError(test, rpcServer.Start())
+	defer rpcServer.Stop()
+	remoteLeader := NewRemoteCosigner(1, rpcServer.Addr().String()).ForChain("chain-id")
+	defer remoteLeader.Close()
+
+	dir, err := ioutil.TempDir("", "follower")
+	require.NoError(test, err)
+	defer os.RemoveAll(dir)
+	signState, err := LoadOrCreateSignState(path.Join(dir, "state.json"))
+	require.NoError(test, err)
+	follower := NewThresholdValidator(&ThresholdValidatorOpt{
+		Pubkey:    pubKey,
+		Threshold: 2,
+		SignState: signState,
+		Cosigner:  cosigners[1],
+		Peers:     []Cosigner{remoteLeader, cosigners[2]},
+		Election:  &staticElection{leaderID: 1},
+	})
+
+	// the node is attached to the follower
+	nodeKey := tmCryptoEd25519.GenPrivKey()
+	rs := NewListenRemoteSigner("tcp://127.0.0.1:0", log.NewNopLogger(), "chain-id", &PvGuard{PrivValidator: follower},
+		tmCryptoEd25519.GenPrivKey(), []tmCrypto.PubKey{nodeKey.PubKey()}, PrivvalProtocolV034)
+	require.NoError(test, rs.Start())
+	defer rs.Stop()
+	conn := dialListenRemoteSigner(test, rs, nodeKey)
+	defer conn.Close()
+
+	proposal := tmProto.Proposal{Height: 1, Type: tmProto.ProposalType, Timestamp: time.Now().UTC()}
+	require.NoError(test, WriteMsg(conn, tmProtoPrivval.Message{Sum: &tmProtoPrivval.Message_SignProposalRequest{
+		SignProposalRequest: &tmProtoPrivval.SignProposalRequest{Proposal: &proposal, ChainId: "chain-id"},
+	}}))
+	res, err := ReadMsg(conn)
+	require.NoError(test, err)
+	proposalResponse := res.GetSignedProposalResponse()
+	require.NotNil(test, proposalResponse)
+	require.Nil(test, proposalResponse.Error)
+
+	// the leader signed it under its watermark
+	signed := proposalResponse.Proposal
+	require.True(test, proposal.Timestamp.Equal(signed.Timestamp))
+	require.True(test, pubKey.VerifySignature(tm.ProposalSignBytes("chain-id", &signed), signed.Signature))
+	require.Equal(test, int64(1), leader.lastSignState.Height)
+	require.Equal(test, stepPropose, leader.lastSignState.Step)
+
+	// the leader only signs the blocks of its chain
+	_, err = remoteLeader.SignBlock(context.Background(), &CosignerSignBlockRequest{
+		SignBytes: tm.ProposalSignBytes("other-chain", &tmProto.Proposal{Height: 2, Type: tmProto.ProposalType}),
+	})
+	require.Error(test, err)
+}
+
+func TestRaftTLSStreamLayerAcceptsOnlyCosigners(test *testing.T) {
+	dir, err := ioutil.TempDir("", "raft-tls")
+	require.NoError(test, err)
+	defer os.RemoveAll(dir)
+
+	ca := newTestCA(test, dir)
+	config := Config{
+		TLS: ca.issue(test, "cosigner1"),
+		Cosigners: []CosignerConfig{
+			{ID: 2, TLSName: "cosigner2", RaftAddress: "tcp://127.0.0.1:1235"},
+		},
+	}
+	advertise := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1234}
+	layer, err := newRaftTLSStreamLayer(config, "127.0.0.1:0", advertise, ioutil.Discard)
+	require.NoError(test, err)
+	defer layer.Close()
+	address := layer.Listener.Addr().String()
+
+	// dialAs connects to the raft listener with the certificate issued for name and sends a message
+	dialAs := func(name string) {
+		clientConfig, err := ca.issue(test, name).ClientTLSConfig("cosigner1")
+		require.NoError(test, err)
+		go func() {
+			conn, err := tls.Dial("tcp", address, clientConfig)
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			conn.Write([]byte("ping"))
+			ioutil.ReadAll(conn)
+		}()
+	}
+
+	// a configured cosigner is accepted
+	dialAs("cosigner2")
+	conn, err := layer.Accept()
+	require.NoError(test, err)
+	message := make([]byte, 4)
+	_, err = io.ReadFull(conn, message)
+	require.NoError(test, err)
+	require.Equal(test, []byte("ping"), message)
+	conn.Close()
+
+	// a certificate from the same CA that does not map to a cosigner is rejected before any message is read
+	dialAs("intruder")
+	conn, err = layer.Accept()
+	require.NoError(test, err)
+	_, err = conn.Read(message)
+	require.Error(test, err)
+	require.Contains(test, err.Error(), "does not belong to a configured cosigner")
+	conn.Close()
+}
@@ -0,0 +1,191 @@
+package signer
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net"
+	"sync"
+
+	tmCrypto "github.com/tendermint/tendermint/crypto"
+	tmCryptoEd2219 "github.com/tendermint/tendermint/crypto/ed25519"
+	tmLog "github.com/tendermint/tendermint/libs/log"
+	tmNet "github.com/tendermint/tendermint/libs/net"
+	tmService "github.com/tendermint/tendermint/libs/service"
+	tmP2pConn "github.com/tendermint/tendermint/p2p/conn"
+	tm "github.com/tendermint/tendermint/types"
+)
+
+// ParseNodePubKey decodes the base64 ed25519 public key of a node, as in the "value" of its JSON key files
+func ParseNodePubKey(key string) (tmCrypto.PubKey, error) {
+	keyBytes, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid node key %q: %v", key, err)
+	}
+	if len(keyBytes) != tmCryptoEd2219.PubKeySize {
+		return nil, fmt.Errorf("invalid node key %q: not an ed25519 public key", key)
+	}
+	return tmCryptoEd2219.PubKey(keyBytes), nil
+}
+
+// ListenRemoteSigner accepts privval connections from nodes and responds to any
+// signature requests using its privVal.
+// Only the nodes whose SecretConnection key is allowed are served.
+type ListenRemoteSigner struct {
+	tmService.BaseService
+
+	address     string
+	chainID     string
+	privKey     tmCryptoEd2219.PrivKey
+	privVal     tm.PrivValidator
+	allowedKeys []tmCrypto.PubKey
+	protocol    string
+
+	listener net.Listener
+	mtx      sync.Mutex
+	conns    map[net.Conn]struct{}
+}
+
+// NewListenRemoteSigner return a ListenRemoteSigner that will listen on the given
+// address and respond to the signature requests of the nodes holding one of allowedKeys
+// using the given privVal. The SecretConnection is authenticated with privKey and the
+// messages are in the format of the privval protocol.
+func NewListenRemoteSigner(
+	address string,
+	logger tmLog.Logger,
+	chainID string,
+	privVal tm.PrivValidator,
+	privKey tmCryptoEd2219.PrivKey,
+	allowedKeys []tmCrypto.PubKey,
+	protocol string,
+) *ListenRemoteSigner {
+	rs := &ListenRemoteSigner{
+		address:     address,
+		chainID:     chainID,
+		privVal:     privVal,
+		allowedKeys: allowedKeys,
+		privKey:     privKey,
+		protocol:    protocol,
+		conns:       make(map[net.Conn]struct{}),
+	}
+
+	rs.BaseService = *tmService.NewBaseService(logger, "ListenRemoteSigner", rs)
+	return rs
+}
+
+// OnStart implements cmn.Service.
+func (rs *ListenRemoteSigner) OnStart() error {
+	proto, address := tmNet.ProtocolAndAddress(rs.address)
+	listener, err := net.Listen(proto, address)
+	if err != nil {
+		return err
+	}
+	rs.listener = listener
+	rs.Logger.Info("Listening for nodes", "address", rs.address)
+
+	go rs.acceptLoop()
+	return nil
+}
+
+// OnStop implements cmn.Service.
+func (rs *ListenRemoteSigner) OnStop() {
+	if err := rs.listener.Close(); err != nil {
+		rs.Logger.Error("Close", "err", err.Error()+"closing listener failed")
+	}
+
+	rs.mtx.Lock()
+	defer rs.mtx.Unlock()
+	for conn := range rs.conns {
+		conn.Close()
+	}
+}
+
+// Addr returns the address the signer listens on
+func (rs *ListenRemoteSigner) Addr() net.Addr {
+	return rs.listener.Addr()
+}
+
+// accepts the connections of the nodes until the listener is closed
+func (rs *ListenRemoteSigner) acceptLoop() {
+	for {
+		netConn, err := rs.listener.Accept()
+		if err != nil {
+			if !rs.IsRunning() {
+				return
+			}
+			rs.Logger.Error("Accept", "err", err)
+			continue
+		}
+		go rs.serve(netConn)
+	}
+}
+
+// isAllowed returns true if a node holding the key may send sign requests
+func (rs *ListenRemoteSigner) isAllowed(key tmCrypto.PubKey) bool {
+	for _, allowedKey := range rs.allowedKeys {
+		if allowedKey.Equals(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// trackConn registers an open connection to close on stop, it returns false once stopped
+func (rs *ListenRemoteSigner) trackConn(conn net.Conn) bool {
+	rs.mtx.Lock()
+	defer rs.mtx.Unlock()
+	if !rs.IsRunning() {
+		return false
+	}
+	rs.conns[conn] = struct{}{}
+	return true
+}
+
+func (rs *ListenRemoteSigner) untrackConn(conn net.Conn) {
+	rs.mtx.Lock()
+	defer rs.mtx.Unlock()
+	delete(rs.conns, conn)
+}
+
+// serve answers the requests of a node until its connection breaks
+func (rs *ListenRemoteSigner) serve(netConn net.Conn) {
+	remoteAddress := netConn.RemoteAddr().String()
+	if !rs.trackConn(netConn) {
+		netConn.Close()
+		return
+	}
+	defer rs.untrackConn(netConn)
+	defer netConn.Close()
+
+	conn, err := tmP2pConn.MakeSecretConnection(netConn, rs.privKey)
+	if err != nil {
+		rs.Logger.Error("Secret Conn", "err", err, "address", remoteAddress)
+		return
+	}
+
+	remoteKey := conn.RemotePubKey()
+	if !rs.isAllowed(remoteKey) {
+		rs.Logger.Error("Rejected node with a key not allowed", "address", remoteAddress, "key", base64.StdEncoding.EncodeToString(remoteKey.Bytes()))
+		return
+	}
+	rs.Logger.Info("Connected", "address", remoteAddress)
+
+	for rs.IsRunning() {
+		req, ext, err := readRequest(conn, rs.protocol)
+		if err != nil {
+			rs.Logger.Error("readMsg", "err", err, "address", remoteAddress)
+			return
+		}
+
+		res, resExt, err := handleRequest(rs.Logger, remoteAddress, rs.chainID, rs.privVal, req, ext)
+		if err != nil {
+			// only log the error; we reply with an error in handleRequest since the reply needs to be typed based on error
+			rs.Logger.Error("handleRequest", "err", err)
+		}
+
+		err = writeResponse(conn, rs.protocol, res, resExt)
+		if err != nil {
+			rs.Logger.Error("writeMsg", "err", err, "address", remoteAddress)
+			return
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package signer
+
+import (
+	"encoding/base64"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	tmCrypto "github.com/tendermint/tendermint/crypto"
+	tmCryptoEd2219 "github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/libs/log"
+	tmP2pConn "github.com/tendermint/tendermint/p2p/conn"
+	tmProtoPrivval "github.com/tendermint/tendermint/proto/tendermint/privval"
+	tmProto "github.com/tendermint/tendermint/proto/tendermint/types"
+	tm "github.com/tendermint/tendermint/types"
+)
+
+// dialListenRemoteSigner connects to the signer as a node holding nodeKey
+func dialListenRemoteSigner(test *testing.T, rs *ListenRemoteSigner, nodeKey tmCryptoEd2219.PrivKey) net.Conn {
+	netConn, err := net.Dial("tcp", rs.Addr().String())
+	require.NoError(test, err)
+	conn, err := tmP2pConn.MakeSecretConnection(netConn, nodeKey)
+	require.NoError(test, err)
+	return conn
+}
+
+func TestListenRemoteSigner(test *testing.T) {
+	nodeKey := tmCryptoEd2219.GenPrivKey()
+	privVal := tm.NewMockPV()
+
+	rs := NewListenRemoteSigner("tcp://127.0.0.1:0", log.NewNopLogger(), "chain-id", privVal, tmCryptoEd2219.GenPrivKey(), []tmCrypto.PubKey{nodeKey.PubKey()}, PrivvalProtocolV034)
+	require.NoError(test, rs.Start())
+	defer rs.Stop()
+
+	conn := dialListenRemoteSigner(test, rs, nodeKey)
+	defer conn.Close()
+
+	require.NoError(test, WriteMsg(conn, tmProtoPrivval.Message{Sum: &tmProtoPrivval.Message_PubKeyRequest{
+		PubKeyRequest: &tmProtoPrivval.PubKeyRequest{ChainId: "chain-id"},
+	}}))
+	res, err := ReadMsg(conn)
+	require.NoError(test, err)
+	pubKeyResponse := res.GetPubKeyResponse()
+	require.NotNil(test, pubKeyResponse)
+	require.Nil(test, pubKeyResponse.Error)
+	require.Equal(test, privVal.PrivKey.PubKey().Bytes(), pubKeyResponse.PubKey.GetEd25519())
+
+	vote := tmProto.Vote{Height: 1, Type: tmProto.PrevoteType}
+	require.NoError(test, WriteMsg(conn, tmProtoPrivval.Message{Sum: &tmProtoPrivval.Message_SignVoteRequest{
+		SignVoteRequest: &tmProtoPrivval.SignVoteRequest{Vote: &vote, ChainId: "chain-id"},
+	}}))
+	res, err = ReadMsg(conn)
+	require.NoError(test, err)
+	voteResponse := res.GetSignedVoteResponse()
+	require.NotNil(test, voteResponse)
+	require.Nil(test, voteResponse.Error)
+	require.True(test, privVal.PrivKey.PubKey().VerifySignature(tm.VoteSignBytes("chain-id", &vote), voteResponse.Vote.Signature))
+
+	// a node with another key is disconnected without an answer
+	otherConn := dialListenRemoteSigner(test, rs, tmCryptoEd2219.GenPrivKey())
+	defer otherConn.Close()
+	require.NoError(test, WriteMsg(otherConn, tmProtoPrivval.Message{Sum: &tmProtoPrivval.Message_SignVoteRequest{
+		SignVoteRequest: &tmProtoPrivval.SignVoteRequest{Vote: &tmProto.Vote{Height: 2, Type: tmProto.PrevoteType}, ChainId: "chain-id"},
+	}}))
+	_, err = ReadMsg(otherConn)
+	require.Error(test, err)
+}
+
+func TestNodeConfigValidate(test *testing.T) {
+	nodeKey := base64.StdEncoding.EncodeToString(tmCryptoEd2219.GenPrivKey().PubKey().Bytes())
+
+	require.NoError(test, NodeConfig{Address: "tcp://node:1234"}.Validate())
+	require.NoError(test, NodeConfig{Address: "tcp://0.0.0.0:1234", Mode: NodeModeListen, AllowedKeys: []string{nodeKey}}.Validate())
+
+	require.NoError(test, NodeConfig{Address: "tcp://node:1234", Protocol: PrivvalProtocolV038}.Validate())
+	require.Error(test, NodeConfig{Address: "tcp://node:1234", Protocol: "v0.39"}.Validate())
+	require.Error(test, NodeConfig{}.Validate())
+	require.Error(test, NodeConfig{Address: "tcp://node:1234", Mode: "accept"}.Validate())
+	require.Error(test, NodeConfig{Address: "tcp://node:1234", AllowedKeys: []string{nodeKey}}.Validate())
+	// listening without an allowlist would serve anyone reaching the address
+	require.Error(test, NodeConfig{Address: "tcp://0.0.0.0:1234", Mode: NodeModeListen}.Validate())
+	require.Error(test, NodeConfig{Address: "tcp://0.0.0.0:1234", Mode: NodeModeListen, AllowedKeys: []string{"not base64"}}.Validate())
+	require.Error(test, NodeConfig{Address: "tcp://0.0.0.0:1234", Mode: NodeModeListen, AllowedKeys: []string{base64.StdEncoding.EncodeToString([]byte("short"))}}.Validate())
+
+	require.NoError(test, NodeConfig{Address: "tcp://node:1234", PubKey: nodeKey}.Validate())
+	require.Error(test, NodeConfig{Address: "tcp://node:1234", PubKey: "not base64"}.Validate())
+	require.Error(test, NodeConfig{Address: "tcp://0.0.0.0:1234", Mode: NodeModeListen, AllowedKeys: []string{nodeKey}, PubKey: nodeKey}.Validate())
+
+	signerKey := tmCryptoEd2219.GenPrivKey()
+	rs, err := NewNodeRemoteSigner(NodeConfig{Address: "tcp://127.0.0.1:0", Mode: NodeModeListen, AllowedKeys: []string{nodeKey}}, signerKey, log.NewNopLogger(), "chain-id", tm.NewMockPV())
+	require.NoError(test, err)
+	require.IsType(test, &ListenRemoteSigner{}, rs)
+	rs, err = NewNodeRemoteSigner(NodeConfig{Address: "tcp://127.0.0.1:1234", PubKey: nodeKey}, signerKey, log.NewNopLogger(), "chain-id", tm.NewMockPV())
+	require.NoError(test, err)
+	require.IsType(test, &ReconnRemoteSigner{}, rs)
+}
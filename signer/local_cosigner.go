@@ -2,7 +2,9 @@ package signer
 
 import (
 	"bytes"
+	"context"
 	"crypto"
+	"crypto/ed25519"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha256"
@@ -14,6 +16,8 @@ import (
 	tmCryptoEd25519 "github.com/tendermint/tendermint/crypto/ed25519"
 	tmJson "github.com/tendermint/tendermint/libs/json"
 	tmlog "github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/libs/protoio"
+	tmProto "github.com/tendermint/tendermint/proto/tendermint/types"
 	"gitlab.com/polychainlabs/edwards25519"
 	tsed25519 "gitlab.com/polychainlabs/threshold-ed25519/pkg"
 )
@@ -60,18 +64,92 @@ func (hrsKey *HRSKey) Less(other HRSKey) bool {
 	return false
 }
 
+const (
+	// the source signature covers the source ID, the ephemeral public key and the encrypted share part
+	CosignerProtocolVersionLegacy = 1
+	// the source signature also covers the HRS, the destination ID and the chain ID
+	CosignerProtocolVersionHRS = 2
+
+	// highest protocol version supported by this cosigner
+	CosignerProtocolVersion = CosignerProtocolVersionHRS
+)
+
+// ephemeralSecretPartPayload is signed by the source of an ephemeral secret part
+// from protocol version CosignerProtocolVersionHRS on
+type ephemeralSecretPartPayload struct {
+	ProtocolVersion                int32
+	ChainID                        string
+	SourceID                       int32
+	DestinationID                  int32
+	Height                         int64
+	Round                          int64
+	Step                           int32
+	SourceEphemeralSecretPublicKey []byte
+	EncryptedSharePart             []byte
+}
+
+// ephemeralSecretPartDigest returns the digest of an ephemeral secret part signed by its source
+func ephemeralSecretPartDigest(payload ephemeralSecretPartPayload) ([]byte, error) {
+	var jsonBytes []byte
+	var err error
+
+	switch payload.ProtocolVersion {
+	case CosignerProtocolVersionLegacy:
+		jsonBytes, err = tmJson.Marshal(&CosignerGetEphemeralSecretPartResponse{
+			SourceID:                       payload.SourceID,
+			SourceEphemeralSecretPublicKey: payload.SourceEphemeralSecretPublicKey,
+			EncryptedSharePart:             payload.EncryptedSharePart,
+		})
+	case CosignerProtocolVersionHRS:
+		jsonBytes, err = tmJson.Marshal(payload)
+	default:
+		return nil, fmt.Errorf("Unsupported protocol version: %d", payload.ProtocolVersion)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	digest := sha256.Sum256(jsonBytes)
+	return digest[:], nil
+}
+
 type CosignerPeer struct {
-	ID        int
-	PublicKey rsa.PublicKey
+	ID int
+
+	// only one of the keys is set, depending on the transport of the cluster
+	PublicKey       rsa.PublicKey
+	TransportPubKey *CosignerTransportPubKey
 }
 
 type LocalCosignerConfig struct {
 	CosignerKey CosignerKey
 	SignState   *SignState
-	RsaKey      rsa.PrivateKey
-	Peers       []CosignerPeer
-	Total       uint8
-	Threshold   uint8
+
+	// legacy RSA transport key, unused when the cosigner key has a transport key
+	RsaKey    rsa.PrivateKey
+	Peers     []CosignerPeer
+	Total     uint8
+	Threshold uint8
+
+	// ChainID is bound into the signature of the ephemeral secret parts
+	ChainID string
+
+	// Ephemeral secret parts signed with a lower protocol version are rejected
+	// Defaults to CosignerProtocolVersionLegacy so that older peers can be upgraded one at a time.
+	// A peer that sent a part of a higher version is refused lower ones anyway, and every legacy
+	// part accepted is logged. Raise it once all the cosigners are upgraded.
+	MinProtocolVersion int
+
+	// Transport decrypts and signs the share parts, defaults to the transport key of CosignerKey
+	Transport CosignerTransport
+
+	// KeyBackend saves the key when the share is refreshed
+	// Defaults to KeyFile if it is set, the key is only kept in memory otherwise.
+	KeyBackend KeyBackend
+	KeyFile    string
+
+	// Optional, records the share signatures
+	AuditLog *AuditLog
 }
 
 type PeerMetadata struct {
@@ -97,6 +175,16 @@ type LocalCosigner struct {
 	total       uint8
 	threshold   uint8
 
+	chainID            string
+	minProtocolVersion int32
+
+	transport CosignerTransport
+	backend   KeyBackend
+	auditLog  *AuditLog
+
+	// share refresh in progress, protected by lastSignStateMutex
+	refresh *shareRefresh
+
 	// stores the last sign state for a share we have fully signed
 	// incremented whenever we are asked to sign a share
 	lastSignState *SignState
@@ -106,18 +194,43 @@ type LocalCosigner struct {
 
 	// Height, Round, Step -> metadata
 	hrsMeta map[HRSKey]HrsMetadata
+
+	// highest protocol version of the ephemeral secret parts received from each peer, by ID
+	// a peer is refused a downgrade below it
+	peerProtocolVersions map[int]int32
+
+	// persists hrsMeta across restarts, only kept in memory if nil
+	journal *nonceJournal
 	peers   map[int]CosignerPeer
 }
 
 func NewLocalCosigner(cfg LocalCosignerConfig) *LocalCosigner {
 	cosigner := &LocalCosigner{
-		key:           cfg.CosignerKey,
-		lastSignState: cfg.SignState,
-		rsaKey:        cfg.RsaKey,
-		hrsMeta:       make(map[HRSKey]HrsMetadata),
-		peers:         make(map[int]CosignerPeer),
-		total:         cfg.Total,
-		threshold:     cfg.Threshold,
+		key:                  cfg.CosignerKey,
+		lastSignState:        cfg.SignState,
+		rsaKey:               cfg.RsaKey,
+		hrsMeta:              make(map[HRSKey]HrsMetadata),
+		peerProtocolVersions: make(map[int]int32),
+		peers:                make(map[int]CosignerPeer),
+		total:                cfg.Total,
+		threshold:            cfg.Threshold,
+
+		chainID:            cfg.ChainID,
+		minProtocolVersion: int32(cfg.MinProtocolVersion),
+		transport:          cfg.Transport,
+		backend:            cfg.KeyBackend,
+		auditLog:           cfg.AuditLog,
+	}
+
+	if cosigner.transport == nil && cosigner.key.TransportKey != nil {
+		cosigner.transport = cosigner.key.TransportKey
+	}
+	if cosigner.backend == nil && cfg.KeyFile != "" {
+		cosigner.backend = NewFileKeyBackend(cfg.KeyFile, KeyPassphrase(""))
+	}
+
+	if cosigner.minProtocolVersion < CosignerProtocolVersionLegacy {
+		cosigner.minProtocolVersion = CosignerProtocolVersionLegacy
 	}
 
 	for _, peer := range cfg.Peers {
@@ -146,18 +259,27 @@ func (cosigner *LocalCosigner) GetID() int {
 // Sign the sign request using the cosigner's share
 // Return the signed bytes or an error
 // Implements Cosigner interface
-func (cosigner *LocalCosigner) Sign(req *CosignerSignRequest) (*CosignerSignResponse, error) {
+func (cosigner *LocalCosigner) Sign(ctx context.Context, req *CosignerSignRequest) (*CosignerSignResponse, error) {
 	cosigner.lastSignStateMutex.Lock()
 	defer cosigner.lastSignStateMutex.Unlock()
 
 	res := &CosignerSignResponse{}
 	lss := cosigner.lastSignState
 
+	// don't move the watermark for a requester that gave up on us
+	if err := ctx.Err(); err != nil {
+		return res, err
+	}
+
 	height, round, step, err := UnpackHRS(req.SignBytes)
 	if err != nil {
 		return res, err
 	}
 
+	if step == stepVoteExtension {
+		return cosigner.signVoteExtension(ctx, req.SignBytes, height, round)
+	}
+
 	sameHRS, err := lss.CheckHRS(height, round, step)
 	if err != nil {
 		return res, err
@@ -182,19 +304,119 @@ func (cosigner *LocalCosigner) Sign(req *CosignerSignRequest) (*CosignerSignResp
 		Round:  round,
 		Step:   step,
 	}
+	res, err = cosigner.signShare(ctx, hrsKey, req.SignBytes)
+	if err != nil {
+		return res, err
+	}
+
+	// the share signature is only handed out once the watermark is persisted
+	// a new HRS has no vote extension yet
+	newLss := *lss
+	if newLss.hrsKey() != hrsKey {
+		newLss.ExtensionEphemeralPublic = nil
+		newLss.ExtensionSignature = nil
+		newLss.ExtensionSignBytes = nil
+	}
+	newLss.Height = height
+	newLss.Round = round
+	newLss.Step = step
+	newLss.EphemeralPublic = res.EphemeralPublic
+	newLss.Signature = res.Signature
+	newLss.SignBytes = req.SignBytes
+	if err := newLss.Save(); err != nil {
+		return &CosignerSignResponse{}, err
+	}
+	*cosigner.lastSignState = newLss
+
+	return res, nil
+}
+
+// signVoteExtension signs the vote extension of the precommit at the height and round with our share
+// The extension is signed under the watermark of its precommit and kept next to it in the sign state,
+// so the precommit can still be requested again. It has its own ephemeral secrets.
+// The precommit for a block of the same chain must be the last share we signed, the watermark never
+// moves for an extension.
+// cosigner.lastSignStateMutex must be held.
+func (cosigner *LocalCosigner) signVoteExtension(ctx context.Context, signBytes []byte, height int64, round int64) (*CosignerSignResponse, error) {
+	res := &CosignerSignResponse{}
+	lss := cosigner.lastSignState
+
+	sameExtension, err := lss.CheckVoteExtensionHRS(height, round, signBytes)
+	if err != nil {
+		return res, err
+	}
+	if sameExtension {
+		res.EphemeralPublic = lss.ExtensionEphemeralPublic
+		res.Signature = lss.ExtensionSignature
+		return res, nil
+	}
+	if err := checkExtensionPrecommit(lss, height, round, signBytes); err != nil {
+		return res, err
+	}
+
+	res, err = cosigner.signShare(ctx, HRSKey{Height: height, Round: round, Step: stepVoteExtension}, signBytes)
+	if err != nil {
+		return res, err
+	}
+
+	newLss := *lss
+	newLss.ExtensionEphemeralPublic = res.EphemeralPublic
+	newLss.ExtensionSignature = res.Signature
+	newLss.ExtensionSignBytes = signBytes
+	if err := newLss.Save(); err != nil {
+		return &CosignerSignResponse{}, err
+	}
+	*cosigner.lastSignState = newLss
+
+	return res, nil
+}
+
+// checkExtensionPrecommit returns an error unless the last share signed is the precommit for a block
+// that the vote extension at the height and round belongs to
+func checkExtensionPrecommit(lss *SignState, height int64, round int64, extensionSignBytes []byte) error {
+	precommitKey := HRSKey{Height: height, Round: round, Step: stepPrecommit}
+	if lss.hrsKey() != precommitKey || len(lss.SignBytes) == 0 {
+		return fmt.Errorf("the precommit of the vote extension at height %v round %v was not signed", height, round)
+	}
+
+	var precommit tmProto.CanonicalVote
+	if err := protoio.UnmarshalDelimited(lss.SignBytes, &precommit); err != nil {
+		return err
+	}
+	if precommit.BlockID == nil || len(precommit.BlockID.Hash) == 0 {
+		return errors.New("vote extensions are only signed for a precommit for a block")
+	}
+	chainID, err := UnpackChainID(extensionSignBytes)
+	if err != nil {
+		return err
+	}
+	if chainID != precommit.ChainID {
+		return fmt.Errorf("the vote extension is for chain %s, the precommit for chain %s", chainID, precommit.ChainID)
+	}
+	return nil
+}
+
+// signShare signs the sign bytes with our share and the ephemeral secrets exchanged for the HRS
+// The share signature is recorded in the audit log, the caller persists the watermark.
+// cosigner.lastSignStateMutex must be held.
+func (cosigner *LocalCosigner) signShare(ctx context.Context, hrsKey HRSKey, signBytes []byte) (*CosignerSignResponse, error) {
+	res := &CosignerSignResponse{}
+	height, round, step := hrsKey.Height, hrsKey.Round, hrsKey.Step
+
 	meta, ok := cosigner.hrsMeta[hrsKey]
 	if !ok {
 		return res, errors.New("No metadata at HRS")
 	}
-
 	shareParts := make([]tsed25519.Scalar, 0)
 	publicKeys := make([]tsed25519.Element, 0)
+	partIDs := make([]int, 0)
 
 	// calculate secret and public keys
-	for _, peer := range meta.Peers {
+	for idx, peer := range meta.Peers {
 		if len(peer.Share) == 0 {
 			continue
 		}
+		partIDs = append(partIDs, idx+1)
 		shareParts = append(shareParts, peer.Share)
 		publicKeys = append(publicKeys, peer.EphemeralSecretPublicKey)
 	}
@@ -215,33 +437,45 @@ func (cosigner *LocalCosigner) Sign(req *CosignerSignRequest) (*CosignerSignResp
 		}
 	}
 
-	share := cosigner.key.ShareKey[:]
-	sig := tsed25519.SignWithShare(req.SignBytes, share, ephemeralShare, cosigner.pubKeyBytes, ephemeralPublic)
+	// switch to the refreshed share once its activation height is reached
+	cosigner.activateNextShare(height)
 
-	cosigner.lastSignState.Height = height
-	cosigner.lastSignState.Round = round
-	cosigner.lastSignState.Step = step
-	cosigner.lastSignState.EphemeralPublic = ephemeralPublic
-	cosigner.lastSignState.Signature = sig
-	cosigner.lastSignState.SignBytes = req.SignBytes
-	cosigner.lastSignState.Save()
+	share := cosigner.key.ShareKey[:]
+	sig := tsed25519.SignWithShare(signBytes, share, ephemeralShare, cosigner.pubKeyBytes, ephemeralPublic)
 
+	// the nonce of the HRS is forgotten before its share signature is recorded anywhere:
+	// with both, the share can be computed. It is never used again, a second message signed
+	// with it would give the share away too.
 	for existingKey := range cosigner.hrsMeta {
-		// delete any HRS lower than our signed level
-		// we will not be providing parts for any lower HRS
-		if existingKey.Less(hrsKey) {
+		// we will not be providing parts for any lower HRS either
+		if !hrsKey.Less(existingKey) {
 			delete(cosigner.hrsMeta, existingKey)
 		}
 	}
+	if cosigner.journal != nil {
+		if err := cosigner.journal.compact(cosigner.hrsMeta, cosigner.key.ID); err != nil {
+			return res, err
+		}
+	}
+
+	// the share signature is recorded before anything else, so it can't leave without a record
+	if cosigner.auditLog != nil {
+		record := newAuditRecord(ctx, AuditSignerCosigner, cosigner.key.ID, height, round, step, signBytes, sig, partIDs)
+		record.ChainID = cosigner.chainID
+		if err := cosigner.auditLog.Append(record); err != nil {
+			return res, err
+		}
+	}
 
 	res.EphemeralPublic = ephemeralPublic
+	res.EphemeralSharePublic = tsed25519.ScalarMultiplyBase(ephemeralShare)
 	res.Signature = sig
 	return res, nil
 }
 
 // Get the ephemeral secret part for an ephemeral share
 // The ephemeral secret part is encrypted for the receiver
-func (cosigner *LocalCosigner) GetEphemeralSecretPart(req *CosignerGetEphemeralSecretPartRequest) (*CosignerGetEphemeralSecretPartResponse, error) {
+func (cosigner *LocalCosigner) GetEphemeralSecretPart(ctx context.Context, req *CosignerGetEphemeralSecretPartRequest) (*CosignerGetEphemeralSecretPartResponse, error) {
 	res := &CosignerGetEphemeralSecretPartResponse{}
 
 	// protects the meta map
@@ -257,23 +491,19 @@ func (cosigner *LocalCosigner) GetEphemeralSecretPart(req *CosignerGetEphemeralS
 	meta, ok := cosigner.hrsMeta[hrsKey]
 	// generate metadata placeholder
 	if !ok {
-		secret := make([]byte, 32)
-		rand.Read(secret)
-
-		meta = HrsMetadata{
-			Secret: secret,
-			Peers:  make([]PeerMetadata, cosigner.total),
-		}
-
-		// split this secret with shamirs
-		// !! dealt shares need to be saved because dealing produces different shares each time!
-		meta.DealtShares = tsed25519.DealShares(meta.Secret, cosigner.threshold, cosigner.total)
-
+		meta = newHrsMetadata(cosigner.threshold, cosigner.total)
 		cosigner.hrsMeta[hrsKey] = meta
 	}
 
 	ourEphPublicKey := tsed25519.ScalarMultiplyBase(meta.Secret)
 
+	// our secret must survive a restart once a part of it is handed out
+	if cosigner.journal != nil && len(meta.Peers[cosigner.key.ID-1].Share) == 0 {
+		if err := cosigner.journal.appendDeal(hrsKey, meta); err != nil {
+			return res, err
+		}
+	}
+
 	// set our values
 	meta.Peers[cosigner.key.ID-1].Share = meta.DealtShares[cosigner.key.ID-1]
 	meta.Peers[cosigner.key.ID-1].EphemeralSecretPublicKey = ourEphPublicKey
@@ -286,27 +516,47 @@ func (cosigner *LocalCosigner) GetEphemeralSecretPart(req *CosignerGetEphemeralS
 
 	sharePart := meta.DealtShares[req.ID-1]
 
-	// use RSA public to encrypt user's share part
-	encrypted, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, &peer.PublicKey, sharePart, nil)
+	encrypted, err := cosigner.encryptSharePart(peer, sharePart)
 	if err != nil {
 		return res, err
 	}
 
+	// answer with the highest version supported by both sides
+	// requesters that don't send a version only know the legacy payload
+	version := req.ProtocolVersion
+	if version > CosignerProtocolVersion {
+		version = CosignerProtocolVersion
+	}
+	if version < CosignerProtocolVersionLegacy {
+		version = CosignerProtocolVersionLegacy
+	}
+
 	res.SourceID = int32(cosigner.key.ID)
 	res.SourceEphemeralSecretPublicKey = ourEphPublicKey
 	res.EncryptedSharePart = encrypted
+	if version > CosignerProtocolVersionLegacy {
+		res.ProtocolVersion = version
+	}
 
 	// sign the response payload with our private key
 	// cosigners can verify the signature to confirm sender validity
 	{
-		jsonBytes, err := tmJson.Marshal(res)
-
+		digest, err := ephemeralSecretPartDigest(ephemeralSecretPartPayload{
+			ProtocolVersion:                version,
+			ChainID:                        cosigner.chainID,
+			SourceID:                       res.SourceID,
+			DestinationID:                  req.ID,
+			Height:                         req.Height,
+			Round:                          req.Round,
+			Step:                           req.Step,
+			SourceEphemeralSecretPublicKey: res.SourceEphemeralSecretPublicKey,
+			EncryptedSharePart:             res.EncryptedSharePart,
+		})
 		if err != nil {
 			return res, err
 		}
 
-		digest := sha256.Sum256(jsonBytes)
-		signature, err := rsa.SignPSS(rand.Reader, &cosigner.rsaKey, crypto.SHA256, digest[:], nil)
+		signature, err := cosigner.signDigest(digest)
 		if err != nil {
 			return res, err
 		}
@@ -317,11 +567,15 @@ func (cosigner *LocalCosigner) GetEphemeralSecretPart(req *CosignerGetEphemeralS
 	return res, nil
 }
 
-func (cosigner *LocalCosigner) HasEphemeralSecretPart(req CosignerHasEphemeralSecretPartRequest) (CosignerHasEphemeralSecretPartResponse, error) {
-	res := CosignerHasEphemeralSecretPartResponse{
+func (cosigner *LocalCosigner) HasEphemeralSecretPart(ctx context.Context, req *CosignerHasEphemeralSecretPartRequest) (*CosignerHasEphemeralSecretPartResponse, error) {
+	res := &CosignerHasEphemeralSecretPartResponse{
 		Exists: false,
 	}
 
+	if req.ID < 1 || int(req.ID) > int(cosigner.total) {
+		return res, errors.New("Unknown peer ID")
+	}
+
 	// protects the meta map
 	cosigner.lastSignStateMutex.Lock()
 	defer cosigner.lastSignStateMutex.Unlock()
@@ -329,7 +583,7 @@ func (cosigner *LocalCosigner) HasEphemeralSecretPart(req CosignerHasEphemeralSe
 	hrsKey := HRSKey{
 		Height: req.Height,
 		Round:  req.Round,
-		Step:   req.Step,
+		Step:   int8(req.Step),
 	}
 
 	meta, ok := cosigner.hrsMeta[hrsKey]
@@ -344,32 +598,45 @@ func (cosigner *LocalCosigner) HasEphemeralSecretPart(req CosignerHasEphemeralSe
 }
 
 // Store an ephemeral secret share part provided by another cosigner
-func (cosigner *LocalCosigner) SetEphemeralSecretPart(req CosignerSetEphemeralSecretPartRequest) error {
+func (cosigner *LocalCosigner) SetEphemeralSecretPart(ctx context.Context, req *CosignerSetEphemeralSecretPartRequest) error {
+	version := req.ProtocolVersion
+	if version < CosignerProtocolVersionLegacy {
+		version = CosignerProtocolVersionLegacy
+	}
+
 	// Verify the source signature
 	{
 		if req.SourceSig == nil {
 			return errors.New("SourceSig field is required")
 		}
 
-		digestMsg := &CosignerGetEphemeralSecretPartResponse{}
-		digestMsg.SourceID = int32(req.SourceID)
-		digestMsg.SourceEphemeralSecretPublicKey = req.SourceEphemeralSecretPublicKey
-		digestMsg.EncryptedSharePart = req.EncryptedSharePart
+		if version < cosigner.minProtocolVersion {
+			return fmt.Errorf("Protocol version %d of cosigner %d is below the minimum %d", version, req.SourceID, cosigner.minProtocolVersion)
+		}
 
-		digestBytes, err := tmJson.Marshal(digestMsg)
+		// the part must have been dealt for us, on our chain and for this HRS
+		digest, err := ephemeralSecretPartDigest(ephemeralSecretPartPayload{
+			ProtocolVersion:                version,
+			ChainID:                        cosigner.chainID,
+			SourceID:                       req.SourceID,
+			DestinationID:                  int32(cosigner.key.ID),
+			Height:                         req.Height,
+			Round:                          req.Round,
+			Step:                           req.Step,
+			SourceEphemeralSecretPublicKey: req.SourceEphemeralSecretPublicKey,
+			EncryptedSharePart:             req.EncryptedSharePart,
+		})
 		if err != nil {
 			return err
 		}
 
-		digest := sha256.Sum256(digestBytes)
-		peer, ok := cosigner.peers[req.SourceID]
+		peer, ok := cosigner.peers[int(req.SourceID)]
 
 		if !ok {
 			return fmt.Errorf("Unknown cosigner: %d", req.SourceID)
 		}
 
-		peerPub := peer.PublicKey
-		err = rsa.VerifyPSS(&peerPub, crypto.SHA256, digest[:], req.SourceSig, nil)
+		err = cosigner.verifyDigest(peer, digest, req.SourceSig)
 		if err != nil {
 			return err
 		}
@@ -379,37 +646,155 @@ func (cosigner *LocalCosigner) SetEphemeralSecretPart(req CosignerSetEphemeralSe
 	cosigner.lastSignStateMutex.Lock()
 	defer cosigner.lastSignStateMutex.Unlock()
 
+	// once a peer sent a part bound to the HRS, a legacy part labelled as coming from it is a replay
+	sourceID := int(req.SourceID)
+	if highest := cosigner.peerProtocolVersions[sourceID]; version < highest {
+		return fmt.Errorf("Protocol version %d of cosigner %d is a downgrade from version %d", version, req.SourceID, highest)
+	}
+	cosigner.peerProtocolVersions[sourceID] = version
+	if version == CosignerProtocolVersionLegacy {
+		logger.Info("WARNING accepted a legacy ephemeral secret part, not bound to the HRS, chain or destination; "+
+			"raise min_protocol_version to 2 once all cosigners are upgraded", "cosigner", req.SourceID)
+	}
+
 	hrsKey := HRSKey{
 		Height: req.Height,
 		Round:  req.Round,
-		Step:   req.Step,
+		Step:   int8(req.Step),
 	}
 
 	meta, ok := cosigner.hrsMeta[hrsKey]
 	// generate metadata placeholder
 	if !ok {
-		secret := make([]byte, 32)
-		rand.Read(secret)
+		meta = newHrsMetadata(cosigner.threshold, cosigner.total)
+		cosigner.hrsMeta[hrsKey] = meta
+	}
 
-		meta = HrsMetadata{
-			Secret: secret,
-			Peers:  make([]PeerMetadata, cosigner.total),
+	// decrypt share
+	sharePart, err := cosigner.decryptSharePart(req.EncryptedSharePart)
+	if err != nil {
+		return err
+	}
+
+	peerMeta := PeerMetadata{
+		Share:                    sharePart,
+		EphemeralSecretPublicKey: req.SourceEphemeralSecretPublicKey,
+	}
+
+	// the part is only acknowledged once it would survive a restart
+	if cosigner.journal != nil {
+		if err := cosigner.journal.appendPart(hrsKey, int(req.SourceID), peerMeta); err != nil {
+			return err
 		}
+	}
 
-		meta.DealtShares = tsed25519.DealShares(meta.Secret, cosigner.threshold, cosigner.total)
+	// set slot
+	meta.Peers[req.SourceID-1] = peerMeta
 
-		cosigner.hrsMeta[hrsKey] = meta
+	return nil
+}
+
+// newHrsMetadata deals a fresh ephemeral secret to the cosigners
+func newHrsMetadata(threshold uint8, total uint8) HrsMetadata {
+	secret := make([]byte, 32)
+	rand.Read(secret)
+
+	meta := HrsMetadata{
+		Secret: secret,
+		Peers:  make([]PeerMetadata, total),
 	}
 
-	// decrypt share
-	sharePart, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, &cosigner.rsaKey, req.EncryptedSharePart, nil)
+	// split this secret with shamirs
+	// !! dealt shares need to be saved because dealing produces different shares each time!
+	meta.DealtShares = tsed25519.DealShares(meta.Secret, threshold, total)
+	return meta
+}
+
+// OpenNonceJournal restores the ephemeral secrets and parts journaled before a restart
+// and journals the new ones to the file from now on.
+// The journal is sealed to our transport key, a key with legacy RSA transport keys can't have one.
+func (cosigner *LocalCosigner) OpenNonceJournal(file string) error {
+	cosigner.lastSignStateMutex.Lock()
+	defer cosigner.lastSignStateMutex.Unlock()
+
+	if cosigner.transport == nil {
+		return errors.New("The nonce journal is sealed to the transport key, RSA transport keys must be migrated first")
+	}
+
+	journal, hrsMeta, err := openNonceJournal(file, cosigner.key.ID, cosigner.threshold, cosigner.total, cosigner.transport)
 	if err != nil {
 		return err
 	}
 
-	// set slot
-	meta.Peers[req.SourceID-1].Share = sharePart
-	meta.Peers[req.SourceID-1].EphemeralSecretPublicKey = req.SourceEphemeralSecretPublicKey
+	// nothing up to the last signed HRS is signed again, nor the vote extension signed under it
+	lss := cosigner.lastSignState
+	signedKey := HRSKey{
+		Height: lss.Height,
+		Round:  lss.Round,
+		Step:   lss.Step,
+	}
+	if len(lss.ExtensionSignBytes) > 0 {
+		signedKey.Step = stepVoteExtension
+	}
+	for existingKey := range hrsMeta {
+		if !signedKey.Less(existingKey) {
+			delete(hrsMeta, existingKey)
+		}
+	}
+
+	if err := journal.compact(hrsMeta, cosigner.key.ID); err != nil {
+		return err
+	}
+
+	cosigner.journal = journal
+	cosigner.hrsMeta = hrsMeta
+	return nil
+}
+
+// encryptSharePart encrypts a share part for the peer
+// With a legacy key the part is encrypted with RSA-OAEP, otherwise to the X25519 key of the peer.
+func (cosigner *LocalCosigner) encryptSharePart(peer CosignerPeer, sharePart []byte) ([]byte, error) {
+	if cosigner.key.IsLegacy() {
+		return rsa.EncryptOAEP(sha256.New(), rand.Reader, &peer.PublicKey, sharePart, nil)
+	}
+	if peer.TransportPubKey == nil {
+		return nil, fmt.Errorf("No transport key for cosigner %d", peer.ID)
+	}
+	return peer.TransportPubKey.Seal(sharePart)
+}
+
+// decryptSharePart decrypts a share part encrypted for us
+func (cosigner *LocalCosigner) decryptSharePart(encrypted []byte) ([]byte, error) {
+	if cosigner.key.IsLegacy() {
+		return rsa.DecryptOAEP(sha256.New(), rand.Reader, &cosigner.rsaKey, encrypted, nil)
+	}
+	if cosigner.transport == nil {
+		return nil, errors.New("No transport key")
+	}
+	return cosigner.transport.Open(encrypted)
+}
+
+// signDigest signs the digest of a share part so the receiver can authenticate us
+func (cosigner *LocalCosigner) signDigest(digest []byte) ([]byte, error) {
+	if cosigner.key.IsLegacy() {
+		return rsa.SignPSS(rand.Reader, &cosigner.rsaKey, crypto.SHA256, digest, nil)
+	}
+	if cosigner.transport == nil {
+		return nil, errors.New("No transport key")
+	}
+	return cosigner.transport.Sign(digest)
+}
 
+// verifyDigest verifies the signature of a share part sent by the peer
+func (cosigner *LocalCosigner) verifyDigest(peer CosignerPeer, digest []byte, signature []byte) error {
+	if cosigner.key.IsLegacy() {
+		return rsa.VerifyPSS(&peer.PublicKey, crypto.SHA256, digest, signature, nil)
+	}
+	if peer.TransportPubKey == nil {
+		return fmt.Errorf("No transport key for cosigner %d", peer.ID)
+	}
+	if !ed25519.Verify(peer.TransportPubKey.SigningKey, digest, signature) {
+		return errors.New("Invalid signature")
+	}
 	return nil
 }
@@ -2,21 +2,56 @@ package signer
 
 import (
 	"context"
+	"crypto/tls"
+	"fmt"
 	"net"
 	"sync"
-	"time"
 
 	"github.com/tendermint/tendermint/libs/log"
 	tmnet "github.com/tendermint/tendermint/libs/net"
 	"github.com/tendermint/tendermint/libs/service"
 	grpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 )
 
+// CosignerRpcChain is the validator of a chain served by the rpc server
+type CosignerRpcChain struct {
+	ChainID       string
+	LocalCosigner Cosigner
+	Peers         []*RemoteCosigner
+
+	// Optional, pre-deals ephemeral secret parts for the heights after the signed one
+	PreDealer *EphemeralPreDealer
+
+	// Optional, signs the blocks the followers forward for their nodes while we are the leader
+	Validator BlockSigner
+}
+
 type CosignerRpcServerConfig struct {
 	Logger        log.Logger
 	ListenAddress string
+
+	// Validators served by the rpc server, routed by the chain ID of the requests
+	// A single validator can be set with LocalCosigner, Peers and PreDealer instead.
+	Chains []CosignerRpcChain
+
 	LocalCosigner Cosigner
-	Peers         []RemoteCosigner
+	Peers         []*RemoteCosigner
+
+	// When set, the server requires mutual TLS and only accepts clients whose
+	// certificate matches one of PeerNames (cosigner ID -> certificate name)
+	TLSConfig *tls.Config
+	PeerNames map[int]string
+
+	// Timeout and retry policy for the ephemeral secret part requests to the peers
+	Signing SigningConfig
+
+	// Optional, pre-deals ephemeral secret parts for the heights after the signed one
+	PreDealer *EphemeralPreDealer
 }
 
 // CosignerRpcServer responds to rpc sign requests using a cosigner instance
@@ -26,17 +61,33 @@ type CosignerRpcServer struct {
 	logger        log.Logger
 	listenAddress string
 	listener      net.Listener
-	localCosigner Cosigner
-	peers         []RemoteCosigner
+	chains        map[string]*CosignerRpcChain
+	tlsConfig     *tls.Config
+	peerNames     map[int]string
+	signing       SigningConfig
 }
 
 // NewCosignerRpcServer instantiates a local cosigner with the specified key and sign state
 func NewCosignerRpcServer(config *CosignerRpcServerConfig) *CosignerRpcServer {
 	cosignerRpcServer := &CosignerRpcServer{
-		localCosigner: config.LocalCosigner,
 		listenAddress: config.ListenAddress,
-		peers:         config.Peers,
+		chains:        make(map[string]*CosignerRpcChain),
 		logger:        config.Logger,
+		tlsConfig:     config.TLSConfig,
+		peerNames:     config.PeerNames,
+		signing:       config.Signing.withDefaults(),
+	}
+
+	chains := config.Chains
+	if config.LocalCosigner != nil {
+		chains = append(chains, CosignerRpcChain{
+			LocalCosigner: config.LocalCosigner,
+			Peers:         config.Peers,
+			PreDealer:     config.PreDealer,
+		})
+	}
+	for idx := range chains {
+		cosignerRpcServer.chains[chains[idx].ChainID] = &chains[idx]
 	}
 
 	cosignerRpcServer.BaseService = *service.NewBaseService(config.Logger, "CosignerRpcServer", cosignerRpcServer)
@@ -53,7 +104,21 @@ func (rpcServer *CosignerRpcServer) OnStart() error {
 	}
 	rpcServer.listener = lis
 
-	grpcServer := grpc.NewServer()
+	// peers keep their connection open with keepalive pings, allow them
+	opts := []grpc.ServerOption{
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             keepaliveTime / 2,
+			PermitWithoutStream: true,
+		}),
+	}
+	if rpcServer.tlsConfig != nil {
+		opts = append(opts,
+			grpc.Creds(credentials.NewTLS(rpcServer.tlsConfig)),
+			grpc.UnaryInterceptor(cosignerAuthInterceptor(rpcServer.peerNames)),
+		)
+	}
+
+	grpcServer := grpc.NewServer(opts...)
 
 	RegisterCosignerServiceServer(grpcServer, rpcServer)
 
@@ -79,91 +144,68 @@ func (rpcServer *CosignerRpcServer) Addr() net.Addr {
 	return rpcServer.listener.Addr()
 }
 
+// chain returns the validator of the chain a request is for
+// Requests without a chain ID, from peers that predate multi-chain cosigners, go to the only validator.
+func (rpcServer *CosignerRpcServer) chain(chainID string) (*CosignerRpcChain, error) {
+	if chain, ok := rpcServer.chains[chainID]; ok {
+		return chain, nil
+	}
+	// a request without chain ID goes to the only chain, as does any request to a server whose
+	// only chain was configured without chain ID
+	if len(rpcServer.chains) == 1 {
+		for id, chain := range rpcServer.chains {
+			if chainID != "" && id != "" {
+				break
+			}
+			return chain, nil
+		}
+	}
+	return nil, status.Errorf(codes.NotFound, "no validator for chain %q", chainID)
+}
+
+// Sign collects the ephemeral secret parts of our peers and signs the request with our share
+// The deadline of the incoming request bounds all the work, including the requests to our peers
 func (rpcServer *CosignerRpcServer) Sign(ctx context.Context, req *CosignerSignRequest) (*CosignerSignResponse, error) {
 	//rpcServer.logger.Info("rpcSignRequest", "from=")
 
 	response := &CosignerSignResponse{}
 
+	chain, err := rpcServer.chain(req.ChainID)
+	if err != nil {
+		return response, err
+	}
+
+	// the share signature is recorded with the cosigner that asked for it
+	ctx = ContextWithSignSource(ctx, rpcSignSource(ctx))
+
 	height, round, step, err := UnpackHRS(req.SignBytes)
 	if err != nil {
 		return response, err
 	}
 
+	// get the parts for the next heights ready while we sign this one
+	chain.PreDealer.Advance(height)
+
 	wg := sync.WaitGroup{}
-	wg.Add(len(rpcServer.peers))
+	wg.Add(len(chain.Peers))
+
+	// a timed out attempt leaves room for the retries, the deadline of the caller still applies
+	// the requests are cancelled when the budget runs out or the caller goes away
+	partsBudget := rpcServer.signing.retryBudget(rpcServer.signing.EphemeralTimeout.Duration)
+	partsCtx, partsCtxCancel := context.WithTimeout(ctx, partsBudget)
+	defer partsCtxCancel()
 
 	// ping peers for our ephemeral share part
-	for _, peer := range rpcServer.peers {
-		request := func(peer RemoteCosigner) {
-
-			// need to do these requests in parallel..!!
-
-			// RPC requests are blocking
-			// to prevent it from hanging our process indefinitely, we use a timeout context and a goroutine
-			partReqCtx, partReqCtxCancel := context.WithTimeout(context.Background(), time.Second)
-
-			go func() {
-				partRequest := CosignerGetEphemeralSecretPartRequest{
-					ID:     int32(rpcServer.localCosigner.GetID()),
-					Height: height,
-					Round:  round,
-					Step:   int32(step),
-				}
-
-				// if we already have an ephemeral secret part for this HRS, we don't need to re-query for it
-				hasResp, err := rpcServer.localCosigner.HasEphemeralSecretPart(CosignerHasEphemeralSecretPartRequest{
-					ID:     peer.GetID(),
-					Height: height,
-					Round:  round,
-					Step:   step,
-				})
-
-				if err != nil {
-					rpcServer.logger.Error("HasEphemeralSecretPart req error", "error", err)
-					return
-				}
-
-				if hasResp.Exists {
-					partReqCtxCancel()
-					return
-				}
-
-				partResponse, err := peer.GetEphemeralSecretPart(&partRequest)
-				if err != nil {
-					rpcServer.logger.Error("GetEphemeralSecretPart req error", "error", err)
-					return
-				}
-
-				// no need to contine if timed out
-				select {
-				case <-partReqCtx.Done():
-					return
-				default:
-				}
-
-				defer partReqCtxCancel()
-
-				// set the share part from the response
-				err = rpcServer.localCosigner.SetEphemeralSecretPart(CosignerSetEphemeralSecretPartRequest{
-					SourceID:                       int(partResponse.SourceID),
-					SourceEphemeralSecretPublicKey: partResponse.SourceEphemeralSecretPublicKey,
-					EncryptedSharePart:             partResponse.EncryptedSharePart,
-					Height:                         height,
-					Round:                          round,
-					Step:                           step,
-					SourceSig:                      partResponse.SourceSig,
-				})
-				if err != nil {
-					rpcServer.logger.Error("SetEphemeralSecretPart req error", "error", err)
-				}
-			}()
-
-			// wait for timeout or done
-			select {
-			case <-partReqCtx.Done():
+	for _, peer := range chain.Peers {
+		request := func(peer *RemoteCosigner) {
+			defer wg.Done()
+
+			err := rpcServer.signing.retry(partsCtx, rpcServer.signing.EphemeralTimeout.Duration, func(ctx context.Context) error {
+				return exchangeEphemeralSecretPart(ctx, chain.LocalCosigner, peer, height, round, step)
+			})
+			if err != nil {
+				rpcServer.logger.Error("Ephemeral secret part request error", "peer", peer.GetID(), "error", err)
 			}
-
-			wg.Done()
 		}
 
 		go request(peer)
@@ -171,37 +213,162 @@ func (rpcServer *CosignerRpcServer) Sign(ctx context.Context, req *CosignerSignR
 
 	wg.Wait()
 
+	// the caller may have given up while we were collecting share parts
+	if err := ctx.Err(); err != nil {
+		return response, status.FromContextError(err).Err()
+	}
+
 	// after getting any share parts we could, we sign
-	resp, err := rpcServer.localCosigner.Sign(&CosignerSignRequest{
+	resp, err := chain.LocalCosigner.Sign(ctx, &CosignerSignRequest{
 		SignBytes: req.SignBytes,
 	})
 	if err != nil {
 		return response, err
 	}
 
+	response.EphemeralPublic = resp.EphemeralPublic
+	response.EphemeralSharePublic = resp.EphemeralSharePublic
 	response.Timestamp = resp.Timestamp
 	response.Signature = resp.Signature
 	return response, nil
 }
 
+// rpcSignSource names the caller of an rpc request, by cosigner ID when it is authenticated
+func rpcSignSource(ctx context.Context) string {
+	if id, ok := CosignerIDFromContext(ctx); ok {
+		return fmt.Sprintf("cosigner %d", id)
+	}
+	if caller, ok := peer.FromContext(ctx); ok {
+		return caller.Addr.String()
+	}
+	return ""
+}
+
 func (rpcServer *CosignerRpcServer) GetEphemeralSecretPart(ctx context.Context, req *CosignerGetEphemeralSecretPartRequest) (*CosignerGetEphemeralSecretPartResponse, error) {
 	response := &CosignerGetEphemeralSecretPartResponse{}
 
-	partResp, err := rpcServer.localCosigner.GetEphemeralSecretPart(&CosignerGetEphemeralSecretPartRequest{
-		ID:     req.ID,
-		Height: req.Height,
-		Round:  req.Round,
-		Step:   req.Step,
+	chain, err := rpcServer.chain(req.ChainID)
+	if err != nil {
+		return response, err
+	}
+
+	partResp, err := chain.LocalCosigner.GetEphemeralSecretPart(ctx, &CosignerGetEphemeralSecretPartRequest{
+		ID:              req.ID,
+		Height:          req.Height,
+		Round:           req.Round,
+		Step:            req.Step,
+		ProtocolVersion: req.ProtocolVersion,
 	})
 	if err != nil {
-		//rpcServer.logger.Info("NO RESPONSE from : ", ctx.RemoteAddr())
-		return response, nil
+		return response, err
 	}
 
 	response.SourceID = partResp.SourceID
 	response.SourceEphemeralSecretPublicKey = partResp.SourceEphemeralSecretPublicKey
 	response.EncryptedSharePart = partResp.EncryptedSharePart
 	response.SourceSig = partResp.SourceSig
+	response.ProtocolVersion = partResp.ProtocolVersion
 
 	return response, nil
 }
+
+func (rpcServer *CosignerRpcServer) HasEphemeralSecretPart(ctx context.Context, req *CosignerHasEphemeralSecretPartRequest) (*CosignerHasEphemeralSecretPartResponse, error) {
+	chain, err := rpcServer.chain(req.ChainID)
+	if err != nil {
+		return nil, err
+	}
+	return chain.LocalCosigner.HasEphemeralSecretPart(ctx, req)
+}
+
+func (rpcServer *CosignerRpcServer) SetEphemeralSecretPart(ctx context.Context, req *CosignerSetEphemeralSecretPartRequest) (*CosignerSetEphemeralSecretPartResponse, error) {
+	chain, err := rpcServer.chain(req.ChainID)
+	if err != nil {
+		return nil, err
+	}
+	err = chain.LocalCosigner.SetEphemeralSecretPart(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &CosignerSetEphemeralSecretPartResponse{}, nil
+}
+
+// SignBlock signs a block a follower forwarded for the node attached to it, if we are the leader
+// The sign bytes must be for the chain of the request.
+func (rpcServer *CosignerRpcServer) SignBlock(ctx context.Context, req *CosignerSignBlockRequest) (*CosignerSignBlockResponse, error) {
+	chain, err := rpcServer.chain(req.ChainID)
+	if err != nil {
+		return nil, err
+	}
+	if chain.Validator == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "no validator signs the blocks of chain %q", req.ChainID)
+	}
+
+	chainID, err := UnpackChainID(req.SignBytes)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if chain.ChainID != "" && chainID != chain.ChainID {
+		return nil, status.Errorf(codes.InvalidArgument, "sign bytes of chain %q sent for chain %q", chainID, chain.ChainID)
+	}
+
+	// the signature is recorded with the node and the follower it came through
+	ctx = ContextWithSignSource(ctx, fmt.Sprintf("%s via %s", req.Source, rpcSignSource(ctx)))
+
+	return chain.Validator.SignBlock(ctx, &CosignerSignBlockRequest{
+		ChainID:   chainID,
+		SignBytes: req.SignBytes,
+		Timestamp: req.Timestamp,
+		Source:    req.Source,
+	})
+}
+
+// refresher returns the local cosigner of the chain if it can take part in share refreshes
+func (rpcServer *CosignerRpcServer) refresher(chainID string) (ShareRefresher, error) {
+	chain, err := rpcServer.chain(chainID)
+	if err != nil {
+		return nil, err
+	}
+	refresher, ok := chain.LocalCosigner.(ShareRefresher)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "the cosigner doesn't support share refresh")
+	}
+	return refresher, nil
+}
+
+func (rpcServer *CosignerRpcServer) RefreshDeal(ctx context.Context, req *CosignerRefreshDealRequest) (*CosignerRefreshDeal, error) {
+	refresher, err := rpcServer.refresher(req.ChainID)
+	if err != nil {
+		return nil, err
+	}
+	return refresher.RefreshDeal(ctx, req)
+}
+
+func (rpcServer *CosignerRpcServer) RefreshPrepare(ctx context.Context, req *CosignerRefreshPrepareRequest) (*CosignerRefreshPrepareResponse, error) {
+	refresher, err := rpcServer.refresher(req.ChainID)
+	if err != nil {
+		return nil, err
+	}
+	return refresher.RefreshPrepare(ctx, req)
+}
+
+func (rpcServer *CosignerRpcServer) RefreshCommit(ctx context.Context, req *CosignerRefreshCommitRequest) (*CosignerRefreshCommitResponse, error) {
+	refresher, err := rpcServer.refresher(req.ChainID)
+	if err != nil {
+		return nil, err
+	}
+	if err := refresher.RefreshCommit(ctx, req); err != nil {
+		return nil, err
+	}
+	return &CosignerRefreshCommitResponse{}, nil
+}
+
+func (rpcServer *CosignerRpcServer) RefreshAbort(ctx context.Context, req *CosignerRefreshAbortRequest) (*CosignerRefreshAbortResponse, error) {
+	refresher, err := rpcServer.refresher(req.ChainID)
+	if err != nil {
+		return nil, err
+	}
+	if err := refresher.RefreshAbort(ctx, req); err != nil {
+		return nil, err
+	}
+	return &CosignerRefreshAbortResponse{}, nil
+}
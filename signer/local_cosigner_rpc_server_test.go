@@ -1,13 +1,18 @@
 package signer
 
 import (
+	"context"
+	"errors"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"github.com/tendermint/tendermint/libs/log"
 	tmProto "github.com/tendermint/tendermint/proto/tendermint/types"
 	tm "github.com/tendermint/tendermint/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type DummyCosigner struct{}
@@ -16,13 +21,13 @@ func (cosigner *DummyCosigner) GetID() int {
 	return 0
 }
 
-func (cosigner *DummyCosigner) Sign(signReq *CosignerSignRequest) (*CosignerSignResponse, error) {
+func (cosigner *DummyCosigner) Sign(ctx context.Context, signReq *CosignerSignRequest) (*CosignerSignResponse, error) {
 	return &CosignerSignResponse{
 		Signature: []byte("foobar"),
 	}, nil
 }
 
-func (cosigner *DummyCosigner) GetEphemeralSecretPart(req *CosignerGetEphemeralSecretPartRequest) (*CosignerGetEphemeralSecretPartResponse, error) {
+func (cosigner *DummyCosigner) GetEphemeralSecretPart(ctx context.Context, req *CosignerGetEphemeralSecretPartRequest) (*CosignerGetEphemeralSecretPartResponse, error) {
 	return &CosignerGetEphemeralSecretPartResponse{
 		SourceID:                       1,
 		SourceEphemeralSecretPublicKey: []byte("foo"),
@@ -31,13 +36,17 @@ func (cosigner *DummyCosigner) GetEphemeralSecretPart(req *CosignerGetEphemeralS
 	}, nil
 }
 
-func (cosigner *DummyCosigner) HasEphemeralSecretPart(req CosignerHasEphemeralSecretPartRequest) (CosignerHasEphemeralSecretPartResponse, error) {
-	return CosignerHasEphemeralSecretPartResponse{
-		Exists: false,
+func (cosigner *DummyCosigner) HasEphemeralSecretPart(ctx context.Context, req *CosignerHasEphemeralSecretPartRequest) (*CosignerHasEphemeralSecretPartResponse, error) {
+	return &CosignerHasEphemeralSecretPartResponse{
+		Exists:                   req.ID == 3,
+		EphemeralSecretPublicKey: []byte("foo"),
 	}, nil
 }
 
-func (cosigner *DummyCosigner) SetEphemeralSecretPart(req CosignerSetEphemeralSecretPartRequest) error {
+func (cosigner *DummyCosigner) SetEphemeralSecretPart(ctx context.Context, req *CosignerSetEphemeralSecretPartRequest) error {
+	if req.SourceSig == nil {
+		return errors.New("SourceSig field is required")
+	}
 	return nil
 }
 
@@ -64,7 +73,7 @@ func TestCosignerRpcServerSign(test *testing.T) {
 	signBytes := tm.VoteSignBytes("chain-id", &vote)
 
 	remoteCosigner := NewRemoteCosigner(2, rpcServer.Addr().String())
-	resp, err := remoteCosigner.Sign(&CosignerSignRequest{
+	resp, err := remoteCosigner.Sign(context.Background(), &CosignerSignRequest{
 		SignBytes: signBytes,
 	})
 	require.NoError(test, err)
@@ -95,7 +104,7 @@ func TestCosignerRpcServerGetEphemeralSecretPart(test *testing.T) {
 	tempAddress := rpcServer.Addr().String()
 	remoteCosigner := NewRemoteCosigner(2, tempAddress)
 
-	resp, err := remoteCosigner.GetEphemeralSecretPart(&CosignerGetEphemeralSecretPartRequest{})
+	resp, err := remoteCosigner.GetEphemeralSecretPart(context.Background(), &CosignerGetEphemeralSecretPartRequest{})
 	require.NoError(test, err)
 
 	expctedRes := CosignerGetEphemeralSecretPartResponse{
@@ -113,6 +122,190 @@ func TestCosignerRpcServerGetEphemeralSecretPart(test *testing.T) {
 	rpcServer.Stop()
 }
 
+// partlessCosigner has no ephemeral secret part to hand out
+type partlessCosigner struct {
+	DummyCosigner
+}
+
+func (cosigner *partlessCosigner) GetEphemeralSecretPart(ctx context.Context, req *CosignerGetEphemeralSecretPartRequest) (*CosignerGetEphemeralSecretPartResponse, error) {
+	return nil, errors.New("Unknown peer ID")
+}
+
+func TestCosignerRpcServerGetEphemeralSecretPartError(test *testing.T) {
+	rpcServer := NewCosignerRpcServer(&CosignerRpcServerConfig{
+		Logger:        log.NewTMLogger(log.NewSyncWriter(os.Stdout)),
+		ListenAddress: "0.0.0.0:0",
+		LocalCosigner: &partlessCosigner{},
+	})
+	rpcServer.Start()
+	defer rpcServer.Stop()
+
+	// the error of the cosigner reaches the caller instead of an empty part
+	remoteCosigner := NewRemoteCosigner(2, rpcServer.Addr().String())
+	_, err := remoteCosigner.GetEphemeralSecretPart(context.Background(), &CosignerGetEphemeralSecretPartRequest{ID: 9})
+	require.Error(test, err)
+	require.Equal(test, codes.Unknown, status.Code(err))
+	require.Contains(test, err.Error(), "Unknown peer ID")
+}
+
+func TestCosignerRpcServerHasAndSetEphemeralSecretPart(test *testing.T) {
+	logger := log.NewTMLogger(log.NewSyncWriter(os.Stdout))
+
+	config := CosignerRpcServerConfig{
+		Logger:        logger,
+		ListenAddress: "0.0.0.0:0",
+		LocalCosigner: &DummyCosigner{},
+	}
+
+	rpcServer := NewCosignerRpcServer(&config)
+	rpcServer.Start()
+	defer rpcServer.Stop()
+
+	remoteCosigner := NewRemoteCosigner(2, rpcServer.Addr().String())
+	defer remoteCosigner.Close()
+
+	hasResp, err := remoteCosigner.HasEphemeralSecretPart(context.Background(), &CosignerHasEphemeralSecretPartRequest{ID: 3})
+	require.NoError(test, err)
+	require.True(test, hasResp.Exists)
+	require.Equal(test, []byte("foo"), hasResp.EphemeralSecretPublicKey)
+
+	hasResp, err = remoteCosigner.HasEphemeralSecretPart(context.Background(), &CosignerHasEphemeralSecretPartRequest{ID: 1})
+	require.NoError(test, err)
+	require.False(test, hasResp.Exists)
+
+	err = remoteCosigner.SetEphemeralSecretPart(context.Background(), &CosignerSetEphemeralSecretPartRequest{
+		SourceID:  1,
+		SourceSig: []byte("source sig"),
+	})
+	require.NoError(test, err)
+
+	// errors of the local cosigner are returned to the caller
+	err = remoteCosigner.SetEphemeralSecretPart(context.Background(), &CosignerSetEphemeralSecretPartRequest{SourceID: 1})
+	require.Error(test, err)
+}
+
+func TestCosignerRpcServerSignHonoursDeadline(test *testing.T) {
+	peerServer, peerAddress, stop := startHangingCosignerServer(test)
+	defer stop()
+
+	peer := NewRemoteCosigner(4, peerAddress)
+	defer peer.Close()
+
+	config := CosignerRpcServerConfig{
+		Logger:        log.NewNopLogger(),
+		ListenAddress: "127.0.0.1:0",
+		LocalCosigner: &DummyCosigner{},
+		Peers:         []*RemoteCosigner{peer},
+	}
+
+	rpcServer := NewCosignerRpcServer(&config)
+	rpcServer.Start()
+	defer rpcServer.Stop()
+
+	var vote tmProto.Vote
+	vote.Height = 1
+	vote.Round = 0
+	vote.Type = tmProto.PrevoteType
+	signBytes := tm.VoteSignBytes("chain-id", &vote)
+
+	remoteCosigner := NewRemoteCosigner(2, rpcServer.Addr().String())
+	defer remoteCosigner.Close()
+
+	// the deadline is shorter than the ephemeral part timeout of the server
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := remoteCosigner.Sign(ctx, &CosignerSignRequest{
+		SignBytes: signBytes,
+	})
+	require.Error(test, err)
+	require.Equal(test, codes.DeadlineExceeded, status.Code(err))
+	require.True(test, time.Since(start) < time.Second)
+
+	// the server cancelled its own request to the hanging peer
+	select {
+	case <-peerServer.cancelled:
+	case <-time.After(time.Second):
+		test.Fatal("peer request was not cancelled")
+	}
+}
+
+// chainCosigner signs with a signature naming its chain
+type chainCosigner struct {
+	DummyCosigner
+	chainID string
+}
+
+func (cosigner *chainCosigner) Sign(ctx context.Context, signReq *CosignerSignRequest) (*CosignerSignResponse, error) {
+	return &CosignerSignResponse{
+		Signature: []byte(cosigner.chainID),
+	}, nil
+}
+
+func TestCosignerRpcServerChains(test *testing.T) {
+	config := CosignerRpcServerConfig{
+		Logger:        log.NewNopLogger(),
+		ListenAddress: "127.0.0.1:0",
+		Chains: []CosignerRpcChain{
+			{ChainID: "chain-a", LocalCosigner: &chainCosigner{chainID: "chain-a"}},
+			{ChainID: "chain-b", LocalCosigner: &chainCosigner{chainID: "chain-b"}},
+		},
+	}
+
+	rpcServer := NewCosignerRpcServer(&config)
+	rpcServer.Start()
+	defer rpcServer.Stop()
+
+	remoteCosigner := NewRemoteCosigner(2, rpcServer.Addr().String())
+	defer remoteCosigner.Close()
+
+	// the cosigners of the chains share the connection and their requests go to their own validator
+	for _, chainID := range []string{"chain-a", "chain-b"} {
+		signBytes := tm.VoteSignBytes(chainID, &tmProto.Vote{Height: 1, Type: tmProto.PrevoteType})
+		resp, err := remoteCosigner.ForChain(chainID).Sign(context.Background(), &CosignerSignRequest{
+			SignBytes: signBytes,
+		})
+		require.NoError(test, err)
+		require.Equal(test, []byte(chainID), resp.Signature)
+	}
+
+	// an unknown chain, or no chain at all, is refused with several chains hosted
+	signBytes := tm.VoteSignBytes("chain-c", &tmProto.Vote{Height: 1, Type: tmProto.PrevoteType})
+	_, err := remoteCosigner.ForChain("chain-c").Sign(context.Background(), &CosignerSignRequest{
+		SignBytes: signBytes,
+	})
+	require.Equal(test, codes.NotFound, status.Code(err))
+	_, err = remoteCosigner.Sign(context.Background(), &CosignerSignRequest{
+		SignBytes: signBytes,
+	})
+	require.Equal(test, codes.NotFound, status.Code(err))
+
+	// with a single chain hosted, requests of older peers without chain ID go to it
+	single := CosignerRpcServerConfig{
+		Logger:        log.NewNopLogger(),
+		ListenAddress: "127.0.0.1:0",
+		Chains: []CosignerRpcChain{
+			{ChainID: "chain-a", LocalCosigner: &chainCosigner{chainID: "chain-a"}},
+		},
+	}
+	singleServer := NewCosignerRpcServer(&single)
+	singleServer.Start()
+	defer singleServer.Stop()
+
+	singleCosigner := NewRemoteCosigner(2, singleServer.Addr().String())
+	defer singleCosigner.Close()
+	resp, err := singleCosigner.Sign(context.Background(), &CosignerSignRequest{
+		SignBytes: tm.VoteSignBytes("chain-a", &tmProto.Vote{Height: 1, Type: tmProto.PrevoteType}),
+	})
+	require.NoError(test, err)
+	require.Equal(test, []byte("chain-a"), resp.Signature)
+	_, err = singleCosigner.ForChain("chain-b").Sign(context.Background(), &CosignerSignRequest{
+		SignBytes: signBytes,
+	})
+	require.Equal(test, codes.NotFound, status.Code(err))
+}
+
 /*
 func TestGRPCServer(test *testing.T) {
 
@@ -1,6 +1,7 @@
 package signer
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
 	"fmt"
@@ -15,6 +16,69 @@ import (
 	tsed25519 "gitlab.com/polychainlabs/threshold-ed25519/pkg"
 )
 
+// newTestCosigners returns local cosigners with the shares of a new key and X25519 transport keys
+func newTestCosigners(test *testing.T, threshold uint8, total uint8) (tmCryptoEd25519.PrivKey, []*LocalCosigner, func()) {
+
+	transportKeys := make([]*CosignerTransportKey, total)
+	peers := make([]CosignerPeer, total)
+	for idx := range transportKeys {
+		transportKey, err := GenerateCosignerTransportKey()
+		require.NoError(test, err)
+		transportPubKey, err := transportKey.PubKey()
+		require.NoError(test, err)
+
+		transportKeys[idx] = transportKey
+		peers[idx] = CosignerPeer{
+			ID:              idx + 1,
+			TransportPubKey: transportPubKey,
+		}
+	}
+
+	privateKey := tmCryptoEd25519.GenPrivKey()
+	privKeyBytes := [64]byte{}
+	copy(privKeyBytes[:], privateKey[:])
+	secretShares, commitments, err := DealShares(tsed25519.ExpandSecret(privKeyBytes[:32]), threshold, total)
+	require.NoError(test, err)
+
+	sharePubKeys := make([][]byte, total)
+	for idx, share := range secretShares {
+		sharePubKeys[idx] = SharePubKey(share)
+	}
+
+	files := []string{}
+	cosigners := make([]*LocalCosigner, total)
+	for idx := range cosigners {
+		stateFile, err := ioutil.TempFile("", "share_state.json")
+		require.NoError(test, err)
+		files = append(files, stateFile.Name())
+
+		signState, err := LoadOrCreateSignState(stateFile.Name())
+		require.NoError(test, err)
+
+		cosigners[idx] = NewLocalCosigner(LocalCosignerConfig{
+			CosignerKey: CosignerKey{
+				PubKey:       privateKey.PubKey(),
+				ShareKey:     secretShares[idx],
+				ID:           idx + 1,
+				TransportKey: transportKeys[idx],
+				SharePubKeys: sharePubKeys,
+				Commitments:  commitments,
+			},
+			SignState: &signState,
+			Peers:     peers,
+			Total:     total,
+			Threshold: threshold,
+		})
+	}
+
+	cleanup := func() {
+		for _, file := range files {
+			os.Remove(file)
+		}
+	}
+	return privateKey, cosigners, cleanup
+}
+
 func TestLocalCosignerGetID(test *testing.T) {
 	dummyPub := tmCryptoEd25519.PubKey{}
 
@@ -129,7 +193,7 @@ func TestLocalCosignerSign2of2(test *testing.T) {
 
 	// get part 2 from cosigner 1 and give to cosigner 2
 	{
-		resp, err := cosigner1.GetEphemeralSecretPart(&CosignerGetEphemeralSecretPartRequest{
+		resp, err := cosigner1.GetEphemeralSecretPart(context.Background(), &CosignerGetEphemeralSecretPartRequest{
 			ID:     2,
 			Height: 1,
 			Round:  0,
@@ -139,8 +203,8 @@ func TestLocalCosignerSign2of2(test *testing.T) {
 
 		publicKeys = append(publicKeys, resp.SourceEphemeralSecretPublicKey)
 
-		err = cosigner2.SetEphemeralSecretPart(CosignerSetEphemeralSecretPartRequest{
-			SourceID:                       int(resp.SourceID),
+		err = cosigner2.SetEphemeralSecretPart(context.Background(), &CosignerSetEphemeralSecretPartRequest{
+			SourceID:                       resp.SourceID,
 			Height:                         1,
 			Round:                          0,
 			Step:                           2,
@@ -153,7 +217,7 @@ func TestLocalCosignerSign2of2(test *testing.T) {
 
 	// get part 1 from cosigner 2 and give to cosigner 1
 	{
-		resp, err := cosigner2.GetEphemeralSecretPart(&CosignerGetEphemeralSecretPartRequest{
+		resp, err := cosigner2.GetEphemeralSecretPart(context.Background(), &CosignerGetEphemeralSecretPartRequest{
 			ID:     1,
 			Height: 1,
 			Round:  0,
@@ -163,8 +227,8 @@ func TestLocalCosignerSign2of2(test *testing.T) {
 
 		publicKeys = append(publicKeys, resp.SourceEphemeralSecretPublicKey)
 
-		err = cosigner1.SetEphemeralSecretPart(CosignerSetEphemeralSecretPartRequest{
-			SourceID:                       int(resp.SourceID),
+		err = cosigner1.SetEphemeralSecretPart(context.Background(), &CosignerSetEphemeralSecretPartRequest{
+			SourceID:                       resp.SourceID,
 			Height:                         1,
 			Round:                          0,
 			Step:                           2,
@@ -189,12 +253,12 @@ func TestLocalCosignerSign2of2(test *testing.T) {
 	signBytes := tm.VoteSignBytes("chain-id", &vote)
 
 	// sign with cosigner 1
-	sigRes1, err := cosigner1.Sign(&CosignerSignRequest{
+	sigRes1, err := cosigner1.Sign(context.Background(), &CosignerSignRequest{
 		SignBytes: signBytes,
 	})
 	require.NoError(test, err)
 
-	sigRes2, err := cosigner2.Sign(&CosignerSignRequest{
+	sigRes2, err := cosigner2.Sign(context.Background(), &CosignerSignRequest{
 		SignBytes: signBytes,
 	})
 	require.NoError(test, err)
@@ -259,3 +323,142 @@ func TestLocalCosignerWatermark(test *testing.T) {
 		require.Error(test, err, "height regression. Got 1, last height 2")
 	*/
 }
+
+func TestLocalCosignerEphemeralSecretPartBindsHRS(test *testing.T) {
+	_, cosigners, cleanup := newTestCosigners(test, 2, 2)
+	defer cleanup()
+
+	cosigner1 := cosigners[0]
+	cosigner2 := cosigners[1]
+	cosigner1.chainID = "chain-id"
+	cosigner2.chainID = "chain-id"
+	cosigner2.minProtocolVersion = CosignerProtocolVersionHRS
+
+	ctx := context.Background()
+	resp, err := cosigner1.GetEphemeralSecretPart(ctx, &CosignerGetEphemeralSecretPartRequest{
+		ID:              2,
+		Height:          1,
+		Round:           0,
+		Step:            2,
+		ProtocolVersion: CosignerProtocolVersion,
+	})
+	require.NoError(test, err)
+	require.Equal(test, int32(CosignerProtocolVersionHRS), resp.ProtocolVersion)
+
+	setRequest := func(height int64, version int32) *CosignerSetEphemeralSecretPartRequest {
+		return &CosignerSetEphemeralSecretPartRequest{
+			SourceID:                       resp.SourceID,
+			Height:                         height,
+			Round:                          0,
+			Step:                           2,
+			SourceEphemeralSecretPublicKey: resp.SourceEphemeralSecretPublicKey,
+			EncryptedSharePart:             resp.EncryptedSharePart,
+			SourceSig:                      resp.SourceSig,
+			ProtocolVersion:                version,
+		}
+	}
+
+	// the part can't be replayed for another HRS
+	require.Error(test, cosigner2.SetEphemeralSecretPart(ctx, setRequest(2, resp.ProtocolVersion)))
+
+	// nor downgraded to the legacy payload
+	require.Error(test, cosigner2.SetEphemeralSecretPart(ctx, setRequest(1, 0)))
+
+	// nor accepted on another chain
+	cosigner2.chainID = "other-chain-id"
+	require.Error(test, cosigner2.SetEphemeralSecretPart(ctx, setRequest(1, resp.ProtocolVersion)))
+	cosigner2.chainID = "chain-id"
+
+	require.NoError(test, cosigner2.SetEphemeralSecretPart(ctx, setRequest(1, resp.ProtocolVersion)))
+
+	// a part requested for cosigner 1 itself can't be handed to cosigner 2
+	ownResp, err := cosigner1.GetEphemeralSecretPart(ctx, &CosignerGetEphemeralSecretPartRequest{
+		ID:              1,
+		Height:          3,
+		Step:            2,
+		ProtocolVersion: CosignerProtocolVersion,
+	})
+	require.NoError(test, err)
+	err = cosigner2.SetEphemeralSecretPart(ctx, &CosignerSetEphemeralSecretPartRequest{
+		SourceID:                       ownResp.SourceID,
+		Height:                         3,
+		Step:                           2,
+		SourceEphemeralSecretPublicKey: ownResp.SourceEphemeralSecretPublicKey,
+		EncryptedSharePart:             ownResp.EncryptedSharePart,
+		SourceSig:                      ownResp.SourceSig,
+		ProtocolVersion:                ownResp.ProtocolVersion,
+	})
+	require.Error(test, err)
+}
+
+func TestLocalCosignerEphemeralSecretPartLegacyPeer(test *testing.T) {
+	_, cosigners, cleanup := newTestCosigners(test, 2, 2)
+	defer cleanup()
+
+	cosigner1 := cosigners[0]
+	cosigner2 := cosigners[1]
+
+	// a requester that doesn't send a protocol version gets the legacy payload
+	ctx := context.Background()
+	resp, err := cosigner1.GetEphemeralSecretPart(ctx, &CosignerGetEphemeralSecretPartRequest{
+		ID:     2,
+		Height: 1,
+		Step:   2,
+	})
+	require.NoError(test, err)
+	require.Equal(test, int32(0), resp.ProtocolVersion)
+
+	request := &CosignerSetEphemeralSecretPartRequest{
+		SourceID:                       resp.SourceID,
+		Height:                         1,
+		Step:                           2,
+		SourceEphemeralSecretPublicKey: resp.SourceEphemeralSecretPublicKey,
+		EncryptedSharePart:             resp.EncryptedSharePart,
+		SourceSig:                      resp.SourceSig,
+	}
+
+	// rejected once legacy peers are no longer allowed
+	cosigner2.minProtocolVersion = CosignerProtocolVersionHRS
+	require.Error(test, cosigner2.SetEphemeralSecretPart(ctx, request))
+
+	cosigner2.minProtocolVersion = CosignerProtocolVersionLegacy
+	require.NoError(test, cosigner2.SetEphemeralSecretPart(ctx, request))
+}
+
+func TestLocalCosignerEphemeralSecretPartDowngrade(test *testing.T) {
+	_, cosigners, cleanup := newTestCosigners(test, 2, 2)
+	defer cleanup()
+
+	cosigner1 := cosigners[0]
+	cosigner2 := cosigners[1]
+	cosigner1.chainID = "chain-id"
+	cosigner2.chainID = "chain-id"
+
+	ctx := context.Background()
+	setPart := func(height int64, version int32) error {
+		resp, err := cosigner1.GetEphemeralSecretPart(ctx, &CosignerGetEphemeralSecretPartRequest{
+			ID:              2,
+			Height:          height,
+			Step:            2,
+			ProtocolVersion: version,
+		})
+		require.NoError(test, err)
+		return cosigner2.SetEphemeralSecretPart(ctx, &CosignerSetEphemeralSecretPartRequest{
+			SourceID:                       resp.SourceID,
+			Height:                         height,
+			Step:                           2,
+			SourceEphemeralSecretPublicKey: resp.SourceEphemeralSecretPublicKey,
+			EncryptedSharePart:             resp.EncryptedSharePart,
+			SourceSig:                      resp.SourceSig,
+			ProtocolVersion:                resp.ProtocolVersion,
+		})
+	}
+
+	// legacy parts are accepted while the peer hasn't sent a newer one
+	require.NoError(test, setPart(1, 0))
+	require.NoError(test, setPart(2, CosignerProtocolVersion))
+
+	// then the peer can't be downgraded, even with a validly signed legacy part
+	require.Error(test, setPart(3, 0))
+	require.NoError(test, setPart(3, CosignerProtocolVersion))
+}
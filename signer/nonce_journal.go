@@ -0,0 +1,311 @@
+package signer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/tendermint/tendermint/libs/tempfile"
+	tsed25519 "gitlab.com/polychainlabs/threshold-ed25519/pkg"
+)
+
+// nonceJournalRecord is a record of the nonce journal
+// A record either holds the secret we dealt to the other cosigners for an HRS or a part dealt to us by one of them.
+type nonceJournalRecord struct {
+	Height int64 `json:"height"`
+	Round  int64 `json:"round"`
+	Step   int8  `json:"step"`
+
+	Secret      []byte   `json:"secret,omitempty"`
+	DealtShares [][]byte `json:"dealt_shares,omitempty"`
+
+	SourceID                 int    `json:"source_id,omitempty"`
+	Share                    []byte `json:"share,omitempty"`
+	EphemeralSecretPublicKey []byte `json:"ephemeral_public,omitempty"`
+}
+
+// sealedNonceJournalRecord is a line of the nonce journal
+// The record is sealed to our transport key: with the share signature of its HRS, a secret or a part
+// would give the share away. The HRS and source are repeated in clear for inspection only.
+type sealedNonceJournalRecord struct {
+	Height   int64  `json:"height"`
+	Round    int64  `json:"round"`
+	Step     int8   `json:"step"`
+	SourceID int    `json:"source_id,omitempty"`
+	Sealed   []byte `json:"sealed"`
+}
+
+func (record *nonceJournalRecord) hrsKey() HRSKey {
+	return HRSKey{
+		Height: record.Height,
+		Round:  record.Round,
+		Step:   record.Step,
+	}
+}
+
+// nonceJournal persists the ephemeral secrets dealt by a cosigner and the parts it received
+//
+// Records are appended and synced before a part leaves or is acknowledged, so a restarted cosigner
+// deals the same secret again instead of a fresh one the peers don't know about.
+// The journal is compacted when the metadata of the signed HRS are pruned.
+type nonceJournal struct {
+	file string
+	out  *os.File
+
+	// the records are sealed to our transport public key and opened with our transport key
+	transport CosignerTransport
+	sealTo    *CosignerTransportPubKey
+}
+
+// openNonceJournal replays the journal into the metadata of cosigner ourID and opens it for appending
+func openNonceJournal(file string, ourID int, threshold uint8, total uint8, transport CosignerTransport) (*nonceJournal, map[HRSKey]HrsMetadata, error) {
+	sealTo, err := transport.PubKey()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hrsMeta := make(map[HRSKey]HrsMetadata)
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, nil, err
+	}
+
+	lines := bytes.Split(data, []byte("\n"))
+	for idx, line := range lines {
+		if len(line) == 0 {
+			continue
+		}
+
+		var sealed sealedNonceJournalRecord
+		if err := json.Unmarshal(line, &sealed); err != nil {
+			// the last record may have been cut short by a crash before it was synced,
+			// its part was never handed out or acknowledged
+			if idx == len(lines)-1 {
+				logger.Info("Ignoring the truncated last record of the nonce journal", "file", file)
+				break
+			}
+			return nil, nil, fmt.Errorf("Invalid record %d in %v: %v", idx+1, file, err)
+		}
+
+		// a record sealed to another transport key, before a migration for instance, can't be replayed
+		var record nonceJournalRecord
+		if err := openNonceJournalRecord(transport, &sealed, &record); err != nil {
+			logger.Info("Ignoring nonce journal record", "file", file, "reason", err)
+			continue
+		}
+
+		if err := replayNonceJournalRecord(hrsMeta, &record, ourID, threshold, total); err != nil {
+			logger.Info("Ignoring nonce journal record", "file", file, "reason", err)
+		}
+	}
+
+	journal := &nonceJournal{file: file, transport: transport, sealTo: sealTo}
+	if err := journal.reopen(); err != nil {
+		return nil, nil, err
+	}
+	return journal, hrsMeta, nil
+}
+
+// openNonceJournalRecord decrypts a journal line into its record
+func openNonceJournalRecord(transport CosignerTransport, sealed *sealedNonceJournalRecord, record *nonceJournalRecord) error {
+	if len(sealed.Sealed) == 0 {
+		return errors.New("record is not sealed")
+	}
+	plaintext, err := transport.Open(sealed.Sealed)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(plaintext, record)
+}
+
+// replayNonceJournalRecord applies a record to the metadata
+func replayNonceJournalRecord(hrsMeta map[HRSKey]HrsMetadata, record *nonceJournalRecord, ourID int, threshold uint8, total uint8) error {
+	hrsKey := record.hrsKey()
+
+	if len(record.Secret) > 0 {
+		if len(record.DealtShares) != int(total) {
+			return fmt.Errorf("secret dealt to %d cosigners instead of %d", len(record.DealtShares), total)
+		}
+		meta := HrsMetadata{
+			Secret:      record.Secret,
+			DealtShares: make([]tsed25519.Scalar, len(record.DealtShares)),
+			Peers:       make([]PeerMetadata, total),
+		}
+		for idx, share := range record.DealtShares {
+			meta.DealtShares[idx] = share
+		}
+
+		// parts received before we dealt are kept
+		if previous, ok := hrsMeta[hrsKey]; ok {
+			copy(meta.Peers, previous.Peers)
+		}
+		meta.Peers[ourID-1] = PeerMetadata{
+			Share:                    meta.DealtShares[ourID-1],
+			EphemeralSecretPublicKey: tsed25519.ScalarMultiplyBase(meta.Secret),
+		}
+		hrsMeta[hrsKey] = meta
+		return nil
+	}
+
+	if record.SourceID < 1 || record.SourceID > int(total) || record.SourceID == ourID {
+		return fmt.Errorf("unexpected cosigner %d", record.SourceID)
+	}
+
+	// our secret for the HRS was never handed out, a fresh one is as good
+	meta, ok := hrsMeta[hrsKey]
+	if !ok {
+		meta = newHrsMetadata(threshold, total)
+		hrsMeta[hrsKey] = meta
+	}
+	meta.Peers[record.SourceID-1] = PeerMetadata{
+		Share:                    record.Share,
+		EphemeralSecretPublicKey: record.EphemeralSecretPublicKey,
+	}
+	return nil
+}
+
+// reopen opens the journal file for appending
+func (journal *nonceJournal) reopen() error {
+	if journal.out != nil {
+		journal.out.Close()
+	}
+
+	out, err := os.OpenFile(journal.file, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	journal.out = out
+	return nil
+}
+
+// seal encrypts a record into a journal line
+func (journal *nonceJournal) seal(record *nonceJournalRecord) ([]byte, error) {
+	plaintext, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := journal.sealTo.Seal(plaintext)
+	if err != nil {
+		return nil, err
+	}
+	line, err := json.Marshal(&sealedNonceJournalRecord{
+		Height:   record.Height,
+		Round:    record.Round,
+		Step:     record.Step,
+		SourceID: record.SourceID,
+		Sealed:   ciphertext,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(line, '\n'), nil
+}
+
+// append writes a record and waits for it to reach the disk
+func (journal *nonceJournal) append(record *nonceJournalRecord) error {
+	line, err := journal.seal(record)
+	if err != nil {
+		return err
+	}
+	if _, err := journal.out.Write(line); err != nil {
+		return err
+	}
+	return journal.out.Sync()
+}
+
+// appendDeal journals the secret we dealt for an HRS
+func (journal *nonceJournal) appendDeal(hrsKey HRSKey, meta HrsMetadata) error {
+	dealtShares := make([][]byte, len(meta.DealtShares))
+	for idx, share := range meta.DealtShares {
+		dealtShares[idx] = share
+	}
+	return journal.append(&nonceJournalRecord{
+		Height:      hrsKey.Height,
+		Round:       hrsKey.Round,
+		Step:        hrsKey.Step,
+		Secret:      meta.Secret,
+		DealtShares: dealtShares,
+	})
+}
+
+// appendPart journals a part dealt to us by another cosigner
+func (journal *nonceJournal) appendPart(hrsKey HRSKey, sourceID int, peer PeerMetadata) error {
+	return journal.append(&nonceJournalRecord{
+		Height:                   hrsKey.Height,
+		Round:                    hrsKey.Round,
+		Step:                     hrsKey.Step,
+		SourceID:                 sourceID,
+		Share:                    peer.Share,
+		EphemeralSecretPublicKey: peer.EphemeralSecretPublicKey,
+	})
+}
+
+// compact atomically replaces the journal by the records of the remaining metadata
+// The part of cosigner ourID is derived from its secret when the journal is replayed.
+func (journal *nonceJournal) compact(hrsMeta map[HRSKey]HrsMetadata, ourID int) error {
+	hrsKeys := make([]HRSKey, 0, len(hrsMeta))
+	for hrsKey := range hrsMeta {
+		hrsKeys = append(hrsKeys, hrsKey)
+	}
+	sort.Slice(hrsKeys, func(i, j int) bool {
+		return hrsKeys[i].Less(hrsKeys[j])
+	})
+
+	var buffer bytes.Buffer
+	encode := func(record *nonceJournalRecord) error {
+		line, err := journal.seal(record)
+		if err != nil {
+			return err
+		}
+		buffer.Write(line)
+		return nil
+	}
+	for _, hrsKey := range hrsKeys {
+		meta := hrsMeta[hrsKey]
+
+		// a secret that was never handed out doesn't need to survive a restart
+		if len(meta.Peers[ourID-1].Share) > 0 {
+			dealtShares := make([][]byte, len(meta.DealtShares))
+			for idx, share := range meta.DealtShares {
+				dealtShares[idx] = share
+			}
+			err := encode(&nonceJournalRecord{
+				Height:      hrsKey.Height,
+				Round:       hrsKey.Round,
+				Step:        hrsKey.Step,
+				Secret:      meta.Secret,
+				DealtShares: dealtShares,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		for idx, peer := range meta.Peers {
+			if idx+1 == ourID || len(peer.Share) == 0 {
+				continue
+			}
+			err := encode(&nonceJournalRecord{
+				Height:                   hrsKey.Height,
+				Round:                    hrsKey.Round,
+				Step:                     hrsKey.Step,
+				SourceID:                 idx + 1,
+				Share:                    peer.Share,
+				EphemeralSecretPublicKey: peer.EphemeralSecretPublicKey,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tempfile.WriteFileAtomic(journal.file, buffer.Bytes(), 0600); err != nil {
+		return err
+	}
+	return journal.reopen()
+}
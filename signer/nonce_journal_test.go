@@ -0,0 +1,201 @@
+package signer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	tmProto "github.com/tendermint/tendermint/proto/tendermint/types"
+	tm "github.com/tendermint/tendermint/types"
+)
+
+// restartCosigner returns a new cosigner with the key, sign state and nonce journal of cosigner
+func restartCosigner(test *testing.T, cosigner *LocalCosigner, journalFile string) *LocalCosigner {
+	peers := make([]CosignerPeer, 0, len(cosigner.peers))
+	for _, peer := range cosigner.peers {
+		peers = append(peers, peer)
+	}
+
+	restarted := NewLocalCosigner(LocalCosignerConfig{
+		CosignerKey: cosigner.key,
+		SignState:   cosigner.lastSignState,
+		Peers:       peers,
+		Total:       cosigner.total,
+		Threshold:   cosigner.threshold,
+	})
+	require.NoError(test, restarted.OpenNonceJournal(journalFile))
+	return restarted
+}
+
+func TestNonceJournalRestart(test *testing.T) {
+	privateKey, cosigners, cleanup := newTestCosigners(test, 2, 3)
+	defer cleanup()
+
+	dir, err := ioutil.TempDir("", "nonce-journal")
+	require.NoError(test, err)
+	defer os.RemoveAll(dir)
+
+	journalFiles := make([]string, len(cosigners))
+	for idx, cosigner := range cosigners {
+		journalFiles[idx] = filepath.Join(dir, string(rune('1'+idx))+".jsonl")
+		require.NoError(test, cosigner.OpenNonceJournal(journalFiles[idx]))
+	}
+
+	// the parts are handed out, then every cosigner restarts before signing
+	exchangeAllEphemeralSecretParts(test, cosigners, 1, 0, stepPropose)
+	for idx, cosigner := range cosigners {
+		cosigners[idx] = restartCosigner(test, cosigner, journalFiles[idx])
+		require.Equal(test, 1, len(cosigners[idx].hrsMeta))
+	}
+
+	stateFile, err := ioutil.TempFile("", "priv_validator_state.json")
+	require.NoError(test, err)
+	defer os.Remove(stateFile.Name())
+	signState, err := LoadOrCreateSignState(stateFile.Name())
+	require.NoError(test, err)
+
+	validator := NewThresholdValidator(&ThresholdValidatorOpt{
+		Pubkey:    privateKey.PubKey(),
+		Threshold: 2,
+		SignState: signState,
+		Cosigner:  cosigners[0],
+		Peers:     []Cosigner{cosigners[1], cosigners[2]},
+	})
+
+	// the restored parts combine with the ones the peers already hold
+	proposal := tmProto.Proposal{
+		Height: 1,
+		Type:   tmProto.ProposalType,
+	}
+	require.NoError(test, validator.SignProposal("chain-id", &proposal))
+	require.True(test, privateKey.PubKey().VerifySignature(tm.ProposalSignBytes("chain-id", &proposal), proposal.Signature))
+
+	// signing the next height prunes the signed heights from the journal
+	exchangeAllEphemeralSecretParts(test, cosigners, 3, 0, stepPropose)
+	signTestProposal(test, validator, cosigners, 2)
+	restarted := restartCosigner(test, cosigners[0], journalFiles[0])
+	for hrsKey := range restarted.hrsMeta {
+		require.Equal(test, int64(3), hrsKey.Height)
+	}
+}
+
+// readNonceJournal returns the lines of the journal
+func readNonceJournal(test *testing.T, file string) []sealedNonceJournalRecord {
+	data, err := ioutil.ReadFile(file)
+	require.NoError(test, err)
+
+	records := []sealedNonceJournalRecord{}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		var record sealedNonceJournalRecord
+		require.NoError(test, json.Unmarshal(line, &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestNonceJournalSealedAndPrunedOnSign(test *testing.T) {
+	privateKey, cosigners, cleanup := newTestCosigners(test, 2, 3)
+	defer cleanup()
+
+	dir, err := ioutil.TempDir("", "nonce-journal")
+	require.NoError(test, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "nonce_journal.jsonl")
+	require.NoError(test, cosigners[0].OpenNonceJournal(file))
+	exchangeAllEphemeralSecretParts(test, cosigners, 1, 0, stepPropose)
+
+	// neither our secret nor the parts of the peers are readable on disk
+	meta := cosigners[0].hrsMeta[HRSKey{Height: 1, Step: stepPropose}]
+	data, err := ioutil.ReadFile(file)
+	require.NoError(test, err)
+	secrets := [][]byte{meta.Secret, meta.Peers[1].Share, meta.Peers[2].Share}
+	for _, secret := range secrets {
+		require.NotContains(test, string(data), base64.StdEncoding.EncodeToString(secret))
+		require.False(test, bytes.Contains(data, secret))
+	}
+	require.Len(test, readNonceJournal(test, file), 3)
+
+	stateFile, err := ioutil.TempFile("", "priv_validator_state.json")
+	require.NoError(test, err)
+	defer os.Remove(stateFile.Name())
+	signState, err := LoadOrCreateSignState(stateFile.Name())
+	require.NoError(test, err)
+
+	validator := NewThresholdValidator(&ThresholdValidatorOpt{
+		Pubkey:    privateKey.PubKey(),
+		Threshold: 2,
+		SignState: signState,
+		Cosigner:  cosigners[0],
+		Peers:     []Cosigner{cosigners[1], cosigners[2]},
+	})
+	proposal := tmProto.Proposal{
+		Height: 1,
+		Type:   tmProto.ProposalType,
+	}
+	require.NoError(test, validator.SignProposal("chain-id", &proposal))
+
+	// the nonce of the signed HRS is gone, only its share signature remains
+	require.Empty(test, readNonceJournal(test, file))
+	require.Empty(test, cosigners[0].hrsMeta)
+	restarted := restartCosigner(test, cosigners[0], file)
+	require.Empty(test, restarted.hrsMeta)
+
+	// the signature is still served for a retry of the same request
+	require.NoError(test, validator.SignProposal("chain-id", &proposal))
+}
+
+func TestNonceJournalLegacyTransport(test *testing.T) {
+	_, cosigners, cleanup := newTestCosigners(test, 2, 3)
+	defer cleanup()
+
+	// nothing to seal the journal to
+	cosigners[0].transport = nil
+	require.Error(test, cosigners[0].OpenNonceJournal(filepath.Join(os.TempDir(), "nonce_journal.jsonl")))
+}
+
+func TestNonceJournalReplay(test *testing.T) {
+	_, cosigners, cleanup := newTestCosigners(test, 2, 3)
+	defer cleanup()
+
+	dir, err := ioutil.TempDir("", "nonce-journal")
+	require.NoError(test, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "nonce_journal.jsonl")
+	require.NoError(test, cosigners[0].OpenNonceJournal(file))
+	exchangeAllEphemeralSecretParts(test, cosigners, 1, 0, stepPropose)
+	hrsKey := HRSKey{Height: 1, Step: stepPropose}
+	before := cosigners[0].hrsMeta[hrsKey]
+
+	// a record cut short by a crash is ignored
+	out, err := os.OpenFile(file, os.O_WRONLY|os.O_APPEND, 0600)
+	require.NoError(test, err)
+	_, err = out.Write([]byte(`{"height":1,"round":0,"step":1,"sec`))
+	require.NoError(test, err)
+	require.NoError(test, out.Close())
+
+	restarted := restartCosigner(test, cosigners[0], file)
+	after := restarted.hrsMeta[hrsKey]
+	require.Equal(test, before.Secret, after.Secret)
+	require.Equal(test, before.DealtShares, after.DealtShares)
+	require.Equal(test, before.Peers, after.Peers)
+
+	// the journal was compacted when it was opened
+	data, err := ioutil.ReadFile(file)
+	require.NoError(test, err)
+	require.NotContains(test, string(data), `"sec`+"\n")
+
+	// any other corrupted record is an error
+	require.NoError(test, ioutil.WriteFile(file, append([]byte("garbage\n"), data...), 0600))
+	_, _, err = openNonceJournal(file, 1, 2, 3, cosigners[0].transport)
+	require.Error(test, err)
+}
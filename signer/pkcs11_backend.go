@@ -0,0 +1,452 @@
+//go:build cgo
+// +build cgo
+
+package signer
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/miekg/pkcs11"
+)
+
+// PKCS#11 3.0 identifiers of the curve25519 keys, missing from the bindings
+const (
+	ckkECEdwards    = 0x00000040
+	ckkECMontgomery = 0x00000041
+	ckmEdDSA        = 0x00001057
+
+	pkcs11Application = "valink"
+)
+
+var (
+	// DER encoded object identifiers of the curves, used as CKA_EC_PARAMS
+	oidEd25519 = []byte{0x06, 0x03, 0x2b, 0x65, 0x70}
+	oidX25519  = []byte{0x06, 0x03, 0x2b, 0x65, 0x6e}
+)
+
+// PKCS11KeyBackend keeps the key of a cosigner on a PKCS#11 token
+//
+// The transport keys are non-extractable token keys: share parts are decrypted with an ECDH
+// derivation and signed with EdDSA on the token. No PKCS#11 mechanism computes threshold ed25519
+// share signatures, so the share is stored as a private data object and read into memory.
+type PKCS11KeyBackend struct {
+	ctx      *pkcs11.Ctx
+	session  pkcs11.SessionHandle
+	keyLabel string
+
+	// a session can't be used concurrently
+	mtx sync.Mutex
+}
+
+var _ KeyBackend = &PKCS11KeyBackend{}
+
+// NewPKCS11KeyBackend opens a session on the token and logs in
+func NewPKCS11KeyBackend(config PKCS11Config) (KeyBackend, error) {
+	return newPKCS11KeyBackend(config)
+}
+
+func newPKCS11KeyBackend(config PKCS11Config) (*PKCS11KeyBackend, error) {
+	if config.Library == "" || config.TokenLabel == "" || config.KeyLabel == "" {
+		return nil, errors.New("pkcs11 requires library, token_label and key_label")
+	}
+	pin, err := config.Pin()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := pkcs11.New(config.Library)
+	if ctx == nil {
+		return nil, fmt.Errorf("Failed to load the PKCS#11 library %s", config.Library)
+	}
+	if err := ctx.Initialize(); err != nil {
+		ctx.Destroy()
+		return nil, err
+	}
+
+	backend := &PKCS11KeyBackend{
+		ctx:      ctx,
+		keyLabel: config.KeyLabel,
+	}
+
+	slot, err := backend.findSlot(config.TokenLabel)
+	if err != nil {
+		backend.finalize()
+		return nil, err
+	}
+
+	backend.session, err = ctx.OpenSession(slot, pkcs11.CKF_SERIAL_SESSION|pkcs11.CKF_RW_SESSION)
+	if err != nil {
+		backend.finalize()
+		return nil, err
+	}
+
+	err = ctx.Login(backend.session, pkcs11.CKU_USER, pin)
+	if err != nil && err != pkcs11.Error(pkcs11.CKR_USER_ALREADY_LOGGED_IN) {
+		backend.Close()
+		return nil, err
+	}
+	return backend, nil
+}
+
+// findSlot returns the slot of the token with the label
+func (backend *PKCS11KeyBackend) findSlot(tokenLabel string) (uint, error) {
+	slots, err := backend.ctx.GetSlotList(true)
+	if err != nil {
+		return 0, err
+	}
+	for _, slot := range slots {
+		info, err := backend.ctx.GetTokenInfo(slot)
+		if err != nil {
+			return 0, err
+		}
+		// labels are padded with spaces
+		if strings.TrimRight(info.Label, " \x00") == tokenLabel {
+			return slot, nil
+		}
+	}
+	return 0, fmt.Errorf("No PKCS#11 token labelled %s", tokenLabel)
+}
+
+func (backend *PKCS11KeyBackend) shareLabel() string {
+	return backend.keyLabel + "-share"
+}
+
+func (backend *PKCS11KeyBackend) encryptionKeyLabel() string {
+	return backend.keyLabel + "-x25519"
+}
+
+func (backend *PKCS11KeyBackend) signingKeyLabel() string {
+	return backend.keyLabel + "-ed25519"
+}
+
+// findObjects returns the objects of the class with the label
+// The caller must hold mtx.
+func (backend *PKCS11KeyBackend) findObjects(class uint, label string) ([]pkcs11.ObjectHandle, error) {
+	template := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, class),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+	}
+	if err := backend.ctx.FindObjectsInit(backend.session, template); err != nil {
+		return nil, err
+	}
+	objects, _, err := backend.ctx.FindObjects(backend.session, 2)
+	if finalErr := backend.ctx.FindObjectsFinal(backend.session); err == nil {
+		err = finalErr
+	}
+	return objects, err
+}
+
+// findObject returns the only object of the class with the label
+// The caller must hold mtx.
+func (backend *PKCS11KeyBackend) findObject(class uint, label string) (pkcs11.ObjectHandle, error) {
+	objects, err := backend.findObjects(class, label)
+	if err != nil {
+		return 0, err
+	}
+	switch len(objects) {
+	case 0:
+		return 0, fmt.Errorf("No PKCS#11 object labelled %s", label)
+	case 1:
+		return objects[0], nil
+	default:
+		return 0, fmt.Errorf("Several PKCS#11 objects are labelled %s", label)
+	}
+}
+
+// storedKey returns the key as stored in the share object, without the private transport key
+func storedKey(key *CosignerKey) ([]byte, error) {
+	stored := *key
+	stored.TransportKey = nil
+	stored.passphrase = nil
+	return json.Marshal(&stored)
+}
+
+// Load reads the key from the share object of the token
+func (backend *PKCS11KeyBackend) Load() (CosignerKey, error) {
+	backend.mtx.Lock()
+	defer backend.mtx.Unlock()
+
+	key := CosignerKey{}
+	object, err := backend.findObject(pkcs11.CKO_DATA, backend.shareLabel())
+	if err != nil {
+		return key, err
+	}
+	attributes, err := backend.ctx.GetAttributeValue(backend.session, object, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_VALUE, nil),
+	})
+	if err != nil {
+		return key, err
+	}
+
+	if err := json.Unmarshal(attributes[0].Value, &key); err != nil {
+		return key, err
+	}
+	if key.IsLegacy() {
+		return key, errors.New("Keys with RSA transport keys can't be stored on a PKCS#11 token")
+	}
+	if err := key.Validate(); err != nil {
+		return key, fmt.Errorf("Invalid share in %v: %v", backend.shareLabel(), err)
+	}
+	return key, nil
+}
+
+// Save replaces the share object of the token
+func (backend *PKCS11KeyBackend) Save(key *CosignerKey) error {
+	backend.mtx.Lock()
+	defer backend.mtx.Unlock()
+
+	value, err := storedKey(key)
+	if err != nil {
+		return err
+	}
+	object, err := backend.findObject(pkcs11.CKO_DATA, backend.shareLabel())
+	if err != nil {
+		return err
+	}
+	return backend.ctx.SetAttributeValue(backend.session, object, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_VALUE, value),
+	})
+}
+
+// Transport returns the transport key of the token
+// The token keys are checked against the public transport key of the cosigner.
+func (backend *PKCS11KeyBackend) Transport(key *CosignerKey) (CosignerTransport, error) {
+	if key.ID < 1 || key.ID > len(key.TransportPubKeys) {
+		return nil, fmt.Errorf("No transport public key for cosigner %d", key.ID)
+	}
+
+	encryptionKey, signingKey, err := backend.findTransportKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	transport := &pkcs11Transport{
+		backend:       backend,
+		pubKey:        key.TransportPubKeys[key.ID-1],
+		encryptionKey: encryptionKey,
+		signingKey:    signingKey,
+	}
+	if err := checkTransport(transport); err != nil {
+		return nil, err
+	}
+	return transport, nil
+}
+
+// findTransportKeys returns the X25519 and Ed25519 keys of the token
+func (backend *PKCS11KeyBackend) findTransportKeys() (pkcs11.ObjectHandle, pkcs11.ObjectHandle, error) {
+	backend.mtx.Lock()
+	defer backend.mtx.Unlock()
+
+	encryptionKey, err := backend.findObject(pkcs11.CKO_PRIVATE_KEY, backend.encryptionKeyLabel())
+	if err != nil {
+		return 0, 0, err
+	}
+	signingKey, err := backend.findObject(pkcs11.CKO_PRIVATE_KEY, backend.signingKeyLabel())
+	if err != nil {
+		return 0, 0, err
+	}
+	return encryptionKey, signingKey, nil
+}
+
+// checkTransport fails if the keys of the transport don't match its public key
+func checkTransport(transport CosignerTransport) error {
+	pubKey, err := transport.PubKey()
+	if err != nil {
+		return err
+	}
+
+	message := []byte(transportKeyInfo)
+	signature, err := transport.Sign(message)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(pubKey.SigningKey, message, signature) {
+		return errors.New("The signing key of the token doesn't match the transport public key")
+	}
+
+	sealed, err := pubKey.Seal(message)
+	if err != nil {
+		return err
+	}
+	if _, err := transport.Open(sealed); err != nil {
+		return errors.New("The encryption key of the token doesn't match the transport public key")
+	}
+	return nil
+}
+
+// Close logs out and closes the session
+func (backend *PKCS11KeyBackend) Close() error {
+	backend.mtx.Lock()
+	defer backend.mtx.Unlock()
+
+	backend.ctx.Logout(backend.session)
+	err := backend.ctx.CloseSession(backend.session)
+	backend.finalize()
+	return err
+}
+
+func (backend *PKCS11KeyBackend) finalize() {
+	backend.ctx.Finalize()
+	backend.ctx.Destroy()
+}
+
+// Import stores a key on the token, the private transport keys become non-extractable token keys
+// The key must not be on the token already.
+func (backend *PKCS11KeyBackend) Import(key *CosignerKey) error {
+	if key.TransportKey == nil {
+		return errors.New("The key has no private transport key, run `valink migrate-keys` first")
+	}
+	value, err := storedKey(key)
+	if err != nil {
+		return err
+	}
+
+	backend.mtx.Lock()
+	defer backend.mtx.Unlock()
+
+	existing := []struct {
+		class uint
+		label string
+	}{
+		{pkcs11.CKO_PRIVATE_KEY, backend.encryptionKeyLabel()},
+		{pkcs11.CKO_PRIVATE_KEY, backend.signingKeyLabel()},
+		{pkcs11.CKO_DATA, backend.shareLabel()},
+	}
+	for _, object := range existing {
+		objects, err := backend.findObjects(object.class, object.label)
+		if err != nil {
+			return err
+		}
+		if len(objects) > 0 {
+			return fmt.Errorf("The token already has an object labelled %s", object.label)
+		}
+	}
+
+	templates := [][]*pkcs11.Attribute{
+		{
+			pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_PRIVATE_KEY),
+			pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, ckkECMontgomery),
+			pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, oidX25519),
+			pkcs11.NewAttribute(pkcs11.CKA_VALUE, key.TransportKey.EncryptionKey),
+			pkcs11.NewAttribute(pkcs11.CKA_LABEL, backend.encryptionKeyLabel()),
+			pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+			pkcs11.NewAttribute(pkcs11.CKA_PRIVATE, true),
+			pkcs11.NewAttribute(pkcs11.CKA_SENSITIVE, true),
+			pkcs11.NewAttribute(pkcs11.CKA_EXTRACTABLE, false),
+			pkcs11.NewAttribute(pkcs11.CKA_DERIVE, true),
+		},
+		{
+			pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_PRIVATE_KEY),
+			pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, ckkECEdwards),
+			pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, oidEd25519),
+			pkcs11.NewAttribute(pkcs11.CKA_VALUE, key.TransportKey.SigningKey.Seed()),
+			pkcs11.NewAttribute(pkcs11.CKA_LABEL, backend.signingKeyLabel()),
+			pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+			pkcs11.NewAttribute(pkcs11.CKA_PRIVATE, true),
+			pkcs11.NewAttribute(pkcs11.CKA_SENSITIVE, true),
+			pkcs11.NewAttribute(pkcs11.CKA_EXTRACTABLE, false),
+			pkcs11.NewAttribute(pkcs11.CKA_SIGN, true),
+		},
+		{
+			pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_DATA),
+			pkcs11.NewAttribute(pkcs11.CKA_APPLICATION, pkcs11Application),
+			pkcs11.NewAttribute(pkcs11.CKA_VALUE, value),
+			pkcs11.NewAttribute(pkcs11.CKA_LABEL, backend.shareLabel()),
+			pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+			pkcs11.NewAttribute(pkcs11.CKA_PRIVATE, true),
+		},
+	}
+
+	created := make([]pkcs11.ObjectHandle, 0, len(templates))
+	for _, template := range templates {
+		object, err := backend.ctx.CreateObject(backend.session, template)
+		if err != nil {
+			// don't leave half a key on the token
+			for _, object := range created {
+				backend.ctx.DestroyObject(backend.session, object)
+			}
+			return err
+		}
+		created = append(created, object)
+	}
+	return nil
+}
+
+// ImportPKCS11Key stores a key on the token of the configuration
+func ImportPKCS11Key(config PKCS11Config, key *CosignerKey) error {
+	backend, err := newPKCS11KeyBackend(config)
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+	return backend.Import(key)
+}
+
+// pkcs11Transport is a transport key kept on a PKCS#11 token
+type pkcs11Transport struct {
+	backend       *PKCS11KeyBackend
+	pubKey        *CosignerTransportPubKey
+	encryptionKey pkcs11.ObjectHandle
+	signingKey    pkcs11.ObjectHandle
+}
+
+// PubKey returns the public transport key of the cosigner
+func (transport *pkcs11Transport) PubKey() (*CosignerTransportPubKey, error) {
+	return transport.pubKey, nil
+}
+
+// Open decrypts a ciphertext sealed to our public key, the shared secret is derived on the token
+func (transport *pkcs11Transport) Open(ciphertext []byte) ([]byte, error) {
+	return openSealed(ciphertext, transport.pubKey.EncryptionKey, transport.sharedSecret)
+}
+
+// sharedSecret derives the X25519 shared secret with the public key on the token
+// The derived secret is a session object, destroyed once read.
+func (transport *pkcs11Transport) sharedSecret(ephemeralPub []byte) ([]byte, error) {
+	backend := transport.backend
+	backend.mtx.Lock()
+	defer backend.mtx.Unlock()
+
+	params := pkcs11.NewECDH1DeriveParams(pkcs11.CKD_NULL, nil, ephemeralPub)
+	mechanism := []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_ECDH1_DERIVE, params)}
+	template := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_SECRET_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_GENERIC_SECRET),
+		pkcs11.NewAttribute(pkcs11.CKA_VALUE_LEN, 32),
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, false),
+		pkcs11.NewAttribute(pkcs11.CKA_SENSITIVE, false),
+		pkcs11.NewAttribute(pkcs11.CKA_EXTRACTABLE, true),
+	}
+
+	derived, err := backend.ctx.DeriveKey(backend.session, mechanism, transport.encryptionKey, template)
+	if err != nil {
+		return nil, err
+	}
+	defer backend.ctx.DestroyObject(backend.session, derived)
+
+	attributes, err := backend.ctx.GetAttributeValue(backend.session, derived, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_VALUE, nil),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return attributes[0].Value, nil
+}
+
+// Sign signs a digest with the Ed25519 key on the token
+func (transport *pkcs11Transport) Sign(digest []byte) ([]byte, error) {
+	backend := transport.backend
+	backend.mtx.Lock()
+	defer backend.mtx.Unlock()
+
+	mechanism := []*pkcs11.Mechanism{pkcs11.NewMechanism(ckmEdDSA, nil)}
+	if err := backend.ctx.SignInit(backend.session, mechanism, transport.signingKey); err != nil {
+		return nil, err
+	}
+	return backend.ctx.Sign(backend.session, digest)
+}
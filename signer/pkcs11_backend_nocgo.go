@@ -0,0 +1,20 @@
+//go:build !cgo
+// +build !cgo
+
+package signer
+
+import (
+	"errors"
+)
+
+var errPKCS11Unavailable = errors.New("PKCS#11 is not available, valink was built without cgo")
+
+// NewPKCS11KeyBackend fails, the PKCS#11 bindings need cgo
+func NewPKCS11KeyBackend(config PKCS11Config) (KeyBackend, error) {
+	return nil, errPKCS11Unavailable
+}
+
+// ImportPKCS11Key fails, the PKCS#11 bindings need cgo
+func ImportPKCS11Key(config PKCS11Config, key *CosignerKey) error {
+	return errPKCS11Unavailable
+}
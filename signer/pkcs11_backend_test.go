@@ -0,0 +1,145 @@
+//go:build cgo
+// +build cgo
+
+package signer
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/miekg/pkcs11"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	softHSMLibEnv      = "SOFTHSM2_LIB"
+	softHSMTokenLabel  = "valink-test"
+	softHSMUserPin     = "1234"
+	softHSMSecurityPin = "5678"
+)
+
+// newSoftHSMToken initializes a SoftHSM token in a temporary directory
+// The test is skipped unless SOFTHSM2_LIB is the path of the SoftHSM library, /usr/lib/softhsm/libsofthsm2.so for instance.
+func newSoftHSMToken(test *testing.T) (PKCS11Config, func()) {
+	library := os.Getenv(softHSMLibEnv)
+	if library == "" {
+		test.Skipf("%s is not set", softHSMLibEnv)
+	}
+
+	dir, err := ioutil.TempDir("", "softhsm")
+	require.NoError(test, err)
+	tokenDir := filepath.Join(dir, "tokens")
+	require.NoError(test, os.Mkdir(tokenDir, 0700))
+	conf := filepath.Join(dir, "softhsm2.conf")
+	require.NoError(test, ioutil.WriteFile(conf, []byte(fmt.Sprintf("directories.tokendir = %s\nobjectstore.backend = file\n", tokenDir)), 0600))
+	require.NoError(test, os.Setenv("SOFTHSM2_CONF", conf))
+
+	ctx := pkcs11.New(library)
+	require.NotNil(test, ctx)
+	require.NoError(test, ctx.Initialize())
+
+	slots, err := ctx.GetSlotList(false)
+	require.NoError(test, err)
+	require.NoError(test, ctx.InitToken(slots[0], softHSMSecurityPin, softHSMTokenLabel))
+
+	// the initialized token moves to a new slot
+	slots, err = ctx.GetSlotList(true)
+	require.NoError(test, err)
+	var slot uint
+	for _, candidate := range slots {
+		info, err := ctx.GetTokenInfo(candidate)
+		require.NoError(test, err)
+		if strings.TrimRight(info.Label, " \x00") == softHSMTokenLabel {
+			slot = candidate
+		}
+	}
+
+	session, err := ctx.OpenSession(slot, pkcs11.CKF_SERIAL_SESSION|pkcs11.CKF_RW_SESSION)
+	require.NoError(test, err)
+	require.NoError(test, ctx.Login(session, pkcs11.CKU_SO, softHSMSecurityPin))
+	require.NoError(test, ctx.InitPIN(session, softHSMUserPin))
+	require.NoError(test, ctx.Logout(session))
+	require.NoError(test, ctx.CloseSession(session))
+	require.NoError(test, ctx.Finalize())
+	ctx.Destroy()
+
+	require.NoError(test, os.Setenv(PKCS11PinEnv, softHSMUserPin))
+
+	config := PKCS11Config{
+		Library:    library,
+		TokenLabel: softHSMTokenLabel,
+		KeyLabel:   "cosigner-1",
+	}
+	cleanup := func() {
+		os.Unsetenv(PKCS11PinEnv)
+		os.Unsetenv("SOFTHSM2_CONF")
+		os.RemoveAll(dir)
+	}
+	return config, cleanup
+}
+
+func TestPKCS11KeyBackend(test *testing.T) {
+	config, cleanupToken := newSoftHSMToken(test)
+	defer cleanupToken()
+
+	_, cosigners, cleanup := newTestCosigners(test, 2, 3)
+	defer cleanup()
+
+	key := cosigners[0].key
+	for id := 1; id <= len(cosigners); id++ {
+		key.TransportPubKeys = append(key.TransportPubKeys, cosigners[0].peers[id].TransportPubKey)
+	}
+
+	require.NoError(test, ImportPKCS11Key(config, &key))
+	require.Error(test, ImportPKCS11Key(config, &key))
+
+	backend, err := NewPKCS11KeyBackend(config)
+	require.NoError(test, err)
+	defer backend.Close()
+
+	loaded, err := backend.Load()
+	require.NoError(test, err)
+	require.Equal(test, key.ShareKey, loaded.ShareKey)
+	require.Nil(test, loaded.TransportKey)
+	require.False(test, loaded.IsLegacy())
+
+	transport, err := backend.Transport(&loaded)
+	require.NoError(test, err)
+
+	// the token keys work like the transport key they were imported from
+	pubKey, err := key.TransportKey.PubKey()
+	require.NoError(test, err)
+	digest := []byte("digest")
+	signature, err := transport.Sign(digest)
+	require.NoError(test, err)
+	require.True(test, ed25519.Verify(pubKey.SigningKey, digest, signature))
+
+	sealed, err := pubKey.Seal([]byte("share part"))
+	require.NoError(test, err)
+	opened, err := transport.Open(sealed)
+	require.NoError(test, err)
+	require.Equal(test, []byte("share part"), opened)
+
+	// the share object is replaced when the share is refreshed
+	loaded.NextShare = &CosignerNextShare{
+		ShareKey:         cosigners[0].key.ShareKey,
+		SharePubKeys:     cosigners[0].key.SharePubKeys,
+		Commitments:      cosigners[0].key.Commitments,
+		ActivationHeight: 10,
+	}
+	require.NoError(test, backend.Save(&loaded))
+	reloaded, err := backend.Load()
+	require.NoError(test, err)
+	require.Equal(test, int64(10), reloaded.NextShare.ActivationHeight)
+
+	// a token holding the keys of another cosigner is refused
+	other := cosigners[1].key
+	other.TransportPubKeys = key.TransportPubKeys
+	_, err = backend.Transport(&other)
+	require.Error(test, err)
+}
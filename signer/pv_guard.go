@@ -1,6 +1,7 @@
 package signer
 
 import (
+	"errors"
 	"sync"
 
 	"github.com/tendermint/tendermint/crypto"
@@ -8,6 +9,12 @@ import (
 	tm "github.com/tendermint/tendermint/types"
 )
 
+// SourcePrivValidator is implemented by validators that record the node a sign request came from
+type SourcePrivValidator interface {
+	SignVoteFrom(source string, chainID string, vote *tmProto.Vote) error
+	SignProposalFrom(source string, chainID string, proposal *tmProto.Proposal) error
+}
+
 // PvGuard guards access to an underlying PrivValidator by using mutexes
 // for each of the PrivValidator interface functions
 type PvGuard struct {
@@ -35,3 +42,36 @@ func (pv *PvGuard) SignProposal(chainID string, proposal *tmProto.Proposal) erro
 	defer pv.pvMutex.Unlock()
 	return pv.PrivValidator.SignProposal(chainID, proposal)
 }
+
+// SignVoteFrom implements SourcePrivValidator
+// The source is dropped if the underlying PrivValidator doesn't record it.
+func (pv *PvGuard) SignVoteFrom(source string, chainID string, vote *tmProto.Vote) error {
+	pv.pvMutex.Lock()
+	defer pv.pvMutex.Unlock()
+	if sourcePv, ok := pv.PrivValidator.(SourcePrivValidator); ok {
+		return sourcePv.SignVoteFrom(source, chainID, vote)
+	}
+	return pv.PrivValidator.SignVote(chainID, vote)
+}
+
+// SignProposalFrom implements SourcePrivValidator
+// The source is dropped if the underlying PrivValidator doesn't record it.
+func (pv *PvGuard) SignProposalFrom(source string, chainID string, proposal *tmProto.Proposal) error {
+	pv.pvMutex.Lock()
+	defer pv.pvMutex.Unlock()
+	if sourcePv, ok := pv.PrivValidator.(SourcePrivValidator); ok {
+		return sourcePv.SignProposalFrom(source, chainID, proposal)
+	}
+	return pv.PrivValidator.SignProposal(chainID, proposal)
+}
+
+// SignVoteExtensionFrom implements ExtensionPrivValidator
+func (pv *PvGuard) SignVoteExtensionFrom(source string, chainID string, vote *tmProto.Vote, extension []byte) ([]byte, error) {
+	pv.pvMutex.Lock()
+	defer pv.pvMutex.Unlock()
+	extPv, ok := pv.PrivValidator.(ExtensionPrivValidator)
+	if !ok {
+		return nil, errors.New("the validator can't sign vote extensions")
+	}
+	return extPv.SignVoteExtensionFrom(source, chainID, vote, extension)
+}
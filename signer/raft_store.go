@@ -0,0 +1,163 @@
+package signer
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/hashicorp/raft"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	raftLogBucket    = []byte("logs")
+	raftStableBucket = []byte("stable")
+)
+
+// raftBoltStore keeps the raft log and the term and vote of the cosigner in an embedded database
+// Implements raft.LogStore and raft.StableStore.
+type raftBoltStore struct {
+	db *bolt.DB
+}
+
+// openRaftBoltStore opens the raft database, creating it if needed
+func openRaftBoltStore(file string) (*raftBoltStore, error) {
+	db, err := bolt.Open(file, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(raftLogBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(raftStableBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &raftBoltStore{db: db}, nil
+}
+
+// Close closes the raft database
+func (store *raftBoltStore) Close() error {
+	return store.db.Close()
+}
+
+// raftLogKey orders the log entries by index
+func raftLogKey(index uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, index)
+	return key
+}
+
+// FirstIndex implements raft.LogStore
+func (store *raftBoltStore) FirstIndex() (uint64, error) {
+	var index uint64
+	err := store.db.View(func(tx *bolt.Tx) error {
+		if key, _ := tx.Bucket(raftLogBucket).Cursor().First(); key != nil {
+			index = binary.BigEndian.Uint64(key)
+		}
+		return nil
+	})
+	return index, err
+}
+
+// LastIndex implements raft.LogStore
+func (store *raftBoltStore) LastIndex() (uint64, error) {
+	var index uint64
+	err := store.db.View(func(tx *bolt.Tx) error {
+		if key, _ := tx.Bucket(raftLogBucket).Cursor().Last(); key != nil {
+			index = binary.BigEndian.Uint64(key)
+		}
+		return nil
+	})
+	return index, err
+}
+
+// GetLog implements raft.LogStore
+func (store *raftBoltStore) GetLog(index uint64, log *raft.Log) error {
+	return store.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(raftLogBucket).Get(raftLogKey(index))
+		if value == nil {
+			return raft.ErrLogNotFound
+		}
+		return json.Unmarshal(value, log)
+	})
+}
+
+// StoreLog implements raft.LogStore
+func (store *raftBoltStore) StoreLog(log *raft.Log) error {
+	return store.StoreLogs([]*raft.Log{log})
+}
+
+// StoreLogs implements raft.LogStore
+func (store *raftBoltStore) StoreLogs(logs []*raft.Log) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(raftLogBucket)
+		for _, log := range logs {
+			value, err := json.Marshal(log)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put(raftLogKey(log.Index), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteRange implements raft.LogStore
+func (store *raftBoltStore) DeleteRange(min, max uint64) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(raftLogBucket)
+		for index := min; index <= max; index++ {
+			if err := bucket.Delete(raftLogKey(index)); err != nil {
+				return err
+			}
+			// max may be the largest index, which can't be incremented past
+			if index == max {
+				break
+			}
+		}
+		return nil
+	})
+}
+
+// Set implements raft.StableStore
+func (store *raftBoltStore) Set(key []byte, value []byte) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(raftStableBucket).Put(key, value)
+	})
+}
+
+// Get implements raft.StableStore
+func (store *raftBoltStore) Get(key []byte) ([]byte, error) {
+	var value []byte
+	err := store.db.View(func(tx *bolt.Tx) error {
+		// the value is only valid during the transaction
+		value = append([]byte{}, tx.Bucket(raftStableBucket).Get(key)...)
+		return nil
+	})
+	return value, err
+}
+
+// SetUint64 implements raft.StableStore
+func (store *raftBoltStore) SetUint64(key []byte, value uint64) error {
+	return store.Set(key, raftLogKey(value))
+}
+
+// GetUint64 implements raft.StableStore
+func (store *raftBoltStore) GetUint64(key []byte) (uint64, error) {
+	value, err := store.Get(key)
+	if err != nil || len(value) == 0 {
+		return 0, err
+	}
+	if len(value) != 8 {
+		return 0, errors.New("raft stable value is not a uint64")
+	}
+	return binary.BigEndian.Uint64(value), nil
+}
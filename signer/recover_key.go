@@ -0,0 +1,121 @@
+package signer
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math/big"
+
+	tmCrypto "github.com/tendermint/tendermint/crypto"
+	tmCryptoEd25519 "github.com/tendermint/tendermint/crypto/ed25519"
+	tmJson "github.com/tendermint/tendermint/libs/json"
+	"gitlab.com/polychainlabs/edwards25519"
+	tsed25519 "gitlab.com/polychainlabs/threshold-ed25519/pkg"
+)
+
+const PrivKeyEd25519ExpandedName = "valink/PrivKeyEd25519Expanded"
+
+func init() {
+	tmJson.RegisterType(PrivKeyEd25519Expanded{}, PrivKeyEd25519ExpandedName)
+}
+
+// PrivKeyEd25519Expanded is an ed25519 private key only known by its secret scalar, followed by its public key
+//
+// A key combined from threshold shares has no RFC 8032 seed, which the tendermint ed25519 keys
+// need to sign. Its signatures are regular ed25519 signatures for the public key.
+type PrivKeyEd25519Expanded []byte
+
+var _ tmCrypto.PrivKey = PrivKeyEd25519Expanded{}
+
+// Bytes returns the secret scalar followed by the public key
+func (privKey PrivKeyEd25519Expanded) Bytes() []byte {
+	return []byte(privKey)
+}
+
+// Sign produces an ed25519 signature of msg
+// The nonce is derived from the secret scalar and the message, like RFC 8032 derives it from the seed.
+func (privKey PrivKeyEd25519Expanded) Sign(msg []byte) ([]byte, error) {
+	if len(privKey) != 64 {
+		return nil, errors.New("Expanded ed25519 private keys must be 64 bytes")
+	}
+	scalar := privKey[:32]
+	pubKey := privKey[32:]
+
+	prefix := sha512.Sum512(append([]byte(PrivKeyEd25519ExpandedName), scalar...))
+
+	nonceHash := sha512.New()
+	nonceHash.Write(prefix[32:])
+	nonceHash.Write(msg)
+	var nonceDigest [64]byte
+	nonceHash.Sum(nonceDigest[:0])
+
+	var nonce [32]byte
+	edwards25519.ScReduce(&nonce, &nonceDigest)
+	ephemeralPublic := tsed25519.ScalarMultiplyBase(nonce[:])
+
+	s := tsed25519.SignWithShare(msg, scalar, nonce[:], pubKey, ephemeralPublic)
+
+	signature := make([]byte, 0, 64)
+	signature = append(signature, ephemeralPublic...)
+	return append(signature, s...), nil
+}
+
+// PubKey returns the public key
+func (privKey PrivKeyEd25519Expanded) PubKey() tmCrypto.PubKey {
+	return tmCryptoEd25519.PubKey(append([]byte(nil), privKey[32:]...))
+}
+
+// Equals compares the keys in constant time
+func (privKey PrivKeyEd25519Expanded) Equals(other tmCrypto.PrivKey) bool {
+	if otherKey, ok := other.(PrivKeyEd25519Expanded); ok {
+		return subtle.ConstantTimeCompare(privKey, otherKey) == 1
+	}
+	return false
+}
+
+// Type returns the key type
+func (privKey PrivKeyEd25519Expanded) Type() string {
+	return tmCryptoEd25519.KeyType
+}
+
+// RecoverPrivKey combines the shares of at least threshold cosigners into the validator private key
+// The shares must belong to the same validator and the combined key must match its public key.
+func RecoverPrivKey(keys []CosignerKey) (PrivKeyEd25519Expanded, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("No shares")
+	}
+
+	pubKey := keys[0].PubKey.Bytes()
+	seen := make(map[int]bool)
+	ids := make([]int, len(keys))
+	for idx, key := range keys {
+		if !bytes.Equal(key.PubKey.Bytes(), pubKey) {
+			return nil, fmt.Errorf("Share %d belongs to validator %X, not %X", key.ID, key.PubKey.Bytes(), pubKey)
+		}
+		if key.ID < 1 || seen[key.ID] {
+			return nil, fmt.Errorf("Unexpected share ID %d", key.ID)
+		}
+		if len(key.ShareKey) != 32 {
+			return nil, fmt.Errorf("Share %d must be 32 bytes", key.ID)
+		}
+		seen[key.ID] = true
+		ids[idx] = key.ID
+	}
+
+	secret := new(big.Int)
+	for _, key := range keys {
+		term := new(big.Int).Mul(scalarToBig(key.ShareKey), lagrangeCoefficient(key.ID, ids))
+		secret.Mod(secret.Add(secret, term), curveOrder)
+	}
+	scalar := bigToScalar(secret)
+
+	if !bytes.Equal(tsed25519.ScalarMultiplyBase(scalar), pubKey) {
+		return nil, errors.New("The shares don't combine into the validator key, are there at least threshold shares of the same generation?")
+	}
+
+	privKey := make(PrivKeyEd25519Expanded, 0, 64)
+	privKey = append(privKey, scalar...)
+	return append(privKey, pubKey...), nil
+}
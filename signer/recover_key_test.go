@@ -0,0 +1,90 @@
+package signer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	tmCryptoEd25519 "github.com/tendermint/tendermint/crypto/ed25519"
+	tmJson "github.com/tendermint/tendermint/libs/json"
+	"github.com/tendermint/tendermint/privval"
+	tmProto "github.com/tendermint/tendermint/proto/tendermint/types"
+	tm "github.com/tendermint/tendermint/types"
+)
+
+func TestRecoverPrivKey(test *testing.T) {
+	privateKey, cosigners, cleanup := newTestCosigners(test, 2, 3)
+	defer cleanup()
+
+	privKey, err := RecoverPrivKey([]CosignerKey{cosigners[2].key, cosigners[0].key})
+	require.NoError(test, err)
+	require.True(test, privKey.PubKey().Equals(privateKey.PubKey()))
+
+	message := []byte("hello world")
+	signature, err := privKey.Sign(message)
+	require.NoError(test, err)
+	require.True(test, privateKey.PubKey().VerifySignature(message, signature))
+
+	// the signatures are deterministic
+	again, err := privKey.Sign(message)
+	require.NoError(test, err)
+	require.Equal(test, signature, again)
+
+	// all the shares combine into the same key
+	all, err := RecoverPrivKey([]CosignerKey{cosigners[0].key, cosigners[1].key, cosigners[2].key})
+	require.NoError(test, err)
+	require.True(test, all.Equals(privKey))
+}
+
+func TestRecoverPrivKeyRejectsShares(test *testing.T) {
+	_, cosigners, cleanup := newTestCosigners(test, 2, 3)
+	defer cleanup()
+
+	// less than threshold shares
+	_, err := RecoverPrivKey([]CosignerKey{cosigners[0].key})
+	require.Error(test, err)
+
+	// the same share twice
+	_, err = RecoverPrivKey([]CosignerKey{cosigners[0].key, cosigners[0].key})
+	require.Error(test, err)
+
+	// shares of another validator
+	_, otherCosigners, otherCleanup := newTestCosigners(test, 2, 3)
+	defer otherCleanup()
+	_, err = RecoverPrivKey([]CosignerKey{cosigners[0].key, otherCosigners[1].key})
+	require.Error(test, err)
+}
+
+func TestRecoverPrivKeyFilePV(test *testing.T) {
+	privateKey, cosigners, cleanup := newTestCosigners(test, 2, 3)
+	defer cleanup()
+
+	privKey, err := RecoverPrivKey([]CosignerKey{cosigners[0].key, cosigners[1].key})
+	require.NoError(test, err)
+
+	dir, err := ioutil.TempDir("", "recover-key")
+	require.NoError(test, err)
+	defer os.RemoveAll(dir)
+
+	keyFile := filepath.Join(dir, "priv_validator_key.json")
+	jsonBytes, err := tmJson.MarshalIndent(privval.FilePVKey{
+		Address: privKey.PubKey().Address(),
+		PubKey:  privKey.PubKey(),
+		PrivKey: privKey,
+	}, "", "  ")
+	require.NoError(test, err)
+	require.NoError(test, ioutil.WriteFile(keyFile, jsonBytes, 0600))
+
+	filePV := privval.LoadFilePVEmptyState(keyFile, filepath.Join(dir, "priv_validator_state.json"))
+
+	vote := &tmProto.Vote{
+		Height: 1,
+		Type:   tmProto.PrevoteType,
+	}
+	require.NoError(test, filePV.SignVote("chain-id", vote))
+
+	pubKey := privateKey.PubKey().(tmCryptoEd25519.PubKey)
+	require.True(test, pubKey.VerifySignature(tm.VoteSignBytes("chain-id", vote), vote.Signature))
+}
@@ -2,31 +2,151 @@ package signer
 
 import (
 	"context"
-	"errors"
-	"fmt"
+	"crypto/tls"
+	"sync"
+	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
 )
 
-var (
-	ctx = context.Background()
+const (
+	// interval of the keepalive pings on idle cosigner connections
+	keepaliveTime = 10 * time.Second
+	// time to wait for a keepalive ack before considering the connection dead
+	keepaliveTimeout = 3 * time.Second
 )
 
 // RemoteCosigner uses tendermint rpc to request signing from a remote cosigner
+//
+// A single grpc connection is kept open to the remote cosigner and shared by all requests.
+// The connection is dialed on first use and re-established by grpc if it breaks.
 type RemoteCosigner struct {
-	id      int
-	address string
+	id int
+
+	// chain of the validator the requests are for, empty for the only chain of the remote cosigner
+	chainID string
+
+	*cosignerConn
+}
+
+// cosignerConn is the connection to a remote cosigner, shared by the chains it signs for
+type cosignerConn struct {
+	address   string
+	tlsConfig *tls.Config
+
+	// protects conn and client
+	connMutex sync.Mutex
+	conn      *grpc.ClientConn
+	client    CosignerServiceClient
 }
 
 // NewRemoteCosigner returns a newly initialized RemoteCosigner
 func NewRemoteCosigner(id int, address string) *RemoteCosigner {
 	cosigner := &RemoteCosigner{
-		id:      id,
-		address: address,
+		id:           id,
+		cosignerConn: &cosignerConn{address: address},
+	}
+	return cosigner
+}
+
+// ForChain returns a RemoteCosigner for the validator of chainID on the same remote cosigner
+// Both share the connection, closing either closes it.
+func (cosigner *RemoteCosigner) ForChain(chainID string) *RemoteCosigner {
+	return &RemoteCosigner{
+		id:           cosigner.id,
+		chainID:      chainID,
+		cosignerConn: cosigner.cosignerConn,
 	}
+}
+
+// NewRemoteCosignerWithTLS returns a RemoteCosigner which authenticates to the remote
+// cosigner, and verifies its identity, using the given mutual TLS config
+func NewRemoteCosignerWithTLS(id int, address string, tlsConfig *tls.Config) *RemoteCosigner {
+	cosigner := NewRemoteCosigner(id, address)
+	cosigner.tlsConfig = tlsConfig
 	return cosigner
 }
 
+func (cosigner *cosignerConn) dialOption() grpc.DialOption {
+	if cosigner.tlsConfig == nil {
+		return grpc.WithInsecure()
+	}
+	return grpc.WithTransportCredentials(credentials.NewTLS(cosigner.tlsConfig))
+}
+
+// getClient returns the client for the shared connection, dialing it if needed
+// grpc.Dial does not block, the connection is established in the background
+func (cosigner *cosignerConn) getClient() (CosignerServiceClient, error) {
+	cosigner.connMutex.Lock()
+	defer cosigner.connMutex.Unlock()
+
+	if cosigner.client != nil {
+		return cosigner.client, nil
+	}
+
+	conn, err := grpc.Dial(
+		cosigner.address,
+		cosigner.dialOption(),
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                keepaliveTime,
+			Timeout:             keepaliveTimeout,
+			PermitWithoutStream: true,
+		}),
+		grpc.WithConnectParams(grpc.ConnectParams{
+			Backoff: backoff.Config{
+				BaseDelay:  100 * time.Millisecond,
+				Multiplier: 1.6,
+				Jitter:     0.2,
+				MaxDelay:   3 * time.Second,
+			},
+			MinConnectTimeout: 3 * time.Second,
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	cosigner.conn = conn
+	cosigner.client = NewCosignerServiceClient(conn)
+	return cosigner.client, nil
+}
+
+// onError resets the reconnect backoff when the remote cosigner is unreachable
+// so that the next request dials again right away instead of waiting for the backoff
+func (cosigner *cosignerConn) onError(err error) {
+	if status.Code(err) != codes.Unavailable {
+		return
+	}
+
+	cosigner.connMutex.Lock()
+	defer cosigner.connMutex.Unlock()
+
+	if cosigner.conn != nil {
+		cosigner.conn.ResetConnectBackoff()
+	}
+}
+
+// Close closes the connection to the remote cosigner
+// A later request dials a new connection
+func (cosigner *cosignerConn) Close() error {
+	cosigner.connMutex.Lock()
+	defer cosigner.connMutex.Unlock()
+
+	if cosigner.conn == nil {
+		return nil
+	}
+
+	err := cosigner.conn.Close()
+	cosigner.conn = nil
+	cosigner.client = nil
+	return err
+}
+
 // GetID returns the ID of the remote cosigner
 // Implements the cosigner interface
 func (cosigner *RemoteCosigner) GetID() int {
@@ -35,50 +155,180 @@ func (cosigner *RemoteCosigner) GetID() int {
 
 // Sign the sign request using the cosigner's share
 // Return the signed bytes or an error
-func (cosigner *RemoteCosigner) Sign(signReq *CosignerSignRequest) (*CosignerSignResponse, error) {
-	//logger.Info("RemoteCosigner Sign", "RemoteCosignerID", cosigner.GetID())
-	var conn *grpc.ClientConn
-	conn, err := grpc.Dial(cosigner.address, grpc.WithInsecure())
+// The request is aborted when ctx is done, and its deadline is sent to the remote cosigner
+func (cosigner *RemoteCosigner) Sign(ctx context.Context, signReq *CosignerSignRequest) (*CosignerSignResponse, error) {
+	if signReq.ChainID == "" {
+		signReq.ChainID = cosigner.chainID
+	}
+
+	client, err := cosigner.getClient()
 	if err != nil {
-		fmt.Printf("could not connect: %s", err)
 		return &CosignerSignResponse{}, err
 	}
-	defer conn.Close()
 
-	c := NewCosignerServiceClient(conn)
-
-	response, err := c.Sign(context.Background(), signReq)
+	response, err := client.Sign(ctx, signReq)
 	if err != nil {
+		cosigner.onError(err)
 		return &CosignerSignResponse{}, err
 	}
 
 	return response, nil
 }
 
-func (cosigner *RemoteCosigner) GetEphemeralSecretPart(req *CosignerGetEphemeralSecretPartRequest) (*CosignerGetEphemeralSecretPartResponse, error) {
-	var conn *grpc.ClientConn
-	conn, err := grpc.Dial(cosigner.address, grpc.WithInsecure())
+// SignBlock has the remote cosigner sign a block for our node, if it is the leader
+// Implements BlockSigner
+func (cosigner *RemoteCosigner) SignBlock(ctx context.Context, req *CosignerSignBlockRequest) (*CosignerSignBlockResponse, error) {
+	if req.ChainID == "" {
+		req.ChainID = cosigner.chainID
+	}
+
+	client, err := cosigner.getClient()
 	if err != nil {
-		fmt.Printf("could not connect: %s", err)
-		return &CosignerGetEphemeralSecretPartResponse{}, err
+		return &CosignerSignBlockResponse{}, err
 	}
-	defer conn.Close()
 
-	c := NewCosignerServiceClient(conn)
+	response, err := client.SignBlock(ctx, req)
+	if err != nil {
+		cosigner.onError(err)
+		return &CosignerSignBlockResponse{}, err
+	}
 
-	response, err := c.GetEphemeralSecretPart(context.Background(), req)
+	return response, nil
+}
+
+func (cosigner *RemoteCosigner) GetEphemeralSecretPart(ctx context.Context, req *CosignerGetEphemeralSecretPartRequest) (*CosignerGetEphemeralSecretPartResponse, error) {
+	if req.ChainID == "" {
+		req.ChainID = cosigner.chainID
+	}
+
+	client, err := cosigner.getClient()
 	if err != nil {
 		return &CosignerGetEphemeralSecretPartResponse{}, err
 	}
 
+	response, err := client.GetEphemeralSecretPart(ctx, req)
+	if err != nil {
+		cosigner.onError(err)
+		return &CosignerGetEphemeralSecretPartResponse{}, err
+	}
+
 	return response, nil
 }
 
-func (cosigner *RemoteCosigner) HasEphemeralSecretPart(req CosignerHasEphemeralSecretPartRequest) (CosignerHasEphemeralSecretPartResponse, error) {
-	res := CosignerHasEphemeralSecretPartResponse{}
-	return res, errors.New("Not Implemented")
+func (cosigner *RemoteCosigner) HasEphemeralSecretPart(ctx context.Context, req *CosignerHasEphemeralSecretPartRequest) (*CosignerHasEphemeralSecretPartResponse, error) {
+	if req.ChainID == "" {
+		req.ChainID = cosigner.chainID
+	}
+
+	client, err := cosigner.getClient()
+	if err != nil {
+		return &CosignerHasEphemeralSecretPartResponse{}, err
+	}
+
+	response, err := client.HasEphemeralSecretPart(ctx, req)
+	if err != nil {
+		cosigner.onError(err)
+		return &CosignerHasEphemeralSecretPartResponse{}, err
+	}
+
+	return response, nil
+}
+
+func (cosigner *RemoteCosigner) SetEphemeralSecretPart(ctx context.Context, req *CosignerSetEphemeralSecretPartRequest) error {
+	if req.ChainID == "" {
+		req.ChainID = cosigner.chainID
+	}
+
+	client, err := cosigner.getClient()
+	if err != nil {
+		return err
+	}
+
+	_, err = client.SetEphemeralSecretPart(ctx, req)
+	if err != nil {
+		cosigner.onError(err)
+		return err
+	}
+
+	return nil
+}
+
+// RefreshDeal implements ShareRefresher
+func (cosigner *RemoteCosigner) RefreshDeal(ctx context.Context, req *CosignerRefreshDealRequest) (*CosignerRefreshDeal, error) {
+	if req.ChainID == "" {
+		req.ChainID = cosigner.chainID
+	}
+
+	client, err := cosigner.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := client.RefreshDeal(ctx, req)
+	if err != nil {
+		cosigner.onError(err)
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// RefreshPrepare implements ShareRefresher
+func (cosigner *RemoteCosigner) RefreshPrepare(ctx context.Context, req *CosignerRefreshPrepareRequest) (*CosignerRefreshPrepareResponse, error) {
+	if req.ChainID == "" {
+		req.ChainID = cosigner.chainID
+	}
+
+	client, err := cosigner.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := client.RefreshPrepare(ctx, req)
+	if err != nil {
+		cosigner.onError(err)
+		return nil, err
+	}
+
+	return response, nil
 }
 
-func (cosigner *RemoteCosigner) SetEphemeralSecretPart(req CosignerSetEphemeralSecretPartRequest) error {
-	return errors.New("Not Implemented")
+// RefreshCommit implements ShareRefresher
+func (cosigner *RemoteCosigner) RefreshCommit(ctx context.Context, req *CosignerRefreshCommitRequest) error {
+	if req.ChainID == "" {
+		req.ChainID = cosigner.chainID
+	}
+
+	client, err := cosigner.getClient()
+	if err != nil {
+		return err
+	}
+
+	_, err = client.RefreshCommit(ctx, req)
+	if err != nil {
+		cosigner.onError(err)
+		return err
+	}
+
+	return nil
+}
+
+// RefreshAbort implements ShareRefresher
+func (cosigner *RemoteCosigner) RefreshAbort(ctx context.Context, req *CosignerRefreshAbortRequest) error {
+	if req.ChainID == "" {
+		req.ChainID = cosigner.chainID
+	}
+
+	client, err := cosigner.getClient()
+	if err != nil {
+		return err
+	}
+
+	_, err = client.RefreshAbort(ctx, req)
+	if err != nil {
+		cosigner.onError(err)
+		return err
+	}
+
+	return nil
 }
@@ -4,10 +4,14 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	grpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type CosignerSeverMock struct {
@@ -28,6 +32,34 @@ func (csm *CosignerSeverMock) GetEphemeralSecretPart(ctx context.Context, req *C
 	return response, nil
 }
 
+func (csm *CosignerSeverMock) HasEphemeralSecretPart(ctx context.Context, req *CosignerHasEphemeralSecretPartRequest) (*CosignerHasEphemeralSecretPartResponse, error) {
+	return &CosignerHasEphemeralSecretPartResponse{Exists: true}, nil
+}
+
+func (csm *CosignerSeverMock) SetEphemeralSecretPart(ctx context.Context, req *CosignerSetEphemeralSecretPartRequest) (*CosignerSetEphemeralSecretPartResponse, error) {
+	return &CosignerSetEphemeralSecretPartResponse{}, nil
+}
+
+func (csm *CosignerSeverMock) RefreshDeal(ctx context.Context, req *CosignerRefreshDealRequest) (*CosignerRefreshDeal, error) {
+	return &CosignerRefreshDeal{RefreshID: req.RefreshID, SourceID: 1}, nil
+}
+
+func (csm *CosignerSeverMock) RefreshPrepare(ctx context.Context, req *CosignerRefreshPrepareRequest) (*CosignerRefreshPrepareResponse, error) {
+	return &CosignerRefreshPrepareResponse{}, nil
+}
+
+func (csm *CosignerSeverMock) RefreshCommit(ctx context.Context, req *CosignerRefreshCommitRequest) (*CosignerRefreshCommitResponse, error) {
+	return &CosignerRefreshCommitResponse{}, nil
+}
+
+func (csm *CosignerSeverMock) RefreshAbort(ctx context.Context, req *CosignerRefreshAbortRequest) (*CosignerRefreshAbortResponse, error) {
+	return &CosignerRefreshAbortResponse{}, nil
+}
+
+func (csm *CosignerSeverMock) SignBlock(ctx context.Context, req *CosignerSignBlockRequest) (*CosignerSignBlockResponse, error) {
+	return &CosignerSignBlockResponse{Signature: []byte("hello world")}, nil
+}
+
 func TestRemoteCosignerSign(test *testing.T) {
 	lis, err := net.Listen("tcp", "0.0.0.0:0")
 	require.NoError(test, err)
@@ -51,7 +83,7 @@ func TestRemoteCosignerSign(test *testing.T) {
 	port := lis.Addr().(*net.TCPAddr).Port
 	cosigner := NewRemoteCosigner(2, fmt.Sprintf("0.0.0.0:%d", port))
 
-	resp, err := cosigner.Sign(&CosignerSignRequest{})
+	resp, err := cosigner.Sign(context.Background(), &CosignerSignRequest{})
 	require.NoError(test, err)
 	require.Equal(test, resp.Signature, []byte("hello world"))
 }
@@ -79,7 +111,7 @@ func TestRemoteCosignerGetEphemeralSecretPart(test *testing.T) {
 	port := lis.Addr().(*net.TCPAddr).Port
 	cosigner := NewRemoteCosigner(2, fmt.Sprintf("0.0.0.0:%d", port))
 
-	resp, err := cosigner.GetEphemeralSecretPart(&CosignerGetEphemeralSecretPartRequest{})
+	resp, err := cosigner.GetEphemeralSecretPart(context.Background(), &CosignerGetEphemeralSecretPartRequest{})
 	require.NoError(test, err)
 
 	expectedRes := CosignerGetEphemeralSecretPartResponse{
@@ -93,3 +125,98 @@ func TestRemoteCosignerGetEphemeralSecretPart(test *testing.T) {
 	require.Equal(test, expectedRes.EncryptedSharePart, resp.EncryptedSharePart)
 
 }
+
+// countingListener counts the connections accepted by the wrapped listener
+type countingListener struct {
+	net.Listener
+	accepted int32
+}
+
+func (lis *countingListener) Accept() (net.Conn, error) {
+	conn, err := lis.Listener.Accept()
+	if err == nil {
+		atomic.AddInt32(&lis.accepted, 1)
+	}
+	return conn, err
+}
+
+func TestRemoteCosignerReusesConnection(test *testing.T) {
+	tcpLis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(test, err)
+	lis := &countingListener{Listener: tcpLis}
+
+	grpcServer := grpc.NewServer()
+	RegisterCosignerServiceServer(grpcServer, &CosignerSeverMock{})
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	cosigner := NewRemoteCosigner(2, lis.Addr().String())
+
+	for i := 0; i < 3; i++ {
+		_, err = cosigner.Sign(context.Background(), &CosignerSignRequest{})
+		require.NoError(test, err)
+		_, err = cosigner.GetEphemeralSecretPart(context.Background(), &CosignerGetEphemeralSecretPartRequest{})
+		require.NoError(test, err)
+	}
+	require.Equal(test, int32(1), atomic.LoadInt32(&lis.accepted))
+
+	// after closing, the next request dials a new connection
+	require.NoError(test, cosigner.Close())
+	_, err = cosigner.Sign(context.Background(), &CosignerSignRequest{})
+	require.NoError(test, err)
+	require.Equal(test, int32(2), atomic.LoadInt32(&lis.accepted))
+	require.NoError(test, cosigner.Close())
+}
+
+// hangingCosignerServer never answers Sign and GetEphemeralSecretPart
+// it reports when the caller cancels the request
+type hangingCosignerServer struct {
+	CosignerSeverMock
+	cancelled chan struct{}
+}
+
+func (hcs *hangingCosignerServer) Sign(ctx context.Context, req *CosignerSignRequest) (*CosignerSignResponse, error) {
+	<-ctx.Done()
+	hcs.cancelled <- struct{}{}
+	return nil, ctx.Err()
+}
+
+func (hcs *hangingCosignerServer) GetEphemeralSecretPart(ctx context.Context, req *CosignerGetEphemeralSecretPartRequest) (*CosignerGetEphemeralSecretPartResponse, error) {
+	<-ctx.Done()
+	hcs.cancelled <- struct{}{}
+	return nil, ctx.Err()
+}
+
+func startHangingCosignerServer(test *testing.T) (*hangingCosignerServer, string, func()) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(test, err)
+
+	hcs := &hangingCosignerServer{cancelled: make(chan struct{}, 1)}
+	grpcServer := grpc.NewServer()
+	RegisterCosignerServiceServer(grpcServer, hcs)
+	go grpcServer.Serve(lis)
+
+	return hcs, lis.Addr().String(), grpcServer.Stop
+}
+
+func TestRemoteCosignerSignDeadline(test *testing.T) {
+	hcs, address, stop := startHangingCosignerServer(test)
+	defer stop()
+
+	cosigner := NewRemoteCosigner(2, address)
+	defer cosigner.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	_, err := cosigner.Sign(ctx, &CosignerSignRequest{})
+	require.Error(test, err)
+	require.Equal(test, codes.DeadlineExceeded, status.Code(err))
+
+	// the deadline reaches the remote cosigner
+	select {
+	case <-hcs.cancelled:
+	case <-time.After(time.Second):
+		test.Fatal("remote request was not cancelled")
+	}
+}
@@ -1,15 +1,22 @@
 package signer
 
 import (
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"net"
+	"os"
 	"time"
 
+	tmCrypto "github.com/tendermint/tendermint/crypto"
 	tmCryptoEd2219 "github.com/tendermint/tendermint/crypto/ed25519"
 	tmCryptoEncoding "github.com/tendermint/tendermint/crypto/encoding"
+	tmJson "github.com/tendermint/tendermint/libs/json"
 	tmLog "github.com/tendermint/tendermint/libs/log"
 	tmNet "github.com/tendermint/tendermint/libs/net"
 	tmService "github.com/tendermint/tendermint/libs/service"
+	"github.com/tendermint/tendermint/libs/tempfile"
 	tmP2pConn "github.com/tendermint/tendermint/p2p/conn"
 	tmProtoCrypto "github.com/tendermint/tendermint/proto/tendermint/crypto"
 	tmProtoPrivval "github.com/tendermint/tendermint/proto/tendermint/privval"
@@ -22,10 +29,12 @@ import (
 type ReconnRemoteSigner struct {
 	tmService.BaseService
 
-	address string
-	chainID string
-	privKey tmCryptoEd2219.PrivKey
-	privVal tm.PrivValidator
+	address  string
+	chainID  string
+	privKey  tmCryptoEd2219.PrivKey
+	privVal  tm.PrivValidator
+	nodeKey  tmCrypto.PubKey
+	protocol string
 
 	dialer net.Dialer
 }
@@ -33,6 +42,8 @@ type ReconnRemoteSigner struct {
 // NewReconnRemoteSigner return a ReconnRemoteSigner that will dial using the given
 // dialer and respond to any signature requests over the connection
 // using the given privVal.
+// The SecretConnection is authenticated with privKey, and rejected unless the node presents
+// nodeKey. A nil nodeKey accepts any node. The messages are in the format of the privval protocol.
 //
 // If the connection is broken, the ReconnRemoteSigner will attempt to reconnect.
 func NewReconnRemoteSigner(
@@ -41,19 +52,90 @@ func NewReconnRemoteSigner(
 	chainID string,
 	privVal tm.PrivValidator,
 	dialer net.Dialer,
+	privKey tmCryptoEd2219.PrivKey,
+	nodeKey tmCrypto.PubKey,
+	protocol string,
 ) *ReconnRemoteSigner {
 	rs := &ReconnRemoteSigner{
-		address: address,
-		chainID: chainID,
-		privVal: privVal,
-		dialer:  dialer,
-		privKey: tmCryptoEd2219.GenPrivKey(),
+		address:  address,
+		chainID:  chainID,
+		privVal:  privVal,
+		dialer:   dialer,
+		privKey:  privKey,
+		nodeKey:  nodeKey,
+		protocol: protocol,
 	}
 
 	rs.BaseService = *tmService.NewBaseService(logger, "RemoteSigner", rs)
 	return rs
 }
 
+// NewNodeRemoteSigner returns the remote signer serving the node with privVal: a ReconnRemoteSigner
+// dialing the node, or a ListenRemoteSigner accepting its connections in listen mode.
+// The signer presents privKey on the SecretConnection and only serves the node keys of the config.
+func NewNodeRemoteSigner(
+	node NodeConfig,
+	privKey tmCryptoEd2219.PrivKey,
+	logger tmLog.Logger,
+	chainID string,
+	privVal tm.PrivValidator,
+) (tmService.Service, error) {
+	if err := node.Validate(); err != nil {
+		return nil, err
+	}
+	allowedKeys, err := node.allowedKeys()
+	if err != nil {
+		return nil, err
+	}
+	if node.Mode == NodeModeListen {
+		return NewListenRemoteSigner(node.Address, logger, chainID, privVal, privKey, allowedKeys, node.Protocol), nil
+	}
+
+	var nodeKey tmCrypto.PubKey
+	if len(allowedKeys) > 0 {
+		nodeKey = allowedKeys[0]
+	} else {
+		logger.Info("The node key is not pinned, set pub_key on the node to reject other keys", "address", node.Address)
+	}
+	dialer := net.Dialer{Timeout: 30 * time.Second}
+	return NewReconnRemoteSigner(node.Address, logger, chainID, privVal, dialer, privKey, nodeKey, node.Protocol), nil
+}
+
+// signerKeyFile is the JSON file of the signer identity key, in the format of a tendermint node_key.json
+type signerKeyFile struct {
+	PrivKey tmCrypto.PrivKey `json:"priv_key"`
+}
+
+// LoadOrGenSignerKey loads the key the signer presents on the SecretConnection to the nodes
+// The key is generated and saved if the file doesn't exist, so the signer keeps its identity across restarts.
+func LoadOrGenSignerKey(file string) (tmCryptoEd2219.PrivKey, error) {
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		privKey := tmCryptoEd2219.GenPrivKey()
+		jsonBytes, err := tmJson.Marshal(signerKeyFile{PrivKey: privKey})
+		if err != nil {
+			return nil, err
+		}
+		if err := tempfile.WriteFileAtomic(file, jsonBytes, 0600); err != nil {
+			return nil, err
+		}
+		return privKey, nil
+	}
+
+	jsonBytes, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	keyFile := signerKeyFile{}
+	if err := tmJson.Unmarshal(jsonBytes, &keyFile); err != nil {
+		return nil, fmt.Errorf("Error reading %v: %v", file, err)
+	}
+	privKey, ok := keyFile.PrivKey.(tmCryptoEd2219.PrivKey)
+	if !ok {
+		return nil, fmt.Errorf("%v does not hold an ed25519 key", file)
+	}
+	return privKey, nil
+}
+
 // OnStart implements cmn.Service.
 func (rs *ReconnRemoteSigner) OnStart() error {
 	go rs.loop()
@@ -84,14 +166,24 @@ func (rs *ReconnRemoteSigner) loop() {
 			}
 
 			rs.Logger.Info("Connected", "address", rs.address)
-			conn, err = tmP2pConn.MakeSecretConnection(netConn, rs.privKey)
+			secretConn, err := tmP2pConn.MakeSecretConnection(netConn, rs.privKey)
 			if err != nil {
-				conn = nil
+				netConn.Close()
 				rs.Logger.Error("Secret Conn", "err", err)
 				rs.Logger.Info("Retrying", "sleep (s)", 3, "address", rs.address)
 				time.Sleep(time.Second * 3)
 				continue
 			}
+
+			// whoever answers on the address must not be able to send sign requests
+			if remoteKey := secretConn.RemotePubKey(); rs.nodeKey != nil && !rs.nodeKey.Equals(remoteKey) {
+				secretConn.Close()
+				rs.Logger.Error("Rejected node with an unexpected key", "address", rs.address, "key", base64.StdEncoding.EncodeToString(remoteKey.Bytes()))
+				rs.Logger.Info("Retrying", "sleep (s)", 3, "address", rs.address)
+				time.Sleep(time.Second * 3)
+				continue
+			}
+			conn = secretConn
 		}
 
 		// since dialing can take time, we check running again
@@ -102,7 +194,7 @@ func (rs *ReconnRemoteSigner) loop() {
 			return
 		}
 
-		req, err := ReadMsg(conn)
+		req, ext, err := readRequest(conn, rs.protocol)
 		if err != nil {
 			rs.Logger.Error("readMsg", "err", err)
 			conn.Close()
@@ -110,13 +202,13 @@ func (rs *ReconnRemoteSigner) loop() {
 			continue
 		}
 
-		res, err := rs.handleRequest(req)
+		res, resExt, err := handleRequest(rs.Logger, rs.address, rs.chainID, rs.privVal, req, ext)
 		if err != nil {
 			// only log the error; we reply with an error in handleRequest since the reply needs to be typed based on error
 			rs.Logger.Error("handleRequest", "err", err)
 		}
 
-		err = WriteMsg(conn, res)
+		err = writeResponse(conn, rs.protocol, res, resExt)
 		if err != nil {
 			rs.Logger.Error("writeMsg", "err", err)
 			conn.Close()
@@ -126,15 +218,19 @@ func (rs *ReconnRemoteSigner) loop() {
 	}
 }
 
-func (rs *ReconnRemoteSigner) handleRequest(req tmProtoPrivval.Message) (tmProtoPrivval.Message, error) {
+// handleRequest answers a privval request of the node at address with privVal
+// The vote extension of a CometBFT 0.38 request is signed along with its vote and returned
+// with the response. It is nil for the protocols without vote extensions.
+func handleRequest(logger tmLog.Logger, address string, chainID string, privVal tm.PrivValidator, req tmProtoPrivval.Message, ext *VoteExtension) (tmProtoPrivval.Message, *VoteExtension, error) {
 	msg := tmProtoPrivval.Message{}
+	var resExt *VoteExtension
 	var err error
 
 	switch typedReq := req.Sum.(type) {
 	case *tmProtoPrivval.Message_PubKeyRequest:
-		pubKey, err := rs.privVal.GetPubKey()
+		pubKey, err := privVal.GetPubKey()
 		if err != nil {
-			rs.Logger.Error("Failed to get Pub Key", "address", rs.address, "error", err, "pubKey", typedReq)
+			logger.Error("Failed to get Pub Key", "address", address, "error", err, "pubKey", typedReq)
 			msg.Sum = &tmProtoPrivval.Message_PubKeyResponse{PubKeyResponse: &tmProtoPrivval.PubKeyResponse{
 				PubKey: tmProtoCrypto.PublicKey{},
 				Error: &tmProtoPrivval.RemoteSignerError{
@@ -145,7 +241,7 @@ func (rs *ReconnRemoteSigner) handleRequest(req tmProtoPrivval.Message) (tmProto
 		} else {
 			pk, err := tmCryptoEncoding.PubKeyToProto(pubKey)
 			if err != nil {
-				rs.Logger.Error("Failed to get Pub Key", "address", rs.address, "error", err, "pubKey", typedReq)
+				logger.Error("Failed to get Pub Key", "address", address, "error", err, "pubKey", typedReq)
 				msg.Sum = &tmProtoPrivval.Message_PubKeyResponse{PubKeyResponse: &tmProtoPrivval.PubKeyResponse{
 					PubKey: tmProtoCrypto.PublicKey{},
 					Error: &tmProtoPrivval.RemoteSignerError{
@@ -159,9 +255,22 @@ func (rs *ReconnRemoteSigner) handleRequest(req tmProtoPrivval.Message) (tmProto
 		}
 	case *tmProtoPrivval.Message_SignVoteRequest:
 		vote := typedReq.SignVoteRequest.Vote
-		err = rs.privVal.SignVote(rs.chainID, vote)
+		if sourcePv, ok := privVal.(SourcePrivValidator); ok {
+			err = sourcePv.SignVoteFrom(address, chainID, vote)
+		} else {
+			err = privVal.SignVote(chainID, vote)
+		}
+		if err == nil && ext != nil {
+			// the precommit is handed out even if its extension can't be signed, only the extension fails
+			var extErr error
+			resExt, extErr = signVoteExtension(address, chainID, privVal, vote, ext)
+			if extErr != nil {
+				logger.Error("Failed to sign vote extension", "address", address, "error", extErr, "vote", vote)
+				resExt = &VoteExtension{Extension: ext.Extension}
+			}
+		}
 		if err != nil {
-			rs.Logger.Error("Failed to sign vote", "address", rs.address, "error", err, "vote", vote)
+			logger.Error("Failed to sign vote", "address", address, "error", err, "vote", vote)
 			msg.Sum = &tmProtoPrivval.Message_SignedVoteResponse{SignedVoteResponse: &tmProtoPrivval.SignedVoteResponse{
 				Vote: tmProto.Vote{},
 				Error: &tmProtoPrivval.RemoteSignerError{
@@ -170,14 +279,18 @@ func (rs *ReconnRemoteSigner) handleRequest(req tmProtoPrivval.Message) (tmProto
 				},
 			}}
 		} else {
-			rs.Logger.Info("Signed vote", "node", rs.address, "height", vote.Height, "round", vote.Round, "step", VoteToStep(vote), "type", vote.Type)
+			logger.Info("Signed vote", "node", address, "height", vote.Height, "round", vote.Round, "step", VoteToStep(vote), "type", vote.Type)
 			msg.Sum = &tmProtoPrivval.Message_SignedVoteResponse{SignedVoteResponse: &tmProtoPrivval.SignedVoteResponse{Vote: *vote, Error: nil}}
 		}
 	case *tmProtoPrivval.Message_SignProposalRequest:
 		proposal := typedReq.SignProposalRequest.Proposal
-		err = rs.privVal.SignProposal(rs.chainID, typedReq.SignProposalRequest.Proposal)
+		if sourcePv, ok := privVal.(SourcePrivValidator); ok {
+			err = sourcePv.SignProposalFrom(address, chainID, proposal)
+		} else {
+			err = privVal.SignProposal(chainID, proposal)
+		}
 		if err != nil {
-			rs.Logger.Error("Failed to sign proposal", "address", rs.address, "error", err, "proposal", proposal)
+			logger.Error("Failed to sign proposal", "address", address, "error", err, "proposal", proposal)
 			msg.Sum = &tmProtoPrivval.Message_SignedProposalResponse{SignedProposalResponse: &tmProtoPrivval.SignedProposalResponse{
 				Proposal: tmProto.Proposal{},
 				Error: &tmProtoPrivval.RemoteSignerError{
@@ -186,7 +299,7 @@ func (rs *ReconnRemoteSigner) handleRequest(req tmProtoPrivval.Message) (tmProto
 				},
 			}}
 		} else {
-			rs.Logger.Info("Signed proposal", "node", rs.address, "height", proposal.Height, "round", proposal.Round, "step", ProposalToStep(proposal), "type", proposal.Type)
+			logger.Info("Signed proposal", "node", address, "height", proposal.Height, "round", proposal.Round, "step", ProposalToStep(proposal), "type", proposal.Type)
 			msg.Sum = &tmProtoPrivval.Message_SignedProposalResponse{SignedProposalResponse: &tmProtoPrivval.SignedProposalResponse{
 				Proposal: *proposal,
 				Error:    nil,
@@ -198,5 +311,25 @@ func (rs *ReconnRemoteSigner) handleRequest(req tmProtoPrivval.Message) (tmProto
 		err = fmt.Errorf("unknown msg: %v", typedReq)
 	}
 
-	return msg, err
+	return msg, resExt, err
+}
+
+// signVoteExtension signs the extension of a signed vote and returns it with its signature
+// Extensions are only signed for precommits for a block, unless the node asks to skip them.
+func signVoteExtension(address string, chainID string, privVal tm.PrivValidator, vote *tmProto.Vote, ext *VoteExtension) (*VoteExtension, error) {
+	resExt := &VoteExtension{Extension: ext.Extension}
+	if ext.SkipSigning || !VoteExtensionRequired(vote) {
+		return resExt, nil
+	}
+
+	extPv, ok := privVal.(ExtensionPrivValidator)
+	if !ok {
+		return nil, errors.New("the validator can't sign vote extensions")
+	}
+	signature, err := extPv.SignVoteExtensionFrom(address, chainID, vote, ext.Extension)
+	if err != nil {
+		return nil, err
+	}
+	resExt.Signature = signature
+	return resExt, nil
 }
@@ -0,0 +1,92 @@
+package signer
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	tmCryptoEd2219 "github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/libs/log"
+	tmP2pConn "github.com/tendermint/tendermint/p2p/conn"
+	tmProtoPrivval "github.com/tendermint/tendermint/proto/tendermint/privval"
+	tm "github.com/tendermint/tendermint/types"
+)
+
+// acceptRemoteSigner waits for the signer to dial the node listening on listener with nodeKey
+func acceptRemoteSigner(test *testing.T, listener net.Listener, nodeKey tmCryptoEd2219.PrivKey) *tmP2pConn.SecretConnection {
+	require.NoError(test, listener.(*net.TCPListener).SetDeadline(time.Now().Add(5*time.Second)))
+	netConn, err := listener.Accept()
+	require.NoError(test, err)
+	conn, err := tmP2pConn.MakeSecretConnection(netConn, nodeKey)
+	require.NoError(test, err)
+	return conn
+}
+
+func TestReconnRemoteSignerNodeKey(test *testing.T) {
+	signerKey := tmCryptoEd2219.GenPrivKey()
+	nodeKey := tmCryptoEd2219.GenPrivKey()
+	pubKeyRequest := tmProtoPrivval.Message{Sum: &tmProtoPrivval.Message_PubKeyRequest{
+		PubKeyRequest: &tmProtoPrivval.PubKeyRequest{ChainId: "chain-id"},
+	}}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(test, err)
+	defer listener.Close()
+
+	// the node with the pinned key is served and sees the signer identity
+	rs := NewReconnRemoteSigner("tcp://"+listener.Addr().String(), log.NewNopLogger(), "chain-id", tm.NewMockPV(),
+		net.Dialer{Timeout: time.Second}, signerKey, nodeKey.PubKey(), PrivvalProtocolV034)
+	require.NoError(test, rs.Start())
+	defer rs.Stop()
+
+	conn := acceptRemoteSigner(test, listener, nodeKey)
+	require.True(test, signerKey.PubKey().Equals(conn.RemotePubKey()))
+	require.NoError(test, WriteMsg(conn, pubKeyRequest))
+	res, err := ReadMsg(conn)
+	require.NoError(test, err)
+	require.NotNil(test, res.GetPubKeyResponse())
+	require.Nil(test, res.GetPubKeyResponse().Error)
+	conn.Close()
+
+	// whoever else answers on the address is dropped without an answer
+	otherListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(test, err)
+	defer otherListener.Close()
+
+	other := NewReconnRemoteSigner("tcp://"+otherListener.Addr().String(), log.NewNopLogger(), "chain-id", tm.NewMockPV(),
+		net.Dialer{Timeout: time.Second}, signerKey, nodeKey.PubKey(), PrivvalProtocolV034)
+	require.NoError(test, other.Start())
+	defer other.Stop()
+
+	otherConn := acceptRemoteSigner(test, otherListener, tmCryptoEd2219.GenPrivKey())
+	defer otherConn.Close()
+	require.NoError(test, otherConn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	WriteMsg(otherConn, pubKeyRequest)
+	_, err = ReadMsg(otherConn)
+	require.Error(test, err)
+}
+
+func TestLoadOrGenSignerKey(test *testing.T) {
+	dir, err := ioutil.TempDir("", "signer-key")
+	require.NoError(test, err)
+	defer os.RemoveAll(dir)
+
+	// the identity is generated once and kept across restarts
+	file := filepath.Join(dir, "signer_key.json")
+	key, err := LoadOrGenSignerKey(file)
+	require.NoError(test, err)
+	loaded, err := LoadOrGenSignerKey(file)
+	require.NoError(test, err)
+	require.Equal(test, key, loaded)
+
+	require.NoError(test, ioutil.WriteFile(file, []byte(`{"priv_key":`), 0600))
+	_, err = LoadOrGenSignerKey(file)
+	require.Error(test, err)
+
+	require.Equal(test, filepath.Join("state", "signer_key.json"), Config{PrivValStateDir: "state"}.SignerKeyPath())
+	require.Equal(test, "key.json", Config{PrivValStateDir: "state", SignerKeyFile: "key.json"}.SignerKeyPath())
+}
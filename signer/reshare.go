@@ -0,0 +1,279 @@
+package signer
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	tmCryptoEd25519 "github.com/tendermint/tendermint/crypto/ed25519"
+	tmJson "github.com/tendermint/tendermint/libs/json"
+	tsed25519 "gitlab.com/polychainlabs/threshold-ed25519/pkg"
+)
+
+// ReshareDeal is the contribution of one of the current cosigners to a resharing
+//
+// Resharing moves the validator key to a new set of cosigners with a new threshold and total.
+// Each dealer splits its share, weighted by its lagrange coefficient among the dealers, with a random
+// polynomial of degree threshold - 1 for the new cosigners. The new share of a cosigner is the sum of
+// the values it gets from every dealer, so the validator key is never assembled in one place.
+type ReshareDeal struct {
+	PubKey    []byte `json:"pub_key"`
+	Threshold uint8  `json:"threshold"`
+	Dealers   []int  `json:"dealers"`
+
+	// Transport public keys of the new cosigners, the new cosigner ID is the index + 1
+	TransportPubKeys []*CosignerTransportPubKey `json:"transport_pubs"`
+
+	// Public keys of the current cluster, used to authenticate the dealers
+	SourceID               int                        `json:"source_id"`
+	SourceSharePubKeys     [][]byte                   `json:"source_share_pubs"`
+	SourceTransportPubKeys []*CosignerTransportPubKey `json:"source_transport_pubs"`
+
+	// Feldman commitments of the coefficients 0 .. threshold - 1 and the value for every new cosigner
+	Commitments     [][]byte `json:"commitments"`
+	EncryptedShares [][]byte `json:"encrypted_shares"`
+
+	// Signature of the deal with the transport key of the dealer
+	Signature []byte `json:"signature"`
+}
+
+// digest returns the digest of the deal signed by the dealer
+func (deal *ReshareDeal) digest() ([]byte, error) {
+	unsigned := *deal
+	unsigned.Signature = nil
+
+	jsonBytes, err := tmJson.Marshal(&unsigned)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := sha256.Sum256(append([]byte("valink reshare deal"), jsonBytes...))
+	return digest[:], nil
+}
+
+func (deal *ReshareDeal) vssDeal() vssDeal {
+	return vssDeal{
+		sourceID:        deal.SourceID,
+		commitments:     deal.Commitments,
+		encryptedShares: deal.EncryptedShares,
+	}
+}
+
+// DealReshare deals the share of key to the new cosigners
+// dealers are the IDs of the current cosigners taking part, at least as many as the current threshold.
+func DealReshare(key *CosignerKey, dealers []int, threshold uint8, transportPubKeys []*CosignerTransportPubKey) (*ReshareDeal, error) {
+	if key.IsLegacy() || len(key.SharePubKeys) == 0 {
+		return nil, errors.New("Share files with RSA transport keys must be migrated with migrate-keys before resharing")
+	}
+	if key.TransportKey == nil {
+		return nil, errors.New("The share file has no private transport key")
+	}
+	if key.NextShare != nil {
+		return nil, fmt.Errorf("A refreshed share is waiting for activation at height %d", key.NextShare.ActivationHeight)
+	}
+
+	dealers, err := validateReshareDealers(dealers, len(key.TransportPubKeys))
+	if err != nil {
+		return nil, err
+	}
+	if !containsID(dealers, key.ID) {
+		return nil, fmt.Errorf("Cosigner %d isn't one of the dealers", key.ID)
+	}
+
+	total := len(transportPubKeys)
+	if threshold < 2 || total > 255 || int(threshold) > total {
+		return nil, fmt.Errorf("Invalid threshold %d for %d cosigners", threshold, total)
+	}
+	if err := validateTransportPubKeys(transportPubKeys); err != nil {
+		return nil, err
+	}
+
+	// the weighted shares of the dealers add up to the validator secret
+	weighted := new(big.Int).Mul(scalarToBig(key.ShareKey), lagrangeCoefficient(key.ID, dealers))
+	coeffs, shares, err := dealPolynomial(weighted, threshold, uint8(total))
+	if err != nil {
+		return nil, err
+	}
+
+	deal := &ReshareDeal{
+		PubKey:                 key.PubKey.Bytes(),
+		Threshold:              threshold,
+		Dealers:                dealers,
+		TransportPubKeys:       transportPubKeys,
+		SourceID:               key.ID,
+		SourceSharePubKeys:     key.SharePubKeys,
+		SourceTransportPubKeys: key.TransportPubKeys,
+		Commitments:            feldmanCommitments(coeffs),
+		EncryptedShares:        make([][]byte, total),
+	}
+
+	for idx, share := range shares {
+		deal.EncryptedShares[idx], err = transportPubKeys[idx].Seal(share)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	digest, err := deal.digest()
+	if err != nil {
+		return nil, err
+	}
+	deal.Signature = ed25519.Sign(key.TransportKey.SigningKey, digest)
+	return deal, nil
+}
+
+// VerifyReshare checks that the deals of all the dealers are consistent and add up to the validator key
+// It only needs public data and returns the public keys of the new shares.
+func VerifyReshare(deals []*ReshareDeal) ([][]byte, error) {
+	_, sharePubKeys, err := verifyReshare(deals)
+	return sharePubKeys, err
+}
+
+// verifyReshare returns the commitments of the new polynomial and the public keys of the new shares
+func verifyReshare(deals []*ReshareDeal) ([][]byte, [][]byte, error) {
+	if len(deals) == 0 {
+		return nil, nil, errors.New("No deals")
+	}
+
+	first := deals[0]
+	if len(first.PubKey) != tmCryptoEd25519.PubKeySize {
+		return nil, nil, errors.New("Invalid validator public key")
+	}
+	dealers, err := validateReshareDealers(first.Dealers, len(first.SourceTransportPubKeys))
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := validateTransportPubKeys(first.SourceTransportPubKeys); err != nil {
+		return nil, nil, err
+	}
+	if err := validateTransportPubKeys(first.TransportPubKeys); err != nil {
+		return nil, nil, err
+	}
+	if len(first.SourceSharePubKeys) != len(first.SourceTransportPubKeys) {
+		return nil, nil, errors.New("Missing share public keys of the current cosigners")
+	}
+	if len(deals) != len(dealers) {
+		return nil, nil, fmt.Errorf("Expected the deals of cosigners %v, got %d deals", dealers, len(deals))
+	}
+
+	total := len(first.TransportPubKeys)
+	threshold := int(first.Threshold)
+	if threshold < 2 || threshold > total {
+		return nil, nil, fmt.Errorf("Invalid threshold %d for %d cosigners", threshold, total)
+	}
+
+	seen := make(map[int]bool)
+	constantTerms := make([]tsed25519.Element, 0, len(deals))
+	vssDeals := make([]vssDeal, 0, len(deals))
+	for _, deal := range deals {
+		// every dealer must agree on the validator key, the new cosigners and the current ones
+		if !bytes.Equal(deal.PubKey, first.PubKey) || deal.Threshold != first.Threshold ||
+			!equalIDs(deal.Dealers, dealers) ||
+			!equalByteSlices(deal.SourceSharePubKeys, first.SourceSharePubKeys) ||
+			!equalTransportPubKeys(deal.SourceTransportPubKeys, first.SourceTransportPubKeys) ||
+			!equalTransportPubKeys(deal.TransportPubKeys, first.TransportPubKeys) {
+			return nil, nil, fmt.Errorf("Deal of cosigner %d is for another resharing", deal.SourceID)
+		}
+
+		if !containsID(dealers, deal.SourceID) || seen[deal.SourceID] {
+			return nil, nil, fmt.Errorf("Unexpected deal of cosigner %d", deal.SourceID)
+		}
+		seen[deal.SourceID] = true
+
+		if len(deal.Commitments) == 0 {
+			return nil, nil, fmt.Errorf("Deal of cosigner %d has no commitments", deal.SourceID)
+		}
+
+		digest, err := deal.digest()
+		if err != nil {
+			return nil, nil, err
+		}
+		if !ed25519.Verify(deal.SourceTransportPubKeys[deal.SourceID-1].SigningKey, digest, deal.Signature) {
+			return nil, nil, fmt.Errorf("Deal of cosigner %d has an invalid signature", deal.SourceID)
+		}
+
+		// the dealer must have split its own weighted share
+		expected, err := scalarMultElement(
+			bigToScalar(lagrangeCoefficient(deal.SourceID, dealers)),
+			deal.SourceSharePubKeys[deal.SourceID-1],
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !bytes.Equal(deal.Commitments[0], expected) {
+			return nil, nil, fmt.Errorf("Deal of cosigner %d doesn't split its share", deal.SourceID)
+		}
+		constantTerms = append(constantTerms, deal.Commitments[0])
+		vssDeals = append(vssDeals, deal.vssDeal())
+	}
+
+	if !bytes.Equal(tsed25519.AddElements(constantTerms), first.PubKey) {
+		return nil, nil, errors.New("The deals don't add up to the validator public key, are enough dealers taking part?")
+	}
+
+	commitments, sharePubKeys, err := combineVSSDeals(vssDeals, threshold, total)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := verifySharePubKeys(first.PubKey, threshold, sharePubKeys); err != nil {
+		return nil, nil, err
+	}
+	return commitments, sharePubKeys, nil
+}
+
+// CombineReshare verifies the deals and adds up the new share of cosigner id
+func CombineReshare(deals []*ReshareDeal, id int, transportKey *CosignerTransportKey) (*CosignerKey, error) {
+	commitments, sharePubKeys, err := verifyReshare(deals)
+	if err != nil {
+		return nil, err
+	}
+
+	vssDeals := make([]vssDeal, len(deals))
+	for idx, deal := range deals {
+		vssDeals[idx] = deal.vssDeal()
+	}
+
+	first := deals[0]
+	share, err := openVSSDeals(vssDeals, id, transportKey, first.TransportPubKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(SharePubKey(share), sharePubKeys[id-1]) {
+		return nil, errors.New("The new share doesn't match its public key")
+	}
+
+	return &CosignerKey{
+		PubKey:           tmCryptoEd25519.PubKey(first.PubKey),
+		ShareKey:         share,
+		ID:               id,
+		SharePubKeys:     sharePubKeys,
+		Commitments:      commitments,
+		TransportKey:     transportKey,
+		TransportPubKeys: first.TransportPubKeys,
+	}, nil
+}
+
+// validateReshareDealers returns the sorted dealer IDs, which must be distinct cosigners of the current cluster
+func validateReshareDealers(dealers []int, total int) ([]int, error) {
+	if len(dealers) == 0 {
+		return nil, errors.New("No dealers")
+	}
+
+	sorted := append([]int(nil), dealers...)
+	sort.Ints(sorted)
+	for idx, id := range sorted {
+		if id < 1 || id > total {
+			return nil, fmt.Errorf("Unexpected dealer ID %d", id)
+		}
+		if idx > 0 && sorted[idx-1] == id {
+			return nil, fmt.Errorf("Duplicate dealer ID %d", id)
+		}
+	}
+	return sorted, nil
+}
@@ -0,0 +1,128 @@
+package signer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	tsed25519 "gitlab.com/polychainlabs/threshold-ed25519/pkg"
+)
+
+// newReshareTestKeys returns the keys of a 2 of 3 cluster and the transport keys of 5 new cosigners
+func newReshareTestKeys(test *testing.T) ([]*CosignerKey, []*CosignerTransportKey, []*CosignerTransportPubKey, func()) {
+	_, cosigners, cleanup := newTestCosigners(test, 2, 3)
+
+	transportPubKeys := make([]*CosignerTransportPubKey, len(cosigners))
+	for idx, cosigner := range cosigners {
+		pub, err := cosigner.key.TransportKey.PubKey()
+		require.NoError(test, err)
+		transportPubKeys[idx] = pub
+	}
+
+	keys := make([]*CosignerKey, len(cosigners))
+	for idx, cosigner := range cosigners {
+		key := cosigner.key
+		key.TransportPubKeys = transportPubKeys
+		keys[idx] = &key
+	}
+
+	newTransportKeys := make([]*CosignerTransportKey, 5)
+	newTransportPubKeys := make([]*CosignerTransportPubKey, 5)
+	for idx := range newTransportKeys {
+		key, err := GenerateCosignerTransportKey()
+		require.NoError(test, err)
+		pub, err := key.PubKey()
+		require.NoError(test, err)
+		newTransportKeys[idx] = key
+		newTransportPubKeys[idx] = pub
+	}
+
+	return keys, newTransportKeys, newTransportPubKeys, cleanup
+}
+
+func TestReshare(test *testing.T) {
+	keys, newTransportKeys, newTransportPubKeys, cleanup := newReshareTestKeys(test)
+	defer cleanup()
+
+	dealers := []int{3, 1}
+	deals := make([]*ReshareDeal, 0, len(dealers))
+	for _, id := range dealers {
+		deal, err := DealReshare(keys[id-1], dealers, 3, newTransportPubKeys)
+		require.NoError(test, err)
+		deals = append(deals, deal)
+	}
+
+	sharePubKeys, err := VerifyReshare(deals)
+	require.NoError(test, err)
+	require.Len(test, sharePubKeys, 5)
+
+	newKeys := make([]*CosignerKey, len(newTransportKeys))
+	for idx, transportKey := range newTransportKeys {
+		newKeys[idx], err = CombineReshare(deals, idx+1, transportKey)
+		require.NoError(test, err)
+		require.Equal(test, idx+1, newKeys[idx].ID)
+		require.Equal(test, keys[0].PubKey, newKeys[idx].PubKey)
+		require.Equal(test, sharePubKeys, newKeys[idx].SharePubKeys)
+		require.NoError(test, newKeys[idx].Validate())
+	}
+
+	// any 3 of the new shares combine into the validator key
+	for _, ids := range [][]int{{1, 2, 3}, {2, 4, 5}, {1, 3, 5}} {
+		shares := make([][]byte, len(ids))
+		for idx, id := range ids {
+			shares[idx] = newKeys[id-1].ShareKey
+		}
+		secret := tsed25519.CombineShares(5, ids, shares)
+		require.Equal(test, keys[0].PubKey.Bytes(), []byte(tsed25519.ScalarMultiplyBase(secret)))
+	}
+
+	// 2 of the new shares don't
+	secret := tsed25519.CombineShares(5, []int{1, 2}, [][]byte{newKeys[0].ShareKey, newKeys[1].ShareKey})
+	require.NotEqual(test, keys[0].PubKey.Bytes(), []byte(tsed25519.ScalarMultiplyBase(secret)))
+
+	// the transport key of another cosigner can't combine our share
+	_, err = CombineReshare(deals, 1, newTransportKeys[1])
+	require.Error(test, err)
+}
+
+func TestReshareRejectsBadDeals(test *testing.T) {
+	keys, _, newTransportPubKeys, cleanup := newReshareTestKeys(test)
+	defer cleanup()
+
+	// a single dealer of a 2 of 3 cluster doesn't hold the validator key
+	deal, err := DealReshare(keys[0], []int{1}, 3, newTransportPubKeys)
+	require.NoError(test, err)
+	_, err = VerifyReshare([]*ReshareDeal{deal})
+	require.Error(test, err)
+
+	dealers := []int{1, 2}
+	deals := make([]*ReshareDeal, len(dealers))
+	for idx, id := range dealers {
+		deals[idx], err = DealReshare(keys[id-1], dealers, 3, newTransportPubKeys)
+		require.NoError(test, err)
+	}
+	_, err = VerifyReshare(deals)
+	require.NoError(test, err)
+
+	// missing deal
+	_, err = VerifyReshare(deals[:1])
+	require.Error(test, err)
+
+	// the same deal twice
+	_, err = VerifyReshare([]*ReshareDeal{deals[0], deals[0]})
+	require.Error(test, err)
+
+	// tampered deal
+	tampered := *deals[1]
+	tampered.EncryptedShares = [][]byte{deals[1].EncryptedShares[1], deals[1].EncryptedShares[0],
+		deals[1].EncryptedShares[2], deals[1].EncryptedShares[3], deals[1].EncryptedShares[4]}
+	_, err = VerifyReshare([]*ReshareDeal{deals[0], &tampered})
+	require.Error(test, err)
+
+	// a dealer that isn't one of the current cosigners
+	_, err = DealReshare(keys[0], []int{1, 4}, 3, newTransportPubKeys)
+	require.Error(test, err)
+
+	// the threshold can't exceed the new cosigners
+	_, err = DealReshare(keys[0], dealers, 6, newTransportPubKeys)
+	require.Error(test, err)
+}
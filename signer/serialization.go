@@ -2,16 +2,29 @@ package signer
 
 import (
 	"errors"
+	"fmt"
 	"io"
 
 	"github.com/tendermint/tendermint/libs/protoio"
 	tmProtoPrivval "github.com/tendermint/tendermint/proto/tendermint/privval"
 	tmProto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"google.golang.org/protobuf/encoding/protowire"
 )
 
+// Versions of the privval protocol spoken with a node
+const (
+	// Tendermint 0.34, the default
+	PrivvalProtocolV034 = "v0.34"
+	// CometBFT 0.37, the same messages as 0.34
+	PrivvalProtocolV037 = "v0.37"
+	// CometBFT 0.38, with the vote extensions of precommits
+	PrivvalProtocolV038 = "v0.38"
+)
+
+const maxRemoteSignerMsgSize = 1024 * 10
+
 // ReadMsg reads a message from an io.Reader
 func ReadMsg(reader io.Reader) (msg tmProtoPrivval.Message, err error) {
-	const maxRemoteSignerMsgSize = 1024 * 10
 	protoReader := protoio.NewDelimitedReader(reader, maxRemoteSignerMsgSize)
 	_, err = protoReader.ReadMsg(&msg)
 	return msg, err
@@ -24,8 +37,96 @@ func WriteMsg(writer io.Writer, msg tmProtoPrivval.Message) (err error) {
 	return err
 }
 
+// ReadMsgV038 reads a CometBFT 0.38 message from an io.Reader
+// The message is decoded with the v0.34 protos, which skip the vote extension returned next to it.
+func ReadMsgV038(reader io.Reader) (msg tmProtoPrivval.Message, ext *VoteExtension, err error) {
+	length, err := readUvarint(reader)
+	if err != nil {
+		return msg, nil, err
+	}
+	if length > maxRemoteSignerMsgSize {
+		return msg, nil, fmt.Errorf("message of %d bytes exceeds the maximum of %d", length, maxRemoteSignerMsgSize)
+	}
+	message := make([]byte, length)
+	if _, err := io.ReadFull(reader, message); err != nil {
+		return msg, nil, err
+	}
+
+	if err := msg.Unmarshal(message); err != nil {
+		return msg, nil, err
+	}
+	ext, err = voteExtensionOfRequest(message)
+	return msg, ext, err
+}
+
+// WriteMsgV038 writes a CometBFT 0.38 message to an io.Writer
+// The vote extension is added to a signed vote response, it is ignored for any other message.
+func WriteMsgV038(writer io.Writer, msg tmProtoPrivval.Message, ext *VoteExtension) (err error) {
+	var message []byte
+	if response := msg.GetSignedVoteResponse(); response != nil && ext != nil {
+		var remoteErr []byte
+		if response.Error != nil {
+			if remoteErr, err = response.Error.Marshal(); err != nil {
+				return err
+			}
+		}
+		message, err = marshalSignedVoteResponseV038(&response.Vote, remoteErr, ext)
+	} else {
+		message, err = msg.Marshal()
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = writer.Write(protowire.AppendBytes(nil, message))
+	return err
+}
+
+// readUvarint reads the length prefix of a message one byte at a time, so nothing past it is consumed
+func readUvarint(reader io.Reader) (uint64, error) {
+	var prefix []byte
+	b := make([]byte, 1)
+	for {
+		if _, err := io.ReadFull(reader, b); err != nil {
+			return 0, err
+		}
+		prefix = append(prefix, b[0])
+		if b[0] < 0x80 {
+			break
+		}
+	}
+	v, n := protowire.ConsumeVarint(prefix)
+	if n < 0 {
+		return 0, protowire.ParseError(n)
+	}
+	return v, nil
+}
+
+// readRequest reads a privval request in the format of the protocol spoken with the node
+// Only CometBFT 0.38 requests carry a vote extension.
+func readRequest(reader io.Reader, protocol string) (tmProtoPrivval.Message, *VoteExtension, error) {
+	if protocol == PrivvalProtocolV038 {
+		return ReadMsgV038(reader)
+	}
+	msg, err := ReadMsg(reader)
+	return msg, nil, err
+}
+
+// writeResponse writes a privval response in the format of the protocol spoken with the node
+func writeResponse(writer io.Writer, protocol string, msg tmProtoPrivval.Message, ext *VoteExtension) error {
+	if protocol == PrivvalProtocolV038 {
+		return WriteMsgV038(writer, msg, ext)
+	}
+	return WriteMsg(writer, msg)
+}
+
 // UnpackHRS deserializes sign bytes and gets the height, round, and step
 func UnpackHRS(signBytes []byte) (height int64, round int64, step int8, err error) {
+	// tried first, the sign bytes of a vote extension could otherwise pass for a vote
+	if height, round, err := unpackVoteExtensionHRS(signBytes); err == nil {
+		return height, round, stepVoteExtension, nil
+	}
+
 	{
 		var proposal tmProto.CanonicalProposal
 		if err := protoio.UnmarshalDelimited(signBytes, &proposal); err == nil {
@@ -42,3 +143,30 @@ func UnpackHRS(signBytes []byte) (height int64, round int64, step int8, err erro
 
 	return 0, 0, 0, errors.New("Could not UnpackHRS from sign bytes")
 }
+
+// UnpackChainID deserializes sign bytes and gets the chain ID they are signed for
+func UnpackChainID(signBytes []byte) (string, error) {
+	_, _, step, err := UnpackHRS(signBytes)
+	if err != nil {
+		return "", err
+	}
+
+	switch step {
+	case stepVoteExtension:
+		canonical, _ := protowire.ConsumeBytes(signBytes)
+		chainID, err := protoBytesField(canonical, canonicalChainIDField)
+		return string(chainID), err
+	case stepPropose:
+		var proposal tmProto.CanonicalProposal
+		if err := protoio.UnmarshalDelimited(signBytes, &proposal); err != nil {
+			return "", err
+		}
+		return proposal.ChainID, nil
+	default:
+		var vote tmProto.CanonicalVote
+		if err := protoio.UnmarshalDelimited(signBytes, &vote); err != nil {
+			return "", err
+		}
+		return vote.ChainID, nil
+	}
+}
@@ -0,0 +1,447 @@
+package signer
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	tmJson "github.com/tendermint/tendermint/libs/json"
+	tmlog "github.com/tendermint/tendermint/libs/log"
+	tsed25519 "gitlab.com/polychainlabs/threshold-ed25519/pkg"
+)
+
+// ShareRefresher is implemented by the cosigners taking part in a proactive share refresh
+//
+// Every cosigner deals shares of zero to all the cosigners. Adding them to the current shares
+// re-randomizes the shares without changing the secret they combine into, so shares stolen
+// before the refresh are useless with the shares stolen after it.
+type ShareRefresher interface {
+	// GetID returns the ID of the cosigner
+	GetID() int
+
+	// Deal shares of zero to every cosigner for the refresh
+	RefreshDeal(ctx context.Context, req *CosignerRefreshDealRequest) (*CosignerRefreshDeal, error)
+
+	// Verify the deals of all the cosigners and compute the refreshed share
+	RefreshPrepare(ctx context.Context, req *CosignerRefreshPrepareRequest) (*CosignerRefreshPrepareResponse, error)
+
+	// Save the refreshed share, it is used from the activation height on
+	RefreshCommit(ctx context.Context, req *CosignerRefreshCommitRequest) error
+
+	// Drop the refresh
+	RefreshAbort(ctx context.Context, req *CosignerRefreshAbortRequest) error
+}
+
+// shareRefresh is the state of a cosigner during a refresh
+type shareRefresh struct {
+	id   string
+	deal *CosignerRefreshDeal
+
+	// set once the deals of all the cosigners are verified
+	prepared *CosignerNextShare
+}
+
+// refreshDealDigest returns the digest of a deal signed by its dealer
+// The validator public key is bound into it so a deal can't be replayed to the cosigners of another key.
+func refreshDealDigest(pubKey []byte, deal *CosignerRefreshDeal) ([]byte, error) {
+	jsonBytes, err := tmJson.Marshal(&struct {
+		PubKey          []byte
+		RefreshID       string
+		SourceID        int32
+		Commitments     [][]byte
+		EncryptedShares [][]byte
+	}{
+		PubKey:          pubKey,
+		RefreshID:       deal.RefreshID,
+		SourceID:        deal.SourceID,
+		Commitments:     deal.Commitments,
+		EncryptedShares: deal.EncryptedShares,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	digest := sha256.Sum256(jsonBytes)
+	return digest[:], nil
+}
+
+// identityElement is the neutral element of the curve, the commitment of a zero secret
+var identityElement = tsed25519.ScalarMultiplyBase(make([]byte, 32))
+
+// RefreshDeal deals shares of zero to every cosigner, encrypted for each of them
+// Dealing again for the same refresh returns the same deal.
+func (cosigner *LocalCosigner) RefreshDeal(ctx context.Context, req *CosignerRefreshDealRequest) (*CosignerRefreshDeal, error) {
+	cosigner.lastSignStateMutex.Lock()
+	defer cosigner.lastSignStateMutex.Unlock()
+
+	if req.RefreshID == "" {
+		return nil, errors.New("Refresh ID is required")
+	}
+
+	if cosigner.refresh != nil && cosigner.refresh.id == req.RefreshID {
+		return cosigner.refresh.deal, nil
+	}
+
+	if next := cosigner.key.NextShare; next != nil {
+		return nil, fmt.Errorf("A refreshed share is already waiting for activation at height %d", next.ActivationHeight)
+	}
+
+	// refuse early, a refresh can't be prepared without the share public keys
+	if _, err := cosigner.refreshSharePubKeys(); err != nil {
+		return nil, err
+	}
+
+	coeffs, shares, err := dealPolynomial(big.NewInt(0), cosigner.threshold, cosigner.total)
+	if err != nil {
+		return nil, err
+	}
+
+	deal := &CosignerRefreshDeal{
+		RefreshID:       req.RefreshID,
+		SourceID:        int32(cosigner.key.ID),
+		Commitments:     feldmanCommitments(coeffs),
+		EncryptedShares: make([][]byte, len(shares)),
+	}
+
+	for idx, share := range shares {
+		peer, ok := cosigner.peers[idx+1]
+		if !ok {
+			return nil, fmt.Errorf("Unknown cosigner: %d", idx+1)
+		}
+		deal.EncryptedShares[idx], err = cosigner.encryptSharePart(peer, share)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	digest, err := refreshDealDigest(cosigner.pubKeyBytes, deal)
+	if err != nil {
+		return nil, err
+	}
+	deal.SourceSig, err = cosigner.signDigest(digest)
+	if err != nil {
+		return nil, err
+	}
+
+	// a new refresh replaces any unfinished one
+	cosigner.refresh = &shareRefresh{
+		id:   req.RefreshID,
+		deal: deal,
+	}
+	return deal, nil
+}
+
+// RefreshPrepare verifies the deals of every cosigner and computes our refreshed share
+// The public keys of the refreshed shares are returned so the coordinator can check that all the cosigners agree.
+func (cosigner *LocalCosigner) RefreshPrepare(ctx context.Context, req *CosignerRefreshPrepareRequest) (*CosignerRefreshPrepareResponse, error) {
+	cosigner.lastSignStateMutex.Lock()
+	defer cosigner.lastSignStateMutex.Unlock()
+
+	refresh := cosigner.refresh
+	if refresh == nil || refresh.id != req.RefreshID {
+		return nil, fmt.Errorf("Unknown refresh: %s", req.RefreshID)
+	}
+
+	if req.ActivationHeight <= cosigner.lastSignState.Height {
+		return nil, fmt.Errorf("Activation height %d is not above the last signed height %d", req.ActivationHeight, cosigner.lastSignState.Height)
+	}
+
+	if len(req.Deals) != int(cosigner.total) {
+		return nil, fmt.Errorf("Expected %d deals, got %d", cosigner.total, len(req.Deals))
+	}
+
+	sharePubKeys, err := cosigner.refreshSharePubKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	ourID := cosigner.key.ID
+	shares := []tsed25519.Scalar{cosigner.key.ShareKey}
+	commitments := make([][]tsed25519.Element, cosigner.threshold)
+	seen := make(map[int32]bool)
+
+	for _, deal := range req.Deals {
+		if deal.RefreshID != req.RefreshID {
+			return nil, fmt.Errorf("Deal of cosigner %d is for another refresh", deal.SourceID)
+		}
+		if seen[deal.SourceID] {
+			return nil, fmt.Errorf("Duplicate deal of cosigner %d", deal.SourceID)
+		}
+		seen[deal.SourceID] = true
+
+		peer, ok := cosigner.peers[int(deal.SourceID)]
+		if !ok {
+			return nil, fmt.Errorf("Unknown cosigner: %d", deal.SourceID)
+		}
+
+		if len(deal.Commitments) != int(cosigner.threshold) || len(deal.EncryptedShares) != int(cosigner.total) {
+			return nil, fmt.Errorf("Deal of cosigner %d has the wrong size", deal.SourceID)
+		}
+
+		// the deal must split zero, or the secret would change
+		if !bytes.Equal(deal.Commitments[0], identityElement) {
+			return nil, fmt.Errorf("Deal of cosigner %d doesn't split zero", deal.SourceID)
+		}
+
+		digest, err := refreshDealDigest(cosigner.pubKeyBytes, deal)
+		if err != nil {
+			return nil, err
+		}
+		if err := cosigner.verifyDigest(peer, digest, deal.SourceSig); err != nil {
+			return nil, fmt.Errorf("Invalid signature on the deal of cosigner %d: %v", deal.SourceID, err)
+		}
+
+		share, err := cosigner.decryptSharePart(deal.EncryptedShares[ourID-1])
+		if err != nil {
+			return nil, err
+		}
+		if !verifyFeldmanShare(deal.Commitments, ourID, share) {
+			return nil, fmt.Errorf("Share dealt by cosigner %d doesn't match its commitments", deal.SourceID)
+		}
+		shares = append(shares, share)
+
+		for k, commitment := range deal.Commitments {
+			commitments[k] = append(commitments[k], commitment)
+		}
+	}
+
+	next := &CosignerNextShare{
+		ShareKey:         tsed25519.AddScalars(shares),
+		ActivationHeight: req.ActivationHeight,
+	}
+
+	// the commitments of the sum of the polynomials give the change of every share public key
+	sumCommitments := make([][]byte, len(commitments))
+	for k := range commitments {
+		sumCommitments[k] = tsed25519.AddElements(commitments[k])
+	}
+
+	next.SharePubKeys = make([][]byte, cosigner.total)
+	for idx := range next.SharePubKeys {
+		delta, err := feldmanEvaluate(sumCommitments, idx+1)
+		if err != nil {
+			return nil, err
+		}
+		next.SharePubKeys[idx] = tsed25519.AddElements([]tsed25519.Element{sharePubKeys[idx], delta})
+	}
+
+	if !bytes.Equal(SharePubKey(next.ShareKey), next.SharePubKeys[ourID-1]) {
+		return nil, errors.New("Refreshed share doesn't match its public key")
+	}
+
+	// the refreshed polynomial is the current one plus the sum of the dealt ones
+	if len(cosigner.key.Commitments) == len(sumCommitments) {
+		next.Commitments = make([][]byte, len(sumCommitments))
+		for k, commitment := range sumCommitments {
+			next.Commitments[k] = tsed25519.AddElements([]tsed25519.Element{cosigner.key.Commitments[k], commitment})
+		}
+	}
+
+	refresh.prepared = next
+	return &CosignerRefreshPrepareResponse{
+		SharePubKeys: next.SharePubKeys,
+	}, nil
+}
+
+// refreshSharePubKeys returns the public keys of the current shares, the refreshed ones are derived from them
+// Key files without them are refreshed with the public keys computed from the commitments. Without either,
+// the cosigners could not check that they agree on the refreshed shares, and the refresh is refused.
+// cosigner.lastSignStateMutex must be held.
+func (cosigner *LocalCosigner) refreshSharePubKeys() ([][]byte, error) {
+	if len(cosigner.key.SharePubKeys) == int(cosigner.total) {
+		return cosigner.key.SharePubKeys, nil
+	}
+	if len(cosigner.key.Commitments) != int(cosigner.threshold) {
+		return nil, errors.New("The key file has neither the share public keys nor the commitments, the cosigners can't check that they agree on the refreshed shares")
+	}
+
+	sharePubKeys := make([][]byte, cosigner.total)
+	for idx := range sharePubKeys {
+		var err error
+		sharePubKeys[idx], err = feldmanEvaluate(cosigner.key.Commitments, idx+1)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !bytes.Equal(SharePubKey(cosigner.key.ShareKey), sharePubKeys[cosigner.key.ID-1]) {
+		return nil, errors.New("The share doesn't match the commitments of the key file")
+	}
+	return sharePubKeys, nil
+}
+
+// RefreshCommit saves the refreshed share with the key backend
+// It replaces the current share once a block at the activation height is signed.
+func (cosigner *LocalCosigner) RefreshCommit(ctx context.Context, req *CosignerRefreshCommitRequest) error {
+	cosigner.lastSignStateMutex.Lock()
+	defer cosigner.lastSignStateMutex.Unlock()
+
+	refresh := cosigner.refresh
+	if refresh == nil || refresh.id != req.RefreshID || refresh.prepared == nil {
+		return fmt.Errorf("Refresh %s is not prepared", req.RefreshID)
+	}
+
+	if refresh.prepared.ActivationHeight <= cosigner.lastSignState.Height {
+		return fmt.Errorf("Activation height %d is not above the last signed height %d", refresh.prepared.ActivationHeight, cosigner.lastSignState.Height)
+	}
+
+	cosigner.key.NextShare = refresh.prepared
+	if cosigner.backend != nil {
+		if err := cosigner.backend.Save(&cosigner.key); err != nil {
+			cosigner.key.NextShare = nil
+			return err
+		}
+	}
+
+	cosigner.refresh = nil
+	return nil
+}
+
+// RefreshAbort drops the refresh if it hasn't been committed
+func (cosigner *LocalCosigner) RefreshAbort(ctx context.Context, req *CosignerRefreshAbortRequest) error {
+	cosigner.lastSignStateMutex.Lock()
+	defer cosigner.lastSignStateMutex.Unlock()
+
+	if cosigner.refresh != nil && cosigner.refresh.id == req.RefreshID {
+		cosigner.refresh = nil
+	}
+	return nil
+}
+
+// SharePubKeys returns the public keys of the shares used to sign at the height
+func (cosigner *LocalCosigner) SharePubKeys(height int64) [][]byte {
+	cosigner.lastSignStateMutex.Lock()
+	defer cosigner.lastSignStateMutex.Unlock()
+
+	if next := cosigner.key.NextShare; next != nil && height >= next.ActivationHeight {
+		return next.SharePubKeys
+	}
+	return cosigner.key.SharePubKeys
+}
+
+// activateNextShare replaces the share by the refreshed one once the activation height is reached
+// The caller must hold lastSignStateMutex.
+func (cosigner *LocalCosigner) activateNextShare(height int64) {
+	next := cosigner.key.NextShare
+	if next == nil || height < next.ActivationHeight {
+		return
+	}
+
+	cosigner.key.ShareKey = next.ShareKey
+	cosigner.key.SharePubKeys = next.SharePubKeys
+	cosigner.key.Commitments = next.Commitments
+	cosigner.key.NextShare = nil
+
+	// the stored key still has the next share if this fails, it is activated again after a restart
+	if cosigner.backend != nil {
+		if err := cosigner.backend.Save(&cosigner.key); err != nil {
+			logger.Error("Failed to save the refreshed share", "error", err)
+		}
+	}
+	logger.Info("Activated refreshed share", "height", height)
+}
+
+// RefreshShares runs a share refresh with all the cosigners of a cluster
+//
+// Every cosigner deals, then verifies the deals of the others. The refresh is only committed
+// once every cosigner agrees on the public keys of the refreshed shares, it is aborted otherwise.
+// The cosigners keep signing with their current share until the activation height.
+func RefreshShares(ctx context.Context, logger tmlog.Logger, refreshID string, activationHeight int64, cosigners []ShareRefresher) error {
+	abort := func() {
+		for _, cosigner := range cosigners {
+			err := cosigner.RefreshAbort(ctx, &CosignerRefreshAbortRequest{RefreshID: refreshID})
+			if err != nil {
+				logger.Error("Failed to abort the refresh", "cosigner", cosigner.GetID(), "error", err)
+			}
+		}
+	}
+
+	deals := make([]*CosignerRefreshDeal, len(cosigners))
+	err := forEachRefresher(cosigners, func(idx int, cosigner ShareRefresher) error {
+		var err error
+		deals[idx], err = cosigner.RefreshDeal(ctx, &CosignerRefreshDealRequest{RefreshID: refreshID})
+		return err
+	})
+	if err != nil {
+		abort()
+		return err
+	}
+	logger.Info("Collected the deals of all the cosigners", "refresh", refreshID)
+
+	responses := make([]*CosignerRefreshPrepareResponse, len(cosigners))
+	err = forEachRefresher(cosigners, func(idx int, cosigner ShareRefresher) error {
+		var err error
+		responses[idx], err = cosigner.RefreshPrepare(ctx, &CosignerRefreshPrepareRequest{
+			RefreshID:        refreshID,
+			ActivationHeight: activationHeight,
+			Deals:            deals,
+		})
+		return err
+	})
+	if err != nil {
+		abort()
+		return err
+	}
+
+	for idx, response := range responses {
+		if len(response.SharePubKeys) != len(cosigners) {
+			abort()
+			return fmt.Errorf("Cosigner %d returned no public keys for the refreshed shares", cosigners[idx].GetID())
+		}
+		if !equalByteSlices(response.SharePubKeys, responses[0].SharePubKeys) {
+			abort()
+			return fmt.Errorf("Cosigner %d disagrees on the public keys of the refreshed shares", cosigners[idx].GetID())
+		}
+	}
+	logger.Info("All the cosigners prepared the refreshed shares", "refresh", refreshID)
+
+	// a cosigner that fails to commit keeps its old share, which stops matching the others at the activation height
+	err = forEachRefresher(cosigners, func(idx int, cosigner ShareRefresher) error {
+		return cosigner.RefreshCommit(ctx, &CosignerRefreshCommitRequest{RefreshID: refreshID})
+	})
+	if err != nil {
+		return fmt.Errorf("Refresh %s is only partially committed, the cosigners that failed must be reshared: %v", refreshID, err)
+	}
+
+	logger.Info("Refreshed shares committed", "refresh", refreshID, "activation_height", activationHeight)
+	return nil
+}
+
+// forEachRefresher calls request for every cosigner in parallel and returns the first error
+func forEachRefresher(cosigners []ShareRefresher, request func(idx int, cosigner ShareRefresher) error) error {
+	errs := make([]error, len(cosigners))
+
+	wg := sync.WaitGroup{}
+	wg.Add(len(cosigners))
+	for idx, cosigner := range cosigners {
+		go func(idx int, cosigner ShareRefresher) {
+			defer wg.Done()
+			if err := request(idx, cosigner); err != nil {
+				errs[idx] = fmt.Errorf("cosigner %d: %v", cosigner.GetID(), err)
+			}
+		}(idx, cosigner)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func equalByteSlices(a [][]byte, b [][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for idx := range a {
+		if !bytes.Equal(a[idx], b[idx]) {
+			return false
+		}
+	}
+	return true
+}
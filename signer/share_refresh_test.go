@@ -0,0 +1,234 @@
+package signer
+
+import (
+	"context"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	tmCryptoEd25519 "github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/libs/log"
+	tmProto "github.com/tendermint/tendermint/proto/tendermint/types"
+	tm "github.com/tendermint/tendermint/types"
+	tsed25519 "gitlab.com/polychainlabs/threshold-ed25519/pkg"
+)
+
+func TestFeldmanCommitments(test *testing.T) {
+	secret := big.NewInt(42)
+	coeffs, shares, err := dealPolynomial(secret, 3, 5)
+	require.NoError(test, err)
+
+	commitments := feldmanCommitments(coeffs)
+	require.Equal(test, []byte(tsed25519.ScalarMultiplyBase(bigToScalar(secret))), commitments[0])
+
+	for idx, share := range shares {
+		require.True(test, verifyFeldmanShare(commitments, idx+1, share))
+		require.False(test, verifyFeldmanShare(commitments, idx+2, share))
+	}
+
+	// any 3 shares combine into the secret
+	combined := tsed25519.CombineShares(5, []int{1, 3, 5}, [][]byte{shares[0], shares[2], shares[4]})
+	require.Equal(test, bigToScalar(secret), combined)
+}
+
+// refreshers returns the cosigners as share refreshers
+func refreshers(cosigners []*LocalCosigner) []ShareRefresher {
+	result := make([]ShareRefresher, len(cosigners))
+	for idx, cosigner := range cosigners {
+		result[idx] = cosigner
+	}
+	return result
+}
+
+func signTestProposal(test *testing.T, validator *ThresholdValidator, cosigners []*LocalCosigner, height int64) *tmProto.Proposal {
+	exchangeAllEphemeralSecretParts(test, cosigners, height, 0, stepPropose)
+
+	proposal := &tmProto.Proposal{
+		Height: height,
+		Type:   tmProto.ProposalType,
+	}
+	require.NoError(test, validator.SignProposal("chain-id", proposal))
+	return proposal
+}
+
+func TestRefreshShares(test *testing.T) {
+	privateKey, cosigners, cleanup := newTestCosigners(test, 2, 3)
+	defer cleanup()
+	pubKey := privateKey.PubKey().(tmCryptoEd25519.PubKey)
+
+	dir, err := ioutil.TempDir("", "refresh-shares")
+	require.NoError(test, err)
+	defer os.RemoveAll(dir)
+
+	oldShares := make([][]byte, len(cosigners))
+	keyFiles := make([]string, len(cosigners))
+	for idx, cosigner := range cosigners {
+		oldShares[idx] = cosigner.key.ShareKey
+		keyFiles[idx] = dir + "/" + string(rune('1'+idx)) + ".json"
+		cosigner.backend = NewFileKeyBackend(keyFiles[idx], nil)
+	}
+
+	stateFile, err := ioutil.TempFile("", "priv_validator_state.json")
+	require.NoError(test, err)
+	defer os.Remove(stateFile.Name())
+	signState, err := LoadOrCreateSignState(stateFile.Name())
+	require.NoError(test, err)
+
+	validator := NewThresholdValidator(&ThresholdValidatorOpt{
+		Pubkey:    pubKey,
+		Threshold: 2,
+		SignState: signState,
+		Cosigner:  cosigners[0],
+		Peers:     []Cosigner{cosigners[1], cosigners[2]},
+	})
+
+	signTestProposal(test, validator, cosigners, 1)
+
+	// the activation height must be ahead of the signed blocks
+	err = RefreshShares(context.Background(), log.NewNopLogger(), "too-late", 1, refreshers(cosigners))
+	require.Error(test, err)
+	for _, cosigner := range cosigners {
+		require.Nil(test, cosigner.refresh)
+	}
+
+	err = RefreshShares(context.Background(), log.NewNopLogger(), "refresh", 3, refreshers(cosigners))
+	require.NoError(test, err)
+
+	// the refreshed share is saved but not used yet
+	for idx, cosigner := range cosigners {
+		require.Equal(test, oldShares[idx], cosigner.key.ShareKey)
+
+		saved, err := LoadCosignerKey(keyFiles[idx])
+		require.NoError(test, err)
+		require.Equal(test, oldShares[idx], saved.ShareKey)
+		require.NotNil(test, saved.NextShare)
+		require.Equal(test, int64(3), saved.NextShare.ActivationHeight)
+	}
+
+	// no other refresh until this one is activated
+	err = RefreshShares(context.Background(), log.NewNopLogger(), "another", 5, refreshers(cosigners))
+	require.Error(test, err)
+
+	proposal := signTestProposal(test, validator, cosigners, 2)
+	require.True(test, pubKey.VerifySignature(tm.ProposalSignBytes("chain-id", proposal), proposal.Signature))
+
+	proposal = signTestProposal(test, validator, cosigners, 3)
+	require.True(test, pubKey.VerifySignature(tm.ProposalSignBytes("chain-id", proposal), proposal.Signature))
+
+	// the shares were replaced, the shares verified one by one against the refreshed public keys
+	for idx, cosigner := range cosigners {
+		require.NotEqual(test, oldShares[idx], cosigner.key.ShareKey)
+		require.Nil(test, cosigner.key.NextShare)
+		require.Equal(test, SharePubKey(cosigner.key.ShareKey), cosigner.key.SharePubKeys[idx])
+		require.NoError(test, cosigner.key.Validate())
+		require.Equal(test, pubKey.Bytes(), cosigner.key.Commitments[0])
+		require.Equal(test, uint64(0), validator.MisbehaviourCount(idx+1))
+
+		saved, err := LoadCosignerKey(keyFiles[idx])
+		require.NoError(test, err)
+		require.Equal(test, cosigner.key.ShareKey, saved.ShareKey)
+		require.Nil(test, saved.NextShare)
+	}
+
+	// an old share doesn't combine with the refreshed ones
+	stale := tsed25519.CombineShares(3, []int{1, 2}, [][]byte{oldShares[0], cosigners[1].key.ShareKey})
+	fresh := tsed25519.CombineShares(3, []int{1, 2}, [][]byte{cosigners[0].key.ShareKey, cosigners[1].key.ShareKey})
+	require.NotEqual(test, stale, fresh)
+}
+
+func TestRefreshSharesWithoutSharePubKeys(test *testing.T) {
+	_, cosigners, cleanup := newTestCosigners(test, 2, 3)
+	defer cleanup()
+
+	// the share public keys of a key file that lacks them are computed from its commitments
+	sharePubKeys := cosigners[0].key.SharePubKeys
+	for _, cosigner := range cosigners {
+		cosigner.key.SharePubKeys = nil
+	}
+	err := RefreshShares(context.Background(), log.NewNopLogger(), "refresh", 3, refreshers(cosigners))
+	require.NoError(test, err)
+	for idx, cosigner := range cosigners {
+		next := cosigner.key.NextShare
+		require.NotNil(test, next)
+		require.Len(test, next.SharePubKeys, len(cosigners))
+		require.Equal(test, SharePubKey(next.ShareKey), next.SharePubKeys[idx])
+		require.NotEqual(test, sharePubKeys[idx], next.SharePubKeys[idx])
+		cosigner.key.NextShare = nil
+	}
+
+	// without the commitments either, the refresh is refused
+	for _, cosigner := range cosigners {
+		cosigner.key.Commitments = nil
+	}
+	err = RefreshShares(context.Background(), log.NewNopLogger(), "unchecked", 3, refreshers(cosigners))
+	require.Error(test, err)
+	for _, cosigner := range cosigners {
+		require.Nil(test, cosigner.refresh)
+		require.Nil(test, cosigner.key.NextShare)
+	}
+}
+
+func copyRefreshDeal(deal *CosignerRefreshDeal) *CosignerRefreshDeal {
+	return &CosignerRefreshDeal{
+		RefreshID:       deal.RefreshID,
+		SourceID:        deal.SourceID,
+		Commitments:     deal.Commitments,
+		EncryptedShares: deal.EncryptedShares,
+		SourceSig:       deal.SourceSig,
+	}
+}
+
+func TestRefreshSharesRejectsTamperedDeal(test *testing.T) {
+	_, cosigners, cleanup := newTestCosigners(test, 2, 3)
+	defer cleanup()
+
+	ctx := context.Background()
+	deals := make([]*CosignerRefreshDeal, len(cosigners))
+	for idx, cosigner := range cosigners {
+		var err error
+		deals[idx], err = cosigner.RefreshDeal(ctx, &CosignerRefreshDealRequest{RefreshID: "refresh"})
+		require.NoError(test, err)
+	}
+
+	// a deal that doesn't split zero would change the validator key
+	nonZero := copyRefreshDeal(deals[1])
+	nonZero.Commitments = append([][]byte{tsed25519.ScalarMultiplyBase(bigToScalar(big.NewInt(1)))}, deals[1].Commitments[1:]...)
+	_, err := cosigners[0].RefreshPrepare(ctx, &CosignerRefreshPrepareRequest{
+		RefreshID:        "refresh",
+		ActivationHeight: 10,
+		Deals:            []*CosignerRefreshDeal{deals[0], nonZero, deals[2]},
+	})
+	require.Error(test, err)
+
+	// the coordinator can't swap the encrypted shares
+	swapped := copyRefreshDeal(deals[1])
+	swapped.EncryptedShares = [][]byte{deals[1].EncryptedShares[1], deals[1].EncryptedShares[0], deals[1].EncryptedShares[2]}
+	_, err = cosigners[0].RefreshPrepare(ctx, &CosignerRefreshPrepareRequest{
+		RefreshID:        "refresh",
+		ActivationHeight: 10,
+		Deals:            []*CosignerRefreshDeal{deals[0], swapped, deals[2]},
+	})
+	require.Error(test, err)
+
+	// every cosigner must deal
+	_, err = cosigners[0].RefreshPrepare(ctx, &CosignerRefreshPrepareRequest{
+		RefreshID:        "refresh",
+		ActivationHeight: 10,
+		Deals:            []*CosignerRefreshDeal{deals[0], deals[1], deals[1]},
+	})
+	require.Error(test, err)
+
+	// the untouched deals are accepted
+	_, err = cosigners[0].RefreshPrepare(ctx, &CosignerRefreshPrepareRequest{
+		RefreshID:        "refresh",
+		ActivationHeight: 10,
+		Deals:            deals,
+	})
+	require.NoError(test, err)
+
+	require.NoError(test, cosigners[0].RefreshAbort(ctx, &CosignerRefreshAbortRequest{RefreshID: "refresh"}))
+	require.Error(test, cosigners[0].RefreshCommit(ctx, &CosignerRefreshCommitRequest{RefreshID: "refresh"}))
+	require.Nil(test, cosigners[0].key.NextShare)
+}
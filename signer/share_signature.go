@@ -0,0 +1,59 @@
+package signer
+
+import (
+	"bytes"
+	"crypto/sha512"
+
+	"gitlab.com/polychainlabs/edwards25519"
+	tsed25519 "gitlab.com/polychainlabs/threshold-ed25519/pkg"
+)
+
+// SharePubKey returns the public commitment of a secret share: share * B
+func SharePubKey(share []byte) []byte {
+	return tsed25519.ScalarMultiplyBase(share)
+}
+
+// verifyShareSignature checks a share signature produced by tsed25519.SignWithShare
+//
+// The share signature is s = r + k * a with r the ephemeral share, a the secret share and
+// k = H(ephemeralPublic || pubKey || message). It is valid if s * B == R + k * A where
+// R = r * B is the ephemeral share public and A = a * B is the share public key.
+func verifyShareSignature(message []byte, pubKey []byte, ephemeralPublic []byte, sharePubKey []byte, ephemeralSharePublic []byte, shareSig []byte) bool {
+	if len(shareSig) != 32 || len(sharePubKey) != 32 || len(ephemeralSharePublic) != 32 {
+		return false
+	}
+
+	var s [32]byte
+	copy(s[:], shareSig)
+	if !edwards25519.ScMinimal(&s) {
+		return false
+	}
+
+	var minusA edwards25519.ExtendedGroupElement
+	var sharePubKeyBytes [32]byte
+	copy(sharePubKeyBytes[:], sharePubKey)
+	if !minusA.FromBytes(&sharePubKeyBytes) {
+		return false
+	}
+	edwards25519.FeNeg(&minusA.X, &minusA.X)
+	edwards25519.FeNeg(&minusA.T, &minusA.T)
+
+	hash := sha512.New()
+	hash.Write(ephemeralPublic)
+	hash.Write(pubKey)
+	hash.Write(message)
+
+	var digest [64]byte
+	hash.Sum(digest[:0])
+
+	var k [32]byte
+	edwards25519.ScReduce(&k, &digest)
+
+	// s * B - k * A must be the ephemeral share public
+	var R edwards25519.ProjectiveGroupElement
+	edwards25519.GeDoubleScalarMultVartime(&R, &k, &minusA, &s)
+
+	var check [32]byte
+	R.ToBytes(&check)
+	return bytes.Equal(check[:], ephemeralSharePublic)
+}
@@ -0,0 +1,36 @@
+package signer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	tmCryptoEd25519 "github.com/tendermint/tendermint/crypto/ed25519"
+	tsed25519 "gitlab.com/polychainlabs/threshold-ed25519/pkg"
+)
+
+func TestVerifyShareSignature(test *testing.T) {
+	privateKey := tmCryptoEd25519.GenPrivKey()
+	pubKey := privateKey.PubKey().Bytes()
+	shares := tsed25519.DealShares(tsed25519.ExpandSecret(privateKey[:32]), 2, 3)
+
+	ephemeralShare := tsed25519.DealShares(tsed25519.ExpandSecret([]byte("ephemeral secret of the cosigner")), 2, 3)[0]
+	ephemeralSharePublic := tsed25519.ScalarMultiplyBase(ephemeralShare)
+	ephemeralPublic := tsed25519.ScalarMultiplyBase(ephemeralShare)
+
+	message := []byte("sign bytes")
+	shareSig := tsed25519.SignWithShare(message, shares[0], ephemeralShare, pubKey, ephemeralPublic)
+
+	require.True(test, verifyShareSignature(message, pubKey, ephemeralPublic, SharePubKey(shares[0]), ephemeralSharePublic, shareSig))
+
+	// signed with another share
+	require.False(test, verifyShareSignature(message, pubKey, ephemeralPublic, SharePubKey(shares[1]), ephemeralSharePublic, shareSig))
+
+	// for another message
+	require.False(test, verifyShareSignature([]byte("other sign bytes"), pubKey, ephemeralPublic, SharePubKey(shares[0]), ephemeralSharePublic, shareSig))
+
+	// corrupted
+	shareSig[0] ^= 1
+	require.False(test, verifyShareSignature(message, pubKey, ephemeralPublic, SharePubKey(shares[0]), ephemeralSharePublic, shareSig))
+
+	require.False(test, verifyShareSignature(message, pubKey, ephemeralPublic, SharePubKey(shares[0]), ephemeralSharePublic, shareSig[:16]))
+}
@@ -1,6 +1,7 @@
 package signer
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -20,8 +21,38 @@ const (
 	stepPropose   int8 = 1
 	stepPrevote   int8 = 2
 	stepPrecommit int8 = 3
+	// The vote extension of a CometBFT 0.38 precommit, signed right after the precommit
+	// It only names the ephemeral secrets and audit records of the extension: the extension is signed
+	// under the watermark of its precommit and kept next to it in the sign state.
+	stepVoteExtension int8 = 4
 )
 
+var stepNames = map[int8]string{
+	stepNone:          "none",
+	stepPropose:       "propose",
+	stepPrevote:       "prevote",
+	stepPrecommit:     "precommit",
+	stepVoteExtension: "extension",
+}
+
+// StepName returns the name of the step, as accepted by ParseStep
+func StepName(step int8) string {
+	if name, ok := stepNames[step]; ok {
+		return name
+	}
+	return fmt.Sprintf("%d", step)
+}
+
+// ParseStep returns the step named propose, prevote, precommit or extension
+func ParseStep(name string) (int8, error) {
+	for step, stepName := range stepNames {
+		if step != stepNone && stepName == name {
+			return step, nil
+		}
+	}
+	return stepNone, fmt.Errorf("unknown step %q", name)
+}
+
 func CanonicalVoteToStep(vote *tmProto.CanonicalVote) int8 {
 	switch vote.Type {
 	case tmProto.PrevoteType:
@@ -57,24 +88,47 @@ type SignState struct {
 	Signature       []byte           `json:"signature,omitempty"`
 	SignBytes       tmBytes.HexBytes `json:"signbytes,omitempty"`
 
+	// The vote extension signed for the precommit at Height and Round, if any
+	ExtensionEphemeralPublic []byte           `json:"extension_ephemeral_public,omitempty"`
+	ExtensionSignature       []byte           `json:"extension_signature,omitempty"`
+	ExtensionSignBytes       tmBytes.HexBytes `json:"extension_signbytes,omitempty"`
+
 	filePath string
+	db       *signStateDB
 }
 
-// Save persists the FilePvLastSignState to its filePath.
-func (signState *SignState) Save() {
-	//logger.Info("Save signState", "filepath=", signState.filePath)
+// Save persists the SignState to its database, or to its filePath.
+func (signState *SignState) Save() error {
+	if signState.db != nil {
+		return signState.db.save(signState)
+	}
+
 	outFile := signState.filePath
 	if outFile == "" {
-		panic("cannot save SignState: filePath not set")
+		return errors.New("cannot save SignState: filePath not set")
 	}
 	jsonBytes, err := tmJson.MarshalIndent(signState, "", "  ")
 	if err != nil {
-		panic(err)
+		return err
 	}
-	err = tempfile.WriteFileAtomic(outFile, jsonBytes, 0600)
-	if err != nil {
-		panic(err)
+	return tempfile.WriteFileAtomic(outFile, jsonBytes, 0600)
+}
+
+// History returns the signed states kept by the database, from the oldest to the most recent HRS
+// A sign state saved to a JSON file has no history.
+func (signState *SignState) History() ([]SignState, error) {
+	if signState.db == nil {
+		return nil, errors.New("the sign state has no history, it is not stored in a database")
+	}
+	return signState.db.signedHistory()
+}
+
+// Close releases the database of the sign state
+func (signState *SignState) Close() error {
+	if signState.db == nil {
+		return nil
 	}
+	return signState.db.db.Close()
 }
 
 // CheckHRS checks the given height, round, step (HRS) against that of the
@@ -82,7 +136,7 @@ func (signState *SignState) Save() {
 // or if they match but the SignBytes are empty.
 // Returns true if the HRS matches the arguments and the SignBytes are not empty (indicating
 // we have already signed for this HRS, and can reuse the existing signature).
-// It panics if the HRS matches the arguments, there's a SignBytes, but no Signature.
+// It returns an error if the HRS matches the arguments, there's a SignBytes, but no Signature.
 func (signState *SignState) CheckHRS(height int64, round int64, step int8) (bool, error) {
 	if signState.Height > height {
 		return false, fmt.Errorf("height regression. Got %v, last height %v", height, signState.Height)
@@ -99,7 +153,7 @@ func (signState *SignState) CheckHRS(height int64, round int64, step int8) (bool
 			} else if signState.Step == step {
 				if signState.SignBytes != nil {
 					if signState.Signature == nil {
-						panic("pv: Signature is nil but SignBytes is not!")
+						return false, errors.New("pv: Signature is nil but SignBytes is not")
 					}
 					return true, nil
 				}
@@ -111,6 +165,41 @@ func (signState *SignState) CheckHRS(height int64, round int64, step int8) (bool
 	return false, nil
 }
 
+// CheckVoteExtensionHRS checks the height and round of a vote extension against the sign state
+// The extension is signed under the watermark of its precommit, which must be the last HRS signed.
+// Returns true if the same extension was signed already, its signature can be reused.
+// A different extension is signed again: the node asks for it whenever it signs the precommit again,
+// and extensions carry no double sign risk.
+func (signState *SignState) CheckVoteExtensionHRS(height int64, round int64, signBytes []byte) (bool, error) {
+	precommitKey := HRSKey{Height: height, Round: round, Step: stepPrecommit}
+	ourKey := signState.hrsKey()
+	if precommitKey.Less(ourKey) {
+		return false, fmt.Errorf("vote extension regression. Got height %v round %v, last signed height %v round %v step %v",
+			height, round, signState.Height, signState.Round, signState.Step)
+	}
+	if ourKey != precommitKey || len(signState.ExtensionSignBytes) == 0 {
+		return false, nil
+	}
+	if !bytes.Equal(signBytes, signState.ExtensionSignBytes) {
+		return false, nil
+	}
+	if signState.ExtensionSignature == nil {
+		return false, errors.New("pv: extension signature is nil but its sign bytes are not")
+	}
+	return true, nil
+}
+
+// raisedBy returns true if the other sign state is above this one,
+// or at the same HRS with a vote extension this one lacks or signed since
+func (signState *SignState) raisedBy(other *SignState) bool {
+	ourKey := signState.hrsKey()
+	if ourKey.Less(other.hrsKey()) {
+		return true
+	}
+	return ourKey == other.hrsKey() && len(other.ExtensionSignBytes) > 0 &&
+		!bytes.Equal(signState.ExtensionSignBytes, other.ExtensionSignBytes)
+}
+
 // LoadSignState loads a sign state from disk.
 func LoadSignState(filepath string) (SignState, error) {
 	logger.Info("LoadSignState", "filepath=", filepath)
@@ -143,8 +232,7 @@ func LoadOrCreateSignState(filepath string) (SignState, error) {
 	// Make an empty sign state and save it
 	state := SignState{}
 	state.filePath = filepath
-	state.Save()
-	return state, nil
+	return state, state.Save()
 }
 
 // OnlyDifferByTimestamp returns true if the sign bytes of the sign state
@@ -0,0 +1,279 @@
+package signer
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	tmJson "github.com/tendermint/tendermint/libs/json"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	SignStateBackendJSON = "json"
+	SignStateBackendBolt = "bolt"
+
+	// DefaultSignStateHistory is the number of signed HRS kept by the bolt backend when none is configured
+	DefaultSignStateHistory = 1000
+)
+
+var (
+	signStateBucket   = []byte("sign_state")
+	signHistoryBucket = []byte("history")
+	lastSignStateKey  = []byte("last")
+
+	errNoSignState = errors.New("no sign state stored")
+)
+
+// SignStateConfig selects where the sign states are persisted
+type SignStateConfig struct {
+	// json (default) rewrites a JSON file, bolt keeps the sign state and its history in an embedded database
+	Backend string `toml:"backend"`
+	// Number of signed HRS kept by the bolt backend
+	History int `toml:"history"`
+}
+
+// Validate returns an error if the sign states can't be persisted with the config
+func (cfg SignStateConfig) Validate() error {
+	switch cfg.Backend {
+	case "", SignStateBackendJSON, SignStateBackendBolt:
+	default:
+		return fmt.Errorf("unknown sign state backend %q", cfg.Backend)
+	}
+	if cfg.History < 0 {
+		return errors.New("sign state history must not be negative")
+	}
+	return nil
+}
+
+// historyLimit returns the number of signed HRS to keep
+func (cfg SignStateConfig) historyLimit() int {
+	if cfg.History == 0 {
+		return DefaultSignStateHistory
+	}
+	return cfg.History
+}
+
+// SignStateDBFile returns the path of the database replacing the JSON sign state file
+func SignStateDBFile(jsonFile string) string {
+	return strings.TrimSuffix(jsonFile, ".json") + ".db"
+}
+
+// Load loads the sign state kept as jsonFile by the JSON backend, creating an empty one if create is set
+//
+// The bolt backend keeps it in the database at SignStateDBFile(jsonFile) instead.
+// An existing JSON file is migrated to the database the first time it is loaded.
+func (cfg SignStateConfig) Load(jsonFile string, create bool) (SignState, error) {
+	if cfg.Backend != SignStateBackendBolt {
+		if create {
+			return LoadOrCreateSignState(jsonFile)
+		}
+		return LoadSignState(jsonFile)
+	}
+
+	dbFile := SignStateDBFile(jsonFile)
+	if _, err := os.Stat(dbFile); os.IsNotExist(err) {
+		if _, err := os.Stat(jsonFile); err == nil {
+			if err := MigrateSignState(jsonFile, dbFile, cfg); err != nil {
+				return SignState{}, err
+			}
+		}
+	}
+
+	if create {
+		return LoadOrCreateSignStateDB(dbFile, cfg)
+	}
+	return LoadSignStateDB(dbFile, cfg)
+}
+
+// signStateDB persists a sign state and the HRS signed before it in a bolt database
+//
+// The last sign state and its history entry are written in a single transaction.
+// The database file is locked, a second process can't open the same sign state.
+type signStateDB struct {
+	db      *bolt.DB
+	history int
+}
+
+// openSignStateDB opens the database at file, creating it if create is set
+func openSignStateDB(file string, cfg SignStateConfig, create bool) (*signStateDB, error) {
+	if !create {
+		if _, err := os.Stat(file); err != nil {
+			return nil, err
+		}
+	}
+
+	db, err := bolt.Open(file, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("Error opening %v: %v", file, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(signStateBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(signHistoryBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &signStateDB{db: db, history: cfg.historyLimit()}, nil
+}
+
+// signHistoryKey orders the history by HRS
+func signHistoryKey(height int64, round int64, step int8) []byte {
+	key := make([]byte, 17)
+	binary.BigEndian.PutUint64(key[0:8], uint64(height))
+	binary.BigEndian.PutUint64(key[8:16], uint64(round))
+	key[16] = byte(step)
+	return key
+}
+
+// load returns the last sign state or errNoSignState if none was saved
+func (store *signStateDB) load() (SignState, error) {
+	state := SignState{}
+	err := store.db.View(func(tx *bolt.Tx) error {
+		stateBytes := tx.Bucket(signStateBucket).Get(lastSignStateKey)
+		if stateBytes == nil {
+			return errNoSignState
+		}
+		return tmJson.Unmarshal(stateBytes, &state)
+	})
+	return state, err
+}
+
+// save stores the sign state and records it in the history if it holds a signature
+// The oldest records are dropped once the history is full.
+func (store *signStateDB) save(state *SignState) error {
+	stateBytes, err := tmJson.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	return store.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(signStateBucket).Put(lastSignStateKey, stateBytes); err != nil {
+			return err
+		}
+		if len(state.Signature) == 0 {
+			return nil
+		}
+
+		history := tx.Bucket(signHistoryBucket)
+		if err := history.Put(signHistoryKey(state.Height, state.Round, state.Step), stateBytes); err != nil {
+			return err
+		}
+
+		// the keys are collected first, deleting moves the cursor
+		stale := make([][]byte, 0)
+		kept := 0
+		cursor := history.Cursor()
+		for key, _ := cursor.Last(); key != nil; key, _ = cursor.Prev() {
+			if kept < store.history {
+				kept++
+				continue
+			}
+			stale = append(stale, append([]byte(nil), key...))
+		}
+		for _, key := range stale {
+			if err := history.Delete(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// signedHistory returns the recorded sign states from the oldest to the most recent HRS
+func (store *signStateDB) signedHistory() ([]SignState, error) {
+	states := make([]SignState, 0)
+	err := store.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(signHistoryBucket).ForEach(func(_, stateBytes []byte) error {
+			state := SignState{}
+			if err := tmJson.Unmarshal(stateBytes, &state); err != nil {
+				return err
+			}
+			states = append(states, state)
+			return nil
+		})
+	})
+	return states, err
+}
+
+// LoadSignStateDB loads the sign state stored in the bolt database at file
+// Like LoadSignState, it fails if no sign state was saved there.
+func LoadSignStateDB(file string, cfg SignStateConfig) (SignState, error) {
+	logger.Info("LoadSignStateDB", "filepath=", file)
+
+	store, err := openSignStateDB(file, cfg, false)
+	if err != nil {
+		return SignState{}, err
+	}
+
+	state, err := store.load()
+	if err != nil {
+		store.db.Close()
+		return state, fmt.Errorf("Error loading the sign state from %v: %v", file, err)
+	}
+	state.db = store
+	return state, nil
+}
+
+// LoadOrCreateSignStateDB loads the sign state stored in the bolt database at file
+// If the database holds no sign state, an empty sign state is saved to it.
+func LoadOrCreateSignStateDB(file string, cfg SignStateConfig) (SignState, error) {
+	logger.Info("LoadOrCreateSignStateDB", "filepath=", file)
+
+	store, err := openSignStateDB(file, cfg, true)
+	if err != nil {
+		return SignState{}, err
+	}
+
+	state, err := store.load()
+	if err == errNoSignState {
+		state = SignState{}
+		err = store.save(&state)
+	}
+	if err != nil {
+		store.db.Close()
+		return state, err
+	}
+	state.db = store
+	return state, nil
+}
+
+// MigrateSignState copies the sign state of jsonFile into a new bolt database at dbFile
+//
+// The JSON file is renamed with a .migrated suffix so it can't be loaded again by mistake
+// with a watermark that no longer moves. The migration fails if dbFile already exists.
+func MigrateSignState(jsonFile string, dbFile string, cfg SignStateConfig) error {
+	if _, err := os.Stat(dbFile); err == nil {
+		return fmt.Errorf("%v already exists", dbFile)
+	}
+
+	state, err := LoadSignState(jsonFile)
+	if err != nil {
+		return err
+	}
+
+	store, err := openSignStateDB(dbFile, cfg, true)
+	if err != nil {
+		return err
+	}
+	defer store.db.Close()
+
+	if err := store.save(&state); err != nil {
+		return err
+	}
+	if err := os.Rename(jsonFile, jsonFile+".migrated"); err != nil {
+		return err
+	}
+
+	logger.Info("Migrated sign state", "from", jsonFile, "to", dbFile, "height", state.Height, "round", state.Round, "step", state.Step)
+	return nil
+}
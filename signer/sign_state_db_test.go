@@ -0,0 +1,148 @@
+package signer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	tm "github.com/tendermint/tendermint/types"
+)
+
+func TestSignStateDB(test *testing.T) {
+	dir, err := ioutil.TempDir("", "sign-state-db")
+	require.NoError(test, err)
+	defer os.RemoveAll(dir)
+
+	cfg := SignStateConfig{Backend: SignStateBackendBolt, History: 3}
+	file := filepath.Join(dir, "share_sign_state.db")
+
+	// like the JSON file, a missing database is only created on request
+	_, err = LoadSignStateDB(file, cfg)
+	require.Error(test, err)
+
+	state, err := LoadOrCreateSignStateDB(file, cfg)
+	require.NoError(test, err)
+	history, err := state.History()
+	require.NoError(test, err)
+	require.Empty(test, history)
+
+	for height := int64(1); height <= 5; height++ {
+		state.Height = height
+		state.Step = stepPropose
+		state.Signature = []byte{byte(height)}
+		state.SignBytes = []byte{byte(height)}
+		require.NoError(test, state.Save())
+	}
+
+	// only the most recent signed HRS are kept
+	history, err = state.History()
+	require.NoError(test, err)
+	require.Len(test, history, 3)
+	for idx, signed := range history {
+		require.Equal(test, int64(idx+3), signed.Height)
+		require.Equal(test, []byte{byte(idx + 3)}, signed.Signature)
+	}
+
+	// the database is locked while it is open
+	_, err = LoadSignStateDB(file, cfg)
+	require.Error(test, err)
+
+	require.NoError(test, state.Close())
+	loaded, err := LoadSignStateDB(file, cfg)
+	require.NoError(test, err)
+	defer loaded.Close()
+	require.Equal(test, int64(5), loaded.Height)
+	require.Equal(test, []byte{5}, loaded.Signature)
+}
+
+func TestSignStateSaveError(test *testing.T) {
+	state := SignState{}
+	require.Error(test, state.Save())
+
+	_, err := LoadOrCreateSignState(filepath.Join("missing-dir", "sign_state.json"))
+	require.Error(test, err)
+
+	_, err = state.History()
+	require.Error(test, err)
+}
+
+func TestMigrateSignState(test *testing.T) {
+	dir, err := ioutil.TempDir("", "sign-state-db")
+	require.NoError(test, err)
+	defer os.RemoveAll(dir)
+
+	jsonFile := filepath.Join(dir, "chain-id_share_sign_state.json")
+	state, err := LoadOrCreateSignState(jsonFile)
+	require.NoError(test, err)
+	state.Height = 7
+	state.Step = stepPrecommit
+	state.Signature = []byte("signature")
+	state.SignBytes = []byte("sign bytes")
+	require.NoError(test, state.Save())
+
+	// the JSON file is migrated the first time the bolt backend loads it
+	cfg := SignStateConfig{Backend: SignStateBackendBolt}
+	migrated, err := cfg.Load(jsonFile, false)
+	require.NoError(test, err)
+	require.Equal(test, int64(7), migrated.Height)
+	require.Equal(test, stepPrecommit, migrated.Step)
+	require.NoFileExists(test, jsonFile)
+	require.FileExists(test, jsonFile+".migrated")
+	require.FileExists(test, filepath.Join(dir, "chain-id_share_sign_state.db"))
+	require.NoError(test, migrated.Close())
+
+	// a second migration would move the watermark back
+	require.NoError(test, ioutil.WriteFile(jsonFile, []byte("{}"), 0600))
+	require.Error(test, MigrateSignState(jsonFile, SignStateDBFile(jsonFile), cfg))
+
+	loaded, err := cfg.Load(jsonFile, false)
+	require.NoError(test, err)
+	defer loaded.Close()
+	require.Equal(test, int64(7), loaded.Height)
+
+	require.Error(test, SignStateConfig{Backend: "redis"}.Validate())
+}
+
+func TestThresholdValidatorSignStateDB(test *testing.T) {
+	privateKey, cosigners, cleanup := newTestCosigners(test, 2, 3)
+	defer cleanup()
+
+	dir, err := ioutil.TempDir("", "sign-state-db")
+	require.NoError(test, err)
+	defer os.RemoveAll(dir)
+
+	cfg := SignStateConfig{Backend: SignStateBackendBolt}
+	shareSignState, err := cfg.Load(filepath.Join(dir, "share_sign_state.json"), true)
+	require.NoError(test, err)
+	defer shareSignState.Close()
+	cosigners[0].lastSignState = &shareSignState
+
+	signState, err := cfg.Load(filepath.Join(dir, "priv_validator_state.json"), true)
+	require.NoError(test, err)
+	defer signState.Close()
+
+	validator := NewThresholdValidator(&ThresholdValidatorOpt{
+		Pubkey:    privateKey.PubKey(),
+		Threshold: 2,
+		SignState: signState,
+		Cosigner:  cosigners[0],
+		Peers:     []Cosigner{cosigners[1], cosigners[2]},
+	})
+
+	signTestProposal(test, validator, cosigners, 1)
+	proposal := signTestProposal(test, validator, cosigners, 2)
+
+	// both the share signatures and the block signatures are recorded
+	history, err := shareSignState.History()
+	require.NoError(test, err)
+	require.Len(test, history, 2)
+
+	history, err = validator.lastSignState.History()
+	require.NoError(test, err)
+	require.Len(test, history, 2)
+	require.Equal(test, int64(2), history[1].Height)
+	require.Equal(test, proposal.Signature, history[1].Signature)
+	require.True(test, privateKey.PubKey().VerifySignature(tm.ProposalSignBytes("chain-id", proposal), history[1].Signature))
+}
@@ -0,0 +1,153 @@
+package signer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SigningConfig holds the timeouts and the retry policy used to collect
+// ephemeral secret parts and share signatures from the cosigners
+type SigningConfig struct {
+	// Time budget of a single ephemeral secret part exchange with a peer
+	EphemeralTimeout Duration `toml:"ephemeral_timeout"`
+	// Time budget of a single share signing request to a peer
+	ShareSignTimeout Duration `toml:"share_sign_timeout"`
+	// Time budget for the whole block signature, retries included
+	BlockTimeout Duration `toml:"block_timeout"`
+
+	// Number of times a failed request to a peer is retried within the block timeout
+	// 0 disables the retries, nil falls back to the default.
+	RetryAttempts *int `toml:"retry_attempts"`
+	// Wait before the first retry, doubled after every retry
+	RetryBackoff Duration `toml:"retry_backoff"`
+	// Upper bound of the wait between retries
+	RetryMaxBackoff Duration `toml:"retry_max_backoff"`
+
+	// Number of upcoming heights for which ephemeral secret parts are dealt
+	// and exchanged in the background. 0 disables pre-dealing.
+	PreDealWindow int64 `toml:"predeal_window"`
+
+	// Lowest protocol version accepted for the ephemeral secret parts of the peers
+	MinProtocolVersion int `toml:"min_protocol_version"`
+}
+
+// DefaultSigningConfig returns the timeouts and retry policy used when none are configured
+func DefaultSigningConfig() SigningConfig {
+	return SigningConfig{
+		EphemeralTimeout: Duration{time.Second},
+		ShareSignTimeout: Duration{2 * time.Second},
+		BlockTimeout:     Duration{4 * time.Second},
+		RetryAttempts:    intPointer(1),
+		RetryBackoff:     Duration{100 * time.Millisecond},
+		RetryMaxBackoff:  Duration{time.Second},
+		PreDealWindow:    2,
+
+		MinProtocolVersion: CosignerProtocolVersionLegacy,
+	}
+}
+
+// Validate returns an error if the config can't be used to sign
+func (cfg SigningConfig) Validate() error {
+	if cfg.EphemeralTimeout.Duration <= 0 || cfg.ShareSignTimeout.Duration <= 0 || cfg.BlockTimeout.Duration <= 0 {
+		return errors.New("signing timeouts must be positive")
+	}
+	if cfg.EphemeralTimeout.Duration > cfg.BlockTimeout.Duration || cfg.ShareSignTimeout.Duration > cfg.BlockTimeout.Duration {
+		return errors.New("ephemeral_timeout and share_sign_timeout must not exceed block_timeout")
+	}
+	if cfg.retryAttempts() < 0 {
+		return errors.New("retry_attempts must not be negative")
+	}
+	if cfg.RetryBackoff.Duration < 0 || cfg.RetryMaxBackoff.Duration < cfg.RetryBackoff.Duration {
+		return errors.New("retry_max_backoff must not be lower than retry_backoff")
+	}
+	if cfg.PreDealWindow < 0 {
+		return errors.New("predeal_window must not be negative")
+	}
+	if cfg.MinProtocolVersion < CosignerProtocolVersionLegacy || cfg.MinProtocolVersion > CosignerProtocolVersion {
+		return fmt.Errorf("min_protocol_version must be between %d and %d", CosignerProtocolVersionLegacy, CosignerProtocolVersion)
+	}
+	return nil
+}
+
+// withDefaults replaces the unset timeouts and retry policy with the default ones
+func (cfg SigningConfig) withDefaults() SigningConfig {
+	defaults := DefaultSigningConfig()
+	if cfg.EphemeralTimeout.Duration == 0 {
+		cfg.EphemeralTimeout = defaults.EphemeralTimeout
+	}
+	if cfg.ShareSignTimeout.Duration == 0 {
+		cfg.ShareSignTimeout = defaults.ShareSignTimeout
+	}
+	if cfg.BlockTimeout.Duration == 0 {
+		cfg.BlockTimeout = defaults.BlockTimeout
+	}
+	if cfg.RetryAttempts == nil {
+		cfg.RetryAttempts = defaults.RetryAttempts
+	}
+	if cfg.RetryBackoff.Duration == 0 {
+		cfg.RetryBackoff = defaults.RetryBackoff
+	}
+	if cfg.RetryMaxBackoff.Duration == 0 {
+		cfg.RetryMaxBackoff = defaults.RetryMaxBackoff
+	}
+	return cfg
+}
+
+// retryAttempts returns the number of retries of a failed request, none if it is unset
+func (cfg SigningConfig) retryAttempts() int {
+	if cfg.RetryAttempts == nil {
+		return 0
+	}
+	return *cfg.RetryAttempts
+}
+
+// intPointer returns a pointer to value, for the optional settings
+func intPointer(value int) *int {
+	return &value
+}
+
+// retryBudget returns the time retry may take with attempts bounded by timeout:
+// every attempt timing out, plus the waits between them
+func (cfg SigningConfig) retryBudget(timeout time.Duration) time.Duration {
+	budget := timeout
+	backoff := cfg.RetryBackoff.Duration
+	for i := 0; i < cfg.retryAttempts(); i++ {
+		budget += backoff + timeout
+
+		backoff *= 2
+		if backoff > cfg.RetryMaxBackoff.Duration {
+			backoff = cfg.RetryMaxBackoff.Duration
+		}
+	}
+	return budget
+}
+
+// retry calls attempt with a fresh context bounded by timeout until it succeeds,
+// the retry attempts are exhausted or ctx is done.
+// The wait between two attempts grows exponentially up to RetryMaxBackoff.
+func (cfg SigningConfig) retry(ctx context.Context, timeout time.Duration, attempt func(ctx context.Context) error) error {
+	backoff := cfg.RetryBackoff.Duration
+
+	for i := 0; ; i++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := attempt(attemptCtx)
+		cancel()
+
+		if err == nil || i >= cfg.retryAttempts() {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > cfg.RetryMaxBackoff.Duration {
+			backoff = cfg.RetryMaxBackoff.Duration
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package signer
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSigningConfigRetry(test *testing.T) {
+	cfg := SigningConfig{
+		RetryAttempts:   intPointer(2),
+		RetryBackoff:    Duration{time.Millisecond},
+		RetryMaxBackoff: Duration{2 * time.Millisecond},
+	}
+
+	// succeeds on the last allowed attempt
+	calls := 0
+	err := cfg.retry(context.Background(), time.Second, func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return errors.New("unavailable")
+		}
+		return nil
+	})
+	require.NoError(test, err)
+	require.Equal(test, 3, calls)
+
+	// gives up once the attempts are exhausted
+	calls = 0
+	err = cfg.retry(context.Background(), time.Second, func(ctx context.Context) error {
+		calls++
+		return errors.New("unavailable")
+	})
+	require.Error(test, err)
+	require.Equal(test, 3, calls)
+
+	// every attempt gets its own timeout
+	err = cfg.retry(context.Background(), 10*time.Millisecond, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	require.Equal(test, context.DeadlineExceeded, err)
+}
+
+func TestSigningConfigRetryStopsWithContext(test *testing.T) {
+	cfg := SigningConfig{
+		RetryAttempts:   intPointer(100),
+		RetryBackoff:    Duration{time.Hour},
+		RetryMaxBackoff: Duration{time.Hour},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	calls := 0
+	start := time.Now()
+	err := cfg.retry(ctx, time.Second, func(ctx context.Context) error {
+		calls++
+		return errors.New("unavailable")
+	})
+	require.Error(test, err)
+	require.Equal(test, 1, calls)
+	require.True(test, time.Since(start) < time.Second)
+}
+
+func TestSigningConfigValidate(test *testing.T) {
+	require.NoError(test, DefaultSigningConfig().Validate())
+
+	cfg := DefaultSigningConfig()
+	cfg.ShareSignTimeout = Duration{10 * time.Second}
+	require.Error(test, cfg.Validate())
+
+	cfg = DefaultSigningConfig()
+	cfg.RetryAttempts = intPointer(-1)
+	require.Error(test, cfg.Validate())
+
+	cfg = DefaultSigningConfig()
+	cfg.EphemeralTimeout = Duration{}
+	require.Error(test, cfg.Validate())
+
+	cfg = DefaultSigningConfig()
+	cfg.MinProtocolVersion = CosignerProtocolVersion + 1
+	require.Error(test, cfg.Validate())
+}
+
+func TestSigningConfigWithDefaults(test *testing.T) {
+	defaults := DefaultSigningConfig()
+
+	// a config built in code gets the default retry policy
+	cfg := SigningConfig{EphemeralTimeout: Duration{300 * time.Millisecond}}.withDefaults()
+	require.Equal(test, 300*time.Millisecond, cfg.EphemeralTimeout.Duration)
+	require.Equal(test, defaults.RetryAttempts, cfg.RetryAttempts)
+	require.Equal(test, defaults.RetryBackoff, cfg.RetryBackoff)
+	require.Equal(test, defaults.RetryMaxBackoff, cfg.RetryMaxBackoff)
+
+	cfg = SigningConfig{RetryAttempts: intPointer(3), RetryBackoff: Duration{time.Millisecond}}.withDefaults()
+	require.Equal(test, 3, cfg.retryAttempts())
+	require.Equal(test, time.Millisecond, cfg.RetryBackoff.Duration)
+
+	// an explicit 0 disables the retries
+	cfg = SigningConfig{RetryAttempts: intPointer(0)}.withDefaults()
+	require.Equal(test, 0, cfg.retryAttempts())
+	calls := 0
+	err := cfg.retry(context.Background(), time.Second, func(ctx context.Context) error {
+		calls++
+		return errors.New("unavailable")
+	})
+	require.Error(test, err)
+	require.Equal(test, 1, calls)
+}
+
+func TestSigningConfigRetryBudget(test *testing.T) {
+	cfg := SigningConfig{
+		RetryAttempts:   intPointer(3),
+		RetryBackoff:    Duration{100 * time.Millisecond},
+		RetryMaxBackoff: Duration{150 * time.Millisecond},
+	}
+
+	// 4 attempts of 1s, waits of 100ms, 150ms and 150ms
+	require.Equal(test, 4400*time.Millisecond, cfg.retryBudget(time.Second))
+
+	// the budget lets a timed out attempt be retried
+	calls := 0
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.retryBudget(10*time.Millisecond))
+	defer cancel()
+	err := cfg.retry(ctx, 10*time.Millisecond, func(ctx context.Context) error {
+		calls++
+		if calls == 1 {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	})
+	require.NoError(test, err)
+	require.Equal(test, 2, calls)
+}
+
+func TestLoadConfigSigningDefaults(test *testing.T) {
+	file, err := ioutil.TempFile("", "config.toml")
+	require.NoError(test, err)
+	defer os.Remove(file.Name())
+
+	_, err = file.WriteString(`
+chain_id = "chain-id"
+
+[signing]
+block_timeout = "1500ms"
+share_sign_timeout = "500ms"
+retry_attempts = 3
+`)
+	require.NoError(test, err)
+	require.NoError(test, file.Close())
+
+	config, err := LoadConfigFromFile(file.Name())
+	require.NoError(test, err)
+
+	defaults := DefaultSigningConfig()
+	require.Equal(test, 1500*time.Millisecond, config.Signing.BlockTimeout.Duration)
+	require.Equal(test, 500*time.Millisecond, config.Signing.ShareSignTimeout.Duration)
+	require.Equal(test, 3, config.Signing.retryAttempts())
+	require.Equal(test, defaults.EphemeralTimeout, config.Signing.EphemeralTimeout)
+	require.Equal(test, defaults.RetryBackoff, config.Signing.RetryBackoff)
+	require.NoError(test, config.Signing.Validate())
+}
+
+func TestLoadConfigSigningNoRetry(test *testing.T) {
+	file, err := ioutil.TempFile("", "config.toml")
+	require.NoError(test, err)
+	defer os.Remove(file.Name())
+
+	_, err = file.WriteString(`
+chain_id = "chain-id"
+
+[signing]
+retry_attempts = 0
+`)
+	require.NoError(test, err)
+	require.NoError(test, file.Close())
+
+	config, err := LoadConfigFromFile(file.Name())
+	require.NoError(test, err)
+
+	// the explicit 0 survives the defaults
+	signing := config.Signing.withDefaults()
+	require.Equal(test, 0, signing.retryAttempts())
+	require.Equal(test, signing.ShareSignTimeout.Duration, signing.retryBudget(signing.ShareSignTimeout.Duration))
+	require.NoError(test, signing.Validate())
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -21,13 +22,31 @@ type ThresholdValidator struct {
 
 	// stores the last sign state for a block we have fully signed
 	// Cached to respond to SignVote requests if we already have a signature
-	lastSignState SignState
+	// Guarded by the mutex as the leader election raises it from its own goroutine.
+	lastSignStateMutex sync.Mutex
+	lastSignState      SignState
 
 	// our own cosigner
 	cosigner Cosigner
 
 	// peer cosigners
 	peers []Cosigner
+
+	// timeouts and retry policy for the requests to the cosigners
+	signing SigningConfig
+
+	// optional, pre-deals ephemeral secret parts for the heights after the signed one
+	preDealer *EphemeralPreDealer
+
+	// optional, records the block signatures
+	auditLog *AuditLog
+
+	// optional, only the elected leader signs blocks
+	election LeaderElection
+
+	// number of invalid share signatures, by cosigner ID
+	misbehaviourMutex sync.Mutex
+	misbehaviour      map[int]uint64
 }
 
 type ThresholdValidatorOpt struct {
@@ -36,6 +55,23 @@ type ThresholdValidatorOpt struct {
 	SignState SignState
 	Cosigner  Cosigner
 	Peers     []Cosigner
+	Signing   SigningConfig
+	PreDealer *EphemeralPreDealer
+	AuditLog  *AuditLog
+	Election  LeaderElection
+}
+
+// BlockSigner is implemented by the cosigners that sign blocks for the nodes attached to the others
+// A follower forwards the blocks of its nodes to the leader, so a node attached to any cosigner is served.
+type BlockSigner interface {
+	SignBlock(ctx context.Context, req *CosignerSignBlockRequest) (*CosignerSignBlockResponse, error)
+}
+
+// SharePubKeysProvider is implemented by cosigners that know the public keys of the shares
+// Share signatures are only verified one by one if our cosigner implements it.
+type SharePubKeysProvider interface {
+	// SharePubKeys returns the public keys of the shares used at the height, by ID - 1
+	SharePubKeys(height int64) [][]byte
 }
 
 // NewThresholdValidator creates and returns a new ThresholdValidator
@@ -46,6 +82,11 @@ func NewThresholdValidator(opt *ThresholdValidatorOpt) *ThresholdValidator {
 	validator.threshold = opt.Threshold
 	validator.pubkey = opt.Pubkey
 	validator.lastSignState = opt.SignState
+	validator.signing = opt.Signing.withDefaults()
+	validator.preDealer = opt.PreDealer
+	validator.auditLog = opt.AuditLog
+	validator.election = opt.Election
+	validator.misbehaviour = make(map[int]uint64)
 	return validator
 }
 
@@ -58,6 +99,11 @@ func (pv *ThresholdValidator) GetPubKey() (crypto.PubKey, error) {
 // SignVote signs a canonical representation of the vote, along with the
 // chainID. Implements PrivValidator.
 func (pv *ThresholdValidator) SignVote(chainID string, vote *tmProto.Vote) error {
+	return pv.SignVoteFrom("", chainID, vote)
+}
+
+// SignVoteFrom signs the vote requested by the source node. Implements SourcePrivValidator.
+func (pv *ThresholdValidator) SignVoteFrom(source string, chainID string, vote *tmProto.Vote) error {
 	// logger.Info("***************** ThresholdValidator SignVote *************************", " height", vote.Height, "round", vote.Round, "step", VoteToStep(vote))
 	block := &block{
 		Height:    vote.Height,
@@ -66,7 +112,7 @@ func (pv *ThresholdValidator) SignVote(chainID string, vote *tmProto.Vote) error
 		Timestamp: vote.Timestamp,
 		SignBytes: tm.VoteSignBytes(chainID, vote),
 	}
-	sig, stamp, err := pv.signBlock(chainID, block)
+	sig, stamp, err := pv.signBlock(ContextWithSignSource(context.Background(), source), chainID, block)
 
 	vote.Signature = sig
 	vote.Timestamp = stamp
@@ -77,6 +123,11 @@ func (pv *ThresholdValidator) SignVote(chainID string, vote *tmProto.Vote) error
 // SignProposal signs a canonical representation of the proposal, along with
 // the chainID. Implements PrivValidator.
 func (pv *ThresholdValidator) SignProposal(chainID string, proposal *tmProto.Proposal) error {
+	return pv.SignProposalFrom("", chainID, proposal)
+}
+
+// SignProposalFrom signs the proposal requested by the source node. Implements SourcePrivValidator.
+func (pv *ThresholdValidator) SignProposalFrom(source string, chainID string, proposal *tmProto.Proposal) error {
 	// logger.Info("***************** ThresholdValidator SignProposal ***********************", " height", proposal.Height, "round", proposal.Round, "step", ProposalToStep(proposal))
 	block := &block{
 		Height:    proposal.Height,
@@ -85,7 +136,7 @@ func (pv *ThresholdValidator) SignProposal(chainID string, proposal *tmProto.Pro
 		Timestamp: proposal.Timestamp,
 		SignBytes: tm.ProposalSignBytes(chainID, proposal),
 	}
-	sig, stamp, err := pv.signBlock(chainID, block)
+	sig, stamp, err := pv.signBlock(ContextWithSignSource(context.Background(), source), chainID, block)
 
 	proposal.Signature = sig
 	proposal.Timestamp = stamp
@@ -93,6 +144,71 @@ func (pv *ThresholdValidator) SignProposal(chainID string, proposal *tmProto.Pro
 	return err
 }
 
+// SignVoteExtension signs the CometBFT 0.38 extension of a precommit signed with SignVote
+func (pv *ThresholdValidator) SignVoteExtension(chainID string, vote *tmProto.Vote, extension []byte) ([]byte, error) {
+	return pv.SignVoteExtensionFrom("", chainID, vote, extension)
+}
+
+// SignVoteExtensionFrom signs the vote extension requested by the source node. Implements ExtensionPrivValidator.
+//
+// The extension is signed under the watermark of its precommit, which must be the last block signed,
+// and kept next to the precommit in the sign state. A precommit requested again afterwards is still
+// served from the sign state, with its extension. The extension has its own ephemeral secrets, and a
+// different extension at the same height and round is signed again and replaces the previous one.
+func (pv *ThresholdValidator) SignVoteExtensionFrom(source string, chainID string, vote *tmProto.Vote, extension []byte) ([]byte, error) {
+	ctx := ContextWithSignSource(context.Background(), source)
+	signBytes := VoteExtensionSignBytes(chainID, vote, extension)
+
+	if pv.election != nil && !pv.election.IsLeader() {
+		signature, _, err := pv.forwardBlock(ctx, chainID, signBytes, time.Time{})
+		return signature, err
+	}
+	return pv.leadVoteExtension(ctx, chainID, vote.Height, int64(vote.Round), signBytes)
+}
+
+// leadVoteExtension signs the vote extension as the leader
+func (pv *ThresholdValidator) leadVoteExtension(ctx context.Context, chainID string, height int64, round int64, signBytes []byte) ([]byte, error) {
+	if pv.election != nil {
+		if err := pv.election.VerifyLeader(); err != nil {
+			return nil, err
+		}
+	}
+
+	pv.lastSignStateMutex.Lock()
+	lss := pv.lastSignState
+	pv.lastSignStateMutex.Unlock()
+
+	sameExtension, err := lss.CheckVoteExtensionHRS(height, round, signBytes)
+	if err != nil {
+		return nil, err
+	}
+	if sameExtension {
+		return lss.ExtensionSignature, nil
+	}
+	precommitKey := HRSKey{Height: height, Round: round, Step: stepPrecommit}
+	if lss.hrsKey() != precommitKey {
+		return nil, fmt.Errorf("the precommit of the vote extension at height %v round %v was not signed", height, round)
+	}
+
+	signature, err := pv.signThreshold(ctx, chainID, height, round, stepVoteExtension, signBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	pv.lastSignStateMutex.Lock()
+	newLss := pv.lastSignState
+	pv.lastSignStateMutex.Unlock()
+	if newLss.hrsKey() != precommitKey {
+		return nil, errors.New("the sign state moved while the vote extension was signed")
+	}
+	newLss.ExtensionSignature = signature
+	newLss.ExtensionSignBytes = signBytes
+	if err := pv.saveSignState(chainID, newLss); err != nil {
+		return nil, err
+	}
+	return signature, nil
+}
+
 type block struct {
 	Height    int64
 	Round     int64
@@ -101,10 +217,109 @@ type block struct {
 	Timestamp time.Time
 }
 
-func (pv *ThresholdValidator) signBlock(chainID string, block *block) ([]byte, time.Time, error) {
+// signBlock signs the block with the threshold of cosigners
+// All requests to the cosigners are cancelled when ctx is done
+func (pv *ThresholdValidator) signBlock(ctx context.Context, chainID string, block *block) ([]byte, time.Time, error) {
+	// the leader signs for the cluster, followers forward the blocks of their nodes to it
+	if pv.election != nil && !pv.election.IsLeader() {
+		return pv.forwardBlock(ctx, chainID, block.SignBytes, block.Timestamp)
+	}
+	return pv.leadBlock(ctx, chainID, block)
+}
+
+// SignBlock signs the block a follower forwarded for the node attached to it
+// The block is refused unless we are the leader, it is never forwarded again.
+// Implements BlockSigner.
+func (pv *ThresholdValidator) SignBlock(ctx context.Context, req *CosignerSignBlockRequest) (*CosignerSignBlockResponse, error) {
+	height, round, step, err := UnpackHRS(req.SignBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	if step == stepVoteExtension {
+		signature, err := pv.leadVoteExtension(ctx, req.ChainID, height, round, req.SignBytes)
+		if err != nil {
+			return nil, err
+		}
+		return &CosignerSignBlockResponse{Signature: signature}, nil
+	}
+
+	signature, stamp, err := pv.leadBlock(ctx, req.ChainID, &block{
+		Height:    height,
+		Round:     round,
+		Step:      step,
+		Timestamp: timeFromUnixNano(req.Timestamp),
+		SignBytes: req.SignBytes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &CosignerSignBlockResponse{Signature: signature, Timestamp: unixNano(stamp)}, nil
+}
+
+// forwardBlock has the leader sign the sign bytes for our node
+// The leader may return the signature of the same block with another timestamp, which is returned with it.
+func (pv *ThresholdValidator) forwardBlock(ctx context.Context, chainID string, signBytes []byte, timestamp time.Time) ([]byte, time.Time, error) {
+	leaderID := pv.election.LeaderID()
+	var leader BlockSigner
+	for _, peer := range pv.peers {
+		if blockSigner, ok := peer.(BlockSigner); ok && peer.GetID() == leaderID {
+			leader = blockSigner
+		}
+	}
+	if leader == nil {
+		return nil, timestamp, ErrNotLeader
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, pv.signing.BlockTimeout.Duration)
+	defer cancel()
+
+	res, err := leader.SignBlock(ctx, &CosignerSignBlockRequest{
+		ChainID:   chainID,
+		SignBytes: signBytes,
+		Timestamp: unixNano(timestamp),
+		Source:    SignSourceFromContext(ctx),
+	})
+	if err != nil {
+		return nil, timestamp, fmt.Errorf("leader cosigner %d: %w", leaderID, err)
+	}
+	if res.Timestamp != 0 {
+		timestamp = timeFromUnixNano(res.Timestamp)
+	}
+	return res.Signature, timestamp, nil
+}
+
+// unixNano returns the unix nanoseconds of a timestamp, 0 for the zero time
+func unixNano(timestamp time.Time) int64 {
+	if timestamp.IsZero() {
+		return 0
+	}
+	return timestamp.UnixNano()
+}
+
+// timeFromUnixNano returns the UTC timestamp of unix nanoseconds, the zero time for 0
+func timeFromUnixNano(nanos int64) time.Time {
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos).UTC()
+}
+
+// leadBlock signs the block as the leader
+func (pv *ThresholdValidator) leadBlock(ctx context.Context, chainID string, block *block) ([]byte, time.Time, error) {
 	height, round, step, stamp := block.Height, block.Round, block.Step, block.Timestamp
+
+	// a leader deposed since, unaware of it yet, may not have the last watermark of the cluster
+	if pv.election != nil {
+		if err := pv.election.VerifyLeader(); err != nil {
+			return nil, stamp, err
+		}
+	}
+
 	// the block sign state for caching full block signatures
+	pv.lastSignStateMutex.Lock()
 	lss := pv.lastSignState
+	pv.lastSignStateMutex.Unlock()
 
 	// check watermark
 	sameHRS, err := lss.CheckHRS(height, int64(round), step)
@@ -123,162 +338,99 @@ func (pv *ThresholdValidator) signBlock(chainID string, block *block) ([]byte, t
 		return nil, stamp, errors.New("conflicting data")
 	}
 
+	signature, err := pv.signThreshold(ctx, chainID, height, round, step, signBytes)
+	if err != nil {
+		return nil, stamp, err
+	}
+
+	// a new HRS has no vote extension yet
+	pv.lastSignStateMutex.Lock()
+	newLss := pv.lastSignState
+	pv.lastSignStateMutex.Unlock()
+	newLss.Height = height
+	newLss.Round = round
+	newLss.Step = step
+	newLss.Signature = signature
+	newLss.SignBytes = signBytes
+	newLss.ExtensionSignature = nil
+	newLss.ExtensionSignBytes = nil
+	if err := pv.saveSignState(chainID, newLss); err != nil {
+		return nil, stamp, err
+	}
+
+	return signature, stamp, nil
+}
+
+// signThreshold collects the share signatures of the cosigners for the sign bytes at the HRS and combines them
+// The signature is recorded in the audit log before it is returned.
+func (pv *ThresholdValidator) signThreshold(ctx context.Context, chainID string, height int64, round int64, step int8, signBytes []byte) ([]byte, error) {
 	total := uint8(len(pv.peers) + 1)
 
 	// destination for share signatures
-	shareSignatures := make([][]byte, total)
+	shareResponses := make([]*CosignerSignResponse, total)
 
 	// share sigs is updated by goroutines
-	shareSignaturesMutex := sync.Mutex{}
+	shareResponsesMutex := sync.Mutex{}
 
 	wg := sync.WaitGroup{}
 	wg.Add(len(pv.peers))
 
 	ourID := pv.cosigner.GetID()
 
+	// get the parts for the next heights ready while we sign this one
+	pv.preDealer.Advance(height)
+
+	// the whole signature, retries included, must complete within the block timeout
+	// when it fires, the outstanding requests to the cosigners are cancelled
+	blockCtx, blockCtxCancel := context.WithTimeout(ctx, pv.signing.BlockTimeout.Duration)
+	defer blockCtxCancel()
+
 	// have our cosigner generate ephemeral info at the current height
-	_, err = pv.cosigner.GetEphemeralSecretPart(&CosignerGetEphemeralSecretPartRequest{
+	_, err := pv.cosigner.GetEphemeralSecretPart(blockCtx, &CosignerGetEphemeralSecretPartRequest{
 		ID:     int32(ourID),
 		Height: height,
 		Round:  round,
 		Step:   int32(step),
 	})
 	if err != nil {
-		return nil, stamp, err
+		return nil, err
 	}
 
-	// There are two layers of goroutines for each cosigner.
-	// The outer routine for each cosigner to dispatch signing in parallel. This outer routine
-	// block on the signing request completing.
-	// The inner routine (formed within each request goroutine), dispatches the actual signing call.
-	// This is to support a time out which can happen when using remote signers.
+	// Each cosigner is requested in its own goroutine so signing happens in parallel
+	// Failed requests are retried as long as the block timeout allows
 	for _, peer := range pv.peers {
 		request := func(peer Cosigner) {
+			defer wg.Done()
+
 			peerId := peer.GetID()
 			peerIdx := peerId - 1
 
-			// cosigner.Sign makes a blocking RPC request (with no timeout)
-			// to prevent it from hanging our process indefinitely, we use a timeout context
-			// and another goroutine
-			signCtx, signCtxCancel := context.WithTimeout(context.Background(), 3*time.Second)
-
-			go func() {
-				hasResp, err := pv.cosigner.HasEphemeralSecretPart(CosignerHasEphemeralSecretPartRequest{
-					ID:     peerId,
-					Height: height,
-					Round:  round,
-					Step:   step,
-				})
+			err := pv.signing.retry(blockCtx, pv.signing.EphemeralTimeout.Duration, func(ctx context.Context) error {
+				return exchangeEphemeralSecretPart(ctx, pv.cosigner, peer, height, round, step)
+			})
+			if err != nil {
+				fmt.Printf("ERROR ephemeral secret part exchange with cosigner %d: %s\n", peerId, err)
+				return
+			}
 
-				// did we timeout or finish elsewhere?
-				select {
-				case <-signCtx.Done():
-					return
-				default:
-				}
-
-				if err != nil {
-					fmt.Printf("ERROR HasEphemeralSecretPart: %s\n", err)
-					signCtxCancel()
-					return
-				}
-
-				if !hasResp.Exists {
-					// if we don't already have an ephemeral secret part for the HRS, we need to get one
-					ephSecretResp, err := peer.GetEphemeralSecretPart(&CosignerGetEphemeralSecretPartRequest{
-						ID:     int32(ourID),
-						Height: height,
-						Round:  round,
-						Step:   int32(step),
-					})
-
-					if err != nil {
-						fmt.Printf("ERROR GetEphemeralSecretPart %s\n", err)
-					}
-
-					// did we timeout or finish elsewhere?
-					select {
-					case <-signCtx.Done():
-						return
-					default:
-					}
-
-					if err != nil {
-						signCtxCancel()
-						return
-					}
-
-					// set the response for ourselves
-					err = pv.cosigner.SetEphemeralSecretPart(CosignerSetEphemeralSecretPartRequest{
-						SourceSig:                      ephSecretResp.SourceSig,
-						SourceID:                       int(ephSecretResp.SourceID),
-						SourceEphemeralSecretPublicKey: ephSecretResp.SourceEphemeralSecretPublicKey,
-						EncryptedSharePart:             ephSecretResp.EncryptedSharePart,
-						Height:                         height,
-						Round:                          round,
-						Step:                           step,
-					})
-
-					if err != nil {
-						fmt.Printf("ERROR SetEphemeralSecretPart %s\n", err)
-					}
-
-					// did we timeout or finish elsewhere?
-					select {
-					case <-signCtx.Done():
-						return
-					default:
-					}
-
-					if err != nil {
-						signCtxCancel()
-						return
-					}
-				}
-
-				// ask the cosigner to sign with their share
-				sigResp, err := peer.Sign(&CosignerSignRequest{
+			// ask the cosigner to sign with their share
+			var sigResp *CosignerSignResponse
+			err = pv.signing.retry(blockCtx, pv.signing.ShareSignTimeout.Duration, func(ctx context.Context) error {
+				var err error
+				sigResp, err = peer.Sign(ctx, &CosignerSignRequest{
 					SignBytes: signBytes,
 				})
-
-				if err != nil {
-					fmt.Printf("ERROR Sign %s\n", err)
-				}
-
-				// did we timeout or finish elsewhere?
-				select {
-				case <-signCtx.Done():
-					return
-				default:
-				}
-
-				if err != nil {
-					signCtxCancel()
-					return
-				}
-
-				// The signCtx is done if it times out or if the blockCtx done cancels it
-				select {
-				case <-signCtx.Done():
-					return
-				default:
-				}
-
-				defer signCtxCancel()
-
-				shareSignaturesMutex.Lock()
-				defer shareSignaturesMutex.Unlock()
-
-				shareSignatures[peerIdx] = make([]byte, len(sigResp.Signature))
-				copy(shareSignatures[peerIdx], sigResp.Signature)
-			}()
-
-			// the sign context finished or timed out
-			select {
-			case <-signCtx.Done():
+				return err
+			})
+			if err != nil {
+				fmt.Printf("ERROR Sign with cosigner %d: %s\n", peerId, err)
+				return
 			}
 
-			wg.Done()
+			shareResponsesMutex.Lock()
+			defer shareResponsesMutex.Unlock()
+
+			shareResponses[peerIdx] = sigResp
 		}
 
 		go request(peer)
@@ -288,56 +440,270 @@ func (pv *ThresholdValidator) signBlock(chainID string, block *block) ([]byte, t
 	// A Cosigner will either respond in time, or be canceled with timeout
 	wg.Wait()
 
-	shareSignaturesMutex.Lock()
-	defer shareSignaturesMutex.Unlock()
+	shareResponsesMutex.Lock()
+	defer shareResponsesMutex.Unlock()
 
 	// sign with our share now
-	signResp, err := pv.cosigner.Sign(&CosignerSignRequest{
+	signResp, err := pv.cosigner.Sign(blockCtx, &CosignerSignRequest{
 		SignBytes: signBytes,
 	})
 	if err != nil {
-		return nil, stamp, err
+		return nil, err
+	}
+
+	shareResponses[ourID-1] = signResp
+
+	signature, sigIds, err := pv.combineShareSignatures(signBytes, signResp.EphemeralPublic, shareResponses, height, round, step)
+	if err != nil {
+		return nil, err
+	}
+
+	// the signature is recorded before anything else, so it can't leave without a record
+	if pv.auditLog != nil {
+		record := newAuditRecord(ctx, AuditSignerValidator, ourID, height, round, step, signBytes, signature, sigIds)
+		record.ChainID = chainID
+		if err := pv.auditLog.Append(record); err != nil {
+			return nil, err
+		}
+	}
+
+	return signature, nil
+}
+
+// saveSignState replicates the sign state to the cluster and persists it as our watermark
+// The signature is only handed out once the watermark is replicated and persisted,
+// the next leader then refuses to sign below it.
+// The replication is applied to our own sign state too, so it must not hold the sign state mutex.
+func (pv *ThresholdValidator) saveSignState(chainID string, newLss SignState) error {
+	if pv.election != nil {
+		if err := pv.election.ReplicateSignState(chainID, newLss); err != nil {
+			return err
+		}
+	}
+
+	pv.lastSignStateMutex.Lock()
+	defer pv.lastSignStateMutex.Unlock()
+
+	if err := newLss.Save(); err != nil {
+		return err
 	}
+	pv.lastSignState = newLss
+	return nil
+}
 
-	ephemeralPublic := signResp.EphemeralPublic
+// RaiseSignState raises the watermark to the sign state replicated by the leader
+// A sign state below ours is ignored, one at our HRS only brings its vote extension.
+// Implements SignStateReceiver.
+func (pv *ThresholdValidator) RaiseSignState(signState SignState) error {
+	pv.lastSignStateMutex.Lock()
+	defer pv.lastSignStateMutex.Unlock()
 
-	shareSignatures[ourID-1] = make([]byte, len(signResp.Signature))
-	copy(shareSignatures[ourID-1], signResp.Signature)
+	if !pv.lastSignState.raisedBy(&signState) {
+		return nil
+	}
 
-	// collect all valid responses into array of ids and signatures for the threshold lib
-	sigIds := make([]int, 0)
-	shareSigs := make([][]byte, 0)
-	for idx, shareSig := range shareSignatures {
-		if len(shareSig) == 0 {
+	newLss := pv.lastSignState
+	newLss.Height = signState.Height
+	newLss.Round = signState.Round
+	newLss.Step = signState.Step
+	newLss.Signature = signState.Signature
+	newLss.SignBytes = signState.SignBytes
+	newLss.ExtensionSignature = signState.ExtensionSignature
+	newLss.ExtensionSignBytes = signState.ExtensionSignBytes
+	if err := newLss.Save(); err != nil {
+		return err
+	}
+	pv.lastSignState = newLss
+	return nil
+}
+
+// combineShareSignatures assembles the share signatures into the block signature
+//
+// Shares signed for another ephemeral public key than the one of the majority, or that fail the
+// verification against the public key of the share, are reported and left out. A share only counts as
+// verified once the ephemeral share publics of a threshold of verified shares also interpolate into the
+// ephemeral public key, a cosigner could otherwise forge a share along with a matching ephemeral share
+// public. Unverified shares are only combined when the verified ones can't make the signature, and the
+// combination is then kept only if the signature verifies. The IDs of the combined shares are returned with the signature.
+func (pv *ThresholdValidator) combineShareSignatures(signBytes []byte, ourEphemeralPublic []byte, responses []*CosignerSignResponse, height int64, round int64, step int8) ([]byte, []int, error) {
+	total := uint8(len(responses))
+	pubKeyBytes := pv.pubkey.Bytes()
+
+	var sharePubKeys [][]byte
+	if provider, ok := pv.cosigner.(SharePubKeysProvider); ok {
+		sharePubKeys = provider.SharePubKeys(height)
+	}
+
+	// the shares can only be combined if they were signed for the same ephemeral public key
+	// cosigners usually agree on it, otherwise go with the majority, and with ours on a tie
+	votes := make(map[string]int)
+	for _, resp := range responses {
+		if resp == nil || len(resp.Signature) == 0 {
 			continue
 		}
-		sigIds = append(sigIds, idx+1)
+		votes[string(resp.EphemeralPublic)]++
+	}
 
-		// we are ok to use the share signatures - complete boolean
-		// prevents future concurrent access
-		shareSigs = append(shareSigs, shareSig)
+	ephemeralPublic := ourEphemeralPublic
+	for key, count := range votes {
+		if count > votes[string(ephemeralPublic)] {
+			ephemeralPublic = []byte(key)
+		}
 	}
 
-	if len(sigIds) < pv.threshold {
-		return nil, stamp, errors.New("Not enough co-signers")
+	// verified shares passed the check against the share public key,
+	// unverified ones come from cosigners running an older version that don't return their ephemeral share public
+	verified := make([]int, 0)
+	unverified := make([]int, 0)
+	for idx, resp := range responses {
+		if resp == nil || len(resp.Signature) == 0 {
+			continue
+		}
+		id := idx + 1
+
+		if !bytes.Equal(resp.EphemeralPublic, ephemeralPublic) {
+			pv.reportMisbehaviour(id, height, round, step, "share signed for another ephemeral public key")
+			continue
+		}
+
+		if id > len(sharePubKeys) || len(resp.EphemeralSharePublic) == 0 {
+			unverified = append(unverified, id)
+			continue
+		}
+
+		if !verifyShareSignature(signBytes, pubKeyBytes, ephemeralPublic, sharePubKeys[id-1], resp.EphemeralSharePublic, resp.Signature) {
+			pv.reportMisbehaviour(id, height, round, step, "invalid share signature")
+			continue
+		}
+
+		verified = append(verified, id)
+	}
+
+	if len(verified)+len(unverified) < pv.threshold {
+		return nil, nil, errors.New("Not enough co-signers")
 	}
 
-	// assemble into final signature
-	combinedSig := tsed25519.CombineShares(total, sigIds, shareSigs)
+	combine := func(sigIds []int) []byte {
+		shareSigs := make([][]byte, len(sigIds))
+		for idx, id := range sigIds {
+			shareSigs[idx] = responses[id-1].Signature
+		}
 
-	signature := append(ephemeralPublic, combinedSig...)
+		// assemble into final signature
+		combinedSig := tsed25519.CombineShares(total, sigIds, shareSigs)
 
-	// verify the combined signature before saving to watermark
-	if !pv.pubkey.VerifySignature(signBytes, signature) {
-		return nil, stamp, errors.New("Combined signature is not valid")
+		signature := make([]byte, 0, len(ephemeralPublic)+len(combinedSig))
+		signature = append(signature, ephemeralPublic...)
+		return append(signature, combinedSig...)
 	}
 
-	pv.lastSignState.Height = height
-	pv.lastSignState.Round = round
-	pv.lastSignState.Step = step
-	pv.lastSignState.Signature = signature
-	pv.lastSignState.SignBytes = signBytes
-	pv.lastSignState.Save()
+	if sigIds := consistentShares(verified, responses, ephemeralPublic, pv.threshold); sigIds != nil {
+		// the verified shares left out carry an ephemeral share public off the polynomial of the others
+		for _, id := range verified {
+			if !containsID(sigIds, id) && !ephemeralSharePublicOnPolynomial(sigIds, responses, id) {
+				pv.reportMisbehaviour(id, height, round, step, "forged ephemeral share public")
+			}
+		}
 
-	return signature, stamp, nil
+		// verify the combined signature before saving to watermark
+		signature := combine(sigIds)
+		if pv.pubkey.VerifySignature(signBytes, signature) {
+			return signature, sigIds, nil
+		}
+	}
+
+	// without a consistent threshold of verified shares, only the combined signature tells the right shares apart
+	// every subset with an unverified share is tried, the ones with the fewest unverified shares first
+	for _, sigIds := range shareSubsets(verified, unverified, pv.threshold) {
+		signature := combine(sigIds)
+		if pv.pubkey.VerifySignature(signBytes, signature) {
+			return signature, sigIds, nil
+		}
+	}
+
+	return nil, nil, fmt.Errorf("Combined signature of cosigners %v is not valid", append(verified, unverified...))
+}
+
+// consistentShares returns the verified shares whose ephemeral share publics interpolate into the ephemeral public key
+// It is all of them when they agree, otherwise the first threshold of them that does, or nil if none does.
+func consistentShares(verified []int, responses []*CosignerSignResponse, ephemeralPublic []byte, threshold int) []int {
+	if len(verified) < threshold {
+		return nil
+	}
+	candidates := [][]int{verified}
+	if len(verified) > threshold {
+		candidates = append(candidates, idSubsets(verified, threshold)...)
+	}
+	for _, ids := range candidates {
+		ephemeralSharePublics := make([][]byte, len(ids))
+		for idx, id := range ids {
+			ephemeralSharePublics[idx] = responses[id-1].EphemeralSharePublic
+		}
+		combined, err := interpolateElements(ids, ephemeralSharePublics)
+		if err == nil && bytes.Equal(combined, ephemeralPublic) {
+			return ids
+		}
+	}
+	return nil
+}
+
+// ephemeralSharePublicOnPolynomial returns true if the ephemeral share public of cosigner id
+// is on the polynomial that goes through the ephemeral share publics of ids
+func ephemeralSharePublicOnPolynomial(ids []int, responses []*CosignerSignResponse, id int) bool {
+	ephemeralSharePublics := make([][]byte, len(ids))
+	for idx, other := range ids {
+		ephemeralSharePublics[idx] = responses[other-1].EphemeralSharePublic
+	}
+	expected, err := interpolateElementsAt(ids, ephemeralSharePublics, id)
+	return err == nil && bytes.Equal(expected, responses[id-1].EphemeralSharePublic)
+}
+
+// shareSubsets returns the subsets of threshold shares with at least one unverified share,
+// ordered by the number of unverified shares they hold
+func shareSubsets(verified []int, unverified []int, threshold int) [][]int {
+	subsets := make([][]int, 0)
+	for count := 1; count <= len(unverified) && count <= threshold; count++ {
+		if threshold-count > len(verified) {
+			continue
+		}
+		for _, unverifiedIds := range idSubsets(unverified, count) {
+			for _, verifiedIds := range idSubsets(verified, threshold-count) {
+				sigIds := append(append([]int{}, verifiedIds...), unverifiedIds...)
+				sort.Ints(sigIds)
+				subsets = append(subsets, sigIds)
+			}
+		}
+	}
+	return subsets
+}
+
+// idSubsets returns the subsets of size IDs of ids, in lexicographic order
+func idSubsets(ids []int, size int) [][]int {
+	if size == 0 {
+		return [][]int{{}}
+	}
+	subsets := make([][]int, 0)
+	for idx := 0; idx+size <= len(ids); idx++ {
+		for _, rest := range idSubsets(ids[idx+1:], size-1) {
+			subsets = append(subsets, append([]int{ids[idx]}, rest...))
+		}
+	}
+	return subsets
+}
+
+// reportMisbehaviour logs and counts a share signature of the cosigner that had to be left out
+func (pv *ThresholdValidator) reportMisbehaviour(id int, height int64, round int64, step int8, reason string) {
+	pv.misbehaviourMutex.Lock()
+	pv.misbehaviour[id]++
+	count := pv.misbehaviour[id]
+	pv.misbehaviourMutex.Unlock()
+
+	fmt.Printf("ERROR cosigner %d misbehaved at height %d round %d step %d: %s (%d times so far)\n", id, height, round, step, reason, count)
+}
+
+// MisbehaviourCount returns the number of share signatures of the cosigner that had to be left out
+func (pv *ThresholdValidator) MisbehaviourCount(id int) uint64 {
+	pv.misbehaviourMutex.Lock()
+	defer pv.misbehaviourMutex.Unlock()
+	return pv.misbehaviour[id]
 }
@@ -1,14 +1,17 @@
 package signer
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
 	"io/ioutil"
+	"math/big"
 	"os"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 	tmCryptoEd25519 "github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/libs/log"
 	tmProto "github.com/tendermint/tendermint/proto/tendermint/types"
 	tm "github.com/tendermint/tendermint/types"
 	tsed25519 "gitlab.com/polychainlabs/threshold-ed25519/pkg"
@@ -118,7 +121,7 @@ func TestThresholdValidator2of2(test *testing.T) {
 	//
 	// An enhancement could be to have Local cosigner logic directly interface their peers.
 	{
-		cosigner1EphSecretPart, err := cosigner1.GetEphemeralSecretPart(&CosignerGetEphemeralSecretPartRequest{
+		cosigner1EphSecretPart, err := cosigner1.GetEphemeralSecretPart(context.Background(), &CosignerGetEphemeralSecretPartRequest{
 			ID:     2,
 			Height: proposal.Height,
 			Round:  int64(proposal.Round),
@@ -126,14 +129,14 @@ func TestThresholdValidator2of2(test *testing.T) {
 		})
 		require.NoError(test, err)
 
-		cosigner2.SetEphemeralSecretPart(CosignerSetEphemeralSecretPartRequest{
+		cosigner2.SetEphemeralSecretPart(context.Background(), &CosignerSetEphemeralSecretPartRequest{
 			SourceSig:                      cosigner1EphSecretPart.SourceSig,
-			SourceID:                       int(cosigner1EphSecretPart.SourceID),
+			SourceID:                       cosigner1EphSecretPart.SourceID,
 			SourceEphemeralSecretPublicKey: cosigner1EphSecretPart.SourceEphemeralSecretPublicKey,
 			EncryptedSharePart:             cosigner1EphSecretPart.EncryptedSharePart,
 			Height:                         proposal.Height,
 			Round:                          int64(proposal.Round),
-			Step:                           ProposalToStep(&proposal),
+			Step:                           int32(ProposalToStep(&proposal)),
 		})
 	}
 
@@ -143,3 +146,225 @@ func TestThresholdValidator2of2(test *testing.T) {
 	require.True(test, privateKey.PubKey().VerifySignature(signBytes, proposal.Signature))
 
 }
+
+// The threshold validator is driven entirely through remote cosigners,
+// each backed by a local cosigner behind its own rpc server
+func TestThresholdValidatorRemoteCosigners(test *testing.T) {
+	total := uint8(2)
+	threshold := uint8(2)
+
+	bitSize := 4096
+	rsaKey1, err := rsa.GenerateKey(rand.Reader, bitSize)
+	require.NoError(test, err)
+
+	rsaKey2, err := rsa.GenerateKey(rand.Reader, bitSize)
+	require.NoError(test, err)
+
+	peers := []CosignerPeer{{
+		ID:        1,
+		PublicKey: rsaKey1.PublicKey,
+	}, {
+		ID:        2,
+		PublicKey: rsaKey2.PublicKey,
+	}}
+
+	privateKey := tmCryptoEd25519.GenPrivKey()
+
+	privKeyBytes := [64]byte{}
+	copy(privKeyBytes[:], privateKey[:])
+	secretShares := tsed25519.DealShares(tsed25519.ExpandSecret(privKeyBytes[:32]), threshold, total)
+
+	rsaKeys := []*rsa.PrivateKey{rsaKey1, rsaKey2}
+	rpcServers := make([]*CosignerRpcServer, total)
+	remoteCosigners := make([]*RemoteCosigner, total)
+
+	for idx := range rpcServers {
+		stateFile, err := ioutil.TempFile("", "share_state.json")
+		require.NoError(test, err)
+		defer os.Remove(stateFile.Name())
+
+		signState, err := LoadOrCreateSignState(stateFile.Name())
+		require.NoError(test, err)
+
+		localCosigner := NewLocalCosigner(LocalCosignerConfig{
+			CosignerKey: CosignerKey{
+				PubKey:   privateKey.PubKey(),
+				ShareKey: secretShares[idx],
+				ID:       idx + 1,
+			},
+			SignState: &signState,
+			RsaKey:    *rsaKeys[idx],
+			Peers:     peers,
+			Total:     total,
+			Threshold: threshold,
+		})
+
+		rpcServers[idx] = NewCosignerRpcServer(&CosignerRpcServerConfig{
+			Logger:        log.NewNopLogger(),
+			ListenAddress: "127.0.0.1:0",
+			LocalCosigner: localCosigner,
+		})
+		require.NoError(test, rpcServers[idx].Start())
+		defer rpcServers[idx].Stop()
+
+		remoteCosigners[idx] = NewRemoteCosigner(idx+1, rpcServers[idx].Addr().String())
+		defer remoteCosigners[idx].Close()
+	}
+
+	// each rpc server fetches ephemeral parts from the other cosigner
+	rpcServers[0].chains[""].Peers = []*RemoteCosigner{remoteCosigners[1]}
+	rpcServers[1].chains[""].Peers = []*RemoteCosigner{remoteCosigners[0]}
+
+	stateFile, err := ioutil.TempFile("", "priv_validator_state.json")
+	require.NoError(test, err)
+	defer os.Remove(stateFile.Name())
+	signState, err := LoadOrCreateSignState(stateFile.Name())
+	require.NoError(test, err)
+
+	validator := NewThresholdValidator(&ThresholdValidatorOpt{
+		Pubkey:    privateKey.PubKey(),
+		Threshold: int(threshold),
+		SignState: signState,
+		Cosigner:  remoteCosigners[0],
+		Peers:     []Cosigner{remoteCosigners[1]},
+	})
+
+	var proposal tmProto.Proposal
+	proposal.Height = 1
+	proposal.Round = 0
+	proposal.Type = tmProto.ProposalType
+
+	err = validator.SignProposal("chain-id", &proposal)
+	require.NoError(test, err)
+
+	signBytes := tm.ProposalSignBytes("chain-id", &proposal)
+	require.True(test, privateKey.PubKey().VerifySignature(signBytes, proposal.Signature))
+}
+
+// corruptShareCosigner returns share signatures that don't verify
+type corruptShareCosigner struct {
+	Cosigner
+}
+
+func (cosigner *corruptShareCosigner) Sign(ctx context.Context, req *CosignerSignRequest) (*CosignerSignResponse, error) {
+	resp, err := cosigner.Cosigner.Sign(ctx, req)
+	if err != nil {
+		return resp, err
+	}
+	resp.Signature[0] ^= 1
+	return resp, nil
+}
+
+// forgedShareCosigner returns share signatures that are off by one, along with an ephemeral share public
+// forged to match them, so they pass the verification against the share public key
+type forgedShareCosigner struct {
+	Cosigner
+}
+
+func (cosigner *forgedShareCosigner) Sign(ctx context.Context, req *CosignerSignRequest) (*CosignerSignResponse, error) {
+	resp, err := cosigner.Cosigner.Sign(ctx, req)
+	if err != nil {
+		return resp, err
+	}
+	one := bigToScalar(big.NewInt(1))
+	resp.Signature = tsed25519.AddScalars([]tsed25519.Scalar{resp.Signature, one})
+	resp.EphemeralSharePublic = tsed25519.AddElements([]tsed25519.Element{resp.EphemeralSharePublic, tsed25519.ScalarMultiplyBase(one)})
+	return resp, nil
+}
+
+// exchangeAllEphemeralSecretParts exchanges the ephemeral secret parts of the HRS between all the cosigners
+func exchangeAllEphemeralSecretParts(test *testing.T, cosigners []*LocalCosigner, height int64, round int64, step int8) {
+	for _, local := range cosigners {
+		for _, peer := range cosigners {
+			if local != peer {
+				require.NoError(test, exchangeEphemeralSecretPart(context.Background(), local, peer, height, round, step))
+			}
+		}
+	}
+}
+
+// newMisbehaviourTestValidator returns a 2 of 3 validator for the first cosigner
+// with the ephemeral secret parts of the proposal at height 1 exchanged between all the cosigners
+func newMisbehaviourTestValidator(test *testing.T, cosigners []*LocalCosigner, pubKey tmCryptoEd25519.PubKey, peers []Cosigner) *ThresholdValidator {
+	exchangeAllEphemeralSecretParts(test, cosigners, 1, 0, stepPropose)
+
+	stateFile, err := ioutil.TempFile("", "priv_validator_state.json")
+	require.NoError(test, err)
+	test.Cleanup(func() { os.Remove(stateFile.Name()) })
+	signState, err := LoadOrCreateSignState(stateFile.Name())
+	require.NoError(test, err)
+
+	return NewThresholdValidator(&ThresholdValidatorOpt{
+		Pubkey:    pubKey,
+		Threshold: 2,
+		SignState: signState,
+		Cosigner:  cosigners[0],
+		Peers:     peers,
+	})
+}
+
+func TestThresholdValidatorExcludesInvalidShare(test *testing.T) {
+	privateKey, cosigners, cleanup := newTestCosigners(test, 2, 3)
+	defer cleanup()
+
+	pubKey := privateKey.PubKey().(tmCryptoEd25519.PubKey)
+	validator := newMisbehaviourTestValidator(test, cosigners, pubKey, []Cosigner{
+		&corruptShareCosigner{cosigners[1]},
+		cosigners[2],
+	})
+
+	var proposal tmProto.Proposal
+	proposal.Height = 1
+	proposal.Type = tmProto.ProposalType
+
+	err := validator.SignProposal("chain-id", &proposal)
+	require.NoError(test, err)
+	require.True(test, pubKey.VerifySignature(tm.ProposalSignBytes("chain-id", &proposal), proposal.Signature))
+
+	require.Equal(test, uint64(1), validator.MisbehaviourCount(2))
+	require.Equal(test, uint64(0), validator.MisbehaviourCount(3))
+}
+
+func TestThresholdValidatorExcludesForgedShare(test *testing.T) {
+	privateKey, cosigners, cleanup := newTestCosigners(test, 2, 3)
+	defer cleanup()
+
+	pubKey := privateKey.PubKey().(tmCryptoEd25519.PubKey)
+	validator := newMisbehaviourTestValidator(test, cosigners, pubKey, []Cosigner{
+		&forgedShareCosigner{cosigners[1]},
+		cosigners[2],
+	})
+
+	var proposal tmProto.Proposal
+	proposal.Height = 1
+	proposal.Type = tmProto.ProposalType
+
+	// the forged share passes its own verification, but not the check against the ephemeral public key
+	err := validator.SignProposal("chain-id", &proposal)
+	require.NoError(test, err)
+	require.True(test, pubKey.VerifySignature(tm.ProposalSignBytes("chain-id", &proposal), proposal.Signature))
+
+	require.Equal(test, uint64(1), validator.MisbehaviourCount(2))
+	require.Equal(test, uint64(0), validator.MisbehaviourCount(3))
+}
+
+func TestThresholdValidatorNotEnoughValidShares(test *testing.T) {
+	privateKey, cosigners, cleanup := newTestCosigners(test, 2, 3)
+	defer cleanup()
+
+	pubKey := privateKey.PubKey().(tmCryptoEd25519.PubKey)
+	validator := newMisbehaviourTestValidator(test, cosigners, pubKey, []Cosigner{
+		&corruptShareCosigner{cosigners[1]},
+		&corruptShareCosigner{cosigners[2]},
+	})
+
+	var proposal tmProto.Proposal
+	proposal.Height = 1
+	proposal.Type = tmProto.ProposalType
+
+	err := validator.SignProposal("chain-id", &proposal)
+	require.Error(test, err)
+
+	require.Equal(test, uint64(1), validator.MisbehaviourCount(2))
+	require.Equal(test, uint64(1), validator.MisbehaviourCount(3))
+}
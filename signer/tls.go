@@ -0,0 +1,186 @@
+package signer
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// TLSConfig points to the certificate material used for mutual TLS
+// between cosigners.
+type TLSConfig struct {
+	// PEM bundle of the CAs allowed to issue cosigner certificates
+	CAFile string `toml:"ca_file"`
+	// PEM certificate presented by this cosigner, both as server and as client
+	CertFile string `toml:"cert_file"`
+	// PEM private key for CertFile
+	KeyFile string `toml:"key_file"`
+}
+
+// Enabled returns true if any of the TLS options is set
+func (cfg TLSConfig) Enabled() bool {
+	return cfg.CAFile != "" || cfg.CertFile != "" || cfg.KeyFile != ""
+}
+
+func (cfg TLSConfig) load() (tls.Certificate, *x509.CertPool, error) {
+	if cfg.CAFile == "" || cfg.CertFile == "" || cfg.KeyFile == "" {
+		return tls.Certificate{}, nil, errors.New("tls requires ca_file, cert_file and key_file")
+	}
+
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+
+	caBytes, err := ioutil.ReadFile(cfg.CAFile)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caBytes) {
+		return tls.Certificate{}, nil, fmt.Errorf("no certificates found in %s", cfg.CAFile)
+	}
+
+	return cert, pool, nil
+}
+
+// ServerTLSConfig returns a tls config for the cosigner rpc server
+// Clients must present a certificate signed by one of the configured CAs
+func (cfg TLSConfig) ServerTLSConfig() (*tls.Config, error) {
+	cert, pool, err := cfg.load()
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// ClientTLSConfig returns a tls config to dial the cosigner identified by serverName
+func (cfg TLSConfig) ClientTLSConfig(serverName string) (*tls.Config, error) {
+	cert, pool, err := cfg.load()
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+		ServerName:   serverName,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// CertificateName returns the name the certificate of this cosigner was issued for
+// It is the common name, or the first DNS name if the common name is empty.
+func (cfg TLSConfig) CertificateName() (string, error) {
+	cert, _, err := cfg.load()
+	if err != nil {
+		return "", err
+	}
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return "", err
+	}
+
+	if leaf.Subject.CommonName != "" {
+		return leaf.Subject.CommonName, nil
+	}
+	if len(leaf.DNSNames) > 0 {
+		return leaf.DNSNames[0], nil
+	}
+	return "", errors.New("certificate has neither a common name nor a DNS name")
+}
+
+type cosignerIDContextKey struct{}
+
+// CosignerIDFromContext returns the ID of the authenticated peer cosigner
+// that issued an rpc request. It is only set when the rpc server uses mutual TLS.
+func CosignerIDFromContext(ctx context.Context) (int, bool) {
+	id, ok := ctx.Value(cosignerIDContextKey{}).(int)
+	return id, ok
+}
+
+// certificateMatchesName returns true if the certificate was issued for the given name
+// The name can be a DNS name or IP SAN, or the certificate common name
+func certificateMatchesName(cert *x509.Certificate, name string) bool {
+	if name == "" {
+		return false
+	}
+	if cert.Subject.CommonName == name {
+		return true
+	}
+	return cert.VerifyHostname(name) == nil
+}
+
+// peerCosignerID maps the verified client certificate of the request to a cosigner ID
+// The certificate must match the name of exactly one cosigner.
+func peerCosignerID(ctx context.Context, peerNames map[int]string) (int, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return 0, errors.New("no peer information in request")
+	}
+
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return 0, errors.New("request is not authenticated with tls")
+	}
+
+	return connectionCosignerID(tlsInfo.State, peerNames)
+}
+
+// connectionCosignerID maps the verified peer certificate of a TLS connection to a cosigner ID
+// The certificate must match the name of exactly one cosigner.
+func connectionCosignerID(state tls.ConnectionState, peerNames map[int]string) (int, error) {
+	chains := state.VerifiedChains
+	if len(chains) == 0 || len(chains[0]) == 0 {
+		return 0, errors.New("no verified peer certificate")
+	}
+	leaf := chains[0][0]
+
+	// a certificate matching several cosigners (wildcard or overlapping SANs) can't tell them apart
+	matches := []int{}
+	for id, name := range peerNames {
+		if certificateMatchesName(leaf, name) {
+			matches = append(matches, id)
+		}
+	}
+
+	switch len(matches) {
+	case 1:
+		return matches[0], nil
+	case 0:
+		return 0, fmt.Errorf("certificate %q does not belong to a configured cosigner", leaf.Subject.CommonName)
+	default:
+		sort.Ints(matches)
+		return 0, fmt.Errorf("certificate %q matches several cosigners %v", leaf.Subject.CommonName, matches)
+	}
+}
+
+// cosignerAuthInterceptor rejects requests from clients whose certificate does not map to a
+// configured cosigner, and stores the cosigner ID of the caller in the request context
+func cosignerAuthInterceptor(peerNames map[int]string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		id, err := peerCosignerID(ctx, peerNames)
+		if err != nil {
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		}
+		return handler(context.WithValue(ctx, cosignerIDContextKey{}, id), req)
+	}
+}
@@ -0,0 +1,173 @@
+package signer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tendermint/tendermint/libs/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	dir  string
+}
+
+func newTestCA(test *testing.T, dir string) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(test, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "cosigner-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(test, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(test, err)
+
+	writePEM(test, filepath.Join(dir, "ca.pem"), "CERTIFICATE", der)
+	return &testCA{cert: cert, key: key, dir: dir}
+}
+
+// issue writes a certificate for name signed by the CA and returns the TLSConfig pointing to it
+func (ca *testCA) issue(test *testing.T, name string) TLSConfig {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(test, err)
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(test, err)
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(test, err)
+
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	require.NoError(test, err)
+
+	cfg := TLSConfig{
+		CAFile:   filepath.Join(ca.dir, "ca.pem"),
+		CertFile: filepath.Join(ca.dir, name+".pem"),
+		KeyFile:  filepath.Join(ca.dir, name+"-key.pem"),
+	}
+	writePEM(test, cfg.CertFile, "CERTIFICATE", der)
+	writePEM(test, cfg.KeyFile, "EC PRIVATE KEY", keyBytes)
+	return cfg
+}
+
+func writePEM(test *testing.T, file string, blockType string, der []byte) {
+	err := ioutil.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600)
+	require.NoError(test, err)
+}
+
+func TestCosignerRpcServerMutualTLS(test *testing.T) {
+	dir, err := ioutil.TempDir("", "cosigner-tls")
+	require.NoError(test, err)
+	defer os.RemoveAll(dir)
+
+	ca := newTestCA(test, dir)
+	serverCfg := ca.issue(test, "cosigner1")
+	peerCfg := ca.issue(test, "cosigner2")
+	intruderCfg := ca.issue(test, "intruder")
+
+	serverTLS, err := serverCfg.ServerTLSConfig()
+	require.NoError(test, err)
+
+	rpcServer := NewCosignerRpcServer(&CosignerRpcServerConfig{
+		Logger:        log.NewTMLogger(log.NewSyncWriter(os.Stdout)),
+		ListenAddress: "127.0.0.1:0",
+		LocalCosigner: &DummyCosigner{},
+		TLSConfig:     serverTLS,
+		PeerNames:     map[int]string{2: "cosigner2"},
+	})
+	require.NoError(test, rpcServer.Start())
+	defer rpcServer.Stop()
+
+	address := rpcServer.Addr().String()
+
+	// a configured peer is accepted
+	peerTLS, err := peerCfg.ClientTLSConfig("cosigner1")
+	require.NoError(test, err)
+	resp, err := NewRemoteCosignerWithTLS(1, address, peerTLS).GetEphemeralSecretPart(context.Background(), &CosignerGetEphemeralSecretPartRequest{})
+	require.NoError(test, err)
+	require.Equal(test, []byte("bar"), resp.EncryptedSharePart)
+
+	// a certificate from the same CA that does not map to a cosigner is rejected
+	intruderTLS, err := intruderCfg.ClientTLSConfig("cosigner1")
+	require.NoError(test, err)
+	_, err = NewRemoteCosignerWithTLS(1, address, intruderTLS).GetEphemeralSecretPart(context.Background(), &CosignerGetEphemeralSecretPartRequest{})
+	require.Error(test, err)
+	require.Equal(test, codes.PermissionDenied, status.Code(err))
+
+	// plaintext clients are rejected
+	_, err = NewRemoteCosigner(1, address).GetEphemeralSecretPart(context.Background(), &CosignerGetEphemeralSecretPartRequest{})
+	require.Error(test, err)
+}
+
+func TestCosignerConfigTLSServerName(test *testing.T) {
+	require.Equal(test, "2.2.2.2", CosignerConfig{Address: "tcp://2.2.2.2:1234"}.TLSServerName())
+	require.Equal(test, "cosigner2", CosignerConfig{Address: "tcp://2.2.2.2:1234", TLSName: "cosigner2"}.TLSServerName())
+}
+
+// tlsPeerContext returns the context of a request authenticated with the certificate
+func tlsPeerContext(cert *x509.Certificate) context.Context {
+	return peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{
+			VerifiedChains: [][]*x509.Certificate{{cert}},
+		}},
+	})
+}
+
+func TestPeerCosignerIDAmbiguousCertificate(test *testing.T) {
+	peerNames := map[int]string{
+		2: "cosigner2.example.com",
+		3: "cosigner3.example.com",
+	}
+
+	cert := &x509.Certificate{
+		Subject:  pkix.Name{CommonName: "cosigner2.example.com"},
+		DNSNames: []string{"cosigner2.example.com"},
+	}
+	id, err := peerCosignerID(tlsPeerContext(cert), peerNames)
+	require.NoError(test, err)
+	require.Equal(test, 2, id)
+
+	// a wildcard certificate matches both cosigners and is rejected
+	wildcard := &x509.Certificate{
+		Subject:  pkix.Name{CommonName: "*.example.com"},
+		DNSNames: []string{"*.example.com"},
+	}
+	_, err = peerCosignerID(tlsPeerContext(wildcard), peerNames)
+	require.Error(test, err)
+	require.Contains(test, err.Error(), "several cosigners")
+}
@@ -0,0 +1,178 @@
+package signer
+
+import (
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/hkdf"
+)
+
+const transportKeyInfo = "valink cosigner ephemeral share part"
+
+// CosignerTransportKey is the private key a cosigner uses to exchange ephemeral share parts
+// Share parts are encrypted to the X25519 key of the receiver and signed with the Ed25519 key of the sender.
+type CosignerTransportKey struct {
+	EncryptionKey []byte             `json:"x25519_key"`
+	SigningKey    ed25519.PrivateKey `json:"ed25519_key"`
+}
+
+// CosignerTransport is the private side of the transport key of a cosigner
+// A CosignerTransportKey implements it in memory, a key backend may keep the keys out of reach instead.
+type CosignerTransport interface {
+	// PubKey returns the public keys that the other cosigners need to talk to us
+	PubKey() (*CosignerTransportPubKey, error)
+
+	// Open decrypts a ciphertext sealed to our public key
+	Open(ciphertext []byte) ([]byte, error)
+
+	// Sign signs a digest with the Ed25519 key
+	Sign(digest []byte) ([]byte, error)
+}
+
+var _ CosignerTransport = &CosignerTransportKey{}
+
+// CosignerTransportPubKey is the public part of a CosignerTransportKey
+type CosignerTransportPubKey struct {
+	EncryptionKey []byte            `json:"x25519_pub"`
+	SigningKey    ed25519.PublicKey `json:"ed25519_pub"`
+}
+
+// GenerateCosignerTransportKey returns a new random transport key
+func GenerateCosignerTransportKey() (*CosignerTransportKey, error) {
+	encryptionKey := make([]byte, curve25519.ScalarSize)
+	if _, err := rand.Read(encryptionKey); err != nil {
+		return nil, err
+	}
+
+	_, signingKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CosignerTransportKey{
+		EncryptionKey: encryptionKey,
+		SigningKey:    signingKey,
+	}, nil
+}
+
+// PubKey returns the public keys that the other cosigners need to talk to us
+func (key *CosignerTransportKey) PubKey() (*CosignerTransportPubKey, error) {
+	encryptionPub, err := curve25519.X25519(key.EncryptionKey, curve25519.Basepoint)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CosignerTransportPubKey{
+		EncryptionKey: encryptionPub,
+		SigningKey:    key.SigningKey.Public().(ed25519.PublicKey),
+	}, nil
+}
+
+// Validate returns an error if the key sizes are wrong
+func (key *CosignerTransportKey) Validate() error {
+	if len(key.EncryptionKey) != curve25519.ScalarSize {
+		return errors.New("x25519 key must be 32 bytes")
+	}
+	if len(key.SigningKey) != ed25519.PrivateKeySize {
+		return errors.New("ed25519 key must be 64 bytes")
+	}
+	return nil
+}
+
+// Validate returns an error if the key sizes are wrong
+func (pub *CosignerTransportPubKey) Validate() error {
+	if len(pub.EncryptionKey) != curve25519.PointSize {
+		return errors.New("x25519 public key must be 32 bytes")
+	}
+	if len(pub.SigningKey) != ed25519.PublicKeySize {
+		return errors.New("ed25519 public key must be 32 bytes")
+	}
+	return nil
+}
+
+// Seal encrypts plaintext so that only the owner of the public key can read it
+// A fresh X25519 key is generated for every message and prepended to the ciphertext.
+func (pub *CosignerTransportPubKey) Seal(plaintext []byte) ([]byte, error) {
+	ephemeralKey := make([]byte, curve25519.ScalarSize)
+	if _, err := rand.Read(ephemeralKey); err != nil {
+		return nil, err
+	}
+
+	ephemeralPub, err := curve25519.X25519(ephemeralKey, curve25519.Basepoint)
+	if err != nil {
+		return nil, err
+	}
+
+	shared, err := curve25519.X25519(ephemeralKey, pub.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := transportAEAD(shared, ephemeralPub, pub.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// the AEAD key is only ever used for this message
+	nonce := make([]byte, aead.NonceSize())
+	return aead.Seal(ephemeralPub, nonce, plaintext, nil), nil
+}
+
+// Open decrypts a ciphertext produced by Seal with our public key
+func (key *CosignerTransportKey) Open(ciphertext []byte) ([]byte, error) {
+	ourPub, err := curve25519.X25519(key.EncryptionKey, curve25519.Basepoint)
+	if err != nil {
+		return nil, err
+	}
+
+	return openSealed(ciphertext, ourPub, func(ephemeralPub []byte) ([]byte, error) {
+		return curve25519.X25519(key.EncryptionKey, ephemeralPub)
+	})
+}
+
+// Sign signs a digest with the Ed25519 key
+func (key *CosignerTransportKey) Sign(digest []byte) ([]byte, error) {
+	return ed25519.Sign(key.SigningKey, digest), nil
+}
+
+// openSealed decrypts a ciphertext produced by Seal for ourPub
+// sharedSecret computes the X25519 shared secret of our key with the ephemeral public key of the message.
+func openSealed(ciphertext []byte, ourPub []byte, sharedSecret func(ephemeralPub []byte) ([]byte, error)) ([]byte, error) {
+	if len(ciphertext) < curve25519.PointSize {
+		return nil, errors.New("ciphertext too short")
+	}
+
+	ephemeralPub := ciphertext[:curve25519.PointSize]
+	shared, err := sharedSecret(ephemeralPub)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := transportAEAD(shared, ephemeralPub, ourPub)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	return aead.Open(nil, nonce, ciphertext[curve25519.PointSize:], nil)
+}
+
+// transportAEAD derives the ChaCha20-Poly1305 key of a message from the X25519 shared secret
+func transportAEAD(shared []byte, ephemeralPub []byte, recipientPub []byte) (cipher.AEAD, error) {
+	salt := make([]byte, 0, len(ephemeralPub)+len(recipientPub))
+	salt = append(salt, ephemeralPub...)
+	salt = append(salt, recipientPub...)
+
+	aeadKey := make([]byte, chacha20poly1305.KeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, shared, salt, []byte(transportKeyInfo)), aeadKey); err != nil {
+		return nil, err
+	}
+
+	return chacha20poly1305.New(aeadKey)
+}
@@ -0,0 +1,44 @@
+package signer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCosignerTransportKeySealOpen(test *testing.T) {
+	key, err := GenerateCosignerTransportKey()
+	require.NoError(test, err)
+	require.NoError(test, key.Validate())
+
+	pub, err := key.PubKey()
+	require.NoError(test, err)
+	require.NoError(test, pub.Validate())
+
+	plaintext := []byte("ephemeral share part")
+	ciphertext, err := pub.Seal(plaintext)
+	require.NoError(test, err)
+
+	opened, err := key.Open(ciphertext)
+	require.NoError(test, err)
+	require.Equal(test, plaintext, opened)
+
+	// every message uses a fresh X25519 key
+	other, err := pub.Seal(plaintext)
+	require.NoError(test, err)
+	require.NotEqual(test, ciphertext, other)
+
+	// another key can't open the message
+	otherKey, err := GenerateCosignerTransportKey()
+	require.NoError(test, err)
+	_, err = otherKey.Open(ciphertext)
+	require.Error(test, err)
+
+	// tampering is detected
+	ciphertext[len(ciphertext)-1] ^= 1
+	_, err = key.Open(ciphertext)
+	require.Error(test, err)
+
+	_, err = key.Open([]byte{1, 2, 3})
+	require.Error(test, err)
+}
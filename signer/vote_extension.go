@@ -0,0 +1,206 @@
+package signer
+
+import (
+	"errors"
+	"fmt"
+
+	tmProto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Fields of the CometBFT 0.38 protos that the v0.34 protos don't know
+const (
+	// Vote
+	voteExtensionField          protowire.Number = 9
+	voteExtensionSignatureField protowire.Number = 10
+	// SignVoteRequest
+	skipExtensionSigningField protowire.Number = 3
+
+	// CanonicalVoteExtension
+	canonicalExtensionField protowire.Number = 1
+	canonicalHeightField    protowire.Number = 2
+	canonicalRoundField     protowire.Number = 3
+	canonicalChainIDField   protowire.Number = 4
+)
+
+// VoteExtension is the vote extension of a CometBFT 0.38 precommit, carried next to the v0.34 vote
+type VoteExtension struct {
+	Extension []byte
+	Signature []byte
+	// set by the node when the extension must not be signed
+	SkipSigning bool
+}
+
+// ExtensionPrivValidator is implemented by validators that sign the vote extensions of CometBFT 0.38
+type ExtensionPrivValidator interface {
+	SignVoteExtensionFrom(source string, chainID string, vote *tmProto.Vote, extension []byte) ([]byte, error)
+}
+
+// VoteExtensionRequired returns true if the extension of the vote must be signed along with it
+// Only precommits for a block carry a signed extension.
+func VoteExtensionRequired(vote *tmProto.Vote) bool {
+	return vote.Type == tmProto.PrecommitType && len(vote.BlockID.Hash) > 0
+}
+
+// VoteExtensionSignBytes returns the CanonicalVoteExtension of the vote signed by the validator
+func VoteExtensionSignBytes(chainID string, vote *tmProto.Vote, extension []byte) []byte {
+	// proto3 leaves out the fields at their default value
+	var canonical []byte
+	if len(extension) > 0 {
+		canonical = protowire.AppendTag(canonical, canonicalExtensionField, protowire.BytesType)
+		canonical = protowire.AppendBytes(canonical, extension)
+	}
+	if vote.Height != 0 {
+		canonical = protowire.AppendTag(canonical, canonicalHeightField, protowire.Fixed64Type)
+		canonical = protowire.AppendFixed64(canonical, uint64(vote.Height))
+	}
+	if vote.Round != 0 {
+		canonical = protowire.AppendTag(canonical, canonicalRoundField, protowire.Fixed64Type)
+		canonical = protowire.AppendFixed64(canonical, uint64(int64(vote.Round)))
+	}
+	if chainID != "" {
+		canonical = protowire.AppendTag(canonical, canonicalChainIDField, protowire.BytesType)
+		canonical = protowire.AppendString(canonical, chainID)
+	}
+	return protowire.AppendBytes(nil, canonical)
+}
+
+// unpackVoteExtensionHRS returns the height and round of CanonicalVoteExtension sign bytes
+// The sign bytes of votes and proposals start with their type, a varint, and are never taken for an extension.
+func unpackVoteExtensionHRS(signBytes []byte) (height int64, round int64, err error) {
+	canonical, n := protowire.ConsumeBytes(signBytes)
+	if n < 0 || n != len(signBytes) {
+		return 0, 0, errors.New("sign bytes are not length delimited")
+	}
+
+	for len(canonical) > 0 {
+		num, typ, n := protowire.ConsumeTag(canonical)
+		if n < 0 {
+			return 0, 0, protowire.ParseError(n)
+		}
+		canonical = canonical[n:]
+
+		switch {
+		case num == canonicalExtensionField && typ == protowire.BytesType,
+			num == canonicalChainIDField && typ == protowire.BytesType:
+			_, n = protowire.ConsumeBytes(canonical)
+		case num == canonicalHeightField && typ == protowire.Fixed64Type:
+			var v uint64
+			v, n = protowire.ConsumeFixed64(canonical)
+			height = int64(v)
+		case num == canonicalRoundField && typ == protowire.Fixed64Type:
+			var v uint64
+			v, n = protowire.ConsumeFixed64(canonical)
+			round = int64(v)
+		default:
+			return 0, 0, fmt.Errorf("unexpected field %d of a vote extension", num)
+		}
+		if n < 0 {
+			return 0, 0, protowire.ParseError(n)
+		}
+		canonical = canonical[n:]
+	}
+	return height, round, nil
+}
+
+// protoField returns the value of the last occurrence of a field in an encoded message
+// Repeated occurrences of a scalar field replace the previous ones.
+func protoField(message []byte, field protowire.Number, fieldType protowire.Type) ([]byte, bool, error) {
+	var value []byte
+	found := false
+	for len(message) > 0 {
+		num, typ, n := protowire.ConsumeTag(message)
+		if n < 0 {
+			return nil, false, protowire.ParseError(n)
+		}
+		message = message[n:]
+
+		n = protowire.ConsumeFieldValue(num, typ, message)
+		if n < 0 {
+			return nil, false, protowire.ParseError(n)
+		}
+		if num == field && typ == fieldType {
+			value = message[:n]
+			found = true
+		}
+		message = message[n:]
+	}
+	return value, found, nil
+}
+
+// protoBytesField returns the value of a bytes or embedded message field of an encoded message
+func protoBytesField(message []byte, field protowire.Number) ([]byte, error) {
+	value, found, err := protoField(message, field, protowire.BytesType)
+	if err != nil || !found {
+		return nil, err
+	}
+	bytesValue, n := protowire.ConsumeBytes(value)
+	if n < 0 {
+		return nil, protowire.ParseError(n)
+	}
+	return bytesValue, nil
+}
+
+// voteExtensionOfRequest returns the vote extension of an encoded CometBFT 0.38 privval message
+// Messages other than a vote sign request have none.
+func voteExtensionOfRequest(message []byte) (*VoteExtension, error) {
+	const signVoteRequestField protowire.Number = 3
+
+	request, err := protoBytesField(message, signVoteRequestField)
+	if err != nil || request == nil {
+		return nil, err
+	}
+
+	ext := &VoteExtension{}
+	skip, found, err := protoField(request, skipExtensionSigningField, protowire.VarintType)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		v, _ := protowire.ConsumeVarint(skip)
+		ext.SkipSigning = protowire.DecodeBool(v)
+	}
+
+	vote, err := protoBytesField(request, 1)
+	if err != nil {
+		return nil, err
+	}
+	if ext.Extension, err = protoBytesField(vote, voteExtensionField); err != nil {
+		return nil, err
+	}
+	if ext.Signature, err = protoBytesField(vote, voteExtensionSignatureField); err != nil {
+		return nil, err
+	}
+	return ext, nil
+}
+
+// marshalSignedVoteResponseV038 encodes a signed vote response, with the vote extension of CometBFT 0.38
+func marshalSignedVoteResponseV038(vote *tmProto.Vote, remoteErr []byte, ext *VoteExtension) ([]byte, error) {
+	const signedVoteResponseField protowire.Number = 4
+
+	voteBytes, err := vote.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	if len(ext.Extension) > 0 {
+		voteBytes = protowire.AppendTag(voteBytes, voteExtensionField, protowire.BytesType)
+		voteBytes = protowire.AppendBytes(voteBytes, ext.Extension)
+	}
+	if len(ext.Signature) > 0 {
+		voteBytes = protowire.AppendTag(voteBytes, voteExtensionSignatureField, protowire.BytesType)
+		voteBytes = protowire.AppendBytes(voteBytes, ext.Signature)
+	}
+
+	var response []byte
+	response = protowire.AppendTag(response, 1, protowire.BytesType)
+	response = protowire.AppendBytes(response, voteBytes)
+	if remoteErr != nil {
+		response = protowire.AppendTag(response, 2, protowire.BytesType)
+		response = protowire.AppendBytes(response, remoteErr)
+	}
+
+	var message []byte
+	message = protowire.AppendTag(message, signedVoteResponseField, protowire.BytesType)
+	message = protowire.AppendBytes(message, response)
+	return message, nil
+}
@@ -0,0 +1,301 @@
+package signer
+
+import (
+	"context"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	tmCrypto "github.com/tendermint/tendermint/crypto"
+	tmCryptoEd2219 "github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/libs/log"
+	tmProtoPrivval "github.com/tendermint/tendermint/proto/tendermint/privval"
+	tmProto "github.com/tendermint/tendermint/proto/tendermint/types"
+	tm "github.com/tendermint/tendermint/types"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// extensionMockPV signs vote extensions with the key of a MockPV
+type extensionMockPV struct {
+	tm.MockPV
+}
+
+func (pv extensionMockPV) SignVoteExtensionFrom(source string, chainID string, vote *tmProto.Vote, extension []byte) ([]byte, error) {
+	return pv.PrivKey.Sign(VoteExtensionSignBytes(chainID, vote, extension))
+}
+
+func testPrecommit(height int64, round int32) *tmProto.Vote {
+	return &tmProto.Vote{
+		Height:  height,
+		Round:   round,
+		Type:    tmProto.PrecommitType,
+		BlockID: tmProto.BlockID{Hash: []byte("block hash 32 bytes long........")},
+	}
+}
+
+func TestVoteExtensionSignBytes(test *testing.T) {
+	// the CometBFT 0.38 encoding of CanonicalVoteExtension{Extension: "ext", Height: 5, Round: 1, ChainId: "chain-id"}
+	signBytes := VoteExtensionSignBytes("chain-id", testPrecommit(5, 1), []byte("ext"))
+	require.Equal(test, "210a036578741105000000000000001901000000000000002208636861696e2d6964", hex.EncodeToString(signBytes))
+
+	height, round, step, err := UnpackHRS(signBytes)
+	require.NoError(test, err)
+	require.Equal(test, int64(5), height)
+	require.Equal(test, int64(1), round)
+	require.Equal(test, stepVoteExtension, step)
+
+	// the fields at their default value are left out
+	height, round, step, err = UnpackHRS(VoteExtensionSignBytes("chain-id", testPrecommit(5, 0), nil))
+	require.NoError(test, err)
+	require.Equal(test, int64(5), height)
+	require.Equal(test, int64(0), round)
+	require.Equal(test, stepVoteExtension, step)
+
+	// votes and proposals are not taken for extensions
+	_, _, step, err = UnpackHRS(tm.VoteSignBytes("chain-id", testPrecommit(5, 0)))
+	require.NoError(test, err)
+	require.Equal(test, stepPrecommit, step)
+	_, _, step, err = UnpackHRS(tm.ProposalSignBytes("chain-id", &tmProto.Proposal{Height: 5, Type: tmProto.ProposalType}))
+	require.NoError(test, err)
+	require.Equal(test, stepPropose, step)
+
+	require.True(test, VoteExtensionRequired(testPrecommit(5, 0)))
+	require.False(test, VoteExtensionRequired(&tmProto.Vote{Height: 5, Type: tmProto.PrecommitType}))
+	require.False(test, VoteExtensionRequired(&tmProto.Vote{Height: 5, Type: tmProto.PrevoteType, BlockID: testPrecommit(5, 0).BlockID}))
+}
+
+func TestThresholdValidatorVoteExtension(test *testing.T) {
+	privateKey, cosigners, cleanup := newTestCosigners(test, 2, 3)
+	defer cleanup()
+
+	stateFile, err := ioutil.TempFile("", "priv_validator_state.json")
+	require.NoError(test, err)
+	defer os.Remove(stateFile.Name())
+	signState, err := LoadOrCreateSignState(stateFile.Name())
+	require.NoError(test, err)
+
+	validator := NewThresholdValidator(&ThresholdValidatorOpt{
+		Pubkey:    privateKey.PubKey(),
+		Threshold: 2,
+		SignState: signState,
+		Cosigner:  cosigners[0],
+		Peers:     []Cosigner{cosigners[1], cosigners[2]},
+	})
+	pv := &PvGuard{PrivValidator: validator}
+
+	exchangeAllEphemeralSecretParts(test, cosigners, 1, 0, stepPrecommit)
+	vote := testPrecommit(1, 0)
+	require.NoError(test, pv.SignVote("chain-id", vote))
+	require.True(test, privateKey.PubKey().VerifySignature(tm.VoteSignBytes("chain-id", vote), vote.Signature))
+
+	// the extension has ephemeral secrets of its own, it is signed under the watermark of the precommit
+	exchangeAllEphemeralSecretParts(test, cosigners, 1, 0, stepVoteExtension)
+	signature, err := pv.SignVoteExtensionFrom("tcp://node:1234", "chain-id", vote, []byte("ext"))
+	require.NoError(test, err)
+	require.True(test, privateKey.PubKey().VerifySignature(VoteExtensionSignBytes("chain-id", vote, []byte("ext")), signature))
+	require.Equal(test, stepPrecommit, validator.lastSignState.Step)
+	require.Equal(test, signature, validator.lastSignState.ExtensionSignature)
+
+	saved, err := LoadSignState(stateFile.Name())
+	require.NoError(test, err)
+	require.Equal(test, stepPrecommit, saved.Step)
+	require.Equal(test, vote.Signature, saved.Signature)
+	require.Equal(test, signature, saved.ExtensionSignature)
+
+	// the same extension is signed again identically, another one is signed with new ephemeral secrets
+	again, err := validator.SignVoteExtension("chain-id", vote, []byte("ext"))
+	require.NoError(test, err)
+	require.Equal(test, signature, again)
+	exchangeAllEphemeralSecretParts(test, cosigners, 1, 0, stepVoteExtension)
+	other, err := validator.SignVoteExtension("chain-id", vote, []byte("other ext"))
+	require.NoError(test, err)
+	require.True(test, privateKey.PubKey().VerifySignature(VoteExtensionSignBytes("chain-id", vote, []byte("other ext")), other))
+	require.NotEqual(test, signature[:32], other[:32])
+	require.Equal(test, other, validator.lastSignState.ExtensionSignature)
+	require.Equal(test, stepPrecommit, validator.lastSignState.Step)
+
+	// the node restarts and asks for the first extension again
+	exchangeAllEphemeralSecretParts(test, cosigners, 1, 0, stepVoteExtension)
+	signature, err = validator.SignVoteExtension("chain-id", vote, []byte("ext"))
+	require.NoError(test, err)
+	require.True(test, privateKey.PubKey().VerifySignature(VoteExtensionSignBytes("chain-id", vote, []byte("ext")), signature))
+
+	// the precommit requested again once its extension is signed gets both signatures back
+	reRequested := testPrecommit(1, 0)
+	require.NoError(test, pv.SignVote("chain-id", reRequested))
+	require.Equal(test, vote.Signature, reRequested.Signature)
+	again, err = pv.SignVoteExtensionFrom("tcp://node:1234", "chain-id", reRequested, []byte("ext"))
+	require.NoError(test, err)
+	require.Equal(test, signature, again)
+
+	// the cosigners keep the share signatures of both too
+	for _, cosigner := range cosigners {
+		shareState := cosigner.lastSignState
+		require.Equal(test, stepPrecommit, shareState.Step)
+		require.NotEmpty(test, shareState.Signature)
+		require.NotEmpty(test, shareState.ExtensionSignature)
+
+		res, err := cosigner.Sign(context.Background(), &CosignerSignRequest{SignBytes: tm.VoteSignBytes("chain-id", vote)})
+		require.NoError(test, err)
+		require.Equal(test, shareState.Signature, res.Signature)
+		res, err = cosigner.Sign(context.Background(), &CosignerSignRequest{SignBytes: VoteExtensionSignBytes("chain-id", vote, []byte("ext"))})
+		require.NoError(test, err)
+		require.Equal(test, shareState.ExtensionSignature, res.Signature)
+	}
+
+	// an extension is only signed for the last precommit signed
+	_, err = validator.SignVoteExtension("chain-id", testPrecommit(2, 0), []byte("ext"))
+	require.Error(test, err)
+
+	exchangeAllEphemeralSecretParts(test, cosigners, 2, 0, stepPrevote)
+	prevote := &tmProto.Vote{Height: 2, Type: tmProto.PrevoteType, BlockID: vote.BlockID}
+	require.NoError(test, pv.SignVote("chain-id", prevote))
+	require.Empty(test, validator.lastSignState.ExtensionSignature)
+	_, err = validator.SignVoteExtension("chain-id", vote, []byte("ext"))
+	require.Error(test, err)
+
+	// a validator without vote extensions refuses them
+	_, err = (&PvGuard{PrivValidator: tm.NewMockPV()}).SignVoteExtensionFrom("", "chain-id", vote, []byte("ext"))
+	require.Error(test, err)
+}
+
+func TestLocalCosignerVoteExtensionRequiresPrecommit(test *testing.T) {
+	_, cosigners, cleanup := newTestCosigners(test, 2, 3)
+	defer cleanup()
+	cosigner := cosigners[0]
+
+	vote := testPrecommit(1, 0)
+	extensionSignBytes := VoteExtensionSignBytes("chain-id", vote, []byte("ext"))
+	exchangeAllEphemeralSecretParts(test, cosigners, 1, 0, stepVoteExtension)
+
+	// a cosigner that did not sign the precommit refuses its extension and keeps its watermark
+	_, err := cosigner.Sign(context.Background(), &CosignerSignRequest{SignBytes: extensionSignBytes})
+	require.Error(test, err)
+	require.Equal(test, int64(0), cosigner.lastSignState.Height)
+
+	exchangeAllEphemeralSecretParts(test, cosigners, 1, 0, stepPrecommit)
+	precommit, err := cosigner.Sign(context.Background(), &CosignerSignRequest{SignBytes: tm.VoteSignBytes("chain-id", vote)})
+	require.NoError(test, err)
+
+	// the extension must be for the chain of the precommit
+	_, err = cosigner.Sign(context.Background(), &CosignerSignRequest{SignBytes: VoteExtensionSignBytes("other-chain", vote, []byte("ext"))})
+	require.Error(test, err)
+
+	// once the precommit is signed its extension is, under the same watermark
+	_, err = cosigner.Sign(context.Background(), &CosignerSignRequest{SignBytes: extensionSignBytes})
+	require.NoError(test, err)
+	require.Equal(test, stepPrecommit, cosigner.lastSignState.Step)
+	require.Equal(test, tm.VoteSignBytes("chain-id", vote), []byte(cosigner.lastSignState.SignBytes))
+
+	res, err := cosigner.Sign(context.Background(), &CosignerSignRequest{SignBytes: tm.VoteSignBytes("chain-id", vote)})
+	require.NoError(test, err)
+	require.Equal(test, precommit.Signature, res.Signature)
+}
+
+// writeSignVoteRequestV038 sends a CometBFT 0.38 vote sign request with an extension
+func writeSignVoteRequestV038(test *testing.T, conn net.Conn, vote *tmProto.Vote, extension []byte, skip bool) {
+	voteBytes, err := vote.Marshal()
+	require.NoError(test, err)
+	voteBytes = protowire.AppendTag(voteBytes, voteExtensionField, protowire.BytesType)
+	voteBytes = protowire.AppendBytes(voteBytes, extension)
+
+	var request []byte
+	request = protowire.AppendTag(request, 1, protowire.BytesType)
+	request = protowire.AppendBytes(request, voteBytes)
+	request = protowire.AppendTag(request, 2, protowire.BytesType)
+	request = protowire.AppendString(request, "chain-id")
+	if skip {
+		request = protowire.AppendTag(request, skipExtensionSigningField, protowire.VarintType)
+		request = protowire.AppendVarint(request, protowire.EncodeBool(true))
+	}
+
+	var message []byte
+	message = protowire.AppendTag(message, 3, protowire.BytesType)
+	message = protowire.AppendBytes(message, request)
+	_, err = conn.Write(protowire.AppendBytes(nil, message))
+	require.NoError(test, err)
+}
+
+// readSignedVoteResponseV038 returns the vote and the extension signature of a CometBFT 0.38 vote response
+func readSignedVoteResponseV038(test *testing.T, conn net.Conn) (tmProto.Vote, []byte) {
+	length, err := readUvarint(conn)
+	require.NoError(test, err)
+	message := make([]byte, length)
+	_, err = io.ReadFull(conn, message)
+	require.NoError(test, err)
+
+	msg := tmProtoPrivval.Message{}
+	require.NoError(test, msg.Unmarshal(message))
+	response := msg.GetSignedVoteResponse()
+	require.NotNil(test, response)
+	require.Nil(test, response.Error)
+
+	responseBytes, err := protoBytesField(message, 4)
+	require.NoError(test, err)
+	voteBytes, err := protoBytesField(responseBytes, 1)
+	require.NoError(test, err)
+	extensionSignature, err := protoBytesField(voteBytes, voteExtensionSignatureField)
+	require.NoError(test, err)
+	return response.Vote, extensionSignature
+}
+
+func TestListenRemoteSignerV038(test *testing.T) {
+	nodeKey := tmCryptoEd2219.GenPrivKey()
+	privVal := extensionMockPV{tm.NewMockPV()}
+
+	rs := NewListenRemoteSigner("tcp://127.0.0.1:0", log.NewNopLogger(), "chain-id", privVal, tmCryptoEd2219.GenPrivKey(),
+		[]tmCrypto.PubKey{nodeKey.PubKey()}, PrivvalProtocolV038)
+	require.NoError(test, rs.Start())
+	defer rs.Stop()
+
+	conn := dialListenRemoteSigner(test, rs, nodeKey)
+	defer conn.Close()
+
+	// the precommit and its extension are both signed
+	vote := testPrecommit(1, 0)
+	writeSignVoteRequestV038(test, conn, vote, []byte("ext"), false)
+	signedVote, extensionSignature := readSignedVoteResponseV038(test, conn)
+	require.True(test, privVal.PrivKey.PubKey().VerifySignature(tm.VoteSignBytes("chain-id", vote), signedVote.Signature))
+	require.True(test, privVal.PrivKey.PubKey().VerifySignature(VoteExtensionSignBytes("chain-id", vote, []byte("ext")), extensionSignature))
+
+	// the precommit is signed again with a different extension, after a restart of the node
+	writeSignVoteRequestV038(test, conn, testPrecommit(1, 0), []byte("other ext"), false)
+	signedVote, extensionSignature = readSignedVoteResponseV038(test, conn)
+	require.True(test, privVal.PrivKey.PubKey().VerifySignature(tm.VoteSignBytes("chain-id", vote), signedVote.Signature))
+	require.True(test, privVal.PrivKey.PubKey().VerifySignature(VoteExtensionSignBytes("chain-id", vote, []byte("other ext")), extensionSignature))
+
+	// unless the node asks to skip it
+	writeSignVoteRequestV038(test, conn, testPrecommit(2, 0), []byte("ext"), true)
+	signedVote, extensionSignature = readSignedVoteResponseV038(test, conn)
+	require.NotEmpty(test, signedVote.Signature)
+	require.Empty(test, extensionSignature)
+
+	// the other requests are the same as in v0.34
+	require.NoError(test, WriteMsg(conn, tmProtoPrivval.Message{Sum: &tmProtoPrivval.Message_PingRequest{PingRequest: &tmProtoPrivval.PingRequest{}}}))
+	res, err := ReadMsg(conn)
+	require.NoError(test, err)
+	require.NotNil(test, res.GetPingResponse())
+}
+
+func TestHandleRequestVoteExtensionFailure(test *testing.T) {
+	// the mock validator can't sign vote extensions
+	privVal := tm.NewMockPV()
+	vote := testPrecommit(1, 0)
+	req := tmProtoPrivval.Message{Sum: &tmProtoPrivval.Message_SignVoteRequest{
+		SignVoteRequest: &tmProtoPrivval.SignVoteRequest{Vote: vote, ChainId: "chain-id"},
+	}}
+
+	// the precommit is still returned, only its extension is left unsigned
+	res, resExt, err := handleRequest(log.NewNopLogger(), "tcp://node:1234", "chain-id", privVal, req, &VoteExtension{Extension: []byte("ext")})
+	require.NoError(test, err)
+	response := res.GetSignedVoteResponse()
+	require.NotNil(test, response)
+	require.Nil(test, response.Error)
+	require.True(test, privVal.PrivKey.PubKey().VerifySignature(tm.VoteSignBytes("chain-id", vote), response.Vote.Signature))
+	require.Equal(test, []byte("ext"), resExt.Extension)
+	require.Empty(test, resExt.Signature)
+}
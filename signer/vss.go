@@ -0,0 +1,143 @@
+package signer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	tsed25519 "gitlab.com/polychainlabs/threshold-ed25519/pkg"
+)
+
+// vssDeal is a polynomial dealt to the cosigners with feldman commitments
+// Resharing and key generation deal one per dealer and give every cosigner the sum of its values.
+type vssDeal struct {
+	sourceID        int
+	commitments     [][]byte
+	encryptedShares [][]byte
+}
+
+// combineVSSDeals adds up the commitments of the deals and returns them along with the public key of the share of every cosigner
+func combineVSSDeals(deals []vssDeal, threshold int, total int) ([][]byte, [][]byte, error) {
+	summed := make([][]byte, threshold)
+	for _, deal := range deals {
+		if len(deal.commitments) != threshold || len(deal.encryptedShares) != total {
+			return nil, nil, fmt.Errorf("Deal of cosigner %d has the wrong size", deal.sourceID)
+		}
+
+		for k, commitment := range deal.commitments {
+			if !validElement(commitment) {
+				return nil, nil, fmt.Errorf("Deal of cosigner %d has an invalid commitment", deal.sourceID)
+			}
+			if summed[k] == nil {
+				summed[k] = commitment
+			} else {
+				summed[k] = tsed25519.AddElements([]tsed25519.Element{summed[k], commitment})
+			}
+		}
+	}
+
+	sharePubKeys := make([][]byte, total)
+	for idx := range sharePubKeys {
+		var err error
+		sharePubKeys[idx], err = feldmanEvaluate(summed, idx+1)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return summed, sharePubKeys, nil
+}
+
+// openVSSDeals decrypts the values dealt to cosigner id, checks them against the commitments and adds them up
+func openVSSDeals(deals []vssDeal, id int, transportKey *CosignerTransportKey, transportPubKeys []*CosignerTransportPubKey) (tsed25519.Scalar, error) {
+	if id < 1 || id > len(transportPubKeys) {
+		return nil, fmt.Errorf("Unexpected cosigner ID %d", id)
+	}
+
+	transportPubKey, err := transportKey.PubKey()
+	if err != nil {
+		return nil, err
+	}
+	if !equalTransportPubKeys([]*CosignerTransportPubKey{transportPubKey}, transportPubKeys[id-1:id]) {
+		return nil, fmt.Errorf("The transport key doesn't belong to cosigner %d", id)
+	}
+
+	parts := make([]tsed25519.Scalar, len(deals))
+	for idx, deal := range deals {
+		part, err := transportKey.Open(deal.encryptedShares[id-1])
+		if err != nil {
+			return nil, fmt.Errorf("Error decrypting the deal of cosigner %d: %v", deal.sourceID, err)
+		}
+		if !verifyFeldmanShare(deal.commitments, id, part) {
+			return nil, fmt.Errorf("Deal of cosigner %d doesn't match its commitments", deal.sourceID)
+		}
+		parts[idx] = part
+	}
+	return tsed25519.AddScalars(parts), nil
+}
+
+// verifySharePubKeys checks that every threshold consecutive share public keys combine into the validator public key
+func verifySharePubKeys(pubKey []byte, threshold int, sharePubKeys [][]byte) error {
+	for start := 0; start+threshold <= len(sharePubKeys); start++ {
+		ids := make([]int, threshold)
+		for idx := range ids {
+			ids[idx] = start + idx + 1
+		}
+
+		combined, err := interpolateElements(ids, sharePubKeys[start:start+threshold])
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(combined, pubKey) {
+			return fmt.Errorf("The shares of cosigners %v don't combine into the validator public key", ids)
+		}
+	}
+	return nil
+}
+
+func validateTransportPubKeys(pubs []*CosignerTransportPubKey) error {
+	if len(pubs) == 0 {
+		return errors.New("No transport public keys")
+	}
+	for _, pub := range pubs {
+		if pub == nil {
+			return errors.New("Missing transport public key")
+		}
+		if err := pub.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func containsID(ids []int, id int) bool {
+	for _, other := range ids {
+		if other == id {
+			return true
+		}
+	}
+	return false
+}
+
+func equalIDs(a []int, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for idx := range a {
+		if a[idx] != b[idx] {
+			return false
+		}
+	}
+	return true
+}
+
+func equalTransportPubKeys(a []*CosignerTransportPubKey, b []*CosignerTransportPubKey) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for idx := range a {
+		if !bytes.Equal(a[idx].EncryptionKey, b[idx].EncryptionKey) || !bytes.Equal(a[idx].SigningKey, b[idx].SigningKey) {
+			return false
+		}
+	}
+	return true
+}
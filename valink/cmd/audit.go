@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"tendermint-signer/signer"
+)
+
+func init() {
+	auditCmd.AddCommand(VerifyAuditCmd())
+	auditCmd.AddCommand(SearchAuditCmd())
+	rootCmd.AddCommand(auditCmd)
+}
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Inspect the audit log of a cosigner",
+	Long: `Inspect the audit log of a cosigner, <chain_id>_audit.jsonl in its state directory.
+Every share signature of the cosigner and every block signature it combined is recorded there,
+chained to the previous record by its hash.`,
+}
+
+// VerifyAuditCmd is a cobra command for checking the hash chain of audit logs
+func VerifyAuditCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify [audit_log] ...",
+		Args:  cobra.MinimumNArgs(1),
+		Short: "Check that no record of the audit logs was changed or removed",
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			for _, file := range args {
+				records, err := signer.ReadAuditLog(file)
+				if err != nil {
+					return err
+				}
+				if err := signer.VerifyAuditLog(records); err != nil {
+					return fmt.Errorf("%v: %v", file, err)
+				}
+
+				if len(records) == 0 {
+					fmt.Printf("%s: no records\n", file)
+					continue
+				}
+				last := records[len(records)-1]
+				fmt.Printf("%s: %d records verified, last hash %s\n", file, len(records), last.Hash)
+			}
+			return nil
+		},
+	}
+	return cmd
+}
+
+// SearchAuditCmd is a cobra command for listing the records of an audit log
+func SearchAuditCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "search [audit_log]",
+		Args:  cobra.ExactArgs(1),
+		Short: "List the signatures of an audit log matching the flags",
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			filter := signer.AuditFilter{}
+			if height, _ := cmd.Flags().GetInt64("height"); height != 0 {
+				filter.MinHeight = height
+				filter.MaxHeight = height
+			} else {
+				filter.MinHeight, _ = cmd.Flags().GetInt64("min-height")
+				filter.MaxHeight, _ = cmd.Flags().GetInt64("max-height")
+			}
+			if cmd.Flags().Changed("round") {
+				round, _ := cmd.Flags().GetInt64("round")
+				filter.Round = &round
+			}
+			if step, _ := cmd.Flags().GetString("step"); step != "" {
+				filter.Step, err = signer.ParseStep(step)
+				if err != nil {
+					return err
+				}
+			}
+			filter.Signer, _ = cmd.Flags().GetString("signer")
+			filter.CosignerID, _ = cmd.Flags().GetInt("cosigner")
+			filter.ChainID, _ = cmd.Flags().GetString("chain-id")
+			filter.Source, _ = cmd.Flags().GetString("source")
+			if hash, _ := cmd.Flags().GetString("sign-bytes-hash"); hash != "" {
+				filter.SignBytesHash, err = hex.DecodeString(hash)
+				if err != nil {
+					return fmt.Errorf("Invalid sign bytes hash: %v", err)
+				}
+			}
+			jsonOutput, _ := cmd.Flags().GetBool("json")
+
+			records, err := signer.ReadAuditLog(args[0])
+			if err != nil {
+				return err
+			}
+			// the records are listed anyway, they may be all that is left to look at
+			if err := signer.VerifyAuditLog(records); err != nil {
+				fmt.Fprintf(os.Stderr, "WARNING the audit log doesn't verify: %v\n", err)
+			}
+
+			encoder := json.NewEncoder(os.Stdout)
+			for _, record := range records {
+				if !filter.Match(record) {
+					continue
+				}
+				if jsonOutput {
+					if err := encoder.Encode(record); err != nil {
+						return err
+					}
+					continue
+				}
+				fmt.Printf("%d %s %s %d %s %d/%d/%s %s cosigners=%v source=%q\n",
+					record.Seq, record.Time.Format(time.RFC3339Nano), record.Signer, record.CosignerID, record.ChainID,
+					record.Height, record.Round, signer.StepName(record.Step), record.SignBytesHash, record.Cosigners, record.Source)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().Int64("height", 0, "only the signatures at this height")
+	cmd.Flags().Int64("min-height", 0, "only the signatures at this height or above")
+	cmd.Flags().Int64("max-height", 0, "only the signatures at this height or below")
+	cmd.Flags().Int64("round", 0, "only the signatures at this round")
+	cmd.Flags().String("step", "", "only the signatures at this step: propose, prevote, precommit or extension")
+	cmd.Flags().String("signer", "", "only the share signatures (cosigner) or the block signatures (validator)")
+	cmd.Flags().Int("cosigner", 0, "only the signatures of the process of this cosigner ID")
+	cmd.Flags().String("chain-id", "", "only the signatures for this chain")
+	cmd.Flags().String("source", "", "only the signatures requested by this node or cosigner")
+	cmd.Flags().String("sign-bytes-hash", "", "only the signatures of the sign bytes with this SHA-256 hash, in hex")
+	cmd.Flags().Bool("json", false, "print the matching records as JSON lines")
+	return cmd
+}
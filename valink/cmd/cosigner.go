@@ -1,14 +1,14 @@
 package cmd
 
 import (
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"log"
-	"net"
 	"os"
 	"path"
 	"runtime/pprof"
 	"sync"
-	"time"
 
 	"github.com/spf13/cobra"
 
@@ -41,9 +41,15 @@ func StartCosignerCmd() *cobra.Command {
 				log.Fatal(err)
 			}
 
+			chainConfigs, err := config.ChainConfigs()
+			if err != nil {
+				log.Fatal(err)
+			}
+
 			profile, _ := cmd.Flags().GetBool("profile")
 			if profile == true {
-				cpuprofile := fmt.Sprintf("%s/cosigner.prof", config.PrivValStateDir)
+				// the state directory of the first chain also holds the profile of the process
+				cpuprofile := fmt.Sprintf("%s/cosigner.prof", chainConfigs[0].PrivValStateDir)
 				f, err := os.Create(cpuprofile)
 				if err != nil {
 					log.Fatal(err)
@@ -60,129 +66,163 @@ func StartCosignerCmd() *cobra.Command {
 				"Tendermint Validator",
 				"mode", config.Mode,
 				"priv-key", config.PrivValKeyFile,
+				"key-backend", config.KeyBackend,
 				"priv-state-dir", config.PrivValStateDir,
+				"chains", len(config.Chains),
 			)
 
 			// services to stop on shutdown
 			var services []tmService.Service
 
-			var pv types.PrivValidator
-
-			chainID := config.ChainID
-			if chainID == "" {
-				log.Fatal("chain_id option is required")
-			}
-
 			logger.Info("Mode: mpc")
-			if config.CosignerThreshold == 0 {
-				log.Fatal("The `cosigner_threshold` option is required in `threshold` mode")
-			}
-
 			if config.ListenAddress == "" {
 				log.Fatal("The cosigner_listen_address option is required in `threshold` mode")
 			}
 
-			key, err := signer.LoadCosignerKey(config.PrivValKeyFile)
-			if err != nil {
-				panic(err)
+			if err := config.Signing.Validate(); err != nil {
+				log.Fatal(err)
 			}
 
-			// ok to auto initialize on disk since the cosigner share is the one that actually
-			// protects against double sign - this exists as a cache for the final signature
-			stateFile := path.Join(config.PrivValStateDir, fmt.Sprintf("%s_priv_validator_state.json", chainID))
-			signState, err := signer.LoadOrCreateSignState(stateFile)
-			if err != nil {
-				panic(err)
+			if err := config.SignState.Validate(); err != nil {
+				log.Fatal(err)
 			}
 
-			sss, _ := cmd.Flags().GetBool("sss")
-			// state for our cosigner share
-			// Not automatically initialized on disk to avoid double sign risk
-			shareStateFile := path.Join(config.PrivValStateDir, fmt.Sprintf("%s_share_sign_state.json", chainID))
-			var shareSignState signer.SignState
-			if sss {
-				shareSignState, err = signer.LoadOrCreateSignState(shareStateFile)
-			} else {
-				shareSignState, err = signer.LoadSignState(shareStateFile)
-			}
-			if err != nil {
-				panic(err)
+			if err := config.Raft.Validate(); err != nil {
+				log.Fatal(err)
 			}
 
-			cosigners := []signer.Cosigner{}
-			remoteCosigners := []signer.RemoteCosigner{}
+			// a single connection to each of the other cosigners is shared by the chains
+			remoteCosigners := []*signer.RemoteCosigner{}
+			for _, cosignerConfig := range config.Cosigners {
+				var cosigner *signer.RemoteCosigner
+				if config.TLS.Enabled() {
+					tlsConfig, err := config.TLS.ClientTLSConfig(cosignerConfig.TLSServerName())
+					if err != nil {
+						log.Fatal(err)
+					}
+					cosigner = signer.NewRemoteCosignerWithTLS(cosignerConfig.ID, cosignerConfig.Address, tlsConfig)
+				} else {
+					cosigner = signer.NewRemoteCosigner(cosignerConfig.ID, cosignerConfig.Address)
+				}
+				remoteCosigners = append(remoteCosigners, cosigner)
+			}
 
-			// add ourselves as a peer so localcosigner can handle GetEphSecPart requests
-			peers := []signer.CosignerPeer{{
-				ID:        key.ID,
-				PublicKey: key.RSAKey.PublicKey,
-			}}
+			sss, _ := cmd.Flags().GetBool("sss")
 
-			for _, cosignerConfig := range config.Cosigners {
-				cosigner := signer.NewRemoteCosigner(cosignerConfig.ID, cosignerConfig.Address)
-				cosigners = append(cosigners, cosigner)
-				remoteCosigners = append(remoteCosigners, *cosigner)
+			chains := make([]*cosignerChain, 0, len(chainConfigs))
+			rpcChains := make([]signer.CosignerRpcChain, 0, len(chainConfigs))
+			for _, chainConfig := range chainConfigs {
+				chain, err := newCosignerChain(logger.With("chain_id", chainConfig.ChainID), chainConfig, remoteCosigners, sss)
+				if err != nil {
+					log.Fatalf("chain %s: %v", chainConfig.ChainID, err)
+				}
+				defer chain.close()
 
-				if cosignerConfig.ID < 1 || cosignerConfig.ID > len(key.CosignerKeys) {
-					log.Fatalf("Unexpected cosigner ID %d", cosignerConfig.ID)
+				// the other cosigners know us by a single ID, the same for all the chains
+				if len(chains) > 0 && chain.key.ID != chains[0].key.ID {
+					log.Fatalf("chain %s: the key has ID %d, the key of chain %s has ID %d", chainConfig.ChainID, chain.key.ID, chains[0].config.ChainID, chains[0].key.ID)
 				}
 
-				pubKey := key.CosignerKeys[cosignerConfig.ID-1]
-				peers = append(peers, signer.CosignerPeer{
-					ID:        cosigner.GetID(),
-					PublicKey: *pubKey,
-				})
+				if chain.preDealer != nil {
+					services = append(services, chain.preDealer)
+				}
+				chains = append(chains, chain)
 			}
 
-			total := len(config.Cosigners) + 1
-			localCosignerConfig := signer.LocalCosignerConfig{
-				CosignerKey: key,
-				SignState:   &shareSignState,
-				RsaKey:      key.RSAKey,
-				Peers:       peers,
-				Total:       uint8(total),
-				Threshold:   uint8(config.CosignerThreshold),
+			// with raft, only the elected leader signs and the watermarks are replicated to the others
+			// the followers forward the blocks of their nodes to the leader
+			var election signer.LeaderElection
+			var raftElection *signer.RaftElection
+			if config.Raft.Enabled() {
+				raftElection, err = signer.NewRaftElection(config, chains[0].key.ID, chainConfigs[0].PrivValStateDir, logger)
+				if err != nil {
+					log.Fatal(err)
+				}
+				election = raftElection
+			} else {
+				logger.Info("raft is not configured, every cosigner connected to the nodes signs blocks")
 			}
 
-			localCosigner := signer.NewLocalCosigner(localCosignerConfig)
+			for _, chain := range chains {
+				chain.newValidator(election)
+				if raftElection != nil {
+					if err := raftElection.Register(chain.config.ChainID, chain.validator); err != nil {
+						log.Fatal(err)
+					}
+				}
 
-			val := signer.NewThresholdValidator(&signer.ThresholdValidatorOpt{
-				Pubkey:    key.PubKey,
-				Threshold: config.CosignerThreshold,
-				SignState: signState,
-				Cosigner:  localCosigner,
-				Peers:     cosigners,
-			})
+				rpcChain := signer.CosignerRpcChain{
+					ChainID:       chain.config.ChainID,
+					LocalCosigner: chain.localCosigner,
+					Peers:         chain.peers,
+					PreDealer:     chain.preDealer,
+				}
+				if raftElection != nil {
+					rpcChain.Validator = chain.validator
+				}
+				rpcChains = append(rpcChains, rpcChain)
+			}
 
 			rpcServerConfig := signer.CosignerRpcServerConfig{
 				Logger:        logger,
 				ListenAddress: config.ListenAddress,
-				LocalCosigner: localCosigner,
-				Peers:         remoteCosigners,
+				Chains:        rpcChains,
+				Signing:       config.Signing,
+			}
+
+			if config.TLS.Enabled() {
+				rpcServerConfig.TLSConfig, err = config.TLS.ServerTLSConfig()
+				if err != nil {
+					log.Fatal(err)
+				}
+				rpcServerConfig.PeerNames = make(map[int]string)
+				for _, cosignerConfig := range config.Cosigners {
+					rpcServerConfig.PeerNames[cosignerConfig.ID] = cosignerConfig.TLSServerName()
+				}
+
+				// our own certificate is accepted too, for the admin commands run next to the cosigner
+				ownName, err := config.TLS.CertificateName()
+				if err != nil {
+					log.Fatal(err)
+				}
+				rpcServerConfig.PeerNames[chains[0].key.ID] = ownName
+			} else {
+				logger.Info("tls is not configured, cosigner traffic is not encrypted")
 			}
 
 			rpcServer := signer.NewCosignerRpcServer(&rpcServerConfig)
 			rpcServer.Start()
 			services = append(services, rpcServer)
 
-			pv = &signer.PvGuard{PrivValidator: val}
-
-			pubkey, err := pv.GetPubKey()
+			// the nodes can pin the key the process presents on the privval connections
+			signerKey, err := signer.LoadOrGenSignerKey(chainConfigs[0].SignerKeyPath())
 			if err != nil {
 				log.Fatal(err)
 			}
-			logger.Info("Signer", "pubkey", pubkey)
+			logger.Info("Signer identity", "pub_key", base64.StdEncoding.EncodeToString(signerKey.PubKey().Bytes()))
 
-			for _, node := range config.Nodes {
-				dialer := net.Dialer{Timeout: 30 * time.Second}
-				signer := signer.NewReconnRemoteSigner(node.Address, logger, config.ChainID, pv, dialer)
+			for _, chain := range chains {
+				var pv types.PrivValidator = &signer.PvGuard{PrivValidator: chain.validator}
 
-				err := signer.Start()
+				pubkey, err := pv.GetPubKey()
 				if err != nil {
-					panic(err)
+					log.Fatal(err)
 				}
+				logger.Info("Signer", "chain_id", chain.config.ChainID, "pubkey", pubkey)
 
-				services = append(services, signer)
+				for _, node := range chain.config.Nodes {
+					signer, err := signer.NewNodeRemoteSigner(node, signerKey, logger, chain.config.ChainID, pv)
+					if err != nil {
+						log.Fatal(err)
+					}
+
+					err = signer.Start()
+					if err != nil {
+						panic(err)
+					}
+
+					services = append(services, signer)
+				}
 			}
 
 			wg := sync.WaitGroup{}
@@ -194,6 +234,16 @@ func StartCosignerCmd() *cobra.Command {
 						panic(err)
 					}
 				}
+				if raftElection != nil {
+					if err := raftElection.Shutdown(); err != nil {
+						logger.Error("Failed to leave the raft election", "error", err)
+					}
+				}
+				for _, cosigner := range remoteCosigners {
+					if err := cosigner.Close(); err != nil {
+						logger.Error("Failed to close cosigner connection", "id", cosigner.GetID(), "error", err)
+					}
+				}
 				if profile == true {
 					pprof.StopCPUProfile()
 				}
@@ -222,3 +272,193 @@ func validateCosignerStart(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// cosignerChain is the validator of a chain hosted by the cosigner process
+type cosignerChain struct {
+	config         signer.Config
+	keyBackend     signer.KeyBackend
+	key            signer.CosignerKey
+	signState      signer.SignState
+	shareSignState signer.SignState
+	auditLog       *signer.AuditLog
+	localCosigner  *signer.LocalCosigner
+	peers          []*signer.RemoteCosigner
+	preDealer      *signer.EphemeralPreDealer
+	validator      *signer.ThresholdValidator
+}
+
+// newCosignerChain loads the key share and the sign states of the chain
+// The validator is built by newValidator once the leader election is set up.
+// The requests to the other cosigners go over the connections of remoteCosigners.
+// The share sign state is only created on disk if createShareState is set.
+func newCosignerChain(logger tmlog.Logger, config signer.Config, remoteCosigners []*signer.RemoteCosigner, createShareState bool) (chain *cosignerChain, err error) {
+	chainID := config.ChainID
+	if config.CosignerThreshold == 0 {
+		return nil, errors.New("The `cosigner_threshold` option is required in `threshold` mode")
+	}
+
+	chain = &cosignerChain{config: config}
+	// whatever was opened is closed again if the chain can't be built
+	defer func() {
+		if err != nil {
+			chain.close()
+		}
+	}()
+
+	chain.keyBackend, err = signer.NewKeyBackend(config)
+	if err != nil {
+		return chain, err
+	}
+
+	chain.key, err = chain.keyBackend.Load()
+	if err != nil {
+		return chain, err
+	}
+	key := chain.key
+
+	transport, err := chain.keyBackend.Transport(&key)
+	if err != nil {
+		return chain, err
+	}
+
+	// ok to auto initialize on disk since the cosigner share is the one that actually
+	// protects against double sign - this exists as a cache for the final signature
+	stateFile, shareStateFile := signStateFiles(config)
+	chain.signState, err = config.SignState.Load(stateFile, true)
+	if err != nil {
+		return chain, err
+	}
+
+	// state for our cosigner share
+	// Not automatically initialized on disk to avoid double sign risk
+	chain.shareSignState, err = config.SignState.Load(shareStateFile, createShareState)
+	if err != nil {
+		return chain, err
+	}
+
+	// the share was checked against the share public keys and commitments when the key file was loaded
+	if len(key.SharePubKeys) == 0 {
+		logger.Info("The key file has no share public keys, share signatures can't be verified one by one")
+	} else if len(key.Commitments) == 0 {
+		logger.Info("The key file has no commitments, the share public keys can't be checked against the validator key")
+	}
+
+	if key.IsLegacy() {
+		logger.Info("The key file uses RSA transport keys, run `valink migrate-keys` to switch to X25519")
+	}
+
+	// add ourselves as a peer so localcosigner can handle GetEphSecPart requests
+	ourPeer, err := key.CosignerPeer(key.ID)
+	if err != nil {
+		return chain, err
+	}
+	peers := []signer.CosignerPeer{ourPeer}
+
+	cosigners := []signer.Cosigner{}
+	for _, remoteCosigner := range remoteCosigners {
+		cosigner := remoteCosigner.ForChain(chainID)
+		cosigners = append(cosigners, cosigner)
+		chain.peers = append(chain.peers, cosigner)
+
+		peer, err := key.CosignerPeer(cosigner.GetID())
+		if err != nil {
+			return chain, err
+		}
+		peers = append(peers, peer)
+	}
+
+	// every share and block signature of the process is recorded, see `valink audit`
+	chain.auditLog, err = signer.OpenAuditLog(path.Join(config.PrivValStateDir, fmt.Sprintf("%s_audit.jsonl", chainID)))
+	if err != nil {
+		return chain, err
+	}
+
+	total := len(remoteCosigners) + 1
+	localCosignerConfig := signer.LocalCosignerConfig{
+		CosignerKey: key,
+		SignState:   &chain.shareSignState,
+		RsaKey:      key.RSAKey,
+		Peers:       peers,
+		Total:       uint8(total),
+		Threshold:   uint8(config.CosignerThreshold),
+
+		ChainID:            chainID,
+		MinProtocolVersion: config.Signing.MinProtocolVersion,
+		Transport:          transport,
+		KeyBackend:         chain.keyBackend,
+		AuditLog:           chain.auditLog,
+	}
+
+	chain.localCosigner = signer.NewLocalCosigner(localCosignerConfig)
+
+	// the ephemeral secrets and parts handed out before a restart are restored, not dealt again
+	// the journal is sealed to the transport key, RSA transport keys keep them in memory only
+	if key.IsLegacy() {
+		logger.Info("The nonce journal is disabled with RSA transport keys, parts handed out before a restart are dealt again")
+	} else {
+		nonceJournalFile := path.Join(config.PrivValStateDir, fmt.Sprintf("%s_nonce_journal.jsonl", chainID))
+		if err := chain.localCosigner.OpenNonceJournal(nonceJournalFile); err != nil {
+			return chain, err
+		}
+	}
+
+	// deal and exchange the ephemeral secret parts of the upcoming heights in the background
+	if config.Signing.PreDealWindow > 0 {
+		chain.preDealer = signer.NewEphemeralPreDealer(&signer.EphemeralPreDealerConfig{
+			Logger:        logger,
+			LocalCosigner: chain.localCosigner,
+			Peers:         cosigners,
+			Window:        config.Signing.PreDealWindow,
+			Signing:       config.Signing,
+		})
+		if err := chain.preDealer.Start(); err != nil {
+			return chain, err
+		}
+	}
+
+	return chain, nil
+}
+
+// newValidator builds the validator of the chain
+// If election is set, the validator only signs while this cosigner is the leader.
+func (chain *cosignerChain) newValidator(election signer.LeaderElection) {
+	cosigners := make([]signer.Cosigner, 0, len(chain.peers))
+	for _, peer := range chain.peers {
+		cosigners = append(cosigners, peer)
+	}
+
+	chain.validator = signer.NewThresholdValidator(&signer.ThresholdValidatorOpt{
+		Pubkey:    chain.key.PubKey,
+		Threshold: chain.config.CosignerThreshold,
+		SignState: chain.signState,
+		Cosigner:  chain.localCosigner,
+		Peers:     cosigners,
+		Signing:   chain.config.Signing,
+		PreDealer: chain.preDealer,
+		AuditLog:  chain.auditLog,
+		Election:  election,
+	})
+}
+
+// close releases the key backend, the sign states and the audit log of the chain
+func (chain *cosignerChain) close() {
+	if chain.auditLog != nil {
+		chain.auditLog.Close()
+	}
+	chain.shareSignState.Close()
+	chain.signState.Close()
+	if chain.keyBackend != nil {
+		chain.keyBackend.Close()
+	}
+}
+
+// loadChainConfig loads a cosigner config file and returns the config of the chain picked with the
+// --chain-id flag of cmd, which may be left out if the cosigner hosts a single chain
+func loadChainConfig(cmd *cobra.Command, file string) (signer.Config, error) {
+	config, err := signer.LoadConfigFromFile(file)
+	if err != nil {
+		return config, err
+	}
+	chainID, _ := cmd.Flags().GetString("chain-id")
+	return config.ForChain(chainID)
+}
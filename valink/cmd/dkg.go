@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	tmCryptoEd25519 "github.com/tendermint/tendermint/crypto/ed25519"
+
+	"tendermint-signer/signer"
+)
+
+func init() {
+	dkgCmd.AddCommand(transportKeygenCmd("dkg"))
+	dkgCmd.AddCommand(DKGDealCmd())
+	dkgCmd.AddCommand(DKGVerifyCmd())
+	dkgCmd.AddCommand(DKGCombineCmd())
+	cosignerCmd.AddCommand(dkgCmd)
+}
+
+var dkgCmd = &cobra.Command{
+	Use:   "dkg",
+	Short: "Generate a new validator key split between the cosigners, without a dealer",
+	Long: `Generate a new validator key split between the cosigners, without a dealer.
+No machine ever holds the validator key. The files can be exchanged over any channel, including air-gapped ones.
+
+1. every cosigner runs "dkg keygen" and hands out its public transport key file
+2. every cosigner runs "dkg deal" with the public transport key files of all the cosigners
+3. anyone can check the deal files with "dkg verify"
+4. every cosigner runs "dkg combine" with all the deal files to get its share file`,
+}
+
+func readDKGDeals(files []string) ([]*signer.DKGDeal, error) {
+	deals := make([]*signer.DKGDeal, len(files))
+	for idx, file := range files {
+		deals[idx] = &signer.DKGDeal{}
+		if err := readJSONFile(file, deals[idx]); err != nil {
+			return nil, err
+		}
+	}
+	return deals, nil
+}
+
+// DKGDealCmd is a cobra command for dealing the random secret of a cosigner to all the cosigners
+func DKGDealCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "deal [dkg_transport_1.pub.json] ... [dkg_transport_n.pub.json]",
+		Args:  cobra.MinimumNArgs(2),
+		Short: "Deal a random secret to all the cosigners",
+		Long: `Deal a random secret to all the cosigners.
+Every cosigner must deal with the same threshold and public transport key files.
+The deal file only holds encrypted shares and can be sent to the other cosigners over any channel.`,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			transportKeyFile, _ := cmd.Flags().GetString("transport-key")
+			threshold, _ := cmd.Flags().GetUint8("threshold")
+			output, _ := cmd.Flags().GetString("output")
+
+			transportKey, err := readTransportKey(transportKeyFile)
+			if err != nil {
+				return err
+			}
+
+			transportPubKeys, err := readTransportPubKeys(args)
+			if err != nil {
+				return err
+			}
+
+			deal, err := signer.DealDKG(transportKey.ID, threshold, transportKey.TransportKey, transportPubKeys)
+			if err != nil {
+				return err
+			}
+
+			if output == "" {
+				output = fmt.Sprintf("dkg_deal_%d.json", transportKey.ID)
+			}
+			if err := writeJSONFile(output, deal, 0644); err != nil {
+				return err
+			}
+			fmt.Printf("Created %s\n", output)
+			return nil
+		},
+	}
+	cmd.Flags().String("transport-key", "", "private transport key file of the cosigner")
+	cmd.Flags().Uint8("threshold", 0, "number of cosigners required to sign")
+	cmd.Flags().String("output", "", "deal file, defaults to dkg_deal_<id>.json")
+	return cmd
+}
+
+// DKGVerifyCmd is a cobra command for checking the deal files of a key generation
+func DKGVerifyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify [dkg_deal_1.json] ... [dkg_deal_n.json]",
+		Args:  cobra.MinimumNArgs(1),
+		Short: "Check the deals of a key generation and print the validator public key",
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			deals, err := readDKGDeals(args)
+			if err != nil {
+				return err
+			}
+
+			pubKey, sharePubKeys, err := signer.VerifyDKG(deals)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Validator public key: %X\n", pubKey)
+			fmt.Printf("Validator public key (base64): %s\n", base64.StdEncoding.EncodeToString(pubKey))
+			fmt.Printf("Validator address: %s\n", tmCryptoEd25519.PubKey(pubKey).Address())
+			fmt.Printf("Any %d of the %d shares can sign\n", deals[0].Threshold, len(sharePubKeys))
+			for idx, sharePubKey := range sharePubKeys {
+				fmt.Printf("Share %d public key: %s\n", idx+1, hex.EncodeToString(sharePubKey))
+			}
+			return nil
+		},
+	}
+	return cmd
+}
+
+// DKGCombineCmd is a cobra command for creating the share file of a cosigner from the deals
+func DKGCombineCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "combine [dkg_deal_1.json] ... [dkg_deal_n.json]",
+		Args:  cobra.MinimumNArgs(1),
+		Short: "Create the share file of a cosigner from the deals",
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			transportKeyFile, _ := cmd.Flags().GetString("transport-key")
+			outputDir, _ := cmd.Flags().GetString("output-dir")
+
+			transportKey, err := readTransportKey(transportKeyFile)
+			if err != nil {
+				return err
+			}
+
+			deals, err := readDKGDeals(args)
+			if err != nil {
+				return err
+			}
+
+			key, err := signer.CombineDKG(deals, transportKey.ID, transportKey.TransportKey)
+			if err != nil {
+				return err
+			}
+			return writeShareFile(outputDir, key)
+		},
+	}
+	cmd.Flags().String("transport-key", "", "private transport key file of the cosigner")
+	cmd.Flags().String("output-dir", ".", "directory where the share file is written")
+	return cmd
+}
@@ -5,8 +5,6 @@ import (
 
 	"github.com/spf13/cobra"
 
-	"crypto/rand"
-	"crypto/rsa"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -36,6 +34,8 @@ func CreateCosignerSharesCmd() *cobra.Command {
 			keyFilePath := args[0]
 			threshold, _ := strconv.ParseInt(args[1], 10, 64)
 			total, _ := strconv.ParseInt(args[2], 10, 64)
+			encrypt, _ := cmd.Flags().GetBool("encrypt")
+			passphraseFile, _ := cmd.Flags().GetString("passphrase-file")
 
 			keyJSONBytes, err := ioutil.ReadFile(keyFilePath)
 			if err != nil {
@@ -62,20 +62,29 @@ func CreateCosignerSharesCmd() *cobra.Command {
 				panic("Not an ed25519 private key")
 			}
 
-			// generate shares from secret
-			shares := tsed25519.DealShares(tsed25519.ExpandSecret(privKeyBytes[:32]), uint8(threshold), uint8(total))
-
-			// generate all rsa keys
-			rsaKeys := make([]*rsa.PrivateKey, len(shares))
-			pubkeys := make([]*rsa.PublicKey, len(shares))
-			for idx := range shares {
-				bitSize := 4096
-				rsaKey, err := rsa.GenerateKey(rand.Reader, bitSize)
+			var passphrase []byte
+			if encrypt {
+				passphrase, err = newKeyPassphrase(passphraseFile)
 				if err != nil {
-					panic(err)
+					return err
 				}
-				rsaKeys[idx] = rsaKey
-				pubkeys[idx] = &rsaKey.PublicKey
+			}
+
+			// generate shares from secret, along with the commitments every cosigner checks its share against
+			shares, commitments, err := signer.DealShares(tsed25519.ExpandSecret(privKeyBytes[:32]), uint8(threshold), uint8(total))
+			if err != nil {
+				tmOS.Exit(err.Error())
+			}
+
+			// generate all transport keys
+			transportKeys, transportPubKeys, err := generateTransportKeys(len(shares))
+			if err != nil {
+				panic(err)
+			}
+
+			sharePubKeys := make([][]byte, len(shares))
+			for idx, share := range shares {
+				sharePubKeys[idx] = signer.SharePubKey(share)
 			}
 
 			// write shares and keys to private share files
@@ -85,11 +94,13 @@ func CreateCosignerSharesCmd() *cobra.Command {
 				privateFilename := fmt.Sprintf("private_share_%d.json", shareID)
 
 				cosignerKey := signer.CosignerKey{
-					PubKey:       pvKey.PubKey,
-					ShareKey:     share,
-					ID:           shareID,
-					RSAKey:       *rsaKeys[idx],
-					CosignerKeys: pubkeys,
+					PubKey:           pvKey.PubKey,
+					ShareKey:         share,
+					ID:               shareID,
+					TransportKey:     transportKeys[idx],
+					TransportPubKeys: transportPubKeys,
+					SharePubKeys:     sharePubKeys,
+					Commitments:      commitments,
 				}
 
 				jsonBytes, err := json.MarshalIndent(&cosignerKey, "", "  ")
@@ -97,7 +108,14 @@ func CreateCosignerSharesCmd() *cobra.Command {
 					panic(err)
 				}
 
-				err = ioutil.WriteFile(privateFilename, jsonBytes, 0644)
+				if encrypt {
+					jsonBytes, err = signer.EncryptKey(jsonBytes, passphrase)
+					if err != nil {
+						return err
+					}
+				}
+
+				err = ioutil.WriteFile(privateFilename, jsonBytes, 0600)
 				if err != nil {
 					panic(err)
 				}
@@ -106,6 +124,8 @@ func CreateCosignerSharesCmd() *cobra.Command {
 			return nil
 		},
 	}
+	cmd.Flags().Bool("encrypt", false, "encrypt the share files with a passphrase")
+	cmd.Flags().String("passphrase-file", "", "file holding the passphrase of the share files")
 	return cmd
 }
 
@@ -123,4 +143,22 @@ func validateCreateCosignerShares(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("threshold must be an integer got(%s)", args[2])
 	}
 	return nil
-}
\ No newline at end of file
+}
+// generateTransportKeys returns a transport key for each of the total cosigners and the list of their public keys
+func generateTransportKeys(total int) ([]*signer.CosignerTransportKey, []*signer.CosignerTransportPubKey, error) {
+	keys := make([]*signer.CosignerTransportKey, total)
+	pubKeys := make([]*signer.CosignerTransportPubKey, total)
+	for idx := range keys {
+		key, err := signer.GenerateCosignerTransportKey()
+		if err != nil {
+			return nil, nil, err
+		}
+		pubKey, err := key.PubKey()
+		if err != nil {
+			return nil, nil, err
+		}
+		keys[idx] = key
+		pubKeys[idx] = pubKey
+	}
+	return keys, pubKeys, nil
+}
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/tendermint/tendermint/libs/tempfile"
+
+	"tendermint-signer/signer"
+)
+
+func init() {
+	keysCmd.AddCommand(EncryptKeysCmd())
+	keysCmd.AddCommand(DecryptKeysCmd())
+	keysCmd.AddCommand(ImportPKCS11Cmd())
+	rootCmd.AddCommand(keysCmd)
+}
+
+var keysCmd = &cobra.Command{
+	Use:   "keys",
+	Short: "Manage the storage of key files",
+	Long: `Manage the storage of key files.
+The passphrase is read from --passphrase-file, the VALINK_KEY_PASSPHRASE environment variable or asked for.`,
+}
+
+// newKeyPassphrase returns the passphrase to encrypt new key files with
+// A passphrase typed in is asked for twice to catch typos.
+func newKeyPassphrase(passphraseFile string) ([]byte, error) {
+	if _, ok := os.LookupEnv(signer.KeyPassphraseEnv); passphraseFile != "" || ok {
+		return signer.KeyPassphrase(passphraseFile)("")
+	}
+
+	passphrase, err := signer.PromptPassphrase("New passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	confirmation, err := signer.PromptPassphrase("Repeat the passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(passphrase, confirmation) {
+		return nil, errors.New("The passphrases don't match")
+	}
+	return passphrase, nil
+}
+
+// EncryptKeysCmd is a cobra command for encrypting key files in place
+func EncryptKeysCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "encrypt [key_file] ...",
+		Args:  cobra.MinimumNArgs(1),
+		Short: "Encrypt key files in place with a passphrase",
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			passphraseFile, _ := cmd.Flags().GetString("passphrase-file")
+
+			for _, file := range args {
+				keyBytes, err := ioutil.ReadFile(file)
+				if err != nil {
+					return err
+				}
+				if signer.IsEncryptedKey(keyBytes) {
+					return fmt.Errorf("%v is already encrypted", file)
+				}
+			}
+
+			passphrase, err := newKeyPassphrase(passphraseFile)
+			if err != nil {
+				return err
+			}
+
+			for _, file := range args {
+				keyBytes, err := ioutil.ReadFile(file)
+				if err != nil {
+					return err
+				}
+				encrypted, err := signer.EncryptKey(keyBytes, passphrase)
+				if err != nil {
+					return err
+				}
+				if err := tempfile.WriteFileAtomic(file, encrypted, 0600); err != nil {
+					return err
+				}
+				fmt.Printf("Encrypted %s\n", file)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().String("passphrase-file", "", "file holding the passphrase")
+	return cmd
+}
+
+// DecryptKeysCmd is a cobra command for decrypting key files in place
+func DecryptKeysCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "decrypt [key_file] ...",
+		Args:  cobra.MinimumNArgs(1),
+		Short: "Decrypt key files in place",
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			passphraseFile, _ := cmd.Flags().GetString("passphrase-file")
+			passphrase := signer.KeyPassphrase(passphraseFile)
+
+			for _, file := range args {
+				keyBytes, err := ioutil.ReadFile(file)
+				if err != nil {
+					return err
+				}
+				if !signer.IsEncryptedKey(keyBytes) {
+					return fmt.Errorf("%v is not encrypted", file)
+				}
+
+				filePassphrase, err := passphrase(file)
+				if err != nil {
+					return err
+				}
+				decrypted, err := signer.DecryptKey(keyBytes, filePassphrase)
+				if err != nil {
+					return fmt.Errorf("Error decrypting %v: %v", file, err)
+				}
+				if err := tempfile.WriteFileAtomic(file, decrypted, 0600); err != nil {
+					return err
+				}
+				fmt.Printf("Decrypted %s\n", file)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().String("passphrase-file", "", "file holding the passphrase")
+	return cmd
+}
+
+// ImportPKCS11Cmd is a cobra command for moving a share file to a PKCS#11 token
+func ImportPKCS11Cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import-pkcs11 [config.toml] [private_share.json]",
+		Args:  cobra.ExactArgs(2),
+		Short: "Store a share file on the PKCS#11 token of a cosigner",
+		Long: `Store a share file on the PKCS#11 token set in the [pkcs11] section of the cosigner config.
+The transport keys become non-extractable keys of the token. Destroy the share file once the cosigner
+is started with key_backend = "pkcs11".`,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			passphraseFile, _ := cmd.Flags().GetString("passphrase-file")
+
+			config, err := loadChainConfig(cmd, args[0])
+			if err != nil {
+				return err
+			}
+
+			key, err := signer.LoadCosignerKeyWithPassphrase(args[1], signer.KeyPassphrase(passphraseFile))
+			if err != nil {
+				return err
+			}
+
+			if err := signer.ImportPKCS11Key(config.PKCS11, &key); err != nil {
+				return err
+			}
+			fmt.Printf("Stored share %d on token %s as %s\n", key.ID, config.PKCS11.TokenLabel, config.PKCS11.KeyLabel)
+			return nil
+		},
+	}
+	cmd.Flags().String("passphrase-file", "", "file holding the passphrase of an encrypted share file")
+	cmd.Flags().String("chain-id", "", "chain whose [pkcs11] section is used, required if the cosigner hosts several chains")
+	return cmd
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	tmOS "github.com/tendermint/tendermint/libs/os"
+
+	"tendermint-signer/signer"
+)
+
+func init() {
+	migrateKeysCmd.AddCommand(transportKeygenCmd("migrate"))
+	migrateKeysCmd.AddCommand(MigrateKeysApplyCmd())
+	rootCmd.AddCommand(migrateKeysCmd)
+}
+
+var migrateKeysCmd = &cobra.Command{
+	Use:   "migrate-keys",
+	Short: "Replace the RSA transport keys of a cluster with X25519 and Ed25519 keys",
+	Long: `Replace the transport keys of every share of a cluster, without gathering the shares in one place.
+
+1. every cosigner runs "migrate-keys keygen" and hands out its public transport key file
+2. every cosigner runs "migrate-keys apply" on its own share file with the public transport key files of all the cosigners
+3. the migrated share files are deployed to all the cosigners at the same time`,
+}
+
+// MigrateKeysApplyCmd is a cobra command for replacing the transport keys of the share of a cosigner
+func MigrateKeysApplyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "apply [private_share.json] [migrate_transport_1.pub.json] ... [migrate_transport_n.pub.json]",
+		Args:  cobra.MinimumNArgs(2),
+		Short: "Replace the transport keys of the share of a cosigner",
+		Long: `Replace the transport keys of the share of a cosigner with its new transport key and the public transport keys of all the cosigners.
+The secret share is left untouched. The migrated file is written to private_share_<id>.json in the output directory,
+encrypted with the same passphrase as the share file if it is encrypted.`,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			transportKeyFile, _ := cmd.Flags().GetString("transport-key")
+			outputDir, _ := cmd.Flags().GetString("output-dir")
+			if transportKeyFile == "" {
+				return fmt.Errorf("--transport-key is required")
+			}
+
+			key, err := signer.LoadCosignerKey(args[0])
+			if err != nil {
+				return fmt.Errorf("Error reading share from %v: %v", args[0], err)
+			}
+
+			transportKey, err := readTransportKey(transportKeyFile)
+			if err != nil {
+				return err
+			}
+			if transportKey.ID != key.ID {
+				return fmt.Errorf("%v is the transport key of cosigner %d, the share belongs to cosigner %d", transportKeyFile, transportKey.ID, key.ID)
+			}
+
+			transportPubKeys, err := readTransportPubKeys(args[1:])
+			if err != nil {
+				return err
+			}
+
+			migrated, err := signer.MigrateCosignerKey(&key, transportKey.TransportKey, transportPubKeys)
+			if err != nil {
+				return err
+			}
+
+			// don't overwrite the legacy file, it is the only copy of the share until the migrated one is deployed
+			output := filepath.Join(outputDir, fmt.Sprintf("private_share_%d.json", key.ID))
+			if tmOS.FileExists(output) {
+				return fmt.Errorf("%v already exists", output)
+			}
+			if err := signer.SaveCosignerKey(output, migrated); err != nil {
+				return err
+			}
+			fmt.Printf("Migrated Share %d to %s\n", key.ID, output)
+			return nil
+		},
+	}
+	cmd.Flags().String("transport-key", "", "private transport key file created by migrate-keys keygen")
+	cmd.Flags().String("output-dir", ".", "directory where the migrated share file is written")
+	return cmd
+}
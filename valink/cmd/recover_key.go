@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/spf13/cobra"
+	tmjson "github.com/tendermint/tendermint/libs/json"
+	tmOS "github.com/tendermint/tendermint/libs/os"
+	"github.com/tendermint/tendermint/privval"
+
+	"tendermint-signer/signer"
+)
+
+func init() {
+	rootCmd.AddCommand(RecoverKeyCmd())
+}
+
+// RecoverKeyCmd is a cobra command for combining cosigner shares back into a priv validator key
+func RecoverKeyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "recover-key [private_share_1.json] ... [private_share_n.json]",
+		Args:  cobra.MinimumNArgs(1),
+		Short: "Combine threshold shares into a priv validator key",
+		Long: `Combine the shares of at least threshold cosigners into a priv validator key.
+The shares must belong to the same validator and the combined key is checked against its public key.
+
+The original seed of the key can't be recovered from the shares, so the key file holds the secret scalar
+instead. It can be used by "valink signer start", not by a stock tendermint node.`,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			output, _ := cmd.Flags().GetString("output")
+			if tmOS.FileExists(output) {
+				return fmt.Errorf("%v already exists", output)
+			}
+
+			keys := make([]signer.CosignerKey, len(args))
+			for idx, file := range args {
+				keys[idx], err = signer.LoadCosignerKey(file)
+				if err != nil {
+					return fmt.Errorf("Error reading share from %v: %v", file, err)
+				}
+			}
+
+			privKey, err := signer.RecoverPrivKey(keys)
+			if err != nil {
+				return err
+			}
+
+			pubKey := privKey.PubKey()
+			jsonBytes, err := tmjson.MarshalIndent(privval.FilePVKey{
+				Address: pubKey.Address(),
+				PubKey:  pubKey,
+				PrivKey: privKey,
+			}, "", "  ")
+			if err != nil {
+				return err
+			}
+
+			err = ioutil.WriteFile(output, jsonBytes, 0600)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Recovered validator %s to %s\n", pubKey.Address(), output)
+			return nil
+		},
+	}
+	cmd.Flags().String("output", "priv_validator_key.json", "priv validator key file to write")
+	return cmd
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	tmlog "github.com/tendermint/tendermint/libs/log"
+	tmnet "github.com/tendermint/tendermint/libs/net"
+
+	"tendermint-signer/signer"
+)
+
+func init() {
+	cosignerCmd.AddCommand(RefreshSharesCmd())
+}
+
+// localDialAddress returns the address to dial a server listening on listenAddress from the same host
+func localDialAddress(listenAddress string) string {
+	_, address := tmnet.ProtocolAndAddress(listenAddress)
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return address
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// RefreshSharesCmd is a cobra command for re-randomizing the shares of a running cluster
+func RefreshSharesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "refresh-shares [config.toml]",
+		Short: "Re-randomize the shares of all the cosigners without changing the validator key",
+		Long: `Re-randomize the shares of all the cosigners without changing the validator key.
+Run it next to any cosigner of the cluster, with the config of that cosigner. Every cosigner must be online.
+The cosigners keep signing with their current share until a block at the activation height is signed,
+so pick a height a few blocks ahead of the chain.`,
+		Args: validateCosignerStart,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			config, err := loadChainConfig(cmd, args[0])
+			if err != nil {
+				return err
+			}
+
+			activationHeight, _ := cmd.Flags().GetInt64("activation-height")
+			if activationHeight <= 0 {
+				return fmt.Errorf("--activation-height is required")
+			}
+			timeout, _ := cmd.Flags().GetDuration("timeout")
+			address, _ := cmd.Flags().GetString("address")
+			if address == "" {
+				address = localDialAddress(config.ListenAddress)
+			}
+
+			keyBackend, err := signer.NewKeyBackend(config)
+			if err != nil {
+				return err
+			}
+			key, err := keyBackend.Load()
+			keyBackend.Close()
+			if err != nil {
+				return err
+			}
+
+			logger := tmlog.NewTMLogger(
+				tmlog.NewSyncWriter(os.Stdout),
+			).With("moniker", config.Moniker)
+
+			// our own cosigner is reached through its rpc server, like the others
+			cosigners := append([]signer.CosignerConfig{{
+				ID:      key.ID,
+				Address: address,
+			}}, config.Cosigners...)
+
+			refreshers := make([]signer.ShareRefresher, 0, len(cosigners))
+			for _, cosignerConfig := range cosigners {
+				var cosigner *signer.RemoteCosigner
+				if config.TLS.Enabled() {
+					serverName := cosignerConfig.TLSServerName()
+					if cosignerConfig.ID == key.ID {
+						serverName, err = config.TLS.CertificateName()
+						if err != nil {
+							return err
+						}
+					}
+
+					tlsConfig, err := config.TLS.ClientTLSConfig(serverName)
+					if err != nil {
+						return err
+					}
+					cosigner = signer.NewRemoteCosignerWithTLS(cosignerConfig.ID, cosignerConfig.Address, tlsConfig)
+				} else {
+					cosigner = signer.NewRemoteCosigner(cosignerConfig.ID, cosignerConfig.Address)
+				}
+				defer cosigner.Close()
+
+				refreshers = append(refreshers, cosigner.ForChain(config.ChainID))
+			}
+
+			refreshIDBytes := make([]byte, 16)
+			if _, err := rand.Read(refreshIDBytes); err != nil {
+				return err
+			}
+			refreshID := hex.EncodeToString(refreshIDBytes)
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			return signer.RefreshShares(ctx, logger, refreshID, activationHeight, refreshers)
+		},
+	}
+
+	cmd.Flags().Int64("activation-height", 0, "first height signed with the refreshed shares")
+	cmd.Flags().Duration("timeout", 30*time.Second, "time budget of the whole refresh")
+	cmd.Flags().String("address", "", "address of our own cosigner, defaults to cosigner_listen_address")
+	cmd.Flags().String("chain-id", "", "chain whose shares are refreshed, required if the cosigner hosts several chains")
+
+	return cmd
+}
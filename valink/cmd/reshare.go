@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"tendermint-signer/signer"
+)
+
+func init() {
+	reshareCmd.AddCommand(transportKeygenCmd("reshare"))
+	reshareCmd.AddCommand(ReshareDealCmd())
+	reshareCmd.AddCommand(ReshareVerifyCmd())
+	reshareCmd.AddCommand(ReshareCombineCmd())
+	cosignerCmd.AddCommand(reshareCmd)
+}
+
+var reshareCmd = &cobra.Command{
+	Use:   "reshare",
+	Short: "Move the validator key to a new set of cosigners without assembling it",
+	Long: `Move the validator key to a new set of cosigners with a new threshold and total, without assembling it.
+
+1. every new cosigner runs "reshare keygen" and hands out its public transport key file
+2. at least threshold current cosigners run "reshare deal" with the public transport key files of all the new cosigners
+3. anyone can check the deal files with "reshare verify"
+4. every new cosigner runs "reshare combine" with all the deal files to get its share file`,
+}
+
+func readReshareDeals(files []string) ([]*signer.ReshareDeal, error) {
+	deals := make([]*signer.ReshareDeal, len(files))
+	for idx, file := range files {
+		deals[idx] = &signer.ReshareDeal{}
+		if err := readJSONFile(file, deals[idx]); err != nil {
+			return nil, err
+		}
+	}
+	return deals, nil
+}
+
+// ReshareDealCmd is a cobra command for dealing the share of a current cosigner to the new cosigners
+func ReshareDealCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "deal [private_share.json] [reshare_transport_1.pub.json] ... [reshare_transport_n.pub.json]",
+		Args:  cobra.MinimumNArgs(3),
+		Short: "Deal the share of a current cosigner to the new cosigners",
+		Long: `Deal the share of a current cosigner to the new cosigners.
+Every cosigner listed in --dealers must deal with the same flags and public transport key files.
+The deal file only holds encrypted shares and can be sent to the new cosigners over any channel.`,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			dealers, _ := cmd.Flags().GetIntSlice("dealers")
+			threshold, _ := cmd.Flags().GetUint8("threshold")
+			output, _ := cmd.Flags().GetString("output")
+
+			key, err := signer.LoadCosignerKey(args[0])
+			if err != nil {
+				return fmt.Errorf("Error reading share from %v: %v", args[0], err)
+			}
+
+			transportPubKeys, err := readTransportPubKeys(args[1:])
+			if err != nil {
+				return err
+			}
+
+			deal, err := signer.DealReshare(&key, dealers, threshold, transportPubKeys)
+			if err != nil {
+				return err
+			}
+
+			if output == "" {
+				output = fmt.Sprintf("reshare_deal_%d.json", key.ID)
+			}
+			if err := writeJSONFile(output, deal, 0644); err != nil {
+				return err
+			}
+			fmt.Printf("Created %s\n", output)
+			return nil
+		},
+	}
+	cmd.Flags().IntSlice("dealers", nil, "IDs of the current cosigners dealing, at least the current threshold")
+	cmd.Flags().Uint8("threshold", 0, "threshold of the new cosigners")
+	cmd.Flags().String("output", "", "deal file, defaults to reshare_deal_<id>.json")
+	return cmd
+}
+
+// ReshareVerifyCmd is a cobra command for checking the deal files of a resharing
+func ReshareVerifyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify [reshare_deal_1.json] ... [reshare_deal_n.json]",
+		Args:  cobra.MinimumNArgs(1),
+		Short: "Check that the deals of a resharing combine into the validator key",
+		Long: `Check that the deals of a resharing combine into the validator key.
+Only public data is used: the deals are checked against the share public keys of the current cosigners,
+and the public keys of the new shares against the validator public key.`,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			deals, err := readReshareDeals(args)
+			if err != nil {
+				return err
+			}
+
+			sharePubKeys, err := signer.VerifyReshare(deals)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Deals of cosigners %v combine into validator public key %X\n", deals[0].Dealers, deals[0].PubKey)
+			fmt.Printf("Any %d of the %d new shares can sign\n", deals[0].Threshold, len(sharePubKeys))
+			for idx, sharePubKey := range sharePubKeys {
+				fmt.Printf("Share %d public key: %s\n", idx+1, hex.EncodeToString(sharePubKey))
+			}
+			return nil
+		},
+	}
+	return cmd
+}
+
+// ReshareCombineCmd is a cobra command for creating the share file of a new cosigner from the deals
+func ReshareCombineCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "combine [reshare_deal_1.json] ... [reshare_deal_n.json]",
+		Args:  cobra.MinimumNArgs(1),
+		Short: "Create the share file of a new cosigner from the deals",
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			transportKeyFile, _ := cmd.Flags().GetString("transport-key")
+			outputDir, _ := cmd.Flags().GetString("output-dir")
+
+			transportKey, err := readTransportKey(transportKeyFile)
+			if err != nil {
+				return err
+			}
+
+			deals, err := readReshareDeals(args)
+			if err != nil {
+				return err
+			}
+
+			key, err := signer.CombineReshare(deals, transportKey.ID, transportKey.TransportKey)
+			if err != nil {
+				return err
+			}
+
+			return writeShareFile(outputDir, key)
+		},
+	}
+	cmd.Flags().String("transport-key", "", "private transport key file of the new cosigner")
+	cmd.Flags().String("output-dir", ".", "directory where the share file is written")
+	return cmd
+}
@@ -1,13 +1,12 @@
 package cmd
 
 import (
+	"encoding/base64"
 	"fmt"
 	"log"
-	"net"
 	"os"
 	"path"
 	"sync"
-	"time"
 
 	"github.com/spf13/cobra"
 
@@ -82,11 +81,20 @@ func StartSignerCmd() *cobra.Command {
 			}
 			logger.Info("Signer", "pubkey", pubkey)
 
+			// the nodes can pin the key the process presents on the privval connections
+			signerKey, err := signer.LoadOrGenSignerKey(config.SignerKeyPath())
+			if err != nil {
+				log.Fatal(err)
+			}
+			logger.Info("Signer identity", "pub_key", base64.StdEncoding.EncodeToString(signerKey.PubKey().Bytes()))
+
 			for _, node := range config.Nodes {
-				dialer := net.Dialer{Timeout: 30 * time.Second}
-				signer := signer.NewReconnRemoteSigner(node.Address, logger, config.ChainID, pv, dialer)
+				signer, err := signer.NewNodeRemoteSigner(node, signerKey, logger, config.ChainID, pv)
+				if err != nil {
+					log.Fatal(err)
+				}
 
-				err := signer.Start()
+				err = signer.Start()
 				if err != nil {
 					panic(err)
 				}
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/spf13/cobra"
+
+	"tendermint-signer/signer"
+)
+
+func init() {
+	stateCmd.AddCommand(ImportStateCmd())
+	stateCmd.AddCommand(ExportStateCmd())
+	rootCmd.AddCommand(stateCmd)
+}
+
+var stateCmd = &cobra.Command{
+	Use:   "state",
+	Short: "Move sign states between a tendermint FilePV and the cosigners",
+	Long: `Move sign states between a tendermint FilePV and the cosigners.
+Stop the cosigner before running these commands, its sign states must not move meanwhile.`,
+}
+
+// signStateFiles returns the validator and share sign state files of the chain in the state directory
+func signStateFiles(config signer.Config) (string, string) {
+	validatorFile := path.Join(config.PrivValStateDir, fmt.Sprintf("%s_priv_validator_state.json", config.ChainID))
+	shareFile := path.Join(config.PrivValStateDir, fmt.Sprintf("%s_share_sign_state.json", config.ChainID))
+	return validatorFile, shareFile
+}
+
+// loadStateConfig loads the cosigner config of the chain picked with --chain-id for the state commands
+func loadStateConfig(cmd *cobra.Command, file string) (signer.Config, error) {
+	config, err := loadChainConfig(cmd, file)
+	if err != nil {
+		return config, err
+	}
+	if config.ChainID == "" {
+		return config, fmt.Errorf("chain_id option is required")
+	}
+	return config, config.SignState.Validate()
+}
+
+// ImportStateCmd is a cobra command for seeding the sign states of a cosigner from a FilePV state
+func ImportStateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import [config.toml] [priv_validator_state.json]",
+		Args:  cobra.ExactArgs(2),
+		Short: "Raise the sign states of a cosigner to the watermark of a tendermint FilePV",
+		Long: `Raise the validator and share sign states of a cosigner to the watermark of the
+priv_validator_state.json of a tendermint FilePV. Sign states already at or above it are left as is.
+Run it on every cosigner with the state of the FilePV the validator is moved from.`,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			config, err := loadStateConfig(cmd, args[0])
+			if err != nil {
+				return err
+			}
+
+			filePVState, err := signer.LoadFilePVLastSignState(args[1])
+			if err != nil {
+				return err
+			}
+
+			validatorFile, shareFile := signStateFiles(config)
+			states := []struct {
+				file          string
+				withSignature bool
+			}{
+				// the block signature of the FilePV is valid for the validator, not for a share
+				{validatorFile, true},
+				{shareFile, false},
+			}
+
+			for _, state := range states {
+				signState, err := config.SignState.Load(state.file, true)
+				if err != nil {
+					return err
+				}
+
+				raised, err := signState.RaiseToFilePVState(filePVState, state.withSignature)
+				signState.Close()
+				if err != nil {
+					return fmt.Errorf("Error saving %v: %v", state.file, err)
+				}
+
+				if raised {
+					fmt.Printf("Raised %s to height %d round %d step %d\n", state.file, signState.Height, signState.Round, signState.Step)
+				} else {
+					fmt.Printf("Kept %s at height %d round %d step %d\n", state.file, signState.Height, signState.Round, signState.Step)
+				}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().String("chain-id", "", "chain to import into, required if the cosigner hosts several chains")
+	return cmd
+}
+
+// ExportStateCmd is a cobra command for writing the sign states of a cosigner as a FilePV state
+func ExportStateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export [config.toml]",
+		Args:  cobra.ExactArgs(1),
+		Short: "Write the highest sign state of a cosigner as a tendermint FilePV state",
+		Long: `Write the highest of the validator and share sign states of a cosigner as a
+priv_validator_state.json that a tendermint FilePV can load.
+Pass the files exported from the other cosigners with --merge to keep the highest watermark of the cluster.
+An existing output file is only replaced by a state at or above its own.`,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			output, _ := cmd.Flags().GetString("output")
+			mergeFiles, _ := cmd.Flags().GetStringSlice("merge")
+
+			config, err := loadStateConfig(cmd, args[0])
+			if err != nil {
+				return err
+			}
+
+			validatorFile, shareFile := signStateFiles(config)
+			validatorState, err := config.SignState.Load(validatorFile, false)
+			if err != nil {
+				return err
+			}
+			defer validatorState.Close()
+			shareState, err := config.SignState.Load(shareFile, false)
+			if err != nil {
+				return err
+			}
+			defer shareState.Close()
+
+			filePVState := signer.FilePVLastSignState(validatorState, shareState)
+			for _, file := range mergeFiles {
+				merged, err := signer.LoadFilePVLastSignState(file)
+				if err != nil {
+					return err
+				}
+				if signer.FilePVStateLess(filePVState, merged) {
+					filePVState = merged
+				}
+			}
+
+			if err := signer.SaveFilePVLastSignState(output, filePVState); err != nil {
+				return err
+			}
+			fmt.Printf("Exported height %d round %d step %d to %s\n", filePVState.Height, filePVState.Round, filePVState.Step, output)
+			return nil
+		},
+	}
+	cmd.Flags().String("output", "priv_validator_state.json", "FilePV state file to write")
+	cmd.Flags().StringSlice("merge", nil, "FilePV state files exported from the other cosigners")
+	cmd.Flags().String("chain-id", "", "chain to export, required if the cosigner hosts several chains")
+	return cmd
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	tmOS "github.com/tendermint/tendermint/libs/os"
+
+	"tendermint-signer/signer"
+)
+
+// transportKeyFile is the transport key file of a cosigner taking part in a resharing or a key generation
+// The public file has no private transport key.
+type transportKeyFile struct {
+	ID           int                             `json:"id"`
+	TransportKey *signer.CosignerTransportKey    `json:"transport_key,omitempty"`
+	TransportPub *signer.CosignerTransportPubKey `json:"transport_pub"`
+}
+
+func readJSONFile(file string, value interface{}) error {
+	jsonBytes, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(jsonBytes, value); err != nil {
+		return fmt.Errorf("Error reading %v: %v", file, err)
+	}
+	return nil
+}
+
+// writeJSONFile writes value to a new file, an existing file is never overwritten
+func writeJSONFile(file string, value interface{}, perm os.FileMode) error {
+	if tmOS.FileExists(file) {
+		return fmt.Errorf("%v already exists", file)
+	}
+	jsonBytes, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, jsonBytes, perm)
+}
+
+// readTransportKey reads a private transport key file
+func readTransportKey(file string) (*transportKeyFile, error) {
+	var transportKey transportKeyFile
+	if err := readJSONFile(file, &transportKey); err != nil {
+		return nil, err
+	}
+	if transportKey.TransportKey == nil {
+		return nil, fmt.Errorf("%v has no private transport key", file)
+	}
+	if err := transportKey.TransportKey.Validate(); err != nil {
+		return nil, err
+	}
+	return &transportKey, nil
+}
+
+// readTransportPubKeys reads the public transport key files of the cosigners 1 .. len(files)
+func readTransportPubKeys(files []string) ([]*signer.CosignerTransportPubKey, error) {
+	transportPubKeys := make([]*signer.CosignerTransportPubKey, len(files))
+	for _, file := range files {
+		var pub transportKeyFile
+		if err := readJSONFile(file, &pub); err != nil {
+			return nil, err
+		}
+		if pub.ID < 1 || pub.ID > len(transportPubKeys) || transportPubKeys[pub.ID-1] != nil {
+			return nil, fmt.Errorf("%v has an unexpected cosigner ID %d", file, pub.ID)
+		}
+		transportPubKeys[pub.ID-1] = pub.TransportPub
+	}
+	return transportPubKeys, nil
+}
+
+// writeShareFile writes the share file of a cosigner to private_share_<id>.json in outputDir
+func writeShareFile(outputDir string, key *signer.CosignerKey) error {
+	output := filepath.Join(outputDir, fmt.Sprintf("private_share_%d.json", key.ID))
+	if err := writeJSONFile(output, key, 0600); err != nil {
+		return err
+	}
+	fmt.Printf("Created Share %d in %s\n", key.ID, output)
+	return nil
+}
+
+// transportKeygenCmd is a cobra command for creating the transport key files of a cosigner
+// The files are named <prefix>_transport_<id>.json and <prefix>_transport_<id>.pub.json.
+func transportKeygenCmd(prefix string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "keygen",
+		Args:  cobra.NoArgs,
+		Short: "Create the transport key of a new cosigner",
+		Long: fmt.Sprintf(`Create the transport key of a new cosigner.
+%[1]s_transport_<id>.json is private and stays with the new cosigner, %[1]s_transport_<id>.pub.json goes to the dealers.`, prefix),
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			id, _ := cmd.Flags().GetInt("id")
+			outputDir, _ := cmd.Flags().GetString("output-dir")
+			if id < 1 {
+				return fmt.Errorf("--id is required")
+			}
+
+			transportKey, err := signer.GenerateCosignerTransportKey()
+			if err != nil {
+				return err
+			}
+			transportPub, err := transportKey.PubKey()
+			if err != nil {
+				return err
+			}
+
+			privateFile := filepath.Join(outputDir, fmt.Sprintf("%s_transport_%d.json", prefix, id))
+			publicFile := filepath.Join(outputDir, fmt.Sprintf("%s_transport_%d.pub.json", prefix, id))
+			if tmOS.FileExists(publicFile) {
+				return fmt.Errorf("%v already exists", publicFile)
+			}
+
+			err = writeJSONFile(privateFile, &transportKeyFile{ID: id, TransportKey: transportKey, TransportPub: transportPub}, 0600)
+			if err != nil {
+				return err
+			}
+			err = writeJSONFile(publicFile, &transportKeyFile{ID: id, TransportPub: transportPub}, 0644)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Created %s and %s\n", privateFile, publicFile)
+			return nil
+		},
+	}
+	cmd.Flags().Int("id", 0, "ID of the new cosigner")
+	cmd.Flags().String("output-dir", ".", "directory where the transport key files are written")
+	return cmd
+}